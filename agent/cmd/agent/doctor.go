@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/config"
+	"github.com/xyidactyl/agent/internal/database"
+	"github.com/xyidactyl/agent/internal/pterodactyl"
+	"github.com/xyidactyl/agent/internal/security"
+)
+
+// doctorTimeout bounds all network checks of a doctor run.
+const doctorTimeout = 2 * time.Minute
+
+// doctor collects the pass/fail checklist printed by `agent doctor`.
+type doctor struct {
+	failed int
+}
+
+func (d *doctor) check(name string, err error) bool {
+	if err != nil {
+		d.failed++
+		fmt.Printf("  [FAIL] %s: %v\n", name, err)
+		return false
+	}
+	fmt.Printf("  [ OK ] %s\n", name)
+	return true
+}
+
+// runDoctor verifies the configuration end to end without starting the monitor.
+// It returns the process exit code.
+func runDoctor(cfg *config.Config) int {
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+
+	d := &doctor{}
+	fmt.Println("XYIDactyl Agent doctor")
+	d.check("configuration loaded", nil)
+
+	db, err := openDoctorDB(cfg.DataDir, cfg.DBPath)
+	if d.check("database opens", err) {
+		db.Close()
+	}
+
+	crypto, err := security.NewCrypto(cfg.AgentSecret, cfg.AgentSecretPrev, cfg.CryptoSalt, cfg.CryptoInfo)
+	d.check("crypto initialized", err)
+
+	provider, err := newPushProvider(cfg)
+	if d.check(fmt.Sprintf("push provider %q configured", cfg.PushProvider), err) {
+		d.check("push provider self-test", provider.Validate(ctx))
+	}
+
+	panels, err := newPanels(cfg)
+	if !d.check("panel client configured", err) || crypto == nil {
+		return d.result()
+	}
+
+	var verifier *security.Crypto
+	if cfg.ControlRequireSig {
+		verifier = crypto
+	}
+	loader := newControlSource(cfg, verifier)
+	if !d.check("control.json loads", loader.LoadInitial()) {
+		return d.result()
+	}
+
+	cf := loader.Get()
+	if len(cf.Users) == 0 {
+		fmt.Println("  [ -- ] no users configured in control.json")
+	}
+	for _, user := range cf.Users {
+		client, err := panels.For(user.PanelURL)
+		if !d.check(fmt.Sprintf("user %s: panel client configured", user.UserUUID), err) {
+			continue
+		}
+		d.checkUser(ctx, client, crypto, user.UserUUID, user.APIKeyEncrypted, user.AllowedServers)
+	}
+
+	return d.result()
+}
+
+func openDoctorDB(dataDir, dbPath string) (*database.DB, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+	return database.Open(dbPath, false) // Never move a corrupt database aside from a diagnostic run
+}
+
+// checkUser decrypts a user's key, lists their servers and probes each allowed server.
+func (d *doctor) checkUser(ctx context.Context, client *pterodactyl.Client, crypto *security.Crypto, userUUID, encryptedKey string, allowed []string) {
+	apiKey, err := crypto.Decrypt(encryptedKey)
+	if !d.check(fmt.Sprintf("user %s: API key decrypts", userUUID), err) {
+		return
+	}
+
+	servers, err := client.ListServers(ctx, apiKey)
+	if !d.check(fmt.Sprintf("user %s: panel accepts API key (%d servers)", userUUID, len(servers)), err) {
+		return
+	}
+
+	known := make(map[string]bool)
+	for _, s := range servers {
+		known[s.Identifier] = true
+		known[s.UUID] = true
+	}
+
+	for _, serverID := range allowed {
+		name := fmt.Sprintf("user %s: server %s reachable", userUUID, serverID)
+		if !known[serverID] {
+			d.check(name, fmt.Errorf("not in the key's server list"))
+			continue
+		}
+		_, err := client.FetchResources(ctx, apiKey, serverID)
+		d.check(name, err)
+	}
+}
+
+func (d *doctor) result() int {
+	if d.failed > 0 {
+		fmt.Printf("%d check(s) failed\n", d.failed)
+		return 1
+	}
+	fmt.Println("All checks passed")
+	return 0
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/pterodactyl"
+	"github.com/xyidactyl/agent/internal/security"
+)
+
+// doctorPanel is a panel whose key "ptlc_good" lists srv-1 and srv-2; srv-2's
+// resources fail. Any other key is rejected.
+func doctorPanel(t *testing.T) *pterodactyl.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ptlc_good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/api/client":
+			fmt.Fprint(w, `{"data":[{"attributes":{"identifier":"srv-1"}},{"attributes":{"identifier":"srv-2"}}],"meta":{"pagination":{"total":2,"current_page":1,"total_pages":1}}}`)
+		case r.URL.Path == "/api/client/servers/srv-1/resources":
+			fmt.Fprint(w, `{"attributes":{"current_state":"running"}}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	c, err := pterodactyl.NewClient(srv.URL, pterodactyl.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func doctorCrypto(t *testing.T) *security.Crypto {
+	t.Helper()
+	c, err := security.NewCrypto("test-agent-secret-0123456789abcdef", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestDoctorCheckAndResult(t *testing.T) {
+	d := &doctor{}
+	if !d.check("passes", nil) || d.result() != 0 {
+		t.Fatal("a passing check failed the run")
+	}
+	if d.check("fails", errors.New("boom")) || d.result() != 1 {
+		t.Fatal("a failing check did not fail the run")
+	}
+}
+
+func TestDoctorCheckUser(t *testing.T) {
+	client := doctorPanel(t)
+	crypto := doctorCrypto(t)
+	good, err := crypto.Encrypt("ptlc_good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad, err := crypto.Encrypt("ptlc_revoked")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		allowed    []string
+		wantFailed int
+	}{
+		{"all reachable", good, []string{"srv-1"}, 0},
+		{"resources fail", good, []string{"srv-1", "srv-2"}, 1},
+		{"not in server list", good, []string{"srv-9"}, 1},
+		{"key does not decrypt", "not-encrypted", []string{"srv-1"}, 1},
+		{"panel rejects key", bad, []string{"srv-1"}, 1},
+	}
+	for _, tc := range tests {
+		d := &doctor{}
+		d.checkUser(context.Background(), client, crypto, "user-1", tc.key, tc.allowed)
+		if d.failed != tc.wantFailed {
+			t.Errorf("%s: %d failed checks, want %d", tc.name, d.failed, tc.wantFailed)
+		}
+	}
+}
+
+func TestOpenDoctorDB(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	db, err := openDoctorDB(dir, filepath.Join(dir, "agent.db"))
+	if err != nil {
+		t.Fatalf("openDoctorDB: %v", err)
+	}
+	db.Close()
+	if _, err := os.Stat(filepath.Join(dir, "agent.db")); err != nil {
+		t.Fatalf("database not created: %v", err)
+	}
+
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openDoctorDB(filepath.Join(blocker, "data"), filepath.Join(blocker, "data", "agent.db")); err == nil || !strings.Contains(err.Error(), "data dir") {
+		t.Fatalf("err = %v, want the data dir failure", err)
+	}
+}
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/xyidactyl/agent/internal/api"
+	"github.com/xyidactyl/agent/internal/clock"
 	"github.com/xyidactyl/agent/internal/config"
 	"github.com/xyidactyl/agent/internal/control"
 	"github.com/xyidactyl/agent/internal/database"
@@ -18,6 +26,9 @@ import (
 
 const version = "1.0.0"
 
+// drainTimeout bounds how long shutdown waits for in-flight automation actions.
+const drainTimeout = 20 * time.Second
+
 func main() {
 	// --- Load Config ---
 	cfg, err := config.Load()
@@ -26,8 +37,18 @@ func main() {
 		os.Exit(1)
 	}
 
+	// `agent healthcheck` exits 0/1 based on status.json, for Docker HEALTHCHECK
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		os.Exit(runHealthcheck(cfg))
+	}
+
+	// `agent doctor` checks panel, keys and push config, then exits
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor(cfg))
+	}
+
 	// --- Init Logging ---
-	if err := logging.Init(cfg.DataDir, cfg.LogLevel); err != nil {
+	if err := logging.Init(cfg.DataDir, cfg.LogLevel, cfg.LogCompress, cfg.LogTotalMaxKB); err != nil {
 		logging.Error("Failed to init logging: %v", err)
 		os.Exit(1)
 	}
@@ -41,64 +62,93 @@ func main() {
 	logging.Info("========================================")
 
 	// --- Init Database ---
-	db, err := database.Open(cfg.DataDir)
+	db, err := database.Open(cfg.DBPath, cfg.DBRecoverCorrupt)
 	if err != nil {
 		logging.Error("Failed to open database: %v", err)
+		if errors.Is(err, database.ErrCorrupt) {
+			logging.Error("Restore a backup, or set DB_RECOVER_ON_CORRUPT=true to start over with an empty database")
+		}
 		os.Exit(1)
 	}
 	defer db.Close()
 
 	// --- Init Crypto ---
-	crypto, err := security.NewCrypto(cfg.AgentSecret)
+	crypto, err := security.NewCrypto(cfg.AgentSecret, cfg.AgentSecretPrev, cfg.CryptoSalt, cfg.CryptoInfo)
 	if err != nil {
 		logging.Error("Failed to init crypto: %v", err)
 		os.Exit(1)
 	}
-	logging.Info("Crypto initialized")
+	if cfg.CryptoSalt != "" || cfg.CryptoInfo != "" {
+		logging.Info("Using custom key derivation parameters (CRYPTO_SALT/CRYPTO_INFO); the app must be configured with the same values")
+	}
+	if cfg.AgentSecretPrev != "" {
+		logging.Info("Crypto initialized (previous secret accepted for decryption)")
+	} else {
+		logging.Info("Crypto initialized")
+	}
 
 	// --- Init Control Loader ---
-	loader := control.NewLoader(cfg.ControlFilePath)
+	var verifier *security.Crypto
+	if cfg.ControlRequireSig {
+		verifier = crypto
+		logging.Info("control.json signature verification enabled")
+	}
+	loader := newControlSource(cfg, verifier)
 	if err := loader.LoadInitial(); err != nil {
 		logging.Error("Failed to load control.json: %v", err)
 		os.Exit(1)
 	}
 	loader.Start()
-	defer loader.Stop()
 
 	// --- Init Push Provider ---
-	var pushProvider push.Provider
-	switch cfg.PushProvider {
-	case "apns":
-		if cfg.APNsKeyBase64 == "" || cfg.APNsKeyID == "" || cfg.APNsTeamID == "" || cfg.APNsBundleID == "" {
-			logging.Error("APNs configuration incomplete. Set APNS_KEY_BASE64, APNS_KEY_ID, APNS_TEAM_ID, APNS_BUNDLE_ID")
-			os.Exit(1)
-		}
-		apns, err := push.NewAPNsProvider(cfg.APNsKeyBase64, cfg.APNsKeyID, cfg.APNsTeamID, cfg.APNsBundleID)
-		if err != nil {
-			logging.Error("Failed to init APNs provider: %v", err)
-			os.Exit(1)
-		}
-		pushProvider = apns
-		logging.Info("APNs push provider initialized")
-	default:
-		pushProvider = push.NewDevProvider()
-		logging.Info("Dev push provider initialized (push notifications logged to console)")
+	pushProvider, err := newPushProvider(cfg)
+	if err != nil {
+		logging.Error("%v", err)
+		os.Exit(1)
+	}
+
+	validateCtx, cancelValidate := context.WithTimeout(context.Background(), 15*time.Second)
+	err = pushProvider.Validate(validateCtx)
+	cancelValidate()
+	if err != nil {
+		logging.Error("Invalid %s push configuration: %v", pushProvider.Name(), err)
+		os.Exit(1)
+	}
+	if apns, ok := pushProvider.(*push.APNsProvider); ok {
+		apns.Start()
 	}
 
 	// --- Init Pterodactyl Client ---
-	pteroClient := pterodactyl.NewClient(cfg.PanelURL)
+	panels, err := newPanels(cfg)
+	if err != nil {
+		logging.Error("Failed to init Pterodactyl client: %v", err)
+		os.Exit(1)
+	}
+	if cfg.PanelInsecureTLS {
+		logging.Warn("Panel TLS certificate verification is disabled (PANEL_INSECURE_SKIP_VERIFY)")
+	}
 
 	// --- Init Status Writer ---
-	statusWriter := status.NewWriter(cfg.DataDir)
-	metricsWriter := status.NewMetricsWriter(cfg.DataDir, db)
+	var stateCrypto *security.Crypto
+	if cfg.EncryptStateFiles {
+		stateCrypto = crypto
+		logging.Info("State files are encrypted (status.json.enc, metrics.json.enc)")
+	}
+	statusWriter := status.NewWriter(cfg.DataDir, stateCrypto)
+	metricsWriter := status.NewMetricsWriter(cfg.DataDir, db, stateCrypto, cfg.MetricsFormat, cfg.MetricsGzip, cfg.MetricsPerServer, metricsGapAfter(cfg))
+	deadTokens := status.NewDeadTokenWriter(cfg.DataDir)
+	tokenPrune := status.NewTokenReconciler(cfg.DataDir, clock.Real{})
 
 	// --- Init Engines ---
-	alertEvaluator := engine.NewAlertEvaluator(db, pushProvider)
-	automationExecutor := engine.NewAutomationExecutor(db, pteroClient, pushProvider, cfg.MaxConcurrent)
+	consoles := engine.NewConsoleBuffer(cfg.ConsoleLines)
+	webhooks := push.NewEventPoster(crypto.SignWebhook)
+	notifier := engine.NewNotifier(pushProvider, deadTokens, tokenPrune, webhooks, cfg.PushRateLimit, splitList(cfg.PushRateExempt), clock.Real{})
+	alertEvaluator := engine.NewAlertEvaluator(db, panels, notifier, cfg.CoalesceAlerts, clock.Real{})
+	automationExecutor := engine.NewAutomationExecutor(db, panels, notifier, consoles, cfg.MaxConcurrent, time.Duration(cfg.ActionCooldown)*time.Second, cfg.AutomationsEnabled, cfg.OrderedActions, clock.Real{})
 
 	monitor := engine.NewMonitor(
 		cfg.SamplingInterval,
-		pteroClient,
+		panels,
 		db,
 		loader,
 		crypto,
@@ -106,26 +156,216 @@ func main() {
 		automationExecutor,
 		statusWriter,
 		metricsWriter,
+		consoles,
+		cfg.SampleConcurrency,
+		cfg.MonitorSuspended,
+		engine.AdaptiveSampling{
+			IdleCycles: cfg.IdleCycles,
+			SlowEvery:  cfg.IdleSampleEvery,
+			IdleCPU:    float64(cfg.IdleCPUPercent),
+		},
+		engine.DeltaStore{
+			Heartbeat:  time.Duration(cfg.DeltaHeartbeat) * time.Minute,
+			CPUEpsilon: float64(cfg.DeltaCPUEpsilon),
+			MemEpsilon: int64(cfg.DeltaMemEpsilonMB) * 1024 * 1024,
+		},
+		clock.Real{},
 	)
 
+	// Evaluate new or changed rules right away instead of on the next tick
+	loader.OnReload(monitor.SampleNow)
+
 	cleanup := engine.NewCleanup(db, cfg.RetentionDays)
 
+	// --- Init HTTP API (optional) ---
+	var apiServer *api.Server
+	if cfg.APIAddr != "" {
+		apiServer = api.NewServer(cfg.APIAddr, db, loader, crypto, monitor)
+	}
+	var pprofServer *api.PprofServer
+	if cfg.PprofAddr != "" {
+		pprofServer = api.NewPprofServer(cfg.PprofAddr, cfg.PprofAllowRemote)
+	}
+
 	// --- Start ---
 	monitor.Start()
 	cleanup.Start()
 
+	// Writing debug/info/warn/error to data/log_level changes verbosity at runtime
+	stopLevelWatch := make(chan struct{})
+	go logging.WatchLevelFile(filepath.Join(cfg.DataDir, "log_level"), 5*time.Second, stopLevelWatch)
+
+	if apiServer != nil {
+		apiServer.Start()
+	}
+	if pprofServer != nil {
+		if err := pprofServer.Start(); err != nil {
+			logging.Error("pprof debug server not started: %v", err)
+			pprofServer = nil
+		}
+	}
+
+	if cfg.NotifyOnStart {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			engine.NotifyAgentStatus(ctx, db, notifier, loader.Get(), "start",
+				"Agent online", "The monitoring agent started and is watching your servers again.")
+		}()
+	}
+
 	logging.Info("🚀 Agent is running. Waiting for signals...")
 
 	// --- Graceful Shutdown ---
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
 	sig := <-sigCh
+	for sig == syscall.SIGHUP {
+		// SIGHUP forces a control reload instead of waiting for the next poll
+		if err := loader.Reload(); err != nil {
+			logging.Error("SIGHUP: control reload failed: %v", err)
+		} else {
+			logging.Info("SIGHUP: control configuration is at version %d", loader.Version())
+		}
+		sig = <-sigCh
+	}
 
 	logging.Info("Received signal %s, shutting down...", sig)
 
+	if cfg.NotifyOnStart {
+		// Best effort: the container may be killed shortly after SIGTERM
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		engine.NotifyAgentStatus(ctx, db, notifier, loader.Get(), "stop",
+			"Agent shutting down", "The monitoring agent is stopping. Alerts and automations pause until it restarts.")
+		cancel()
+	}
+
+	if apiServer != nil {
+		apiServer.Stop()
+	}
+	if pprofServer != nil {
+		pprofServer.Stop()
+	}
 	monitor.Stop()
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), drainTimeout)
+	alertEvaluator.Drain(drainCtx)
+	automationExecutor.Drain(drainCtx)
+	cancelDrain()
+
 	cleanup.Stop()
+	if apns, ok := pushProvider.(*push.APNsProvider); ok {
+		apns.Stop()
+	}
+	close(stopLevelWatch)
 	loader.Stop()
 
 	logging.Info("Agent stopped gracefully")
 }
+
+func runHealthcheck(cfg *config.Config) int {
+	maxAge := 3 * time.Duration(cfg.SamplingInterval) * time.Second
+
+	var crypto *security.Crypto
+	if cfg.EncryptStateFiles {
+		c, err := security.NewCrypto(cfg.AgentSecret, cfg.AgentSecretPrev, cfg.CryptoSalt, cfg.CryptoInfo)
+		if err != nil {
+			fmt.Printf("unhealthy: %v\n", err)
+			return 1
+		}
+		crypto = c
+	}
+
+	h, err := status.ReadHealth(cfg.DataDir, maxAge, crypto)
+	if err != nil {
+		fmt.Printf("unhealthy: %v\n", err)
+		return 1
+	}
+	if !h.Healthy {
+		fmt.Printf("unhealthy: %s\n", strings.Join(h.Failed, "; "))
+		return 1
+	}
+	fmt.Printf("healthy (last sample %s)\n", h.LastSampleAt)
+	return 0
+}
+
+// newPushProvider builds the push provider selected by PUSH_PROVIDER.
+func newPushProvider(cfg *config.Config) (push.Provider, error) {
+	switch cfg.PushProvider {
+	case "apns":
+		if cfg.APNsKeyBase64 == "" || cfg.APNsKeyID == "" || cfg.APNsTeamID == "" || cfg.APNsBundleID == "" {
+			return nil, fmt.Errorf("APNs configuration incomplete. Set APNS_KEY_BASE64, APNS_KEY_ID, APNS_TEAM_ID, APNS_BUNDLE_ID")
+		}
+		apns, err := push.NewAPNsProvider(cfg.APNsKeyBase64, cfg.APNsKeyID, cfg.APNsTeamID, cfg.APNsBundleID, cfg.APNsEnvironment,
+			time.Duration(cfg.APNsRefreshMargin)*time.Second, clock.Real{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to init APNs provider: %w", err)
+		}
+		logging.Info("APNs push provider initialized (environment: %s)", cfg.APNsEnvironment)
+		return apns, nil
+	case "email":
+		if cfg.SMTPHost == "" || cfg.SMTPFrom == "" {
+			return nil, fmt.Errorf("email configuration incomplete. Set SMTP_HOST and SMTP_FROM (and SMTP_USERNAME/SMTP_PASSWORD if required)")
+		}
+		logging.Info("Email push provider initialized (%s:%d)", cfg.SMTPHost, cfg.SMTPPort)
+		return push.NewEmailProvider(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom), nil
+	case "webhook":
+		if cfg.WebhookURL == "" {
+			return nil, fmt.Errorf("webhook configuration incomplete. Set WEBHOOK_URL (and WEBHOOK_AUTH if required)")
+		}
+		logging.Info("Webhook push provider initialized")
+		return push.NewWebhookProvider(cfg.WebhookURL, cfg.WebhookAuth), nil
+	default:
+		logging.Info("Dev push provider initialized (push notifications logged to console)")
+		return push.NewDevProvider(), nil
+	}
+}
+
+// newControlSource builds the control source selected by CONTROL_SOURCE.
+func newControlSource(cfg *config.Config, verifier *security.Crypto) control.Source {
+	poll := time.Duration(cfg.ControlPoll) * time.Second
+	if cfg.ControlSource == "http" {
+		logging.Info("Control configuration fetched from %s", cfg.ControlURL)
+		return control.NewHTTPSource(cfg.ControlURL, cfg.ControlToken, cfg.AgentUUID, poll, verifier,
+			cfg.MinAlertCooldown, cfg.MinAutoCooldown)
+	}
+	return control.NewLoader(cfg.ControlFilePath, poll, verifier, cfg.MinAlertCooldown, cfg.MinAutoCooldown)
+}
+
+// metricsGapAfter is the longest spacing expected between a server's stored snapshots,
+// allowing for adaptive sampling and the delta store heartbeat. Anything longer is
+// marked as a gap in metrics.json.
+func metricsGapAfter(cfg *config.Config) time.Duration {
+	spacing := time.Duration(cfg.SamplingInterval) * time.Second
+	if cfg.IdleCycles > 0 && cfg.IdleSampleEvery > 1 {
+		spacing *= time.Duration(cfg.IdleSampleEvery)
+	}
+	if hb := time.Duration(cfg.DeltaHeartbeat) * time.Minute; hb > spacing {
+		spacing = hb
+	}
+	return 2 * spacing
+}
+
+// splitList splits a comma-separated setting, dropping blanks.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// newPanels builds the panel clients from the PANEL_* settings; PANEL_URL is the default panel.
+func newPanels(cfg *config.Config) (*pterodactyl.Panels, error) {
+	return pterodactyl.NewPanels(cfg.PanelURL, pterodactyl.ClientOptions{
+		Timeout:            time.Duration(cfg.PanelTimeout) * time.Second,
+		InsecureSkipVerify: cfg.PanelInsecureTLS,
+		CACertPath:         cfg.PanelCACert,
+		ProxyURL:           cfg.PanelProxyURL,
+		RateLimitRetries:   cfg.PanelRetries,
+		Retries:            cfg.PanelErrorRetries,
+		RetryDelay:         time.Duration(cfg.PanelRetryDelay) * time.Millisecond,
+	})
+}
@@ -3,6 +3,7 @@ module github.com/xyidactyl/agent
 go 1.22
 
 require (
+	github.com/gorilla/websocket v1.5.3
 	github.com/mattn/go-sqlite3 v1.14.22
 	golang.org/x/crypto v0.28.0
 )
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestExportCSVEndpoint(t *testing.T) {
+	db := openTestDB(t)
+	ts := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	if err := db.InsertSnapshot(models.ResourceSnapshot{ServerID: "srv-1", Timestamp: ts, PowerState: "running", CPUPercent: 12.5}); err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, db)
+
+	resp := get(t, srv, "/export.csv?server=srv-1&since=2026-01-05T00:00:00Z", "key-user-1")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/csv" {
+		t.Fatalf("Content-Type = %q, want text/csv", ct)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "timestamp,power_state,") || !strings.HasPrefix(lines[1], "2026-01-05T12:00:00Z,running,12.50,") {
+		t.Fatalf("body =\n%s", body)
+	}
+}
+
+func TestExportCSVEndpointRejectsBadRequests(t *testing.T) {
+	srv := newTestServer(t, openTestDB(t))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/export.csv", http.StatusBadRequest},
+		{"/export.csv?server=srv-2", http.StatusForbidden}, // user-1 may only see srv-1
+		{"/export.csv?server=srv-1&since=yesterday", http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		if resp := get(t, srv, tc.path, "key-user-1"); resp.StatusCode != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.path, resp.StatusCode, tc.want)
+		}
+	}
+}
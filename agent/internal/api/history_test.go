@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/database"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// seedHistory stores n alerts and n automation runs for user-1 and one of each for user-2.
+func seedHistory(t *testing.T, db *database.DB, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := db.InsertAlertHistory(models.AlertHistoryEntry{
+			RuleID:    fmt.Sprintf("alert-%d", i),
+			UserUUID:  "user-1",
+			ServerID:  "srv-1",
+			Condition: "cpu",
+			Severity:  "warning",
+			Value:     float64(90 + i),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.InsertAutomationLog(models.AutomationLogEntry{
+			RuleID:   fmt.Sprintf("auto-%d", i),
+			UserUUID: "user-1",
+			ServerID: "srv-1",
+			Action:   "restart",
+			Result:   "success",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.InsertAlertHistory(models.AlertHistoryEntry{RuleID: "other", UserUUID: "user-2", ServerID: "srv-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertAutomationLog(models.AutomationLogEntry{RuleID: "other", UserUUID: "user-2", ServerID: "srv-2"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// historyPage is the body of /alerts/history and /automations/log.
+type historyPage struct {
+	Entries []struct {
+		ID       int64  `json:"id"`
+		RuleID   string `json:"rule_id"`
+		UserUUID string `json:"user_uuid"`
+	} `json:"entries"`
+	NextBeforeID int64 `json:"next_before_id"`
+}
+
+func getPage(t *testing.T, resp *http.Response) historyPage {
+	t.Helper()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	var page historyPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func TestHistoryEndpointsPaginate(t *testing.T) {
+	db := openTestDB(t)
+	seedHistory(t, db, 3)
+	srv := newTestServer(t, db)
+
+	for _, tc := range []struct{ path, prefix string }{
+		{"/alerts/history", "alert-"},
+		{"/automations/log", "auto-"},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			first := getPage(t, get(t, srv, tc.path+"?limit=2", "key-user-1"))
+			if len(first.Entries) != 2 {
+				t.Fatalf("first page has %d entries, want 2", len(first.Entries))
+			}
+			if first.Entries[0].RuleID != tc.prefix+"2" || first.Entries[1].RuleID != tc.prefix+"1" {
+				t.Fatalf("first page = %+v, want newest first", first.Entries)
+			}
+			if first.NextBeforeID != first.Entries[1].ID {
+				t.Fatalf("next_before_id = %d, want %d", first.NextBeforeID, first.Entries[1].ID)
+			}
+
+			second := getPage(t, get(t, srv, fmt.Sprintf("%s?limit=2&before_id=%d", tc.path, first.NextBeforeID), "key-user-1"))
+			if len(second.Entries) != 1 || second.Entries[0].RuleID != tc.prefix+"0" {
+				t.Fatalf("second page = %+v, want only %s0", second.Entries, tc.prefix)
+			}
+			if second.NextBeforeID != 0 {
+				t.Fatalf("last page next_before_id = %d, want 0", second.NextBeforeID)
+			}
+		})
+	}
+}
+
+func TestHistoryEndpointsFilterByUser(t *testing.T) {
+	db := openTestDB(t)
+	seedHistory(t, db, 3)
+	srv := newTestServer(t, db)
+
+	for _, path := range []string{"/alerts/history", "/automations/log"} {
+		page := getPage(t, get(t, srv, path, "key-user-2"))
+		if len(page.Entries) != 1 || page.Entries[0].UserUUID != "user-2" {
+			t.Fatalf("%s for user-2 = %+v, want only user-2's entry", path, page.Entries)
+		}
+	}
+}
+
+func TestHistoryEndpointsRejectBadRequests(t *testing.T) {
+	srv := newTestServer(t, openTestDB(t))
+
+	tests := []struct {
+		path, token string
+		want        int
+	}{
+		{"/alerts/history", "", http.StatusUnauthorized},
+		{"/alerts/history", "wrong-key", http.StatusUnauthorized},
+		{"/alerts/history?limit=0", "key-user-1", http.StatusBadRequest},
+		{"/automations/log?before_id=-1", "key-user-1", http.StatusBadRequest},
+		{"/automations/log?limit=abc", "key-user-1", http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		if resp := get(t, srv, tc.path, tc.token); resp.StatusCode != tc.want {
+			t.Errorf("%s (token %q): status %d, want %d", tc.path, tc.token, resp.StatusCode, tc.want)
+		}
+	}
+}
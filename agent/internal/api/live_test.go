@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/control"
+	"github.com/xyidactyl/agent/internal/engine"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/pterodactyl"
+	"github.com/xyidactyl/agent/internal/security"
+	"github.com/xyidactyl/agent/internal/status"
+)
+
+// newLiveServer serves the API with a running monitor over a fake panel that reports
+// srv-1 at 42% CPU. The monitor samples once on start.
+func newLiveServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"attributes":{"current_state":"running","resources":{"memory_bytes":1048576,"cpu_absolute":42}}}`)
+	}))
+	t.Cleanup(panel.Close)
+	panels, err := pterodactyl.NewPanels(panel.URL, pterodactyl.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := openTestDB(t)
+	return newMonitoredServer(t, db, func(src control.Source, crypto *security.Crypto) *engine.Monitor {
+		dataDir := t.TempDir()
+		notifier := engine.NewNotifier(nil, nil, nil, nil, 0, nil, nil)
+		consoles := engine.NewConsoleBuffer(10)
+		m := engine.NewMonitor(3600, panels, db, src, crypto,
+			engine.NewAlertEvaluator(db, panels, notifier, false, nil),
+			engine.NewAutomationExecutor(db, panels, notifier, consoles, 1, 0, true, false, nil),
+			status.NewWriter(dataDir, nil),
+			status.NewMetricsWriter(dataDir, db, nil, "", false, false, 0),
+			consoles, 2, false, engine.AdaptiveSampling{}, engine.DeltaStore{}, nil)
+		m.Start()
+		t.Cleanup(m.Stop)
+		return m
+	})
+}
+
+func TestLiveEndpoint(t *testing.T) {
+	srv := newLiveServer(t)
+
+	// The first sample lands shortly after start
+	deadline := time.Now().Add(5 * time.Second)
+	resp := get(t, srv, "/live?server=srv-1", "key-user-1")
+	for resp.StatusCode == http.StatusNotFound && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		resp = get(t, srv, "/live?server=srv-1", "key-user-1")
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	var snap models.ResourceSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snap); err != nil {
+		t.Fatal(err)
+	}
+	if snap.ServerID != "srv-1" || snap.CPUPercent != 42 || snap.PowerState != "running" {
+		t.Fatalf("live snapshot = %+v, want srv-1 running at 42%%", snap)
+	}
+}
+
+func TestLiveEndpointRejectsBadRequests(t *testing.T) {
+	srv := newLiveServer(t)
+	for _, tc := range []struct {
+		path, token string
+		want        int
+	}{
+		{"/live?server=srv-1", "", http.StatusUnauthorized},
+		{"/live", "key-user-1", http.StatusBadRequest},
+		{"/live?server=srv-2", "key-user-1", http.StatusForbidden},
+	} {
+		if resp := get(t, srv, tc.path, tc.token); resp.StatusCode != tc.want {
+			t.Errorf("GET %s: status %d, want %d", tc.path, resp.StatusCode, tc.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// syntheticLog points the global logger at a temp dir and fills agent.log with n
+// numbered lines.
+func syntheticLog(t *testing.T, n int) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := logging.Init(dir, "info", false, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logging.Close)
+
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "[INFO] 2026-01-05T12:00:00Z line %d\n", i)
+	}
+	fmt.Fprintf(&b, "[INFO] 2026-01-05T12:00:00Z key ptlc_%s\n", strings.Repeat("x", 40))
+	if err := os.WriteFile(filepath.Join(dir, "logs", "agent.log"), []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// requestLogs calls the logs handler as user and decodes the returned lines.
+func requestLogs(t *testing.T, query string, user models.ControlUser) (int, []string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	(&Server{}).handleLogs(w, httptest.NewRequest(http.MethodGet, "/logs"+query, nil), user)
+	if w.Code != http.StatusOK {
+		return w.Code, nil
+	}
+	var body struct {
+		Lines []string `json:"lines"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, body.Lines
+}
+
+func TestLogsEndpoint(t *testing.T) {
+	syntheticLog(t, 2000)
+	admin := models.ControlUser{UserUUID: "user-1", IsAdmin: true}
+
+	code, lines := requestLogs(t, "?lines=5", admin)
+	if code != http.StatusOK || len(lines) != 5 {
+		t.Fatalf("lines=5: status %d, %d lines", code, len(lines))
+	}
+	if lines[0] != "[INFO] 2026-01-05T12:00:00Z line 1996" {
+		t.Fatalf("first line = %q, want line 1996", lines[0])
+	}
+	if last := lines[4]; strings.Contains(last, "ptlc_x") || !strings.Contains(last, "[REDACTED]") {
+		t.Fatalf("last line = %q, want the panel key redacted", last)
+	}
+
+	if _, lines := requestLogs(t, "", admin); len(lines) != defaultLogLines {
+		t.Fatalf("no lines param: %d lines, want %d", len(lines), defaultLogLines)
+	}
+	if _, lines := requestLogs(t, "?lines=100000", admin); len(lines) != maxLogLines {
+		t.Fatalf("lines=100000: %d lines, want the %d cap", len(lines), maxLogLines)
+	}
+}
+
+func TestLogsEndpointRejects(t *testing.T) {
+	syntheticLog(t, 10)
+
+	if code, _ := requestLogs(t, "?lines=5", models.ControlUser{UserUUID: "user-1"}); code != http.StatusForbidden {
+		t.Fatalf("non-admin: status %d, want 403", code)
+	}
+	admin := models.ControlUser{UserUUID: "user-1", IsAdmin: true}
+	for _, q := range []string{"?lines=0", "?lines=-3", "?lines=ten"} {
+		if code, _ := requestLogs(t, q, admin); code != http.StatusBadRequest {
+			t.Fatalf("%s: status %d, want 400", q, code)
+		}
+	}
+}
+
+func TestLogsEndpointRequiresToken(t *testing.T) {
+	srv := newTestServer(t, openTestDB(t))
+	if resp := get(t, srv, "/logs", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("no token: status %d, want 401", resp.StatusCode)
+	}
+	if resp := get(t, srv, "/logs", "key-user-1"); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("non-admin token: status %d, want 403", resp.StatusCode)
+	}
+}
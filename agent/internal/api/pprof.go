@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/logging"
+)
+
+// PprofServer serves net/http/pprof profiles for debugging, only when DEBUG_PPROF_ADDR is set.
+// Profiles expose memory contents and goroutine stacks, so the listener must stay on
+// localhost (e.g. 127.0.0.1:6060) and never be published from the container. Other
+// addresses are refused unless DEBUG_PPROF_ALLOW_REMOTE is set.
+type PprofServer struct {
+	httpServer  *http.Server
+	allowRemote bool
+	listener    net.Listener // set by Start
+}
+
+// NewPprofServer creates a pprof server listening on addr. It uses its own mux so the
+// profiling handlers never leak onto http.DefaultServeMux.
+func NewPprofServer(addr string, allowRemote bool) *PprofServer {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return &PprofServer{
+		httpServer: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		allowRemote: allowRemote,
+	}
+}
+
+// Start binds the listener and serves in the background. It fails for a non-loopback
+// address unless remote access was explicitly allowed.
+func (s *PprofServer) Start() error {
+	addr := s.httpServer.Addr
+	if !isLoopback(addr) {
+		if !s.allowRemote {
+			return fmt.Errorf("%s is not a loopback address; use e.g. 127.0.0.1:6060 or set DEBUG_PPROF_ALLOW_REMOTE=true", addr)
+		}
+		logging.Warn("pprof is listening on %s, which is not a loopback address; do not expose it publicly", addr)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	s.listener = ln
+	logging.Info("pprof debug server listening on %s", ln.Addr())
+	go func() {
+		if err := s.httpServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Error("pprof debug server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop shuts the server down.
+func (s *PprofServer) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		logging.Warn("pprof debug server shutdown: %v", err)
+	}
+}
+
+// isLoopback reports whether addr binds only to localhost.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPprofServesIndex(t *testing.T) {
+	s := NewPprofServer("127.0.0.1:0", false)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer s.Stop()
+
+	resp, err := http.Get("http://" + s.listener.Addr().String() + "/debug/pprof/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	if !strings.Contains(string(body), "goroutine") {
+		t.Fatal("index does not list the goroutine profile")
+	}
+}
+
+func TestPprofRefusesNonLoopback(t *testing.T) {
+	s := NewPprofServer("0.0.0.0:0", false)
+	if err := s.Start(); err == nil {
+		s.Stop()
+		t.Fatal("pprof started on a non-loopback address without DEBUG_PPROF_ALLOW_REMOTE")
+	}
+	if s.listener != nil {
+		t.Fatal("listener bound despite the refusal")
+	}
+}
+
+func TestPprofAllowRemote(t *testing.T) {
+	s := NewPprofServer("0.0.0.0:0", true)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start with override: %v", err)
+	}
+	s.Stop()
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1:6060": true,
+		"localhost:6060": true,
+		"[::1]:6060":     true,
+		"0.0.0.0:6060":   false,
+		":6060":          false,
+		"10.0.0.5:6060":  false,
+		"no-port":        false,
+	}
+	for addr, want := range tests {
+		if got := isLoopback(addr); got != want {
+			t.Errorf("isLoopback(%q) = %t, want %t", addr, got, want)
+		}
+	}
+}
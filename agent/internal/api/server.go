@@ -0,0 +1,470 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/control"
+	"github.com/xyidactyl/agent/internal/database"
+	"github.com/xyidactyl/agent/internal/engine"
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/security"
+	"github.com/xyidactyl/agent/internal/status"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+
+	defaultLogLines = 100
+	maxLogLines     = 1000
+
+	defaultSimulateWindow = 7 * 24 * time.Hour
+	maxSimulateBody       = 64 << 10 // bytes
+)
+
+// Server is the optional HTTP API for the iOS app. It is only started when API_ADDR is set;
+// by default the agent has no inbound networking.
+//
+// Requests authenticate with "Authorization: Bearer <panel API key>"; the key is matched
+// against the decrypted api_key_encrypted values in control.json to identify the user.
+type Server struct {
+	db         *database.DB
+	loader     control.Source
+	crypto     *security.Crypto
+	monitor    *engine.Monitor
+	httpServer *http.Server
+}
+
+// NewServer creates an API server listening on addr.
+func NewServer(addr string, db *database.DB, loader control.Source, crypto *security.Crypto, monitor *engine.Monitor) *Server {
+	s := &Server{
+		db:      db,
+		loader:  loader,
+		crypto:  crypto,
+		monitor: monitor,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealthz) // unauthenticated for orchestrator probes
+	mux.HandleFunc("GET /alerts/history", s.withUser(s.handleAlertHistory))
+	mux.HandleFunc("GET /automations/log", s.withUser(s.handleAutomationLog))
+	mux.HandleFunc("GET /export.csv", s.withUser(s.handleExportCSV))
+	mux.HandleFunc("GET /live", s.withUser(s.handleLive))
+	mux.HandleFunc("GET /logs", s.withUser(s.handleLogs))
+	mux.HandleFunc("POST /test-push", s.withUser(s.handleTestPush))
+	mux.HandleFunc("POST /simulate", s.withUser(s.handleSimulate))
+	mux.HandleFunc("GET /automations/enabled", s.withUser(s.handleGetAutomationsEnabled))
+	mux.HandleFunc("PUT /automations/enabled", s.withUser(s.handleSetAutomationsEnabled))
+	mux.HandleFunc("PUT /monitor/paused", s.withUser(s.handleSetPaused))
+
+	s.httpServer = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Start begins serving in the background.
+func (s *Server) Start() {
+	logging.Info("HTTP API listening on %s", s.httpServer.Addr)
+	go func() {
+		if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Error("HTTP API stopped: %v", err)
+		}
+	}()
+}
+
+// Stop gracefully shuts the server down.
+func (s *Server) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		logging.Warn("HTTP API shutdown: %v", err)
+	}
+}
+
+type userHandler func(w http.ResponseWriter, r *http.Request, user models.ControlUser)
+
+// withUser authenticates the request and passes the matching control user to next.
+func (s *Server) withUser(next userHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := s.authenticate(r)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "invalid or missing bearer token")
+			return
+		}
+		next(w, r, user)
+	}
+}
+
+func (s *Server) authenticate(r *http.Request) (models.ControlUser, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return models.ControlUser{}, false
+	}
+
+	cf := s.loader.Get()
+	if cf == nil {
+		return models.ControlUser{}, false
+	}
+
+	for _, u := range cf.Users {
+		key, err := s.crypto.Decrypt(u.APIKeyEncrypted)
+		if err != nil {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+			return u, true
+		}
+	}
+	return models.ControlUser{}, false
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	h := status.CheckHealth(s.monitor.LastSampleAt(), 3*s.monitor.Interval(), s.monitor.Paused())
+	code := http.StatusOK
+	if !h.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, h)
+}
+
+func (s *Server) handleAlertHistory(w http.ResponseWriter, r *http.Request, user models.ControlUser) {
+	beforeID, limit, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := s.db.GetAlertHistory(user.UserUUID, beforeID, limit)
+	if err != nil {
+		logging.Error("API: failed to read alert history: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to read alert history")
+		return
+	}
+
+	var next int64
+	if len(entries) == limit {
+		next = entries[len(entries)-1].ID
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries":        entries,
+		"next_before_id": next,
+	})
+}
+
+func (s *Server) handleAutomationLog(w http.ResponseWriter, r *http.Request, user models.ControlUser) {
+	beforeID, limit, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := s.db.GetAutomationLog(user.UserUUID, beforeID, limit)
+	if err != nil {
+		logging.Error("API: failed to read automation log: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to read automation log")
+		return
+	}
+
+	var next int64
+	if len(entries) == limit {
+		next = entries[len(entries)-1].ID
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries":        entries,
+		"next_before_id": next,
+	})
+}
+
+func (s *Server) handleExportCSV(w http.ResponseWriter, r *http.Request, user models.ControlUser) {
+	serverID := r.URL.Query().Get("server")
+	if serverID == "" {
+		writeError(w, http.StatusBadRequest, "server is required")
+		return
+	}
+	if !userCanAccess(user, serverID) {
+		writeError(w, http.StatusForbidden, "server not in allowed_servers")
+		return
+	}
+
+	since := time.Now().Add(-24 * time.Hour)
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since must be RFC3339 or unix seconds")
+			return
+		}
+		since = t
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, serverID))
+	if err := s.db.ExportSnapshotsCSV(serverID, since, w); err != nil {
+		// Headers are already sent; all we can do is log and cut the stream short
+		logging.Error("API: CSV export for %s failed: %v", serverID, err)
+	}
+}
+
+// handleLive returns the latest in-memory snapshot of a server, without touching the database.
+func (s *Server) handleLive(w http.ResponseWriter, r *http.Request, user models.ControlUser) {
+	serverID := r.URL.Query().Get("server")
+	if serverID == "" {
+		writeError(w, http.StatusBadRequest, "server is required")
+		return
+	}
+	if !userCanAccess(user, serverID) {
+		writeError(w, http.StatusForbidden, "server not in allowed_servers")
+		return
+	}
+
+	snap := s.monitor.LatestSnapshot(serverID)
+	if snap == nil {
+		writeError(w, http.StatusNotFound, "no sample yet for this server")
+		return
+	}
+	writeJSON(w, http.StatusOK, snap)
+}
+
+// handleLogs returns the tail of agent.log. The log covers every user's servers,
+// so it is limited to admins.
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request, user models.ControlUser) {
+	if !user.IsAdmin {
+		writeError(w, http.StatusForbidden, "admin only")
+		return
+	}
+
+	n := defaultLogLines
+	if v := r.URL.Query().Get("lines"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, "lines must be a positive integer")
+			return
+		}
+		n = parsed
+	}
+	if n > maxLogLines {
+		n = maxLogLines
+	}
+
+	lines, err := logging.Tail(n)
+	if err != nil {
+		logging.Error("API: failed to read log: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to read log")
+		return
+	}
+	if lines == nil {
+		lines = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"lines": lines})
+}
+
+// handleTestPush sends a test notification to the caller's devices. Admins may
+// target another user with ?user=<uuid>.
+func (s *Server) handleTestPush(w http.ResponseWriter, r *http.Request, user models.ControlUser) {
+	target := user
+	if uuid := r.URL.Query().Get("user"); uuid != "" && uuid != user.UserUUID {
+		if !user.IsAdmin {
+			writeError(w, http.StatusForbidden, "only admins can send test pushes to other users")
+			return
+		}
+		found := false
+		for _, u := range s.loader.Get().Users {
+			if u.UserUUID == uuid {
+				target, found = u, true
+				break
+			}
+		}
+		if !found {
+			writeError(w, http.StatusNotFound, "unknown user")
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	writeJSON(w, http.StatusOK, s.monitor.SendTestPush(ctx, target))
+}
+
+// handleSimulate replays stored snapshots through a candidate alert rule and returns
+// the alerts it would have fired. Body: {"rule": {...}, "since": RFC3339 or unix seconds}.
+func (s *Server) handleSimulate(w http.ResponseWriter, r *http.Request, user models.ControlUser) {
+	var req struct {
+		Rule  models.AlertRule `json:"rule"`
+		Since string           `json:"since"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxSimulateBody)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "body must be {\"rule\": {...}, \"since\": ...}")
+		return
+	}
+
+	since := time.Now().Add(-defaultSimulateWindow)
+	if req.Since != "" {
+		t, err := parseTime(req.Since)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since must be RFC3339 or unix seconds")
+			return
+		}
+		since = t
+	}
+
+	rule := req.Rule
+	rule.UserUUID = user.UserUUID
+	rule.Enabled = true
+	if rule.ID == "" {
+		rule.ID = "simulation"
+	}
+
+	cf := s.loader.Get()
+	groups := make(map[string]bool, len(cf.Groups))
+	for _, g := range cf.Groups {
+		groups[g.ID] = true
+	}
+	if err := control.ValidateAlert(rule, groups); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, id := range engine.RuleServers(cf, rule.ServerID) {
+		if !userCanAccess(user, id) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("server %s not in allowed_servers", id))
+			return
+		}
+	}
+
+	entries, err := s.monitor.Simulate([]models.AlertRule{rule}, since)
+	if err != nil {
+		logging.Error("API: simulation failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "simulation failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"since":    since.UTC().Format(time.RFC3339),
+		"triggers": entries,
+	})
+}
+
+// handleGetAutomationsEnabled reports the global automation kill-switch.
+func (s *Server) handleGetAutomationsEnabled(w http.ResponseWriter, r *http.Request, user models.ControlUser) {
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": s.monitor.AutomationsEnabled()})
+}
+
+// handleSetAutomationsEnabled flips the global kill-switch (admin only); it applies from the next sampling cycle.
+func (s *Server) handleSetAutomationsEnabled(w http.ResponseWriter, r *http.Request, user models.ControlUser) {
+	if !user.IsAdmin {
+		writeError(w, http.StatusForbidden, "admin only")
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		writeError(w, http.StatusBadRequest, `body must be {"enabled": true|false}`)
+		return
+	}
+
+	if err := s.db.SetState(engine.AutomationsEnabledKey, strconv.FormatBool(*req.Enabled)); err != nil {
+		logging.Error("API: failed to set automation kill-switch: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to update kill-switch")
+		return
+	}
+	logging.Info("API: user %s set automations enabled=%t", user.UserUUID, *req.Enabled)
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": *req.Enabled})
+}
+
+// handleSetPaused pauses or resumes sampling immediately (admin only).
+func (s *Server) handleSetPaused(w http.ResponseWriter, r *http.Request, user models.ControlUser) {
+	if !user.IsAdmin {
+		writeError(w, http.StatusForbidden, "admin only")
+		return
+	}
+
+	var req struct {
+		Paused *bool `json:"paused"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Paused == nil {
+		writeError(w, http.StatusBadRequest, `body must be {"paused": true|false}`)
+		return
+	}
+
+	setPaused := s.monitor.Resume
+	if *req.Paused {
+		setPaused = s.monitor.Pause
+	}
+	if err := setPaused(); err != nil {
+		logging.Error("API: failed to set pause state: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to update pause state")
+		return
+	}
+	logging.Info("API: user %s set monitor paused=%t", user.UserUUID, *req.Paused)
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": *req.Paused})
+}
+
+// userCanAccess reports whether serverID is in the user's allowed servers.
+func userCanAccess(user models.ControlUser, serverID string) bool {
+	for _, s := range user.AllowedServers {
+		if s == serverID {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTime accepts RFC3339 timestamps or unix seconds.
+func parseTime(v string) (time.Time, error) {
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// pagination parses the before_id and limit query parameters.
+func pagination(r *http.Request) (int64, int, error) {
+	q := r.URL.Query()
+
+	var beforeID int64
+	if v := q.Get("before_id"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("before_id must be a non-negative integer")
+		}
+		beforeID = n
+	}
+
+	limit := defaultPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, errors.New("limit must be a positive integer")
+		}
+		limit = n
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	return beforeID, limit, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.Warn("API: failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/control"
+	"github.com/xyidactyl/agent/internal/database"
+	"github.com/xyidactyl/agent/internal/engine"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/security"
+)
+
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "agent.db"), false)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newTestServer serves the API over httptest for user-1 (key "key-user-1", srv-1) and
+// user-2 (key "key-user-2", srv-2), backed by db.
+func newTestServer(t *testing.T, db *database.DB) *httptest.Server {
+	t.Helper()
+	return newMonitoredServer(t, db, nil)
+}
+
+// newMonitoredServer is newTestServer with the monitor newMonitor builds over the
+// server's control source and crypto. A nil newMonitor serves without one.
+func newMonitoredServer(t *testing.T, db *database.DB, newMonitor func(control.Source, *security.Crypto) *engine.Monitor) *httptest.Server {
+	t.Helper()
+	crypto, err := security.NewCrypto("test-agent-secret-0123456789abcdef", "", "test-salt", "test-info")
+	if err != nil {
+		t.Fatalf("new crypto: %v", err)
+	}
+	cf := models.ControlFile{Version: 1}
+	for _, u := range []struct{ uuid, server string }{{"user-1", "srv-1"}, {"user-2", "srv-2"}} {
+		key, err := crypto.Encrypt("key-" + u.uuid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cf.Users = append(cf.Users, models.ControlUser{
+			UserUUID:        u.uuid,
+			APIKeyEncrypted: key,
+			AllowedServers:  []string{u.server},
+		})
+	}
+	data, err := json.Marshal(cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "control.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	loader := control.NewLoader(path, time.Minute, nil, 0, 0)
+	if err := loader.LoadInitial(); err != nil {
+		t.Fatalf("load control file: %v", err)
+	}
+
+	var monitor *engine.Monitor
+	if newMonitor != nil {
+		monitor = newMonitor(loader, crypto)
+	}
+	s := NewServer("", db, loader, crypto, monitor)
+	srv := httptest.NewServer(s.httpServer.Handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// get requests path from srv with the given bearer token ("" = none).
+func get(t *testing.T, srv *httptest.Server, path, token string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestSetAutomationsEnabledRequiresAdmin(t *testing.T) {
+	s := &Server{db: openTestDB(t)}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/automations/enabled", strings.NewReader(`{"enabled": false}`))
+	s.handleSetAutomationsEnabled(w, r, models.ControlUser{UserUUID: "user-1"})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("non-admin: status %d, want 403", w.Code)
+	}
+	if val, _ := s.db.GetState(engine.AutomationsEnabledKey); val != "" {
+		t.Fatalf("non-admin changed the kill-switch to %q", val)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/automations/enabled", strings.NewReader(`{"enabled": false}`))
+	s.handleSetAutomationsEnabled(w, r, models.ControlUser{UserUUID: "admin", IsAdmin: true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin: status %d, want 200", w.Code)
+	}
+	if val, _ := s.db.GetState(engine.AutomationsEnabledKey); val != "false" {
+		t.Fatalf("kill-switch = %q, want false", val)
+	}
+}
+
+func TestSetPausedRequiresAdmin(t *testing.T) {
+	s := &Server{db: openTestDB(t)}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/monitor/paused", strings.NewReader(`{"paused": true}`))
+	s.handleSetPaused(w, r, models.ControlUser{UserUUID: "user-1"})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("non-admin: status %d, want 403", w.Code)
+	}
+	if val, _ := s.db.GetState(engine.MonitorPausedKey); val != "" {
+		t.Fatalf("non-admin changed the pause state to %q", val)
+	}
+}
+
+func TestTestPushForOtherUserRequiresAdmin(t *testing.T) {
+	srv := newTestServer(t, openTestDB(t))
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/test-push?user=user-2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer key-user-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status %d, want 403", resp.StatusCode)
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/control"
+	"github.com/xyidactyl/agent/internal/database"
+	"github.com/xyidactyl/agent/internal/engine"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/pterodactyl"
+	"github.com/xyidactyl/agent/internal/security"
+	"github.com/xyidactyl/agent/internal/status"
+)
+
+// newSimulateServer serves the API with an idle monitor over db.
+func newSimulateServer(t *testing.T, db *database.DB) *httptest.Server {
+	t.Helper()
+	panels, err := pterodactyl.NewPanels("http://127.0.0.1:1", pterodactyl.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newMonitoredServer(t, db, func(src control.Source, crypto *security.Crypto) *engine.Monitor {
+		dataDir := t.TempDir()
+		notifier := engine.NewNotifier(nil, nil, nil, nil, 0, nil, nil)
+		consoles := engine.NewConsoleBuffer(10)
+		return engine.NewMonitor(3600, panels, db, src, crypto,
+			engine.NewAlertEvaluator(db, panels, notifier, false, nil),
+			engine.NewAutomationExecutor(db, panels, notifier, consoles, 1, 0, true, false, nil),
+			status.NewWriter(dataDir, nil),
+			status.NewMetricsWriter(dataDir, db, nil, "", false, false, 0),
+			consoles, 2, false, engine.AdaptiveSampling{}, engine.DeltaStore{}, nil)
+	})
+}
+
+// postSimulate posts body to /simulate as user-1.
+func postSimulate(t *testing.T, srv *httptest.Server, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/simulate", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer key-user-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestSimulateEndpoint(t *testing.T) {
+	db := openTestDB(t)
+	start := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	var snaps []models.ResourceSnapshot
+	for i, cpu := range []float64{20, 95, 95, 20, 95} {
+		snaps = append(snaps, models.ResourceSnapshot{
+			ServerID:   "srv-1",
+			Timestamp:  start.Add(time.Duration(i) * time.Minute),
+			PowerState: "running",
+			CPUPercent: cpu,
+		})
+	}
+	if err := db.InsertSnapshots(snaps); err != nil {
+		t.Fatal(err)
+	}
+	srv := newSimulateServer(t, db)
+
+	resp := postSimulate(t, srv, fmt.Sprintf(`{"rule":{"server_id":"srv-1","condition_type":"cpu_threshold","threshold":80,"cooldown":60},"since":"%d"}`, start.Unix()))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	var out struct {
+		Triggers []models.AlertHistoryEntry `json:"triggers"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Triggers) != 3 {
+		t.Fatalf("%d triggers, want 3: %+v", len(out.Triggers), out.Triggers)
+	}
+	for _, e := range out.Triggers {
+		if e.RuleID != "simulation" || e.UserUUID != "user-1" || e.ServerID != "srv-1" {
+			t.Fatalf("trigger %+v, want the caller's simulation rule on srv-1", e)
+		}
+	}
+	if history, _ := db.GetAlertHistory("user-1", 0, 10); len(history) != 0 {
+		t.Fatalf("simulation wrote %d history entries", len(history))
+	}
+}
+
+func TestSimulateEndpointRejectsBadRequests(t *testing.T) {
+	srv := newSimulateServer(t, openTestDB(t))
+	for _, tc := range []struct {
+		name, body string
+		want       int
+	}{
+		{"not json", `rule`, http.StatusBadRequest},
+		{"bad since", `{"rule":{"server_id":"srv-1","condition_type":"cpu_threshold","threshold":80},"since":"yesterday"}`, http.StatusBadRequest},
+		{"unknown condition", `{"rule":{"server_id":"srv-1","condition_type":"nope","threshold":80}}`, http.StatusBadRequest},
+		{"other user's server", `{"rule":{"server_id":"srv-2","condition_type":"cpu_threshold","threshold":80}}`, http.StatusForbidden},
+		{"oversized body", `{"rule":{"server_id":"srv-1","condition_type":"cpu_threshold","threshold":80,"padding":"` + strings.Repeat("x", maxSimulateBody) + `"}}`, http.StatusBadRequest},
+	} {
+		if resp := postSimulate(t, srv, tc.body); resp.StatusCode != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+}
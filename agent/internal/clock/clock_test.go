@@ -0,0 +1,32 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	if !f.Now().Equal(start) {
+		t.Fatalf("Now = %s, want %s", f.Now(), start)
+	}
+	f.Advance(90 * time.Second)
+	if got := f.Now().Sub(start); got != 90*time.Second {
+		t.Fatalf("advanced %s, want 90s", got)
+	}
+	f.Set(start)
+	if !f.Now().Equal(start) {
+		t.Fatal("Set did not move the clock back")
+	}
+}
+
+func TestOrReal(t *testing.T) {
+	if _, ok := OrReal(nil).(Real); !ok {
+		t.Fatal("OrReal(nil) is not the wall clock")
+	}
+	f := NewFake(time.Time{})
+	if OrReal(f) != f {
+		t.Fatal("OrReal replaced a given clock")
+	}
+}
@@ -1,48 +1,169 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 // Config holds all agent configuration loaded from environment variables.
 type Config struct {
-	AgentUUID        string
-	AgentSecret      string
-	PanelURL         string
-	PanelAPIKey      string
-	SamplingInterval int    // seconds, default 30
-	RetentionDays    int    // max 30
-	LogLevel         string // "debug", "info", "warn", "error"
-	MaxConcurrent    int    // max concurrent automation actions
-	ControlFilePath  string // path to control.json
-	DataDir          string // path to data directory
-	APNsKeyBase64    string
-	APNsKeyID        string
-	APNsTeamID       string
-	APNsBundleID     string
-	PushProvider     string // "apns" or "dev"
+	AgentUUID          string
+	AgentSecret        string
+	AgentSecretPrev    string // previous secret, accepted for decryption during rotation
+	CryptoSalt         string // HKDF salt, empty = built-in default
+	CryptoInfo         string // HKDF info for API key encryption, empty = built-in default
+	EncryptStateFiles  bool   // write status.json/metrics.json encrypted as *.enc
+	MetricsFormat      string // "verbose" (default) or "compact" columnar metrics.json
+	MetricsGzip        bool   // write metrics.json.gz instead of metrics.json
+	MetricsPerServer   bool   // write metrics/<server_id>.json instead of one metrics.json
+	PanelURL           string
+	PanelAPIKey        string
+	PanelRetries       int    // retries after a 429 from the panel, default 1
+	PanelErrorRetries  int    // retries of panel reads after network errors or 5xx, default 2
+	PanelRetryDelay    int    // base backoff for those retries in milliseconds, default 500
+	PanelTimeout       int    // seconds, default 25
+	PanelInsecureTLS   bool   // skip panel certificate verification
+	PanelCACert        string // path to a PEM CA bundle for the panel
+	PanelProxyURL      string
+	SamplingInterval   int    // seconds, default 30
+	RetentionDays      int    // max 30
+	LogLevel           string // "debug", "info", "warn", "error"
+	LogCompress        bool   // gzip rotated agent.log.N files
+	LogTotalMaxKB      int    // cap on agent.log plus rotations in KB, oldest rotations deleted first; 0 = unlimited
+	MaxConcurrent      int    // max concurrent automation actions
+	ActionCooldown     int    // seconds between the same action on a server across rules, 0 = off
+	MinAlertCooldown   int    // floor for alert rule cooldowns, in seconds
+	MinAutoCooldown    int    // floor for automation rule cooldowns, in seconds
+	AutomationsEnabled bool   // default state of the automation kill-switch
+	OrderedActions     bool   // run each server's automation actions sequentially in rule order
+	ControlSource      string // "file" (default) reads ControlFilePath, "http" polls ControlURL
+	ControlFilePath    string // path to control.json
+	ControlURL         string // control endpoint for CONTROL_SOURCE=http
+	ControlToken       string // bearer token sent to ControlURL
+	ControlPoll        int    // seconds between control.json checks, default 15
+	ControlRequireSig  bool   // reject control.json without a valid signature
+	DataDir            string // path to data directory
+	DBPath             string // SQLite database file, default <DataDir>/agent.db
+	DBRecoverCorrupt   bool   // move a corrupt database aside and start fresh instead of failing
+	APIAddr            string // listen address for the optional HTTP API, empty = disabled
+	PprofAddr          string // localhost address for the pprof debug server, empty = disabled
+	PprofAllowRemote   bool   // allow PprofAddr to be a non-loopback address
+	APNsKeyBase64      string
+	APNsKeyID          string
+	APNsTeamID         string
+	APNsBundleID       string
+	APNsEnvironment    string // "production" or "sandbox"
+	APNsRefreshMargin  int    // seconds before expiry to re-sign the APNs JWT, default 300
+	SMTPHost           string
+	SMTPPort           int
+	SMTPUsername       string
+	SMTPPassword       string
+	SMTPFrom           string
+	WebhookURL         string
+	WebhookAuth        string // optional Authorization header value
+	PushProvider       string // "apns", "email", "webhook" or "dev"
+	CoalesceAlerts     bool   // one push per user per sampling pass instead of one per alert
+	NotifyOnStart      bool   // push "agent online"/"shutting down" notifications
+	MonitorSuspended   bool   // keep collecting full data for suspended servers
+	SampleConcurrency  int    // max servers sampled in parallel, default 8
+	IdleCycles         int    // stable cycles before an idle server is sampled less often, 0 = off
+	IdleSampleEvery    int    // idle servers are sampled every this many cycles
+	IdleCPUPercent     int    // CPU percent below which a running server counts as idle
+	ConsoleLines       int    // console lines kept per server for crash reports, 0 = disabled
+	PushRateLimit      int    // max pushes per user per minute, 0 = unlimited
+	PushRateExempt     string // comma-separated severities/event types exempt from PushRateLimit
+	DeltaHeartbeat     int    // minutes between forced snapshot writes in delta store mode, 0 = off
+	DeltaCPUEpsilon    int    // CPU percent points a snapshot must move to be stored in delta mode
+	DeltaMemEpsilonMB  int    // memory/disk MB a snapshot must move to be stored in delta mode
 }
 
 // Load reads configuration from environment variables with sensible defaults.
+// If CONFIG_FILE points to a JSON file of {"ENV_NAME": value} pairs, those values are
+// used for any variable not set in the environment.
 func Load() (*Config, error) {
+	src, err := newSource(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
-		AgentUUID:        os.Getenv("AGENT_UUID"),
-		AgentSecret:      os.Getenv("AGENT_SECRET"),
-		PanelURL:         os.Getenv("PANEL_URL"),
-		PanelAPIKey:      os.Getenv("PANEL_API_KEY"),
-		SamplingInterval: envInt("SAMPLING_INTERVAL", 30),
-		RetentionDays:    envInt("RETENTION_DAYS", 30),
-		LogLevel:         envStr("LOG_LEVEL", "info"),
-		MaxConcurrent:    envInt("MAX_CONCURRENT_ACTIONS", 5),
-		ControlFilePath:  envStr("CONTROL_FILE_PATH", "./control/control.json"),
-		DataDir:          envStr("DATA_DIR", "./data"),
-		APNsKeyBase64:    os.Getenv("APNS_KEY_BASE64"),
-		APNsKeyID:        os.Getenv("APNS_KEY_ID"),
-		APNsTeamID:       os.Getenv("APNS_TEAM_ID"),
-		APNsBundleID:     os.Getenv("APNS_BUNDLE_ID"),
-		PushProvider:     envStr("PUSH_PROVIDER", "dev"),
+		AgentUUID:          src.envRaw("AGENT_UUID"),
+		AgentSecret:        src.envRaw("AGENT_SECRET"),
+		AgentSecretPrev:    src.envRaw("AGENT_SECRET_PREVIOUS"),
+		CryptoSalt:         src.envRaw("CRYPTO_SALT"),
+		CryptoInfo:         src.envRaw("CRYPTO_INFO"),
+		EncryptStateFiles:  src.envBool("ENCRYPT_STATE_FILES", false),
+		MetricsFormat:      src.envStr("METRICS_FORMAT", "verbose"),
+		MetricsGzip:        src.envBool("METRICS_GZIP", false),
+		MetricsPerServer:   src.envBool("METRICS_PER_SERVER", false),
+		PanelURL:           src.envRaw("PANEL_URL"),
+		PanelAPIKey:        src.envRaw("PANEL_API_KEY"),
+		PanelRetries:       src.envInt("PANEL_RATE_LIMIT_RETRIES", 1),
+		PanelErrorRetries:  src.envInt("PANEL_RETRIES", 2),
+		PanelRetryDelay:    src.envInt("PANEL_RETRY_DELAY_MS", 500),
+		PanelTimeout:       src.envInt("PANEL_TIMEOUT", 25),
+		PanelInsecureTLS:   src.envBool("PANEL_INSECURE_SKIP_VERIFY", false),
+		PanelCACert:        src.envRaw("PANEL_CA_CERT"),
+		PanelProxyURL:      src.envRaw("PANEL_PROXY_URL"),
+		SamplingInterval:   src.envInt("SAMPLING_INTERVAL", 30),
+		RetentionDays:      src.envInt("RETENTION_DAYS", 30),
+		LogLevel:           src.envStr("LOG_LEVEL", "info"),
+		LogCompress:        src.envBool("LOG_COMPRESS", false),
+		LogTotalMaxKB:      src.envInt("LOG_TOTAL_MAX_KB", 0),
+		MaxConcurrent:      src.envInt("MAX_CONCURRENT_ACTIONS", 5),
+		ActionCooldown:     src.envInt("AUTOMATION_ACTION_COOLDOWN", 0),
+		MinAlertCooldown:   src.envInt("ALERT_MIN_COOLDOWN", 60),
+		MinAutoCooldown:    src.envInt("AUTOMATION_MIN_COOLDOWN", 60),
+		AutomationsEnabled: src.envBool("AUTOMATIONS_ENABLED", true),
+		OrderedActions:     src.envBool("AUTOMATION_ORDERED_ACTIONS", false),
+		ControlSource:      src.envStr("CONTROL_SOURCE", "file"),
+		ControlFilePath:    src.envStr("CONTROL_FILE_PATH", "./control/control.json"),
+		ControlURL:         src.envRaw("CONTROL_URL"),
+		ControlToken:       src.envRaw("CONTROL_TOKEN"),
+		ControlPoll:        src.envInt("CONTROL_POLL_INTERVAL", 15),
+		ControlRequireSig:  src.envBool("CONTROL_REQUIRE_SIGNATURE", false),
+		DataDir:            src.envStr("DATA_DIR", "./data"),
+		DBPath:             src.envRaw("DB_PATH"),
+		DBRecoverCorrupt:   src.envBool("DB_RECOVER_ON_CORRUPT", false),
+		APIAddr:            src.envRaw("API_ADDR"),
+		PprofAddr:          src.envRaw("DEBUG_PPROF_ADDR"),
+		PprofAllowRemote:   src.envBool("DEBUG_PPROF_ALLOW_REMOTE", false),
+		APNsKeyBase64:      src.envRaw("APNS_KEY_BASE64"),
+		APNsKeyID:          src.envRaw("APNS_KEY_ID"),
+		APNsTeamID:         src.envRaw("APNS_TEAM_ID"),
+		APNsBundleID:       src.envRaw("APNS_BUNDLE_ID"),
+		APNsEnvironment:    src.envStr("APNS_ENVIRONMENT", "production"),
+		APNsRefreshMargin:  src.envInt("APNS_JWT_REFRESH_MARGIN", 300),
+		SMTPHost:           src.envRaw("SMTP_HOST"),
+		SMTPPort:           src.envInt("SMTP_PORT", 587),
+		SMTPUsername:       src.envRaw("SMTP_USERNAME"),
+		SMTPPassword:       src.envRaw("SMTP_PASSWORD"),
+		SMTPFrom:           src.envRaw("SMTP_FROM"),
+		WebhookURL:         src.envRaw("WEBHOOK_URL"),
+		WebhookAuth:        src.envRaw("WEBHOOK_AUTH"),
+		PushProvider:       src.envStr("PUSH_PROVIDER", "dev"),
+		CoalesceAlerts:     src.envBool("COALESCE_ALERTS", false),
+		NotifyOnStart:      src.envBool("NOTIFY_ON_START", false),
+		MonitorSuspended:   src.envBool("MONITOR_SUSPENDED", false),
+		SampleConcurrency:  src.envInt("SAMPLE_CONCURRENCY", 8),
+		IdleCycles:         src.envInt("ADAPTIVE_IDLE_CYCLES", 0),
+		IdleSampleEvery:    src.envInt("ADAPTIVE_IDLE_EVERY", 4),
+		IdleCPUPercent:     src.envInt("ADAPTIVE_IDLE_CPU", 2),
+		ConsoleLines:       src.envInt("CONSOLE_BUFFER_LINES", 50),
+		PushRateLimit:      src.envInt("PUSH_RATE_LIMIT", 10),
+		PushRateExempt:     src.envStr("PUSH_RATE_LIMIT_EXEMPT", "critical,alert_recovery"),
+		DeltaHeartbeat:     src.envInt("DELTA_STORE_HEARTBEAT", 0),
+		DeltaCPUEpsilon:    src.envInt("DELTA_STORE_CPU_EPSILON", 1),
+		DeltaMemEpsilonMB:  src.envInt("DELTA_STORE_MEM_EPSILON_MB", 16),
+	}
+
+	if unknown := src.unknownKeys(); len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown keys in config file: %s", strings.Join(unknown, ", "))
 	}
 
 	// Validate required fields
@@ -59,6 +180,38 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("PANEL_API_KEY is required")
 	}
 
+	if cfg.MetricsFormat != "verbose" && cfg.MetricsFormat != "compact" {
+		return nil, fmt.Errorf("METRICS_FORMAT must be \"verbose\" or \"compact\", got %q", cfg.MetricsFormat)
+	}
+
+	if cfg.APNsEnvironment != "production" && cfg.APNsEnvironment != "sandbox" {
+		return nil, fmt.Errorf("APNS_ENVIRONMENT must be \"production\" or \"sandbox\", got %q", cfg.APNsEnvironment)
+	}
+
+	switch cfg.ControlSource {
+	case "file":
+	case "http":
+		if cfg.ControlURL == "" {
+			return nil, fmt.Errorf("CONTROL_URL is required when CONTROL_SOURCE is \"http\"")
+		}
+	default:
+		return nil, fmt.Errorf("CONTROL_SOURCE must be \"file\" or \"http\", got %q", cfg.ControlSource)
+	}
+
+	if cfg.DBPath == "" {
+		cfg.DBPath = filepath.Join(cfg.DataDir, "agent.db")
+	} else if err := checkDBPath(cfg.DBPath); err != nil {
+		return nil, fmt.Errorf("DB_PATH: %w", err)
+	}
+
+	// Clamp APNs refresh margin below the 45 minute token lifetime
+	if cfg.APNsRefreshMargin < 0 {
+		cfg.APNsRefreshMargin = 0
+	}
+	if cfg.APNsRefreshMargin > 1800 {
+		cfg.APNsRefreshMargin = 1800
+	}
+
 	// Clamp retention
 	if cfg.RetentionDays > 30 {
 		cfg.RetentionDays = 30
@@ -71,19 +224,133 @@ func Load() (*Config, error) {
 	if cfg.SamplingInterval < 5 {
 		cfg.SamplingInterval = 5
 	}
+	if cfg.ControlPoll < 1 {
+		cfg.ControlPoll = 1
+	}
+	if cfg.ControlPoll > 300 {
+		cfg.ControlPoll = 300
+	}
+	if cfg.SampleConcurrency < 1 {
+		cfg.SampleConcurrency = 1
+	}
+	if cfg.PanelTimeout < 1 {
+		cfg.PanelTimeout = 25
+	}
+	if cfg.PanelRetries < 0 {
+		cfg.PanelRetries = 0
+	}
+	if cfg.LogTotalMaxKB < 0 {
+		cfg.LogTotalMaxKB = 0
+	}
+	if cfg.PanelErrorRetries < 0 {
+		cfg.PanelErrorRetries = 0
+	}
+	if cfg.ConsoleLines < 0 {
+		cfg.ConsoleLines = 0
+	}
+	if cfg.MinAlertCooldown < 0 {
+		cfg.MinAlertCooldown = 0
+	}
+	if cfg.MinAutoCooldown < 0 {
+		cfg.MinAutoCooldown = 0
+	}
+	if cfg.IdleCycles < 0 {
+		cfg.IdleCycles = 0
+	}
+	if cfg.IdleSampleEvery < 1 {
+		cfg.IdleSampleEvery = 1
+	}
+	if cfg.PushRateLimit < 0 {
+		cfg.PushRateLimit = 0
+	}
+	if cfg.DeltaHeartbeat < 0 {
+		cfg.DeltaHeartbeat = 0
+	}
+	if cfg.DeltaCPUEpsilon < 0 {
+		cfg.DeltaCPUEpsilon = 0
+	}
+	if cfg.DeltaMemEpsilonMB < 0 {
+		cfg.DeltaMemEpsilonMB = 0
+	}
 
 	return cfg, nil
 }
 
-func envStr(key, fallback string) string {
+// checkDBPath confirms the database path is not a directory and its parent
+// directory exists or can be created.
+func checkDBPath(path string) error {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return fmt.Errorf("%s is a directory, expected a database file path", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	return nil
+}
+
+// source resolves config values from the environment, then the optional config file.
+type source struct {
+	file map[string]string
+	used map[string]bool // keys looked up, to detect unknown file keys
+}
+
+func newSource(path string) (*source, error) {
+	src := &source{file: make(map[string]string), used: make(map[string]bool)}
+	if path == "" {
+		return src, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	for k, v := range raw {
+		switch val := v.(type) {
+		case string:
+			src.file[k] = val
+		case float64, bool:
+			src.file[k] = fmt.Sprint(val)
+		default:
+			return nil, fmt.Errorf("config file key %s: value must be a string, number or boolean", k)
+		}
+	}
+	return src, nil
+}
+
+// envRaw returns the value for key, with the environment taking precedence over the file.
+func (s *source) envRaw(key string) string {
+	s.used[key] = true
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
+	return s.file[key]
+}
+
+func (s *source) unknownKeys() []string {
+	var unknown []string
+	for k := range s.file {
+		if !s.used[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+func (s *source) envStr(key, fallback string) string {
+	if v := s.envRaw(key); v != "" {
+		return v
+	}
 	return fallback
 }
 
-func envInt(key string, fallback int) int {
-	v := os.Getenv(key)
+func (s *source) envInt(key string, fallback int) int {
+	v := s.envRaw(key)
 	if v == "" {
 		return fallback
 	}
@@ -93,3 +360,15 @@ func envInt(key string, fallback int) int {
 	}
 	return n
 }
+
+func (s *source) envBool(key string, fallback bool) bool {
+	v := s.envRaw(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
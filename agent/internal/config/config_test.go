@@ -0,0 +1,256 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// required are the settings Load insists on.
+var required = map[string]interface{}{
+	"AGENT_UUID":    "agent-1",
+	"AGENT_SECRET":  "test-agent-secret-0123456789abcdef",
+	"PANEL_URL":     "https://panel.example.com",
+	"PANEL_API_KEY": "ptla_test",
+}
+
+// writeConfigFile writes values plus the required settings to a config file, points
+// CONFIG_FILE at it and clears the environment for every key it sets.
+func writeConfigFile(t *testing.T, values map[string]interface{}) {
+	t.Helper()
+	all := make(map[string]interface{})
+	for k, v := range required {
+		all[k] = v
+	}
+	for k, v := range values {
+		all[k] = v
+	}
+	for k := range all {
+		t.Setenv(k, "")
+	}
+	t.Setenv("DATA_DIR", t.TempDir())
+
+	data, err := json.Marshal(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+}
+
+func TestLoadFromFile(t *testing.T) {
+	writeConfigFile(t, map[string]interface{}{
+		"SAMPLING_INTERVAL": 60,
+		"COALESCE_ALERTS":   true,
+		"LOG_LEVEL":         "debug",
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.AgentUUID != "agent-1" || cfg.PanelURL != "https://panel.example.com" {
+		t.Errorf("required values not read from the file: %+v", cfg)
+	}
+	if cfg.SamplingInterval != 60 || !cfg.CoalesceAlerts || cfg.LogLevel != "debug" {
+		t.Errorf("sampling %d, coalesce %v, log level %q", cfg.SamplingInterval, cfg.CoalesceAlerts, cfg.LogLevel)
+	}
+	if cfg.RetentionDays != 30 {
+		t.Errorf("retention = %d, want the default 30", cfg.RetentionDays)
+	}
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	writeConfigFile(t, map[string]interface{}{
+		"SAMPLING_INTERVAL": 60,
+		"LOG_LEVEL":         "debug",
+	})
+	t.Setenv("SAMPLING_INTERVAL", "15")
+	t.Setenv("PANEL_URL", "https://other.example.com")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.SamplingInterval != 15 {
+		t.Errorf("sampling = %d, want the env value 15", cfg.SamplingInterval)
+	}
+	if cfg.PanelURL != "https://other.example.com" {
+		t.Errorf("panel URL = %q, want the env value", cfg.PanelURL)
+	}
+	if cfg.LogLevel != "debug" {
+		t.Errorf("log level = %q, want the file value", cfg.LogLevel)
+	}
+}
+
+func TestFileValuesAreClamped(t *testing.T) {
+	writeConfigFile(t, map[string]interface{}{
+		"SAMPLING_INTERVAL": 1,
+		"RETENTION_DAYS":    365,
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.SamplingInterval != 5 || cfg.RetentionDays != 30 {
+		t.Errorf("sampling %d, retention %d; want clamped to 5 and 30", cfg.SamplingInterval, cfg.RetentionDays)
+	}
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	t.Run("unknown keys", func(t *testing.T) {
+		writeConfigFile(t, map[string]interface{}{"SAMPLNG_INTERVAL": 60})
+		_, err := Load()
+		if err == nil || !strings.Contains(err.Error(), "SAMPLNG_INTERVAL") {
+			t.Fatalf("err = %v, want the unknown key reported", err)
+		}
+	})
+
+	t.Run("nested value", func(t *testing.T) {
+		writeConfigFile(t, map[string]interface{}{"LOG_LEVEL": []string{"debug"}})
+		if _, err := Load(); err == nil {
+			t.Fatal("want an error for a non-scalar value")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.json"))
+		if _, err := Load(); err == nil {
+			t.Fatal("want an error for a missing config file")
+		}
+	})
+}
+
+func TestControlPollIntervalClamped(t *testing.T) {
+	for _, tc := range []struct {
+		value interface{}
+		want  int
+	}{
+		{nil, 15},
+		{0, 1},
+		{5, 5},
+		{1000, 300},
+	} {
+		values := map[string]interface{}{}
+		if tc.value != nil {
+			values["CONTROL_POLL_INTERVAL"] = tc.value
+		}
+		writeConfigFile(t, values)
+		t.Setenv("CONTROL_POLL_INTERVAL", "")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.ControlPoll != tc.want {
+			t.Errorf("CONTROL_POLL_INTERVAL=%v: got %d, want %d", tc.value, cfg.ControlPoll, tc.want)
+		}
+	}
+}
+
+func TestMinCooldowns(t *testing.T) {
+	writeConfigFile(t, nil)
+	t.Setenv("ALERT_MIN_COOLDOWN", "")
+	t.Setenv("AUTOMATION_MIN_COOLDOWN", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MinAlertCooldown != 60 || cfg.MinAutoCooldown != 60 {
+		t.Fatalf("default floors = %d/%d, want 60/60", cfg.MinAlertCooldown, cfg.MinAutoCooldown)
+	}
+
+	t.Setenv("ALERT_MIN_COOLDOWN", "300")
+	t.Setenv("AUTOMATION_MIN_COOLDOWN", "-5")
+	if cfg, err = Load(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MinAlertCooldown != 300 || cfg.MinAutoCooldown != 0 {
+		t.Fatalf("floors = %d/%d, want 300 and a negative value clamped to 0", cfg.MinAlertCooldown, cfg.MinAutoCooldown)
+	}
+}
+
+func TestDeltaStoreOptIn(t *testing.T) {
+	writeConfigFile(t, nil)
+	t.Setenv("DELTA_STORE_HEARTBEAT", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DeltaHeartbeat != 0 {
+		t.Fatalf("default heartbeat = %d, want delta store off", cfg.DeltaHeartbeat)
+	}
+
+	t.Setenv("DELTA_STORE_HEARTBEAT", "10")
+	t.Setenv("DELTA_STORE_CPU_EPSILON", "-2")
+	if cfg, err = Load(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DeltaHeartbeat != 10 || cfg.DeltaCPUEpsilon != 0 || cfg.DeltaMemEpsilonMB != 16 {
+		t.Fatalf("delta store = %d/%d/%d, want 10 minutes, epsilon clamped to 0, default 16MB", cfg.DeltaHeartbeat, cfg.DeltaCPUEpsilon, cfg.DeltaMemEpsilonMB)
+	}
+}
+
+func TestDBPath(t *testing.T) {
+	writeConfigFile(t, nil)
+	t.Setenv("DB_PATH", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBPath != filepath.Join(cfg.DataDir, "agent.db") {
+		t.Fatalf("default DBPath = %s, want agent.db in %s", cfg.DBPath, cfg.DataDir)
+	}
+
+	custom := filepath.Join(t.TempDir(), "ssd", "agent.db")
+	t.Setenv("DB_PATH", custom)
+	if cfg, err = Load(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBPath != custom {
+		t.Fatalf("DBPath = %s, want %s", cfg.DBPath, custom)
+	}
+	if info, err := os.Stat(filepath.Dir(custom)); err != nil || !info.IsDir() {
+		t.Fatalf("DB_PATH directory not created: %v", err)
+	}
+
+	t.Setenv("DB_PATH", t.TempDir())
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Fatalf("DB_PATH pointing at a directory: err = %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DB_PATH", filepath.Join(file, "agent.db"))
+	if _, err := Load(); err == nil {
+		t.Fatal("DB_PATH under a file: want an error")
+	}
+}
+
+func TestAdaptiveSamplingOptIn(t *testing.T) {
+	writeConfigFile(t, nil)
+	t.Setenv("ADAPTIVE_IDLE_CYCLES", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.IdleCycles != 0 {
+		t.Fatalf("default idle cycles = %d, want adaptive sampling off", cfg.IdleCycles)
+	}
+
+	t.Setenv("ADAPTIVE_IDLE_CYCLES", "20")
+	if cfg, err = Load(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.IdleCycles != 20 || cfg.IdleSampleEvery != 4 {
+		t.Fatalf("adaptive sampling = %d/%d, want 20 cycles, every 4", cfg.IdleCycles, cfg.IdleSampleEvery)
+	}
+}
@@ -0,0 +1,108 @@
+package control
+
+import "github.com/xyidactyl/agent/internal/models"
+
+// cloneControlFile returns a deep copy of cf, so callers of Get can read and even
+// modify it while the loader swaps in a new version.
+func cloneControlFile(cf *models.ControlFile) *models.ControlFile {
+	if cf == nil {
+		return nil
+	}
+	out := *cf
+
+	if cf.Users != nil {
+		out.Users = make([]models.ControlUser, len(cf.Users))
+		for i, u := range cf.Users {
+			u.AllowedServers = cloneStrings(u.AllowedServers)
+			u.DeviceTokens = cloneStrings(u.DeviceTokens)
+			u.ExcludedServers = cloneStrings(u.ExcludedServers)
+			if u.QuietHours != nil {
+				qh := *u.QuietHours
+				u.QuietHours = &qh
+			}
+			out.Users[i] = u
+		}
+	}
+
+	if cf.Alerts != nil {
+		out.Alerts = make([]models.AlertRule, len(cf.Alerts))
+		for i, a := range cf.Alerts {
+			a.Sound = cloneSound(a.Sound)
+			a.Badge = cloneBadge(a.Badge)
+			if a.Conditions != nil {
+				a.Conditions = append([]models.SubCondition(nil), a.Conditions...)
+			}
+			out.Alerts[i] = a
+		}
+	}
+
+	if cf.Automations != nil {
+		out.Automations = make([]models.AutomationRule, len(cf.Automations))
+		for i, a := range cf.Automations {
+			a.TriggerConfig = cloneMap(a.TriggerConfig)
+			a.ActionConfig = cloneMap(a.ActionConfig)
+			a.Sound = cloneSound(a.Sound)
+			a.Badge = cloneBadge(a.Badge)
+			out.Automations[i] = a
+		}
+	}
+
+	if cf.Groups != nil {
+		out.Groups = make([]models.ServerGroup, len(cf.Groups))
+		for i, g := range cf.Groups {
+			g.ServerIDs = cloneStrings(g.ServerIDs)
+			out.Groups[i] = g
+		}
+	}
+	return &out
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s...)
+}
+
+func cloneSound(p *string) *string {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func cloneBadge(p *int) *int {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// cloneMap copies a decoded JSON object, including nested objects and arrays.
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = cloneJSONValue(v)
+	}
+	return out
+}
+
+func cloneJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return cloneMap(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = cloneJSONValue(e)
+		}
+		return out
+	default:
+		return v // strings, numbers, bools and nil are immutable
+	}
+}
@@ -0,0 +1,89 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestGetReturnsCopy(t *testing.T) {
+	cf := validControlFile()
+	cf.Users[0].DeviceTokens = []string{"token-1"}
+	l, _ := writeControl(t, cf)
+	if err := l.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := l.Get()
+	got.Alerts[0].Threshold = 1
+	got.Users[0].DeviceTokens[0] = "changed"
+	got.Automations = nil
+
+	again := l.Get()
+	if again.Alerts[0].Threshold != 90 || again.Users[0].DeviceTokens[0] != "token-1" || len(again.Automations) != 1 {
+		t.Fatalf("modifying a Get result changed the loader's copy: %+v", again)
+	}
+}
+
+// TestGetDuringReload is meant for -race: readers iterate and modify their copies
+// while reloads swap in new versions.
+func TestGetDuringReload(t *testing.T) {
+	l, path := writeControl(t, validControlFile())
+	if err := l.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				cf := l.Get()
+				for j := range cf.Alerts {
+					cf.Alerts[j].Threshold++
+				}
+				for _, u := range cf.Users {
+					_ = len(u.AllowedServers)
+				}
+			}
+		}()
+	}
+
+	for v := 2; v <= 50; v++ {
+		cf := validControlFile()
+		cf.Version = v
+		for i := 0; i < v%5; i++ {
+			a := cf.Alerts[0]
+			a.ID = fmt.Sprintf("extra-%d", i)
+			cf.Alerts = append(cf.Alerts, a)
+		}
+		data, err := json.Marshal(cf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Reload(); err != nil {
+			t.Fatalf("reload version %d: %v", v, err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if l.Version() != 50 {
+		t.Errorf("version %d, want 50", l.Version())
+	}
+}
@@ -0,0 +1,126 @@
+package control
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// cooldownControlFile has enabled rules with zero, short and long cooldowns, plus a
+// disabled rule and an escalation chain, which the floor leaves alone.
+func cooldownControlFile() *models.ControlFile {
+	cf := validControlFile()
+	alert := cf.Alerts[0]
+	cf.Alerts = nil
+	for _, a := range []struct {
+		id       string
+		cooldown int
+		enabled  bool
+	}{
+		{"zero", 0, true},
+		{"short", 30, true},
+		{"long", 600, true},
+		{"disabled", 0, false},
+	} {
+		alert.ID, alert.Cooldown, alert.Enabled = a.id, a.cooldown, a.enabled
+		cf.Alerts = append(cf.Alerts, alert)
+	}
+
+	auto := cf.Automations[0]
+	cf.Automations = nil
+	for _, a := range []struct {
+		id       string
+		cooldown int
+		enabled  bool
+		config   map[string]interface{}
+	}{
+		{"zero", 0, true, nil},
+		{"long", 900, true, nil},
+		{"disabled", 5, false, nil},
+		{"escalating", 0, true, map[string]interface{}{
+			"escalation": []interface{}{map[string]interface{}{"action": "restart"}},
+		}},
+	} {
+		auto.ID, auto.Cooldown, auto.Enabled, auto.ActionConfig = a.id, a.cooldown, a.enabled, a.config
+		cf.Automations = append(cf.Automations, auto)
+	}
+	return cf
+}
+
+// cooldowns returns each rule's cooldown by ID.
+func cooldowns(cf *models.ControlFile) (alerts, automations map[string]int) {
+	alerts, automations = make(map[string]int), make(map[string]int)
+	for _, a := range cf.Alerts {
+		alerts[a.ID] = a.Cooldown
+	}
+	for _, a := range cf.Automations {
+		automations[a.ID] = a.Cooldown
+	}
+	return alerts, automations
+}
+
+func TestCooldownsClampedToMinimum(t *testing.T) {
+	_, path := writeControl(t, cooldownControlFile())
+	l := NewLoader(path, time.Minute, nil, 60, 120)
+	if err := l.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+
+	alerts, automations := cooldowns(l.Get())
+	wantAlerts := map[string]int{"zero": 60, "short": 60, "long": 600, "disabled": 0}
+	wantAutos := map[string]int{"zero": 120, "long": 900, "disabled": 5, "escalating": 0}
+	for id, want := range wantAlerts {
+		if alerts[id] != want {
+			t.Errorf("alert %s cooldown = %d, want %d", id, alerts[id], want)
+		}
+	}
+	for id, want := range wantAutos {
+		if automations[id] != want {
+			t.Errorf("automation %s cooldown = %d, want %d", id, automations[id], want)
+		}
+	}
+	if len(alerts) != len(wantAlerts) || len(automations) != len(wantAutos) {
+		t.Fatalf("rules were dropped: alerts %v, automations %v", alerts, automations)
+	}
+}
+
+func TestCooldownsClampedOnReload(t *testing.T) {
+	_, path := writeControl(t, validControlFile())
+	l := NewLoader(path, time.Minute, nil, 60, 60)
+	if err := l.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+
+	cf := cooldownControlFile()
+	cf.Version = 2
+	data, err := json.Marshal(cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	alerts, automations := cooldowns(l.Get())
+	if alerts["zero"] != 60 || automations["zero"] != 60 {
+		t.Fatalf("reloaded cooldowns: alerts %v, automations %v, want zero raised to 60", alerts, automations)
+	}
+}
+
+func TestCooldownFloorOff(t *testing.T) {
+	_, path := writeControl(t, cooldownControlFile())
+	l := NewLoader(path, time.Minute, nil, 0, 0)
+	if err := l.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+	alerts, automations := cooldowns(l.Get())
+	if alerts["zero"] != 0 || alerts["short"] != 30 || automations["zero"] != 0 {
+		t.Fatalf("cooldowns changed with no floor: alerts %v, automations %v", alerts, automations)
+	}
+}
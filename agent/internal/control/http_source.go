@@ -0,0 +1,93 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/security"
+)
+
+const (
+	httpSourceTimeout = 15 * time.Second
+	maxControlSize    = 10 << 20 // bytes accepted from the control endpoint
+)
+
+// HTTPSource polls a control endpoint for control.json instead of reading a shared
+// volume. Requests carry the agent UUID and a bearer token, and the ETag of the last
+// response so an unchanged file is not downloaded again. Validation, signatures,
+// cooldown floors and reload callbacks are handled by the embedded Loader.
+type HTTPSource struct {
+	*Loader
+	url       string
+	token     string
+	agentUUID string
+	client    *http.Client
+
+	// Last response, only touched by fetch, which the Loader never runs concurrently
+	etag string
+	body []byte
+}
+
+// NewHTTPSource creates a control source that fetches url every pollInterval.
+// The remaining parameters are as for NewLoader.
+func NewHTTPSource(url, token, agentUUID string, pollInterval time.Duration, verifier *security.Crypto, minAlertCooldown, minAutomationCooldown int) *HTTPSource {
+	h := &HTTPSource{
+		Loader:    NewLoader("", pollInterval, verifier, minAlertCooldown, minAutomationCooldown),
+		url:       url,
+		token:     token,
+		agentUUID: agentUUID,
+		client:    &http.Client{Timeout: httpSourceTimeout},
+	}
+	h.Loader.read = h.fetch
+	return h
+}
+
+// fetch downloads the control file, reusing the cached copy on 304 Not Modified.
+// A 404 means nothing has been published for this agent yet.
+func (h *HTTPSource) fetch() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), httpSourceTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Agent-UUID", h.agentUUID)
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+	if h.etag != "" && h.body != nil {
+		req.Header.Set("If-None-Match", h.etag)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch control file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxControlSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("read control file: %w", err)
+		}
+		if len(body) > maxControlSize {
+			return nil, fmt.Errorf("control file exceeds %d bytes", maxControlSize)
+		}
+		h.etag = resp.Header.Get("ETag")
+		h.body = body
+		return body, nil
+	case http.StatusNotModified:
+		return h.body, nil
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("control endpoint: %w", os.ErrNotExist)
+	default:
+		return nil, fmt.Errorf("control endpoint returned %s", resp.Status)
+	}
+}
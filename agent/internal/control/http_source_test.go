@@ -0,0 +1,141 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// controlServer serves a control file with an ETag and records each request.
+type controlServer struct {
+	mu       sync.Mutex
+	body     []byte
+	etag     string
+	status   int // overrides the response when non-zero
+	requests []*http.Request
+}
+
+func (s *controlServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+	switch {
+	case s.status != 0:
+		w.WriteHeader(s.status)
+	case s.etag != "" && r.Header.Get("If-None-Match") == s.etag:
+		w.WriteHeader(http.StatusNotModified)
+	default:
+		w.Header().Set("ETag", s.etag)
+		w.Write(s.body)
+	}
+}
+
+func (s *controlServer) lastRequest() *http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[len(s.requests)-1]
+}
+
+func newControlServer(t *testing.T, version int, etag string) (*controlServer, *HTTPSource) {
+	t.Helper()
+	cf := validControlFile()
+	cf.Version = version
+	body, err := json.Marshal(cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := &controlServer{body: body, etag: etag}
+	srv := httptest.NewServer(cs)
+	t.Cleanup(srv.Close)
+	return cs, NewHTTPSource(srv.URL, "secret-token", "agent-1", time.Minute, nil, 0, 0)
+}
+
+func TestHTTPSourceLoadsWithCredentials(t *testing.T) {
+	cs, h := newControlServer(t, 3, `"v3"`)
+	if err := h.LoadInitial(); err != nil {
+		t.Fatalf("LoadInitial: %v", err)
+	}
+	if h.Version() != 3 || len(h.Get().Alerts) != 1 {
+		t.Fatalf("loaded version %d with %d alerts", h.Version(), len(h.Get().Alerts))
+	}
+
+	r := cs.lastRequest()
+	if r.Header.Get("Authorization") != "Bearer secret-token" || r.Header.Get("X-Agent-UUID") != "agent-1" {
+		t.Errorf("request headers %v", r.Header)
+	}
+	if r.Header.Get("If-None-Match") != "" {
+		t.Error("first request sent If-None-Match")
+	}
+}
+
+func TestHTTPSourceETagNotModified(t *testing.T) {
+	cs, h := newControlServer(t, 3, `"v3"`)
+	if err := h.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Reload(); err != nil {
+		t.Fatalf("Reload on 304: %v", err)
+	}
+	if got := cs.lastRequest().Header.Get("If-None-Match"); got != `"v3"` {
+		t.Errorf("If-None-Match = %q, want \"v3\"", got)
+	}
+	if h.Version() != 3 {
+		t.Errorf("version %d after 304, want 3", h.Version())
+	}
+
+	// A new version with a new ETag is downloaded and applied
+	cf := validControlFile()
+	cf.Version = 4
+	body, _ := json.Marshal(cf)
+	cs.mu.Lock()
+	cs.body, cs.etag = body, `"v4"`
+	cs.mu.Unlock()
+	if err := h.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if h.Version() != 4 {
+		t.Errorf("version %d after update, want 4", h.Version())
+	}
+}
+
+func TestHTTPSourceNotFound(t *testing.T) {
+	cs, h := newControlServer(t, 3, "")
+	cs.status = http.StatusNotFound
+
+	if _, err := h.fetch(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("fetch on 404: %v, want os.ErrNotExist", err)
+	}
+	if err := h.LoadInitial(); err != nil {
+		t.Fatalf("LoadInitial on 404: %v", err)
+	}
+	if h.Version() != 0 {
+		t.Errorf("version %d, want empty configuration", h.Version())
+	}
+}
+
+func TestHTTPSourceSizeLimit(t *testing.T) {
+	cs, h := newControlServer(t, 3, "")
+	cs.body = []byte(`{"version":3,"pad":"` + strings.Repeat("x", maxControlSize) + `"}`)
+
+	_, err := h.fetch()
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("fetch of oversized file: %v, want size error", err)
+	}
+	if h.body != nil {
+		t.Error("oversized body was cached")
+	}
+}
+
+func TestHTTPSourceServerError(t *testing.T) {
+	cs, h := newControlServer(t, 3, "")
+	cs.status = http.StatusInternalServerError
+	if err := h.LoadInitial(); err == nil {
+		t.Fatal("LoadInitial succeeded on 500")
+	}
+}
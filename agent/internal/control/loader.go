@@ -2,40 +2,83 @@ package control
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/xyidactyl/agent/internal/cron"
 	"github.com/xyidactyl/agent/internal/logging"
 	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/pterodactyl"
+	"github.com/xyidactyl/agent/internal/security"
 )
 
+// maxSnooze is the furthest in the future a user's snooze_until may be.
+const maxSnooze = 30 * 24 * time.Hour
+
+// Source provides the agent's control configuration and reports changes to it.
+// Loader reads control.json from disk; HTTPSource fetches it from an endpoint.
+type Source interface {
+	LoadInitial() error
+	Start()
+	Stop()
+	Get() *models.ControlFile // a deep copy, see Loader.Get
+	Version() int
+	PollInterval() time.Duration
+	OnReload(fn func())
+	Reload() error
+	LastError() (string, time.Time)
+}
+
 // Loader watches control.json and reloads configuration when the version changes.
 type Loader struct {
-	mu          sync.RWMutex
-	filePath    string
-	current     *models.ControlFile
-	version     int
+	mu           sync.RWMutex
+	filePath     string                 // empty for remote sources; no .lastgood copy is kept then
+	read         func() ([]byte, error) // returns the raw file; an os.ErrNotExist error means none yet
+	current      *models.ControlFile
+	version      int
 	pollInterval time.Duration
-	stopCh      chan struct{}
+	stopCh       chan struct{}
+	verifier     *security.Crypto // when set, control.json must carry a valid signature
+
+	// Cooldown floors in seconds; enabled rules below them are clamped on load
+	minAlertCooldown      int
+	minAutomationCooldown int
+
+	lastGood  []byte // raw bytes of the last accepted file, restored to .lastgood on rejection
+	lastErr   string // last validation error, cleared by a successful reload
+	lastErrAt time.Time
+
+	onReload func() // called after a new version is accepted, see OnReload
+
+	checkMu sync.Mutex // serializes checkForUpdate between the poll loop and Reload
 }
 
-// NewLoader creates a new control file loader.
-func NewLoader(filePath string) *Loader {
+// NewLoader creates a new control file loader that checks for changes every pollInterval.
+// If verifier is non-nil, files without a valid signature are rejected. Enabled rules
+// with a cooldown below minAlertCooldown/minAutomationCooldown seconds are raised to it.
+func NewLoader(filePath string, pollInterval time.Duration, verifier *security.Crypto, minAlertCooldown, minAutomationCooldown int) *Loader {
 	return &Loader{
-		filePath:     filePath,
-		pollInterval: 15 * time.Second,
-		stopCh:       make(chan struct{}),
+		filePath:              filePath,
+		read:                  func() ([]byte, error) { return os.ReadFile(filePath) },
+		verifier:              verifier,
+		pollInterval:          pollInterval,
+		stopCh:                make(chan struct{}),
+		minAlertCooldown:      minAlertCooldown,
+		minAutomationCooldown: minAutomationCooldown,
 	}
 }
 
 // LoadInitial performs the first load of control.json. Returns error if file doesn't exist or is invalid.
 func (l *Loader) LoadInitial() error {
-	cf, err := l.readFile()
+	cf, raw, err := l.readFile()
 	if err != nil {
 		// If file doesn't exist, start with empty config
-		if os.IsNotExist(err) {
+		if errors.Is(err, os.ErrNotExist) {
 			logging.Info("No control.json found, starting with empty configuration")
 			l.mu.Lock()
 			l.current = &models.ControlFile{Version: 0}
@@ -46,9 +89,23 @@ func (l *Loader) LoadInitial() error {
 		return fmt.Errorf("initial load: %w", err)
 	}
 
+	// A rejected file is retried by the poll loop, since its version differs from 0
+	if err := l.validate(cf, raw); err != nil {
+		l.reject(cf.Version, err)
+		logging.Warn("Starting with empty configuration until control.json is fixed")
+		l.mu.Lock()
+		l.current = &models.ControlFile{Version: 0}
+		l.version = 0
+		l.mu.Unlock()
+		return nil
+	}
+
+	l.clampCooldowns(cf)
+
 	l.mu.Lock()
 	l.current = cf
 	l.version = cf.Version
+	l.lastGood = raw
 	l.mu.Unlock()
 
 	logging.Info("Loaded control.json version %d (%d users, %d alerts, %d automations)",
@@ -56,6 +113,13 @@ func (l *Loader) LoadInitial() error {
 	return nil
 }
 
+// OnReload registers fn to run after each accepted version change.
+func (l *Loader) OnReload(fn func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onReload = fn
+}
+
 // Start begins the periodic polling loop.
 func (l *Loader) Start() {
 	go l.pollLoop()
@@ -66,11 +130,18 @@ func (l *Loader) Stop() {
 	close(l.stopCh)
 }
 
-// Get returns the current control file (thread-safe).
+// Get returns a deep copy of the current control file. Each call gets its own copy,
+// so callers may iterate or modify it freely while a reload swaps in a new version,
+// but should call Get once per pass rather than per lookup.
 func (l *Loader) Get() *models.ControlFile {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	return l.current
+	return cloneControlFile(l.current)
+}
+
+// PollInterval returns how often control.json is checked for changes.
+func (l *Loader) PollInterval() time.Duration {
+	return l.pollInterval
 }
 
 // Version returns the current loaded version.
@@ -94,14 +165,24 @@ func (l *Loader) pollLoop() {
 	}
 }
 
-func (l *Loader) checkForUpdate() {
+// Reload checks for a new version right away instead of waiting for the next poll.
+// It returns the read or validation error, if any; an unchanged or missing file is not an error.
+func (l *Loader) Reload() error {
+	return l.checkForUpdate()
+}
+
+func (l *Loader) checkForUpdate() error {
+	l.checkMu.Lock()
+	defer l.checkMu.Unlock()
+
 	// Quick version check: read file and compare version only
-	cf, err := l.readFile()
+	cf, raw, err := l.readFile()
 	if err != nil {
-		if !os.IsNotExist(err) {
-			logging.Warn("Failed to read control.json: %v", err)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
 		}
-		return
+		logging.Warn("Failed to read control.json: %v", err)
+		return err
 	}
 
 	l.mu.RLock()
@@ -109,72 +190,397 @@ func (l *Loader) checkForUpdate() {
 	l.mu.RUnlock()
 
 	if cf.Version == currentVersion {
-		return // No change
+		return nil // No change
 	}
 
 	// Validate before accepting
-	if err := l.validate(cf); err != nil {
-		logging.Error("Invalid control.json version %d: %v", cf.Version, err)
-		return
+	if err := l.validate(cf, raw); err != nil {
+		l.reject(cf.Version, err)
+		return err
 	}
 
+	l.clampCooldowns(cf)
+
 	l.mu.Lock()
 	l.current = cf
 	l.version = cf.Version
+	l.lastGood = raw
+	l.lastErr = ""
+	onReload := l.onReload
 	l.mu.Unlock()
 
 	logging.Info("Reloaded control.json: version %d → %d (%d users, %d alerts, %d automations)",
 		currentVersion, cf.Version, len(cf.Users), len(cf.Alerts), len(cf.Automations))
+
+	if onReload != nil {
+		onReload()
+	}
+	return nil
 }
 
-func (l *Loader) readFile() (*models.ControlFile, error) {
-	data, err := os.ReadFile(l.filePath)
+// reject records a validation failure and saves the last accepted file next to
+// control.json so operators can restore it.
+func (l *Loader) reject(version int, err error) {
+	l.mu.Lock()
+	alreadyReported := l.lastErr == err.Error()
+	l.lastErr = err.Error()
+	l.lastErrAt = time.Now()
+	lastGood := l.lastGood
+	l.mu.Unlock()
+
+	// The bad file stays in place until fixed; only log each distinct error once
+	if alreadyReported {
+		return
+	}
+	logging.Error("Invalid control.json version %d: %v", version, err)
+
+	if lastGood == nil || l.filePath == "" {
+		return
+	}
+	path := l.filePath + ".lastgood"
+	tmp := path + ".tmp"
+	if werr := os.WriteFile(tmp, lastGood, 0600); werr != nil {
+		logging.Warn("Failed to save last good control.json: %v", werr)
+		return
+	}
+	if werr := os.Rename(tmp, path); werr != nil {
+		logging.Warn("Failed to save last good control.json: %v", werr)
+		return
+	}
+	logging.Info("Last accepted control.json saved to %s", path)
+}
+
+// LastError returns the most recent validation error and when it happened,
+// or "" if the latest file was accepted.
+func (l *Loader) LastError() (string, time.Time) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.lastErr, l.lastErrAt
+}
+
+func (l *Loader) readFile() (*models.ControlFile, []byte, error) {
+	data, err := l.read()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var cf models.ControlFile
 	if err := json.Unmarshal(data, &cf); err != nil {
-		return nil, fmt.Errorf("parse control.json: %w", err)
+		return nil, nil, fmt.Errorf("parse control.json: %w", err)
 	}
 
-	return &cf, nil
+	return &cf, data, nil
+}
+
+// verifySignature checks the file signature when signatures are required.
+func (l *Loader) verifySignature(raw []byte) error {
+	if l.verifier == nil {
+		return nil
+	}
+	return l.verifier.VerifyControlFile(raw)
 }
 
-func (l *Loader) validate(cf *models.ControlFile) error {
+func (l *Loader) validate(cf *models.ControlFile, raw []byte) error {
+	if err := l.verifySignature(raw); err != nil {
+		return err
+	}
+
 	// Basic structural validation
+	users := make(map[string]bool)
 	for i, u := range cf.Users {
 		if u.UserUUID == "" {
 			return fmt.Errorf("user[%d]: empty user_uuid", i)
 		}
+		if users[u.UserUUID] {
+			return fmt.Errorf("user[%d]: duplicate user_uuid %s", i, u.UserUUID)
+		}
+		users[u.UserUUID] = true
 		if u.APIKeyEncrypted == "" {
 			return fmt.Errorf("user[%d] (%s): empty api_key_encrypted", i, u.UserUUID)
 		}
+		if u.SnoozeUntil > time.Now().Add(maxSnooze).Unix() {
+			return fmt.Errorf("user[%d] (%s): snooze_until is more than %d days in the future", i, u.UserUUID, int(maxSnooze.Hours()/24))
+		}
+		if u.PanelURL != "" {
+			if err := validateHTTPURL(u.PanelURL); err != nil {
+				return fmt.Errorf("user[%d] (%s): panel_url: %w", i, u.UserUUID, err)
+			}
+		}
+		if u.WebhookURL != "" {
+			if err := validateHTTPURL(u.WebhookURL); err != nil {
+				return fmt.Errorf("user[%d] (%s): webhook_url: %w", i, u.UserUUID, err)
+			}
+		}
+		if u.QuietHours != nil {
+			if err := validateQuietHours(*u.QuietHours); err != nil {
+				return fmt.Errorf("user[%d] (%s): quiet_hours: %w", i, u.UserUUID, err)
+			}
+		}
+		if err := validateExcluded(u); err != nil {
+			return fmt.Errorf("user[%d] (%s): excluded_servers: %w", i, u.UserUUID, err)
+		}
 	}
 
+	groups := make(map[string]bool)
+	for i, g := range cf.Groups {
+		if g.ID == "" {
+			return fmt.Errorf("group[%d]: empty id", i)
+		}
+		if groups[g.ID] {
+			return fmt.Errorf("group[%d]: duplicate id %s", i, g.ID)
+		}
+		groups[g.ID] = true
+		if len(g.ServerIDs) == 0 {
+			return fmt.Errorf("group[%d] (%s): no server_ids", i, g.ID)
+		}
+	}
+
+	alertIDs := make(map[string]bool)
 	for i, a := range cf.Alerts {
 		if a.ID == "" {
 			return fmt.Errorf("alert[%d]: empty id", i)
 		}
-		if a.UserUUID == "" {
-			return fmt.Errorf("alert[%d] (%s): empty user_uuid", i, a.ID)
+		if alertIDs[a.ID] {
+			return fmt.Errorf("alert[%d]: duplicate id %s", i, a.ID)
 		}
-		if a.ServerID == "" {
-			return fmt.Errorf("alert[%d] (%s): empty server_id", i, a.ID)
+		alertIDs[a.ID] = true
+		if err := ValidateAlert(a, groups); err != nil {
+			return fmt.Errorf("alert[%d] (%s): %w", i, a.ID, err)
 		}
 	}
 
+	autoIDs := make(map[string]bool)
 	for i, a := range cf.Automations {
 		if a.ID == "" {
 			return fmt.Errorf("automation[%d]: empty id", i)
 		}
+		if autoIDs[a.ID] {
+			return fmt.Errorf("automation[%d]: duplicate id %s", i, a.ID)
+		}
+		autoIDs[a.ID] = true
+		if !a.TriggerType.Valid() {
+			return fmt.Errorf("automation[%d] (%s): unknown trigger_type %q", i, a.ID, a.TriggerType)
+		}
+		if err := validateActions(a); err != nil {
+			return fmt.Errorf("automation[%d] (%s): %w", i, a.ID, err)
+		}
+		if a.Badge != nil && *a.Badge < 0 {
+			return fmt.Errorf("automation[%d] (%s): badge must not be negative", i, a.ID)
+		}
+		if a.ActiveCron != "" {
+			if _, err := cron.Parse(a.ActiveCron); err != nil {
+				return fmt.Errorf("automation[%d] (%s): invalid active_cron: %w", i, a.ID, err)
+			}
+		}
+		if a.Cooldown < 0 {
+			return fmt.Errorf("automation[%d] (%s): cooldown must not be negative", i, a.ID)
+		}
 		if a.UserUUID == "" {
 			return fmt.Errorf("automation[%d] (%s): empty user_uuid", i, a.ID)
 		}
 		if a.ServerID == "" {
 			return fmt.Errorf("automation[%d] (%s): empty server_id", i, a.ID)
 		}
+		if err := checkGroupRef(a.ServerID, groups); err != nil {
+			return fmt.Errorf("automation[%d] (%s): %w", i, a.ID, err)
+		}
 	}
 
 	return nil
 }
+
+// validateExcluded requires each excluded server to be listed once and to be one of the
+// user's allowed servers, so a typo can't silently leave the intended server sampled.
+func validateExcluded(u models.ControlUser) error {
+	allowed := make(map[string]bool, len(u.AllowedServers))
+	for _, s := range u.AllowedServers {
+		allowed[s] = true
+	}
+	seen := make(map[string]bool, len(u.ExcludedServers))
+	for _, s := range u.ExcludedServers {
+		if seen[s] {
+			return fmt.Errorf("%s is listed twice", s)
+		}
+		seen[s] = true
+		if !allowed[s] {
+			return fmt.Errorf("%s is not in allowed_servers", s)
+		}
+	}
+	return nil
+}
+
+// validateHTTPURL requires an absolute http(s) URL with a host.
+func validateHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q must be an absolute http(s) URL", raw)
+	}
+	return nil
+}
+
+// validateQuietHours checks the window times, timezone and mode.
+func validateQuietHours(q models.QuietHours) error {
+	for _, t := range []string{q.Start, q.End} {
+		if _, err := time.Parse("15:04", t); err != nil {
+			return fmt.Errorf("%q is not HH:MM", t)
+		}
+	}
+	if q.Start == q.End {
+		return fmt.Errorf("start and end must differ")
+	}
+	if q.TZ != "" {
+		if _, err := time.LoadLocation(q.TZ); err != nil {
+			return fmt.Errorf("invalid tz %q", q.TZ)
+		}
+	}
+	switch q.Mode {
+	case "", "drop", "queue":
+	default:
+		return fmt.Errorf("mode must be \"drop\" or \"queue\", got %q", q.Mode)
+	}
+	return nil
+}
+
+// ValidateAlert checks a single alert rule's fields. groups holds the known group IDs.
+func ValidateAlert(a models.AlertRule, groups map[string]bool) error {
+	if !a.ConditionType.Valid() {
+		return fmt.Errorf("unknown condition_type %q", a.ConditionType)
+	}
+	if a.Cooldown < 0 || a.Duration < 0 {
+		return fmt.Errorf("cooldown and duration must not be negative")
+	}
+	if a.UserUUID == "" {
+		return fmt.Errorf("empty user_uuid")
+	}
+	if a.ServerID == "" {
+		return fmt.Errorf("empty server_id")
+	}
+	if err := checkGroupRef(a.ServerID, groups); err != nil {
+		return err
+	}
+	switch a.Severity {
+	case "", "info", "warning", "critical":
+	default:
+		return fmt.Errorf("unknown severity %q", a.Severity)
+	}
+	if a.Smoothing < 0 || a.Smoothing >= 1 {
+		return fmt.Errorf("smoothing must be in [0, 1)")
+	}
+	if a.EscalateAfter < 0 || a.EscalateWindow < 0 {
+		return fmt.Errorf("escalate_after and escalate_window must not be negative")
+	}
+	if a.Badge != nil && *a.Badge < 0 {
+		return fmt.Errorf("badge must not be negative")
+	}
+	if a.ActiveCron != "" {
+		if _, err := cron.Parse(a.ActiveCron); err != nil {
+			return fmt.Errorf("invalid active_cron: %w", err)
+		}
+	}
+	if a.ConditionType == models.ConditionDataStale && a.Threshold <= 0 {
+		return fmt.Errorf("data_stale threshold must be a positive number of seconds")
+	}
+	if a.ConditionType == models.ConditionComposite {
+		if err := validateComposite(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkGroupRef fails when serverID is a "group:<id>" reference to an undefined group.
+func checkGroupRef(serverID string, groups map[string]bool) error {
+	groupID, ok := strings.CutPrefix(serverID, models.GroupPrefix)
+	if ok && !groups[groupID] {
+		return fmt.Errorf("server_id %q references unknown group", serverID)
+	}
+	return nil
+}
+
+func validateComposite(a models.AlertRule) error {
+	op := strings.ToLower(a.Operator)
+	if op != "and" && op != "or" {
+		return fmt.Errorf("composite operator must be \"and\" or \"or\", got %q", a.Operator)
+	}
+	if len(a.Conditions) == 0 {
+		return fmt.Errorf("composite rule has no conditions")
+	}
+	for j, c := range a.Conditions {
+		if c.ConditionType == "" {
+			return fmt.Errorf("conditions[%d]: empty condition_type", j)
+		}
+		if c.ConditionType == models.ConditionComposite {
+			return fmt.Errorf("conditions[%d]: nested composite conditions are not supported", j)
+		}
+		if !c.ConditionType.Valid() {
+			return fmt.Errorf("conditions[%d]: unknown condition_type %q", j, c.ConditionType)
+		}
+	}
+	return nil
+}
+
+// clampCooldowns raises enabled rules' cooldowns to the configured floors, so a zero
+// cooldown can't fire on every sampling cycle. Escalation chains pace themselves with
+// per-step waits and are left alone.
+func (l *Loader) clampCooldowns(cf *models.ControlFile) {
+	for i := range cf.Alerts {
+		a := &cf.Alerts[i]
+		if a.Enabled && a.Cooldown < l.minAlertCooldown {
+			logging.Warn("Alert %s has cooldown %ds, raising it to the %ds minimum (ALERT_MIN_COOLDOWN)",
+				a.ID, a.Cooldown, l.minAlertCooldown)
+			a.Cooldown = l.minAlertCooldown
+		}
+	}
+	for i := range cf.Automations {
+		a := &cf.Automations[i]
+		if _, escalates := a.ActionConfig["escalation"]; escalates || !a.Enabled {
+			continue
+		}
+		if a.Cooldown < l.minAutomationCooldown {
+			logging.Warn("Automation %s has cooldown %ds, raising it to the %ds minimum (AUTOMATION_MIN_COOLDOWN)",
+				a.ID, a.Cooldown, l.minAutomationCooldown)
+			a.Cooldown = l.minAutomationCooldown
+		}
+	}
+}
+
+// validateActions checks the rule's action, or each step of its escalation chain.
+func validateActions(a models.AutomationRule) error {
+	steps, ok := a.ActionConfig["escalation"].([]interface{})
+	if !ok {
+		return validateAction(a.Action, a.ActionConfig)
+	}
+	for j, item := range steps {
+		step, _ := item.(map[string]interface{})
+		action, _ := step["action"].(string)
+		if action == string(models.ActionPower) {
+			return fmt.Errorf("escalation[%d]: use the named restart, stop, start or kill actions in escalation steps", j)
+		}
+		if err := validateAction(models.ActionType(action), step); err != nil {
+			return fmt.Errorf("escalation[%d]: %w", j, err)
+		}
+	}
+	return nil
+}
+
+// validateAction checks an action against the settings it needs from its config: a
+// rule's action_config, or the escalation step itself.
+func validateAction(action models.ActionType, cfg map[string]interface{}) error {
+	if !action.Valid() {
+		return fmt.Errorf("unknown action %q", action)
+	}
+	switch action {
+	case models.ActionPower:
+		if signal, _ := cfg["signal"].(string); !pterodactyl.IsPowerSignal(signal) {
+			return fmt.Errorf("power action needs action_config signal start, stop, restart or kill, got %q", signal)
+		}
+	case models.ActionReinstall:
+		if confirm, _ := cfg["confirm"].(bool); !confirm {
+			return fmt.Errorf("reinstall action needs \"confirm\": true")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package control
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// validControlFile is a minimal control file that passes validation.
+func validControlFile() *models.ControlFile {
+	return &models.ControlFile{
+		Version: 1,
+		Users: []models.ControlUser{{
+			UserUUID:        "user-1",
+			APIKeyEncrypted: "enc",
+			AllowedServers:  []string{"srv-1", "srv-2"},
+		}},
+		Alerts: []models.AlertRule{{
+			ID:            "cpu-high",
+			UserUUID:      "user-1",
+			ServerID:      "srv-1",
+			ConditionType: models.ConditionCPU,
+			Threshold:     90,
+			Enabled:       true,
+		}},
+		Automations: []models.AutomationRule{{
+			ID:          "restart-crash",
+			UserUUID:    "user-1",
+			ServerID:    "srv-1",
+			TriggerType: models.TriggerCrash,
+			Action:      models.ActionRestart,
+			Enabled:     true,
+		}},
+	}
+}
+
+// writeControl writes cf as control.json in a temp dir and returns a loader for it.
+func writeControl(t *testing.T, cf *models.ControlFile) (*Loader, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "control.json")
+	data, err := json.Marshal(cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return NewLoader(path, time.Minute, nil, 0, 0), path
+}
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(cf *models.ControlFile)
+		wantErr string // "" = valid
+	}{
+		{"valid", func(cf *models.ControlFile) {}, ""},
+		{"duplicate user", func(cf *models.ControlFile) {
+			cf.Users = append(cf.Users, cf.Users[0])
+		}, "duplicate user_uuid"},
+		{"missing api key", func(cf *models.ControlFile) {
+			cf.Users[0].APIKeyEncrypted = ""
+		}, "empty api_key_encrypted"},
+		{"snooze within a day", func(cf *models.ControlFile) {
+			cf.Users[0].SnoozeUntil = time.Now().Add(2 * time.Hour).Unix()
+		}, ""},
+		{"snooze too far ahead", func(cf *models.ControlFile) {
+			cf.Users[0].SnoozeUntil = time.Now().Add(90 * 24 * time.Hour).Unix()
+		}, "snooze_until is more than 30 days"},
+		{"panel url", func(cf *models.ControlFile) {
+			cf.Users[0].PanelURL = "https://panel-eu.example.com"
+		}, ""},
+		{"panel url without scheme", func(cf *models.ControlFile) {
+			cf.Users[0].PanelURL = "panel-eu.example.com"
+		}, "panel_url"},
+		{"panel url bad scheme", func(cf *models.ControlFile) {
+			cf.Users[0].PanelURL = "ftp://panel-eu.example.com"
+		}, "panel_url"},
+		{"excluded server", func(cf *models.ControlFile) {
+			cf.Users[0].ExcludedServers = []string{"srv-2"}
+		}, ""},
+		{"excluded server not allowed", func(cf *models.ControlFile) {
+			cf.Users[0].ExcludedServers = []string{"srv-9"}
+		}, "srv-9 is not in allowed_servers"},
+		{"excluded server twice", func(cf *models.ControlFile) {
+			cf.Users[0].ExcludedServers = []string{"srv-2", "srv-2"}
+		}, "srv-2 is listed twice"},
+		{"quiet hours", func(cf *models.ControlFile) {
+			cf.Users[0].QuietHours = &models.QuietHours{Start: "22:00", End: "07:00", TZ: "Europe/Berlin", Mode: "queue"}
+		}, ""},
+		{"quiet hours bad time", func(cf *models.ControlFile) {
+			cf.Users[0].QuietHours = &models.QuietHours{Start: "10pm", End: "07:00"}
+		}, "is not HH:MM"},
+		{"quiet hours bad tz", func(cf *models.ControlFile) {
+			cf.Users[0].QuietHours = &models.QuietHours{Start: "22:00", End: "07:00", TZ: "Mars/Olympus"}
+		}, "invalid tz"},
+		{"quiet hours bad mode", func(cf *models.ControlFile) {
+			cf.Users[0].QuietHours = &models.QuietHours{Start: "22:00", End: "07:00", Mode: "mute"}
+		}, "mode must be"},
+		{"duplicate alert id", func(cf *models.ControlFile) {
+			cf.Alerts = append(cf.Alerts, cf.Alerts[0])
+		}, "duplicate id cpu-high"},
+		{"unknown condition", func(cf *models.ControlFile) {
+			cf.Alerts[0].ConditionType = "cpu_treshold"
+		}, `unknown condition_type "cpu_treshold"`},
+		{"negative alert cooldown", func(cf *models.ControlFile) {
+			cf.Alerts[0].Cooldown = -1
+		}, "must not be negative"},
+		{"unknown severity", func(cf *models.ControlFile) {
+			cf.Alerts[0].Severity = "urgent"
+		}, `unknown severity "urgent"`},
+		{"alert active cron", func(cf *models.ControlFile) {
+			cf.Alerts[0].ActiveCron = "* 9-17 * * mon-fri"
+		}, ""},
+		{"alert bad active cron", func(cf *models.ControlFile) {
+			cf.Alerts[0].ActiveCron = "* 25 * * *"
+		}, "active_cron"},
+		{"automation bad active cron", func(cf *models.ControlFile) {
+			cf.Automations[0].ActiveCron = "every day"
+		}, "active_cron"},
+		{"negative escalation", func(cf *models.ControlFile) {
+			cf.Alerts[0].EscalateAfter = -1
+		}, "escalate_after and escalate_window must not be negative"},
+		{"smoothing out of range", func(cf *models.ControlFile) {
+			cf.Alerts[0].Smoothing = 1
+		}, "smoothing"},
+		{"unknown group", func(cf *models.ControlFile) {
+			cf.Alerts[0].ServerID = "group:web"
+		}, "references unknown group"},
+		{"known group", func(cf *models.ControlFile) {
+			cf.Groups = []models.ServerGroup{{ID: "web", ServerIDs: []string{"srv-1", "srv-2"}}}
+			cf.Alerts[0].ServerID = "group:web"
+			cf.Automations[0].ServerID = "group:web"
+		}, ""},
+		{"automation unknown group", func(cf *models.ControlFile) {
+			cf.Groups = []models.ServerGroup{{ID: "web", ServerIDs: []string{"srv-1"}}}
+			cf.Automations[0].ServerID = "group:db"
+		}, "references unknown group"},
+		{"duplicate group", func(cf *models.ControlFile) {
+			cf.Groups = []models.ServerGroup{{ID: "web", ServerIDs: []string{"srv-1"}}, {ID: "web", ServerIDs: []string{"srv-2"}}}
+		}, "duplicate id web"},
+		{"empty group", func(cf *models.ControlFile) {
+			cf.Groups = []models.ServerGroup{{ID: "web"}}
+		}, "no server_ids"},
+		{"valid composite", func(cf *models.ControlFile) {
+			cf.Alerts[0].ConditionType = models.ConditionComposite
+			cf.Alerts[0].Operator = "AND"
+			cf.Alerts[0].Conditions = []models.SubCondition{{ConditionType: models.ConditionCPU, Threshold: 90}, {ConditionType: models.ConditionRAM, Threshold: 80}}
+		}, ""},
+		{"composite without operator", func(cf *models.ControlFile) {
+			cf.Alerts[0].ConditionType = models.ConditionComposite
+			cf.Alerts[0].Conditions = []models.SubCondition{{ConditionType: models.ConditionCPU, Threshold: 90}}
+		}, "composite operator"},
+		{"composite without conditions", func(cf *models.ControlFile) {
+			cf.Alerts[0].ConditionType = models.ConditionComposite
+			cf.Alerts[0].Operator = "or"
+		}, "no conditions"},
+		{"nested composite", func(cf *models.ControlFile) {
+			cf.Alerts[0].ConditionType = models.ConditionComposite
+			cf.Alerts[0].Operator = "or"
+			cf.Alerts[0].Conditions = []models.SubCondition{{ConditionType: models.ConditionComposite}}
+		}, "nested composite"},
+		{"alert without user", func(cf *models.ControlFile) {
+			cf.Alerts[0].UserUUID = ""
+		}, "empty user_uuid"},
+		{"duplicate automation id", func(cf *models.ControlFile) {
+			cf.Automations = append(cf.Automations, cf.Automations[0])
+		}, "duplicate id restart-crash"},
+		{"unknown trigger", func(cf *models.ControlFile) {
+			cf.Automations[0].TriggerType = "server_crashed"
+		}, `unknown trigger_type "server_crashed"`},
+		{"unknown action", func(cf *models.ControlFile) {
+			cf.Automations[0].Action = "reboot"
+		}, `unknown action "reboot"`},
+		{"power without signal", func(cf *models.ControlFile) {
+			cf.Automations[0].Action = models.ActionPower
+		}, "power action needs"},
+		{"power with signal", func(cf *models.ControlFile) {
+			cf.Automations[0].Action = models.ActionPower
+			cf.Automations[0].ActionConfig = map[string]interface{}{"signal": "kill"}
+		}, ""},
+		{"power unknown signal", func(cf *models.ControlFile) {
+			cf.Automations[0].Action = models.ActionPower
+			cf.Automations[0].ActionConfig = map[string]interface{}{"signal": "freeze"}
+		}, "power action needs"},
+		{"reinstall without confirm", func(cf *models.ControlFile) {
+			cf.Automations[0].Action = models.ActionReinstall
+		}, `reinstall action needs "confirm": true`},
+		{"escalation steps", func(cf *models.ControlFile) {
+			cf.Automations[0].ActionConfig = map[string]interface{}{"escalation": []interface{}{
+				map[string]interface{}{"action": "backup", "wait": 60.0, "rotate": true, "max_backups": 3.0},
+				map[string]interface{}{"action": "reinstall", "confirm": true},
+			}}
+		}, ""},
+		{"escalation reinstall without confirm", func(cf *models.ControlFile) {
+			cf.Automations[0].ActionConfig = map[string]interface{}{"escalation": []interface{}{
+				map[string]interface{}{"action": "restart", "wait": 60.0},
+				map[string]interface{}{"action": "reinstall"},
+			}}
+		}, `escalation[1]: reinstall action needs "confirm": true`},
+		{"escalation power step", func(cf *models.ControlFile) {
+			cf.Automations[0].ActionConfig = map[string]interface{}{"escalation": []interface{}{
+				map[string]interface{}{"action": "power", "signal": "kill"},
+			}}
+		}, "escalation[0]: use the named"},
+		{"negative automation cooldown", func(cf *models.ControlFile) {
+			cf.Automations[0].Cooldown = -5
+		}, "cooldown must not be negative"},
+		{"automation without server", func(cf *models.ControlFile) {
+			cf.Automations[0].ServerID = ""
+		}, "empty server_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cf := validControlFile()
+			tt.mutate(cf)
+			l := NewLoader("", time.Minute, nil, 0, 0)
+			err := l.validate(cf, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadInitialValidates(t *testing.T) {
+	cf := validControlFile()
+	cf.Version = 7
+	cf.Automations[0].Cooldown = -1
+	l, path := writeControl(t, cf)
+
+	if err := l.LoadInitial(); err != nil {
+		t.Fatalf("LoadInitial: %v", err)
+	}
+	if l.Version() != 0 || len(l.Get().Automations) != 0 {
+		t.Fatalf("invalid startup file accepted (version %d)", l.Version())
+	}
+	if msg, _ := l.LastError(); !strings.Contains(msg, "cooldown must not be negative") {
+		t.Fatalf("LastError = %q", msg)
+	}
+
+	// Fixing the file in place, same version, is picked up by the next poll
+	cf.Automations[0].Cooldown = 60
+	data, _ := json.Marshal(cf)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if l.Version() != 7 {
+		t.Fatalf("version = %d after fix, want 7", l.Version())
+	}
+	if msg, _ := l.LastError(); msg != "" {
+		t.Fatalf("LastError not cleared: %q", msg)
+	}
+}
+
+func TestLoadInitialMissingFile(t *testing.T) {
+	l := NewLoader(filepath.Join(t.TempDir(), "control.json"), time.Minute, nil, 0, 0)
+	if err := l.LoadInitial(); err != nil {
+		t.Fatalf("LoadInitial: %v", err)
+	}
+	if l.Version() != 0 || l.Get() == nil {
+		t.Fatal("missing file should start with an empty configuration")
+	}
+}
+
+func TestReloadKeepsLastGoodOnRejection(t *testing.T) {
+	l, path := writeControl(t, validControlFile())
+	if err := l.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := validControlFile()
+	bad.Version = 2
+	bad.Alerts[0].ConditionType = "cpu_treshold"
+	data, _ := json.Marshal(bad)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Reload(); err == nil {
+		t.Fatal("Reload accepted an unknown condition_type")
+	}
+	if l.Version() != 1 {
+		t.Fatalf("version = %d, want the last good 1", l.Version())
+	}
+	if _, err := os.Stat(path + ".lastgood"); err != nil {
+		t.Fatalf("last good copy not saved: %v", err)
+	}
+}
+
+func TestLoadInitialRejectsTypedRuleTypos(t *testing.T) {
+	for _, mutate := range []func(cf *models.ControlFile){
+		func(cf *models.ControlFile) { cf.Alerts[0].ConditionType = "ram_treshold" },
+		func(cf *models.ControlFile) { cf.Automations[0].TriggerType = "server_crashed" },
+		func(cf *models.ControlFile) { cf.Automations[0].Action = "reboot" },
+	} {
+		cf := validControlFile()
+		mutate(cf)
+		l, _ := writeControl(t, cf)
+		if err := l.LoadInitial(); err != nil {
+			t.Fatal(err)
+		}
+		if l.Version() != 0 {
+			t.Errorf("startup file with a typo accepted: %+v", cf)
+		}
+		if msg, _ := l.LastError(); !strings.Contains(msg, "unknown") {
+			t.Errorf("LastError = %q, want an unknown-type error", msg)
+		}
+	}
+}
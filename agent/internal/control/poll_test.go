@@ -0,0 +1,48 @@
+package control
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLoaderPollInterval(t *testing.T) {
+	l, path := writeControl(t, validControlFile())
+	l.pollInterval = 20 * time.Millisecond
+	if got := l.PollInterval(); got != 20*time.Millisecond {
+		t.Fatalf("PollInterval() = %s, want 20ms", got)
+	}
+	if err := l.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+	l.Start()
+	defer l.Stop()
+
+	cf := validControlFile()
+	cf.Version = 2
+	data, err := json.Marshal(cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Picked up within a few ticks, far sooner than the 15s default
+	deadline := time.Now().Add(2 * time.Second)
+	for l.Version() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("version %d after 2s of polling every 20ms, want 2", l.Version())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewLoaderKeepsInterval(t *testing.T) {
+	for _, d := range []time.Duration{time.Second, 15 * time.Second, 300 * time.Second} {
+		if got := NewLoader("control.json", d, nil, 0, 0).PollInterval(); got != d {
+			t.Errorf("PollInterval() = %s, want %s", got, d)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package control
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestOnReloadCalledForNewVersions(t *testing.T) {
+	l, path := writeControl(t, validControlFile())
+	reloads := 0
+	l.OnReload(func() { reloads++ })
+	if err := l.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+	if reloads != 0 {
+		t.Fatalf("OnReload called %d times by LoadInitial, want 0", reloads)
+	}
+
+	write := func(cf interface{}) {
+		t.Helper()
+		data, err := json.Marshal(cf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Same version: nothing changed
+	l.Reload()
+	if reloads != 0 {
+		t.Fatalf("OnReload called %d times for an unchanged version, want 0", reloads)
+	}
+
+	cf := validControlFile()
+	cf.Version = 2
+	write(cf)
+	if err := l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if reloads != 1 {
+		t.Fatalf("OnReload called %d times after a version bump, want 1", reloads)
+	}
+
+	// A rejected version keeps the last good one and does not trigger a pass
+	cf.Version = 3
+	cf.Alerts[0].ConditionType = "nope"
+	write(cf)
+	if err := l.Reload(); err == nil {
+		t.Fatal("want an error for an invalid control file")
+	}
+	if reloads != 1 || l.Version() != 2 {
+		t.Fatalf("after a rejected version: %d reloads, version %d; want 1 and 2", reloads, l.Version())
+	}
+}
+
+func TestReloadLoadsNewVersion(t *testing.T) {
+	l, path := writeControl(t, validControlFile())
+	if err := l.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+
+	cf := validControlFile()
+	cf.Version = 7
+	cf.Alerts[0].Threshold = 55
+	data, err := json.Marshal(cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// No poll loop is running; Reload alone picks the change up
+	if err := l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Get(); l.Version() != 7 || got.Version != 7 || got.Alerts[0].Threshold != 55 {
+		t.Fatalf("after Reload: version %d, threshold %g; want 7 and 55", l.Version(), got.Alerts[0].Threshold)
+	}
+}
+
+func TestReloadAlongsidePollLoop(t *testing.T) {
+	l, path := writeControl(t, validControlFile())
+	if err := l.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+	l.pollInterval = time.Millisecond
+	l.Start()
+	defer l.Stop()
+
+	cf := validControlFile()
+	for v := 2; v <= 20; v++ {
+		cf.Version = v
+		data, err := json.Marshal(cf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Reload(); err != nil {
+			t.Fatalf("version %d: %v", v, err)
+		}
+		if got := l.Version(); got != v {
+			t.Fatalf("after Reload: version %d, want %d", got, v)
+		}
+	}
+}
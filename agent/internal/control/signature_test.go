@@ -0,0 +1,116 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/security"
+)
+
+func testVerifier(t *testing.T) *security.Crypto {
+	t.Helper()
+	c, err := security.NewCrypto("test-agent-secret-0123456789abcdef", "", "test-salt", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// writeSigned writes cf with the given signature ("" = unsigned) and returns a loader
+// that requires signatures.
+func writeSigned(t *testing.T, cf *models.ControlFile, sign func(data []byte) string) *Loader {
+	t.Helper()
+	data, err := json.Marshal(cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sign != nil {
+		cf.Signature = sign(data)
+		if data, err = json.Marshal(cf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "control.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return NewLoader(path, time.Minute, testVerifier(t), 0, 0)
+}
+
+func TestSignedControlFile(t *testing.T) {
+	verifier := testVerifier(t)
+	sign := func(data []byte) string {
+		sig, err := verifier.SignControlFile(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+	other, err := security.NewCrypto("another-agent-secret-0123456789", "", "test-salt", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		sign    func(data []byte) string
+		wantErr error
+	}{
+		{"valid", sign, nil},
+		{"missing", nil, security.ErrSignatureMissing},
+		{"wrong key", func(data []byte) string {
+			sig, _ := other.SignControlFile(data)
+			return sig
+		}, security.ErrSignatureInvalid},
+		{"garbage", func([]byte) string { return "deadbeef" }, security.ErrSignatureInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cf := validControlFile()
+			l := writeSigned(t, cf, tt.sign)
+			if err := l.LoadInitial(); err != nil {
+				t.Fatal(err)
+			}
+			msg, _ := l.LastError()
+			if tt.wantErr == nil {
+				if l.Version() != 1 || msg != "" {
+					t.Fatalf("signed file not accepted: version %d, error %q", l.Version(), msg)
+				}
+				return
+			}
+			if l.Version() != 0 || msg != tt.wantErr.Error() {
+				t.Fatalf("version %d, error %q, want rejection with %q", l.Version(), msg, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSignatureCoversContents(t *testing.T) {
+	verifier := testVerifier(t)
+	cf := validControlFile()
+	data, _ := json.Marshal(cf)
+	sig, err := verifier.SignControlFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tampering with a threshold after signing
+	cf.Alerts[0].Threshold = 1
+	cf.Signature = sig
+	tampered, _ := json.Marshal(cf)
+	if err := verifier.VerifyControlFile(tampered); !errors.Is(err, security.ErrSignatureInvalid) {
+		t.Fatalf("tampered file: %v, want ErrSignatureInvalid", err)
+	}
+}
+
+func TestUnsignedAcceptedWithoutVerifier(t *testing.T) {
+	l, _ := writeControl(t, validControlFile())
+	if err := l.LoadInitial(); err != nil || l.Version() != 1 {
+		t.Fatalf("unsigned file without CONTROL_REQUIRE_SIGNATURE: version %d, %v", l.Version(), err)
+	}
+}
@@ -0,0 +1,62 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatches(t *testing.T) {
+	monNoon := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC) // a Monday
+	for _, tc := range []struct {
+		expr string
+		at   time.Time
+		want bool
+	}{
+		{"* * * * *", monNoon, true},
+		{"* 9-17 * * mon-fri", monNoon, true},
+		{"* 9-17 * * mon-fri", monNoon.Add(6 * time.Hour), false},
+		{"* 9-17 * * mon-fri", monNoon.AddDate(0, 0, 5), false}, // Saturday
+		{"* * * * sat,sun", monNoon.AddDate(0, 0, 6), true},     // Sunday
+		{"* * * * 7", monNoon.AddDate(0, 0, 6), true},           // Sunday as 7
+		{"*/15 * * * *", monNoon.Add(45 * time.Minute), true},
+		{"*/15 * * * *", monNoon.Add(50 * time.Minute), false},
+		{"5/10 * * * *", monNoon.Add(25 * time.Minute), true},
+		{"5/10 * * * *", monNoon.Add(20 * time.Minute), false},
+		{"0 12 5 jan *", monNoon, true},
+		{"0 12 5 feb *", monNoon, false},
+		{"* * 1 * fri", monNoon, false},                     // neither day field matches
+		{"* * 5 * fri", monNoon, true},                      // day of month matches
+		{"* * 1 * mon", monNoon, true},                      // day of week matches
+		{"CRON_TZ=Europe/Berlin * 13 * * *", monNoon, true}, // 12:00 UTC is 13:00 in Berlin
+		{"CRON_TZ=Europe/Berlin * 12 * * *", monNoon, false},
+	} {
+		s, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.expr, err)
+		}
+		if got := s.Matches(tc.at); got != tc.want {
+			t.Errorf("%q at %s: Matches = %t, want %t", tc.expr, tc.at.Format(time.RFC3339), got, tc.want)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* 17-9 * * *",
+		"*/0 * * * *",
+		"* * * * funday",
+		"CRON_TZ=Mars/Olympus * * * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -2,8 +2,14 @@ package database
 
 import (
 	"database/sql"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -14,11 +20,39 @@ import (
 // DB wraps the SQLite database connection.
 type DB struct {
 	conn *sql.DB
+	path string
 }
 
-// Open creates or opens the SQLite database and runs migrations.
-func Open(dataDir string) (*DB, error) {
-	dbPath := filepath.Join(dataDir, "agent.db")
+// Open creates or opens the SQLite database at dbPath, checks its integrity and runs
+// migrations. The parent directory is created if needed. With recoverCorrupt, a corrupt
+// database is moved aside to <dbPath>.corrupt.<unix time> and replaced by an empty one,
+// so monitoring continues with lost history instead of failing on every query.
+func Open(dbPath string, recoverCorrupt bool) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("create database directory: %w", err)
+	}
+
+	db, err := open(dbPath)
+	if err == nil || !recoverCorrupt || !errors.Is(err, ErrCorrupt) {
+		return db, err
+	}
+
+	aside := fmt.Sprintf("%s.corrupt.%d", dbPath, time.Now().Unix())
+	logging.Error("!!! Database %s: %v", dbPath, err)
+	logging.Error("!!! Moving it to %s and starting with an empty database; snapshot and alert history are lost", aside)
+	if err := os.Rename(dbPath, aside); err != nil {
+		return nil, fmt.Errorf("move corrupt database aside: %w", err)
+	}
+	// The WAL and shared-memory files belong to the corrupt database
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Rename(dbPath+suffix, aside+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("move corrupt database %s file aside: %w", suffix, err)
+		}
+	}
+	return open(dbPath)
+}
+
+func open(dbPath string) (*DB, error) {
 	conn, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_busy_timeout=5000")
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
@@ -27,7 +61,11 @@ func Open(dataDir string) (*DB, error) {
 	conn.SetMaxOpenConns(1) // SQLite single-writer
 	conn.SetMaxIdleConns(1)
 
-	db := &DB{conn: conn}
+	db := &DB{conn: conn, path: dbPath}
+	if err := db.checkIntegrity(); err != nil {
+		conn.Close()
+		return nil, err
+	}
 	if err := db.migrate(); err != nil {
 		conn.Close()
 		return nil, fmt.Errorf("migrate: %w", err)
@@ -42,81 +80,60 @@ func (db *DB) Close() error {
 	return db.conn.Close()
 }
 
-func (db *DB) migrate() error {
-	migrations := []string{
-		`CREATE TABLE IF NOT EXISTS resource_snapshots (
-			id          INTEGER PRIMARY KEY AUTOINCREMENT,
-			server_id   TEXT NOT NULL,
-			timestamp   DATETIME NOT NULL,
-			power_state TEXT,
-			cpu_percent REAL,
-			mem_bytes   INTEGER,
-			mem_limit   INTEGER,
-			disk_bytes  INTEGER,
-			disk_limit  INTEGER,
-			net_rx      INTEGER,
-			net_tx      INTEGER,
-			uptime_ms   INTEGER
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_snap_server_time ON resource_snapshots(server_id, timestamp)`,
-
-		`CREATE TABLE IF NOT EXISTS automation_log (
-			id          INTEGER PRIMARY KEY AUTOINCREMENT,
-			rule_id     TEXT NOT NULL,
-			user_uuid   TEXT NOT NULL,
-			server_id   TEXT NOT NULL,
-			action      TEXT NOT NULL,
-			result      TEXT NOT NULL,
-			error_msg   TEXT,
-			executed_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		`CREATE TABLE IF NOT EXISTS alert_history (
-			id           INTEGER PRIMARY KEY AUTOINCREMENT,
-			rule_id      TEXT NOT NULL,
-			user_uuid    TEXT NOT NULL,
-			server_id    TEXT NOT NULL,
-			condition    TEXT NOT NULL,
-			value        REAL,
-			triggered_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_alert_hist_time ON alert_history(triggered_at)`,
-
-		`CREATE TABLE IF NOT EXISTS agent_state (
-			key   TEXT PRIMARY KEY,
-			value TEXT
-		)`,
-	}
-
-	for _, m := range migrations {
-		if _, err := db.conn.Exec(m); err != nil {
-			return fmt.Errorf("execute migration: %w", err)
-		}
-	}
-	return nil
-}
-
 // InsertSnapshot stores a resource snapshot.
 func (db *DB) InsertSnapshot(s models.ResourceSnapshot) error {
 	_, err := db.conn.Exec(
-		`INSERT INTO resource_snapshots (server_id, timestamp, power_state, cpu_percent, mem_bytes, mem_limit, disk_bytes, disk_limit, net_rx, net_tx, uptime_ms)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		s.ServerID, s.Timestamp, s.PowerState, s.CPUPercent,
+		`INSERT INTO resource_snapshots (server_id, timestamp, power_state, cpu_percent, cpu_percent_normalized, mem_bytes, mem_limit, disk_bytes, disk_limit, net_rx, net_tx, uptime_ms, is_suspended)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ServerID, s.Timestamp, s.PowerState, s.CPUPercent, s.CPUNormalized,
 		s.MemBytes, s.MemLimit, s.DiskBytes, s.DiskLimit,
-		s.NetRx, s.NetTx, s.UptimeMs,
+		s.NetRx, s.NetTx, s.UptimeMs, s.IsSuspended,
 	)
 	return err
 }
 
+// InsertSnapshots stores a sampling cycle's snapshots in a single transaction.
+func (db *DB) InsertSnapshots(snaps []models.ResourceSnapshot) error {
+	if len(snaps) == 0 {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`INSERT INTO resource_snapshots (server_id, timestamp, power_state, cpu_percent, cpu_percent_normalized, mem_bytes, mem_limit, disk_bytes, disk_limit, net_rx, net_tx, uptime_ms, is_suspended)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, s := range snaps {
+		if _, err := stmt.Exec(
+			s.ServerID, s.Timestamp, s.PowerState, s.CPUPercent, s.CPUNormalized,
+			s.MemBytes, s.MemLimit, s.DiskBytes, s.DiskLimit,
+			s.NetRx, s.NetTx, s.UptimeMs, s.IsSuspended,
+		); err != nil {
+			return fmt.Errorf("insert snapshot for %s: %w", s.ServerID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
 // GetLatestSnapshot returns the most recent snapshot for a server.
 func (db *DB) GetLatestSnapshot(serverID string) (*models.ResourceSnapshot, error) {
 	row := db.conn.QueryRow(
-		`SELECT id, server_id, timestamp, power_state, cpu_percent, mem_bytes, mem_limit, disk_bytes, disk_limit, net_rx, net_tx, uptime_ms
+		`SELECT id, server_id, timestamp, power_state, cpu_percent, cpu_percent_normalized, mem_bytes, mem_limit, disk_bytes, disk_limit, net_rx, net_tx, uptime_ms, is_suspended
 		 FROM resource_snapshots WHERE server_id = ? ORDER BY timestamp DESC LIMIT 1`, serverID,
 	)
 	var s models.ResourceSnapshot
-	err := row.Scan(&s.ID, &s.ServerID, &s.Timestamp, &s.PowerState, &s.CPUPercent,
-		&s.MemBytes, &s.MemLimit, &s.DiskBytes, &s.DiskLimit, &s.NetRx, &s.NetTx, &s.UptimeMs)
+	err := row.Scan(&s.ID, &s.ServerID, &s.Timestamp, &s.PowerState, &s.CPUPercent, &s.CPUNormalized,
+		&s.MemBytes, &s.MemLimit, &s.DiskBytes, &s.DiskLimit, &s.NetRx, &s.NetTx, &s.UptimeMs, &s.IsSuspended)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -128,7 +145,7 @@ func (db *DB) GetLatestSnapshot(serverID string) (*models.ResourceSnapshot, erro
 
 // GetRecentSnapshots returns the last N snapshots for a server, most recent last.
 func (db *DB) GetRecentSnapshots(serverID string, limit int) ([]models.ResourceSnapshot, error) {
-	query := `SELECT id, server_id, timestamp, power_state, cpu_percent, mem_bytes, mem_limit, disk_bytes, disk_limit, net_rx, net_tx, uptime_ms
+	query := `SELECT id, server_id, timestamp, power_state, cpu_percent, cpu_percent_normalized, mem_bytes, mem_limit, disk_bytes, disk_limit, net_rx, net_tx, uptime_ms, is_suspended
 	          FROM resource_snapshots WHERE server_id = ? ORDER BY timestamp DESC LIMIT ?`
 
 	rows, err := db.conn.Query(query, serverID, limit)
@@ -140,8 +157,8 @@ func (db *DB) GetRecentSnapshots(serverID string, limit int) ([]models.ResourceS
 	var snapshots []models.ResourceSnapshot
 	for rows.Next() {
 		var s models.ResourceSnapshot
-		if err := rows.Scan(&s.ID, &s.ServerID, &s.Timestamp, &s.PowerState, &s.CPUPercent,
-			&s.MemBytes, &s.MemLimit, &s.DiskBytes, &s.DiskLimit, &s.NetRx, &s.NetTx, &s.UptimeMs); err != nil {
+		if err := rows.Scan(&s.ID, &s.ServerID, &s.Timestamp, &s.PowerState, &s.CPUPercent, &s.CPUNormalized,
+			&s.MemBytes, &s.MemLimit, &s.DiskBytes, &s.DiskLimit, &s.NetRx, &s.NetTx, &s.UptimeMs, &s.IsSuspended); err != nil {
 			return nil, err
 		}
 		snapshots = append(snapshots, s)
@@ -155,11 +172,95 @@ func (db *DB) GetRecentSnapshots(serverID string, limit int) ([]models.ResourceS
 	return snapshots, nil
 }
 
+// GetSnapshotsSince returns a server's snapshots taken at or after since, oldest first.
+func (db *DB) GetSnapshotsSince(serverID string, since time.Time) ([]models.ResourceSnapshot, error) {
+	rows, err := db.conn.Query(
+		`SELECT id, server_id, timestamp, power_state, cpu_percent, cpu_percent_normalized, mem_bytes, mem_limit, disk_bytes, disk_limit, net_rx, net_tx, uptime_ms, is_suspended
+		 FROM resource_snapshots WHERE server_id = ? AND timestamp >= ? ORDER BY timestamp ASC`,
+		serverID, since.Local(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []models.ResourceSnapshot
+	for rows.Next() {
+		var s models.ResourceSnapshot
+		if err := rows.Scan(&s.ID, &s.ServerID, &s.Timestamp, &s.PowerState, &s.CPUPercent, &s.CPUNormalized,
+			&s.MemBytes, &s.MemLimit, &s.DiskBytes, &s.DiskLimit, &s.NetRx, &s.NetTx, &s.UptimeMs, &s.IsSuspended); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+// ExportSnapshotsCSV streams a server's snapshots since the given time to w as CSV,
+// oldest first, without loading the result set into memory.
+func (db *DB) ExportSnapshotsCSV(serverID string, since time.Time, w io.Writer) error {
+	rows, err := db.conn.Query(
+		`SELECT timestamp, power_state, cpu_percent, mem_bytes, mem_limit, disk_bytes, disk_limit, net_rx, net_tx, uptime_ms
+		 FROM resource_snapshots WHERE server_id = ? AND timestamp >= ? ORDER BY timestamp ASC`,
+		serverID, since.Local(),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"timestamp", "power_state", "cpu_percent", "mem_bytes", "mem_limit",
+		"disk_bytes", "disk_limit", "net_rx", "net_tx", "uptime_ms"}); err != nil {
+		return err
+	}
+
+	n := 0
+	for rows.Next() {
+		var s models.ResourceSnapshot
+		var powerState sql.NullString
+		if err := rows.Scan(&s.Timestamp, &powerState, &s.CPUPercent, &s.MemBytes, &s.MemLimit,
+			&s.DiskBytes, &s.DiskLimit, &s.NetRx, &s.NetTx, &s.UptimeMs); err != nil {
+			return err
+		}
+
+		if err := cw.Write([]string{
+			s.Timestamp.UTC().Format(time.RFC3339),
+			powerState.String,
+			strconv.FormatFloat(s.CPUPercent, 'f', 2, 64),
+			strconv.FormatInt(s.MemBytes, 10),
+			strconv.FormatInt(s.MemLimit, 10),
+			strconv.FormatInt(s.DiskBytes, 10),
+			strconv.FormatInt(s.DiskLimit, 10),
+			strconv.FormatInt(s.NetRx, 10),
+			strconv.FormatInt(s.NetTx, 10),
+			strconv.FormatInt(s.UptimeMs, 10),
+		}); err != nil {
+			return err
+		}
+
+		// Flush periodically so large exports stream instead of buffering
+		n++
+		if n%500 == 0 {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
 // InsertAlertHistory logs a triggered alert.
 func (db *DB) InsertAlertHistory(entry models.AlertHistoryEntry) error {
 	_, err := db.conn.Exec(
-		`INSERT INTO alert_history (rule_id, user_uuid, server_id, condition, value) VALUES (?, ?, ?, ?, ?)`,
-		entry.RuleID, entry.UserUUID, entry.ServerID, entry.Condition, entry.Value,
+		`INSERT INTO alert_history (rule_id, user_uuid, server_id, condition, severity, value) VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.RuleID, entry.UserUUID, entry.ServerID, entry.Condition, entry.Severity, entry.Value,
 	)
 	return err
 }
@@ -167,12 +268,110 @@ func (db *DB) InsertAlertHistory(entry models.AlertHistoryEntry) error {
 // InsertAutomationLog logs an automation execution.
 func (db *DB) InsertAutomationLog(entry models.AutomationLogEntry) error {
 	_, err := db.conn.Exec(
-		`INSERT INTO automation_log (rule_id, user_uuid, server_id, action, result, error_msg) VALUES (?, ?, ?, ?, ?, ?)`,
-		entry.RuleID, entry.UserUUID, entry.ServerID, entry.Action, entry.Result, entry.ErrorMsg,
+		`INSERT INTO automation_log (rule_id, user_uuid, server_id, action, step, result, error_msg) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.RuleID, entry.UserUUID, entry.ServerID, entry.Action, entry.Step, entry.Result, entry.ErrorMsg,
 	)
 	return err
 }
 
+// GetAlertHistory returns a user's triggered alerts, newest first.
+// If beforeID > 0, only entries with a smaller id are returned (for pagination).
+func (db *DB) GetAlertHistory(userUUID string, beforeID int64, limit int) ([]models.AlertHistoryEntry, error) {
+	query := `SELECT id, rule_id, user_uuid, server_id, condition, severity, value, triggered_at
+	          FROM alert_history WHERE user_uuid = ? AND (? <= 0 OR id < ?)
+	          ORDER BY triggered_at DESC, id DESC LIMIT ?`
+
+	rows, err := db.conn.Query(query, userUUID, beforeID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAlertHistory(rows)
+}
+
+// GetAutomationLog returns a user's automation executions, newest first.
+// If beforeID > 0, only entries with a smaller id are returned (for pagination).
+func (db *DB) GetAutomationLog(userUUID string, beforeID int64, limit int) ([]models.AutomationLogEntry, error) {
+	query := `SELECT id, rule_id, user_uuid, server_id, action, step, result, error_msg, executed_at
+	          FROM automation_log WHERE user_uuid = ? AND (? <= 0 OR id < ?)
+	          ORDER BY executed_at DESC, id DESC LIMIT ?`
+
+	rows, err := db.conn.Query(query, userUUID, beforeID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAutomationLog(rows)
+}
+
+// GetAlertHistoryForServer returns a server's alert history, newest first.
+// If beforeID > 0, only entries with a smaller id are returned (for pagination).
+func (db *DB) GetAlertHistoryForServer(serverID string, beforeID int64, limit int) ([]models.AlertHistoryEntry, error) {
+	query := `SELECT id, rule_id, user_uuid, server_id, condition, severity, value, triggered_at
+	          FROM alert_history WHERE server_id = ? AND (? <= 0 OR id < ?)
+	          ORDER BY triggered_at DESC, id DESC LIMIT ?`
+
+	rows, err := db.conn.Query(query, serverID, beforeID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAlertHistory(rows)
+}
+
+// GetAutomationLogForServer returns a server's automation executions, newest first.
+// If beforeID > 0, only entries with a smaller id are returned (for pagination).
+func (db *DB) GetAutomationLogForServer(serverID string, beforeID int64, limit int) ([]models.AutomationLogEntry, error) {
+	query := `SELECT id, rule_id, user_uuid, server_id, action, step, result, error_msg, executed_at
+	          FROM automation_log WHERE server_id = ? AND (? <= 0 OR id < ?)
+	          ORDER BY executed_at DESC, id DESC LIMIT ?`
+
+	rows, err := db.conn.Query(query, serverID, beforeID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAutomationLog(rows)
+}
+
+func scanAlertHistory(rows *sql.Rows) ([]models.AlertHistoryEntry, error) {
+	entries := []models.AlertHistoryEntry{}
+	for rows.Next() {
+		var e models.AlertHistoryEntry
+		var severity sql.NullString
+		var value sql.NullFloat64
+		if err := rows.Scan(&e.ID, &e.RuleID, &e.UserUUID, &e.ServerID, &e.Condition,
+			&severity, &value, &e.TriggeredAt); err != nil {
+			return nil, err
+		}
+		e.Severity = severity.String
+		e.Value = value.Float64
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func scanAutomationLog(rows *sql.Rows) ([]models.AutomationLogEntry, error) {
+	entries := []models.AutomationLogEntry{}
+	for rows.Next() {
+		var e models.AutomationLogEntry
+		var step sql.NullInt64
+		var errMsg sql.NullString
+		if err := rows.Scan(&e.ID, &e.RuleID, &e.UserUUID, &e.ServerID, &e.Action,
+			&step, &e.Result, &errMsg, &e.ExecutedAt); err != nil {
+			return nil, err
+		}
+		e.Step = int(step.Int64)
+		e.ErrorMsg = errMsg.String
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
 // CleanupOlderThan deletes records older than the given duration.
 func (db *DB) CleanupOlderThan(days int) (int64, error) {
 	cutoff := time.Now().AddDate(0, 0, -days).Format(time.RFC3339)
@@ -203,6 +402,33 @@ func (db *DB) CleanupOlderThan(days int) (int64, error) {
 	return total, nil
 }
 
+// Maintenance checkpoints and truncates the WAL and vacuums the database to return
+// space freed by cleanup to the filesystem.
+func (db *DB) Maintenance() error {
+	if _, err := db.conn.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return fmt.Errorf("wal checkpoint: %w", err)
+	}
+	if _, err := db.conn.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	// VACUUM goes through the WAL in WAL mode, so truncate it again
+	if _, err := db.conn.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return fmt.Errorf("wal checkpoint: %w", err)
+	}
+	return nil
+}
+
+// SizeBytes returns the on-disk size of the database including its WAL and shared-memory files.
+func (db *DB) SizeBytes() int64 {
+	var total int64
+	for _, p := range []string{db.path, db.path + "-wal", db.path + "-shm"} {
+		if info, err := os.Stat(p); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
 // GetSnapshotCount returns total number of snapshots in database.
 func (db *DB) GetSnapshotCount() (int64, error) {
 	var count int64
@@ -228,3 +454,94 @@ func (db *DB) SetState(key, value string) error {
 	)
 	return err
 }
+
+// GetServerStates returns the last known power state of every server.
+func (db *DB) GetServerStates() ([]models.ServerState, error) {
+	rows, err := db.conn.Query(`SELECT server_id, power_state, changed_at, last_seen_at FROM server_state`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var states []models.ServerState
+	for rows.Next() {
+		var st models.ServerState
+		if err := rows.Scan(&st.ServerID, &st.PowerState, &st.ChangedAt, &st.LastSeenAt); err != nil {
+			return nil, err
+		}
+		states = append(states, st)
+	}
+	return states, rows.Err()
+}
+
+// UpsertServerStates stores server power states in a single transaction.
+func (db *DB) UpsertServerStates(states []models.ServerState) error {
+	if len(states) == 0 {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`INSERT INTO server_state (server_id, power_state, changed_at, last_seen_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(server_id) DO UPDATE SET power_state = excluded.power_state,
+		   changed_at = excluded.changed_at, last_seen_at = excluded.last_seen_at`)
+	if err != nil {
+		return fmt.Errorf("prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, st := range states {
+		if _, err := stmt.Exec(st.ServerID, st.PowerState, st.ChangedAt, st.LastSeenAt); err != nil {
+			return fmt.Errorf("upsert state for %s: %w", st.ServerID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpsertServerInfo stores a server's name and limits (memory and disk in bytes, CPU in
+// cores; 0 = unlimited).
+func (db *DB) UpsertServerInfo(id, name string, memLimit, diskLimit int64, cpuCores float64) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO servers (server_id, name, mem_limit, disk_limit, cpu_cores, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(server_id) DO UPDATE SET name = excluded.name, mem_limit = excluded.mem_limit,
+		   disk_limit = excluded.disk_limit, cpu_cores = excluded.cpu_cores, updated_at = excluded.updated_at`,
+		id, name, memLimit, diskLimit, cpuCores, time.Now(),
+	)
+	return err
+}
+
+// GetServerInfo returns the stored info for the given servers, keyed by server ID.
+// Servers that were never listed are absent.
+func (db *DB) GetServerInfo(serverIDs []string) (map[string]models.ServerInfo, error) {
+	out := make(map[string]models.ServerInfo)
+	if len(serverIDs) == 0 {
+		return out, nil
+	}
+
+	args := make([]interface{}, len(serverIDs))
+	for i, id := range serverIDs {
+		args[i] = id
+	}
+	rows, err := db.conn.Query(
+		`SELECT server_id, name, mem_limit, disk_limit, cpu_cores, updated_at FROM servers
+		 WHERE server_id IN (?`+strings.Repeat(", ?", len(serverIDs)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var si models.ServerInfo
+		if err := rows.Scan(&si.ServerID, &si.Name, &si.MemLimit, &si.DiskLimit, &si.CPUCores, &si.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out[si.ServerID] = si
+	}
+	return out, rows.Err()
+}
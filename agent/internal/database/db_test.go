@@ -0,0 +1,411 @@
+package database
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// openTestDB opens a fresh database in a temp dir, closed when the test ends.
+func openTestDB(tb testing.TB) *DB {
+	tb.Helper()
+	db, err := Open(filepath.Join(tb.TempDir(), "agent.db"), false)
+	if err != nil {
+		tb.Fatalf("open db: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	return db
+}
+
+// cycleSnapshots returns one snapshot per server, all taken at ts.
+func cycleSnapshots(servers int, ts time.Time) []models.ResourceSnapshot {
+	snaps := make([]models.ResourceSnapshot, servers)
+	for i := range snaps {
+		snaps[i] = models.ResourceSnapshot{
+			ServerID:   fmt.Sprintf("srv-%d", i),
+			Timestamp:  ts,
+			PowerState: "running",
+			CPUPercent: float64(i),
+			MemBytes:   int64(i) << 20,
+			MemLimit:   1 << 30,
+		}
+	}
+	return snaps
+}
+
+func TestInsertSnapshotsStoresAllRows(t *testing.T) {
+	db := openTestDB(t)
+	start := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	for cycle := 0; cycle < 3; cycle++ {
+		if err := db.InsertSnapshots(cycleSnapshots(25, start.Add(time.Duration(cycle)*time.Minute))); err != nil {
+			t.Fatalf("InsertSnapshots: %v", err)
+		}
+	}
+	if err := db.InsertSnapshots(nil); err != nil {
+		t.Fatalf("InsertSnapshots(nil): %v", err)
+	}
+
+	count, err := db.GetSnapshotCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 75 {
+		t.Fatalf("%d rows, want 75", count)
+	}
+
+	recent, err := db.GetRecentSnapshots("srv-7", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 3 {
+		t.Fatalf("srv-7 has %d snapshots, want 3", len(recent))
+	}
+	for i, s := range recent {
+		if !s.Timestamp.Equal(start.Add(time.Duration(i) * time.Minute)) {
+			t.Errorf("snapshot %d at %v, want oldest first", i, s.Timestamp)
+		}
+		if s.CPUPercent != 7 || s.MemBytes != 7<<20 {
+			t.Errorf("snapshot %d = %+v", i, s)
+		}
+	}
+}
+
+func BenchmarkInsertSnapshots(b *testing.B) {
+	db := openTestDB(b)
+	snaps := cycleSnapshots(100, time.Now())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.InsertSnapshots(snaps); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkInsertSnapshotEach is the per-row baseline InsertSnapshots replaced.
+func BenchmarkInsertSnapshotEach(b *testing.B) {
+	db := openTestDB(b)
+	snaps := cycleSnapshots(100, time.Now())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, s := range snaps {
+			if err := db.InsertSnapshot(s); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestGetAlertHistoryPagination(t *testing.T) {
+	db := openTestDB(t)
+	for i := 0; i < 5; i++ {
+		if err := db.InsertAlertHistory(models.AlertHistoryEntry{RuleID: fmt.Sprintf("r%d", i), UserUUID: "user-1", ServerID: "srv-1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.InsertAlertHistory(models.AlertHistoryEntry{RuleID: "other", UserUUID: "user-2", ServerID: "srv-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := db.GetAlertHistory("user-1", 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 3 || page[0].RuleID != "r4" || page[2].RuleID != "r2" {
+		t.Fatalf("first page = %+v, want r4..r2", page)
+	}
+
+	rest, err := db.GetAlertHistory("user-1", page[2].ID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 2 || rest[0].RuleID != "r1" || rest[1].RuleID != "r0" {
+		t.Fatalf("second page = %+v, want r1, r0", rest)
+	}
+}
+
+func TestGetAutomationLogPagination(t *testing.T) {
+	db := openTestDB(t)
+	for i := 0; i < 5; i++ {
+		if err := db.InsertAutomationLog(models.AutomationLogEntry{RuleID: fmt.Sprintf("r%d", i), UserUUID: "user-1", ServerID: "srv-1", Step: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.InsertAutomationLog(models.AutomationLogEntry{RuleID: "other", UserUUID: "user-2", ServerID: "srv-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := db.GetAutomationLog("user-1", 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 3 || page[0].RuleID != "r4" || page[0].Step != 4 || page[2].RuleID != "r2" {
+		t.Fatalf("first page = %+v, want r4..r2", page)
+	}
+
+	rest, err := db.GetAutomationLog("user-1", page[2].ID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 2 || rest[1].RuleID != "r0" {
+		t.Fatalf("second page = %+v, want r1, r0", rest)
+	}
+
+	other, err := db.GetAutomationLog("user-2", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(other) != 1 || other[0].RuleID != "other" {
+		t.Fatalf("user-2 log = %+v, want only its own entry", other)
+	}
+}
+
+func TestExportSnapshotsCSV(t *testing.T) {
+	db := openTestDB(t)
+	base := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	snaps := []models.ResourceSnapshot{
+		{ServerID: "srv-1", Timestamp: base.Add(-2 * time.Hour), PowerState: "running", CPUPercent: 1}, // before since
+		{
+			ServerID:   "srv-1",
+			Timestamp:  base,
+			PowerState: "running",
+			CPUPercent: 12.5,
+			MemBytes:   512,
+			MemLimit:   1024,
+			DiskBytes:  2048,
+			DiskLimit:  4096,
+			NetRx:      10,
+			NetTx:      20,
+			UptimeMs:   60000,
+		},
+		{ServerID: "srv-1", Timestamp: base.Add(time.Minute), PowerState: "offline"},
+		{ServerID: "srv-2", Timestamp: base, PowerState: "running", CPUPercent: 50}, // other server
+	}
+	for _, s := range snaps {
+		if err := db.InsertSnapshot(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf strings.Builder
+	if err := db.ExportSnapshotsCSV("srv-1", base.Add(-time.Hour), &buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v\n%s", err, buf.String())
+	}
+
+	want := [][]string{
+		{"timestamp", "power_state", "cpu_percent", "mem_bytes", "mem_limit", "disk_bytes", "disk_limit", "net_rx", "net_tx", "uptime_ms"},
+		{"2026-01-05T12:00:00Z", "running", "12.50", "512", "1024", "2048", "4096", "10", "20", "60000"},
+		{"2026-01-05T12:01:00Z", "offline", "0.00", "0", "0", "0", "0", "0", "0", "0"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("csv =\n%v\nwant\n%v", records, want)
+	}
+}
+
+func TestMaintenanceOnPopulatedDB(t *testing.T) {
+	db := openTestDB(t)
+	old := time.Now().AddDate(0, 0, -30)
+	for i := 0; i < 50; i++ {
+		if err := db.InsertSnapshots(cycleSnapshots(100, old.Add(time.Duration(i)*time.Minute))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.InsertSnapshots(cycleSnapshots(10, time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CleanupOlderThan(7); err != nil {
+		t.Fatal(err)
+	}
+
+	before := db.SizeBytes()
+	if err := db.Maintenance(); err != nil {
+		t.Fatalf("maintenance: %v", err)
+	}
+	if after := db.SizeBytes(); after >= before {
+		t.Fatalf("size %d -> %d bytes, want it to shrink", before, after)
+	}
+
+	count, err := db.GetSnapshotCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 10 {
+		t.Fatalf("%d snapshots after maintenance, want the 10 kept by cleanup", count)
+	}
+}
+
+func TestHistoryForServer(t *testing.T) {
+	db := openTestDB(t)
+	for i := 0; i < 4; i++ {
+		server := []string{"srv-1", "srv-2"}[i%2]
+		user := fmt.Sprintf("user-%d", i)
+		if err := db.InsertAlertHistory(models.AlertHistoryEntry{RuleID: fmt.Sprintf("a%d", i), UserUUID: user, ServerID: server}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.InsertAutomationLog(models.AutomationLogEntry{RuleID: fmt.Sprintf("r%d", i), UserUUID: user, ServerID: server}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alerts, err := db.GetAlertHistoryForServer("srv-1", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 || alerts[0].RuleID != "a2" || alerts[1].RuleID != "a0" {
+		t.Fatalf("srv-1 alerts = %+v, want a2, a0", alerts)
+	}
+
+	log, err := db.GetAutomationLogForServer("srv-2", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 1 || log[0].RuleID != "r3" {
+		t.Fatalf("srv-2 log page = %+v, want r3", log)
+	}
+	log, err = db.GetAutomationLogForServer("srv-2", log[0].ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 1 || log[0].RuleID != "r1" {
+		t.Fatalf("srv-2 log next page = %+v, want r1", log)
+	}
+}
+
+func TestServerHistoryQueriesUseIndexes(t *testing.T) {
+	db := openTestDB(t)
+	for query, index := range map[string]string{
+		"SELECT id FROM alert_history WHERE server_id = 'srv-1' ORDER BY triggered_at DESC": "idx_alert_hist_server_time",
+		"SELECT id FROM automation_log WHERE server_id = 'srv-1' ORDER BY executed_at DESC": "idx_auto_log_server_time",
+	} {
+		rows, err := db.conn.Query("EXPLAIN QUERY PLAN " + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var plan []string
+		for rows.Next() {
+			var id, parent, notused int
+			var detail string
+			if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+				t.Fatal(err)
+			}
+			plan = append(plan, detail)
+		}
+		rows.Close()
+		if !strings.Contains(strings.Join(plan, "; "), index) {
+			t.Errorf("plan for %q = %q, want %s", query, plan, index)
+		}
+	}
+}
+
+func TestMigrationsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.db")
+	for i := 0; i < 2; i++ {
+		db, err := Open(path, false)
+		if err != nil {
+			t.Fatalf("open #%d: %v", i+1, err)
+		}
+		db.Close()
+	}
+}
+
+func TestServerStatesRoundTrip(t *testing.T) {
+	db := openTestDB(t)
+	changed := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	if err := db.UpsertServerStates([]models.ServerState{
+		{ServerID: "srv-1", PowerState: "offline", ChangedAt: changed, LastSeenAt: changed.Add(time.Minute)},
+		{ServerID: "srv-2", PowerState: "running", ChangedAt: changed, LastSeenAt: changed},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Upserting again replaces the row
+	if err := db.UpsertServerStates([]models.ServerState{
+		{ServerID: "srv-2", PowerState: "stopped", ChangedAt: changed.Add(time.Hour), LastSeenAt: changed.Add(time.Hour)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	states, err := db.GetServerStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[string]models.ServerState)
+	for _, s := range states {
+		byID[s.ServerID] = s
+	}
+	if len(byID) != 2 {
+		t.Fatalf("states = %+v, want two servers", states)
+	}
+	if s := byID["srv-1"]; s.PowerState != "offline" || !s.ChangedAt.Equal(changed) || !s.LastSeenAt.Equal(changed.Add(time.Minute)) {
+		t.Errorf("srv-1 = %+v", s)
+	}
+	if s := byID["srv-2"]; s.PowerState != "stopped" || !s.ChangedAt.Equal(changed.Add(time.Hour)) {
+		t.Errorf("srv-2 = %+v, want the upserted state", s)
+	}
+}
+
+func TestUpsertServerInfo(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.UpsertServerInfo("srv-1", "survival", 2<<30, 10<<30, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertServerInfo("srv-2", "creative", 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	// A renamed or resized server replaces its row
+	if err := db.UpsertServerInfo("srv-1", "survival-2", 4<<30, 10<<30, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := db.GetServerInfo([]string{"srv-1", "srv-2", "srv-3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info) != 2 {
+		t.Fatalf("info = %+v, want srv-1 and srv-2 only", info)
+	}
+	if si := info["srv-1"]; si.Name != "survival-2" || si.MemLimit != 4<<30 || si.DiskLimit != 10<<30 || si.CPUCores != 4 || si.UpdatedAt.IsZero() {
+		t.Errorf("srv-1 = %+v", si)
+	}
+	if si := info["srv-2"]; si.Name != "creative" || si.MemLimit != 0 {
+		t.Errorf("srv-2 = %+v", si)
+	}
+
+	if info, err := db.GetServerInfo(nil); err != nil || len(info) != 0 {
+		t.Fatalf("GetServerInfo(nil) = %+v, %v", info, err)
+	}
+}
+
+func TestOpenAtCustomPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fast", "nested", "custom.db")
+	db, err := Open(path, false)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	if err := db.InsertSnapshots(cycleSnapshots(1, time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("database not created at the custom path: %v", err)
+	}
+	db, err = Open(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if snaps, err := db.GetRecentSnapshots("srv-0", 10); err != nil || len(snaps) != 1 {
+		t.Fatalf("reopened database has %d snapshots (%v), want 1", len(snaps), err)
+	}
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrCorrupt is returned (wrapped) by Open when the database file is damaged.
+var ErrCorrupt = errors.New("database is corrupt")
+
+// maxIntegrityProblems caps how many integrity_check messages end up in the error.
+const maxIntegrityProblems = 5
+
+// checkIntegrity runs PRAGMA integrity_check. Damage, including a file that is not
+// a database at all, is reported as ErrCorrupt; other failures (permissions, locks)
+// are returned as is.
+func (db *DB) checkIntegrity() error {
+	rows, err := db.conn.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return classifyOpenError(err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return classifyOpenError(err)
+		}
+		if msg != "ok" && len(problems) < maxIntegrityProblems {
+			problems = append(problems, msg)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return classifyOpenError(err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrCorrupt, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func classifyOpenError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrCorrupt || sqliteErr.Code == sqlite3.ErrNotADB) {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return fmt.Errorf("integrity check: %w", err)
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// corruptFiles returns the files Open moved aside next to path.
+func corruptFiles(t *testing.T, path string) []string {
+	t.Helper()
+	files, err := filepath.Glob(path + ".corrupt.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// damagedDB writes a populated database to path and overwrites some of its pages.
+func damagedDB(t *testing.T, path string) {
+	t.Helper()
+	db, err := Open(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 50; i++ {
+		if err := db.InsertSnapshots(cycleSnapshots(20, start.Add(time.Duration(i)*time.Minute))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteAt(bytes.Repeat([]byte{0xA5}, 4*4096), 2*4096); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenDetectsCorruption(t *testing.T) {
+	for name, setup := range map[string]func(t *testing.T, path string){
+		"not a database": func(t *testing.T, path string) {
+			if err := os.WriteFile(path, bytes.Repeat([]byte("garbage!"), 1024), 0644); err != nil {
+				t.Fatal(err)
+			}
+		},
+		"damaged pages": damagedDB,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "agent.db")
+			setup(t, path)
+			before, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Without recovery Open fails and leaves the file alone
+			if _, err := Open(path, false); !errors.Is(err, ErrCorrupt) {
+				t.Fatalf("Open = %v, want ErrCorrupt", err)
+			}
+			if after, _ := os.ReadFile(path); !bytes.Equal(before, after) || len(corruptFiles(t, path)) != 0 {
+				t.Fatal("Open without recovery touched the corrupt file")
+			}
+
+			// With recovery the file is moved aside and a fresh database is created
+			db, err := Open(path, true)
+			if err != nil {
+				t.Fatalf("Open with recovery: %v", err)
+			}
+			defer db.Close()
+			aside := corruptFiles(t, path)
+			if len(aside) != 1 {
+				t.Fatalf("moved aside %v, want one agent.db.corrupt.<ts>", aside)
+			}
+			if kept, _ := os.ReadFile(aside[0]); !bytes.Equal(before, kept) {
+				t.Fatal("corrupt file not preserved as is")
+			}
+			if err := db.InsertSnapshots(cycleSnapshots(1, time.Now())); err != nil {
+				t.Fatalf("fresh database unusable: %v", err)
+			}
+			if snaps, err := db.GetRecentSnapshots("srv-0", 10); err != nil || len(snaps) != 1 {
+				t.Fatalf("fresh database has %d snapshots (%v), want 1", len(snaps), err)
+			}
+		})
+	}
+}
+
+func TestOpenHealthyWithRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.db")
+	db, err := Open(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertSnapshots(cycleSnapshots(1, time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if db, err = Open(path, true); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if len(corruptFiles(t, path)) != 0 {
+		t.Fatal("a healthy database was moved aside")
+	}
+	if snaps, _ := db.GetRecentSnapshots("srv-0", 10); len(snaps) != 1 {
+		t.Fatalf("%d snapshots after reopening, want the stored 1", len(snaps))
+	}
+}
@@ -0,0 +1,209 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/xyidactyl/agent/internal/logging"
+)
+
+// migration is one schema change. Versions are applied in order, once each, and
+// recorded in schema_migrations. Never edit a shipped migration; append a new one.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// Early migrations are idempotent because databases created before schema_migrations
+// existed already have some or all of their changes and replay them once.
+var migrations = []migration{
+	{1, "initial schema", execAll(
+		`CREATE TABLE IF NOT EXISTS resource_snapshots (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			server_id   TEXT NOT NULL,
+			timestamp   DATETIME NOT NULL,
+			power_state TEXT,
+			cpu_percent REAL,
+			mem_bytes   INTEGER,
+			mem_limit   INTEGER,
+			disk_bytes  INTEGER,
+			disk_limit  INTEGER,
+			net_rx      INTEGER,
+			net_tx      INTEGER,
+			uptime_ms   INTEGER
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_snap_server_time ON resource_snapshots(server_id, timestamp)`,
+
+		`CREATE TABLE IF NOT EXISTS automation_log (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			rule_id     TEXT NOT NULL,
+			user_uuid   TEXT NOT NULL,
+			server_id   TEXT NOT NULL,
+			action      TEXT NOT NULL,
+			result      TEXT NOT NULL,
+			error_msg   TEXT,
+			executed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS alert_history (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			rule_id      TEXT NOT NULL,
+			user_uuid    TEXT NOT NULL,
+			server_id    TEXT NOT NULL,
+			condition    TEXT NOT NULL,
+			value        REAL,
+			triggered_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_alert_hist_time ON alert_history(triggered_at)`,
+
+		`CREATE TABLE IF NOT EXISTS agent_state (
+			key   TEXT PRIMARY KEY,
+			value TEXT
+		)`,
+	)},
+	{2, "automation_log.step", func(tx *sql.Tx) error {
+		return ensureColumn(tx, "automation_log", "step", "INTEGER DEFAULT 0")
+	}},
+	{3, "alert_history.severity", func(tx *sql.Tx) error {
+		return ensureColumn(tx, "alert_history", "severity", "TEXT DEFAULT 'warning'")
+	}},
+	{4, "per-server history indexes", execAll(
+		`CREATE INDEX IF NOT EXISTS idx_auto_log_server_time ON automation_log(server_id, executed_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_alert_hist_server_time ON alert_history(server_id, triggered_at)`,
+	)},
+	{5, "server_state", execAll(
+		`CREATE TABLE IF NOT EXISTS server_state (
+			server_id    TEXT PRIMARY KEY,
+			power_state  TEXT NOT NULL,
+			changed_at   DATETIME NOT NULL,
+			last_seen_at DATETIME NOT NULL
+		)`,
+	)},
+	{6, "servers", execAll(
+		`CREATE TABLE servers (
+			server_id  TEXT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			mem_limit  INTEGER NOT NULL DEFAULT 0,
+			disk_limit INTEGER NOT NULL DEFAULT 0,
+			updated_at DATETIME NOT NULL
+		)`,
+	)},
+	{7, "resource_snapshots.is_suspended", func(tx *sql.Tx) error {
+		return ensureColumn(tx, "resource_snapshots", "is_suspended", "INTEGER NOT NULL DEFAULT 0")
+	}},
+	{8, "resource_snapshots.cpu_percent_normalized", func(tx *sql.Tx) error {
+		if err := ensureColumn(tx, "resource_snapshots", "cpu_percent_normalized", "REAL NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+		// Core counts of past samples are unknown; fall back to absolute as for unlimited servers
+		_, err := tx.Exec(`UPDATE resource_snapshots SET cpu_percent_normalized = cpu_percent`)
+		return err
+	}},
+	{9, "servers.cpu_cores", func(tx *sql.Tx) error {
+		return ensureColumn(tx, "servers", "cpu_cores", "REAL NOT NULL DEFAULT 0")
+	}},
+}
+
+// migrate applies pending migrations, each in its own transaction.
+func (db *DB) migrate() error {
+	if _, err := db.conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied := make(map[int]bool)
+	rows, err := db.conn.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("read schema_migrations: %w", err)
+	}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return fmt.Errorf("read schema_migrations: %w", err)
+		}
+		applied[v] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := db.applyMigration(m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		logging.Info("Applied database migration %d: %s", m.version, m.name)
+	}
+	return nil
+}
+
+func (db *DB) applyMigration(m migration) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name); err != nil {
+		return fmt.Errorf("record: %w", err)
+	}
+	return tx.Commit()
+}
+
+// execAll returns a migration step that runs the statements in order.
+func execAll(stmts ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, s := range stmts {
+			if _, err := tx.Exec(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// ensureColumn adds a column to an existing table if it is not present yet.
+func ensureColumn(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return fmt.Errorf("inspect %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// countMigrations replaces the migration list with counting wrappers of the real
+// migrations plus one extra, restoring it when the test ends.
+func countMigrations(t *testing.T, extra func(tx *sql.Tx) error) map[int]int {
+	t.Helper()
+	orig := migrations
+	t.Cleanup(func() { migrations = orig })
+
+	runs := make(map[int]int)
+	wrapped := make([]migration, 0, len(orig)+1)
+	for _, m := range append(append([]migration(nil), orig...), migration{len(orig) + 1, "test", extra}) {
+		up := m.up
+		m.up = func(tx *sql.Tx) error {
+			runs[m.version]++
+			return up(tx)
+		}
+		wrapped = append(wrapped, m)
+	}
+	migrations = wrapped
+	return runs
+}
+
+func TestMigrationsRunOnce(t *testing.T) {
+	runs := countMigrations(t, execAll(`CREATE TABLE test_extra (id INTEGER)`))
+	path := filepath.Join(t.TempDir(), "agent.db")
+
+	db, err := Open(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Migrating an up-to-date database again, in-process or after a reopen, is a no-op
+	if err := db.migrate(); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	db, err = Open(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, m := range migrations {
+		if runs[m.version] != 1 {
+			t.Errorf("migration %d (%s) ran %d times, want 1", m.version, m.name, runs[m.version])
+		}
+	}
+	var recorded int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&recorded); err != nil {
+		t.Fatal(err)
+	}
+	if recorded != len(migrations) {
+		t.Fatalf("schema_migrations has %d rows, want %d", recorded, len(migrations))
+	}
+}
+
+func TestFailedMigrationIsRetried(t *testing.T) {
+	fail := true
+	runs := countMigrations(t, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`CREATE TABLE test_extra (id INTEGER)`); err != nil {
+			return err
+		}
+		if fail {
+			return errors.New("backfill failed")
+		}
+		return nil
+	})
+	path := filepath.Join(t.TempDir(), "agent.db")
+
+	if _, err := Open(path, false); err == nil {
+		t.Fatal("Open succeeded with a failing migration")
+	}
+	// The failed migration rolled back, so it applies cleanly once fixed
+	fail = false
+	db, err := Open(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if n := runs[len(migrations)]; n != 2 {
+		t.Fatalf("extra migration ran %d times, want 2", n)
+	}
+	if n := runs[1]; n != 1 {
+		t.Fatalf("initial migration ran %d times, want 1", n)
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.db")
+	// A database from before schema_migrations, already holding data
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE resource_snapshots (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			server_id   TEXT NOT NULL,
+			timestamp   DATETIME NOT NULL,
+			power_state TEXT,
+			cpu_percent REAL,
+			mem_bytes   INTEGER,
+			mem_limit   INTEGER,
+			disk_bytes  INTEGER,
+			disk_limit  INTEGER,
+			net_rx      INTEGER,
+			net_tx      INTEGER,
+			uptime_ms   INTEGER
+		)`,
+		`INSERT INTO resource_snapshots (server_id, timestamp, power_state, cpu_percent) VALUES ('srv-1', '2026-01-05 12:00:00', 'running', 42)`,
+	} {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.Close()
+
+	db, err := Open(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var cpu, normalized float64
+	if err := db.conn.QueryRow(`SELECT cpu_percent, cpu_percent_normalized FROM resource_snapshots WHERE server_id = 'srv-1'`).Scan(&cpu, &normalized); err != nil {
+		t.Fatal(err)
+	}
+	if cpu != 42 || normalized != 42 {
+		t.Fatalf("migrated row cpu = %v, normalized = %v, want both backfilled to 42", cpu, normalized)
+	}
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// actionQueueSize bounds the actions waiting on one server; more are dropped.
+const actionQueueSize = 32
+
+// queuedAction is an action registered with beginAction and waiting for its server's worker.
+type queuedAction struct {
+	ctx    context.Context
+	done   func()
+	user   models.ControlUser
+	apiKey string
+	rule   models.AutomationRule
+	step   int
+}
+
+// enqueue hands an action to its server's worker, starting the worker on first use.
+// Actions for one server run one at a time in the order they were queued, which is
+// rule order within a sampling pass; different servers run in parallel.
+func (ae *AutomationExecutor) enqueue(a queuedAction) {
+	ae.queueMu.Lock()
+	q, ok := ae.queues[a.rule.ServerID]
+	if !ok {
+		q = make(chan queuedAction, actionQueueSize)
+		ae.queues[a.rule.ServerID] = q
+		go ae.runQueue(q)
+	}
+	ae.queueMu.Unlock()
+
+	select {
+	case q <- a:
+	default:
+		logging.Warn("Automation %s: action queue for server %s is full, dropping %s", a.rule.ID, a.rule.ServerID, a.rule.Action)
+		a.done()
+	}
+}
+
+// runQueue executes a server's queued actions sequentially. Once Drain cancels the
+// action context, remaining actions fail fast and are still logged.
+func (ae *AutomationExecutor) runQueue(q chan queuedAction) {
+	for a := range q {
+		ae.runAction(a.ctx, a.user, a.apiKey, a.rule, a.step)
+		a.done()
+	}
+}
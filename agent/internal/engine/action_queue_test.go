@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+func TestOrderedCommandsRunInRuleOrder(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	fp := newFakePanel(t)
+	ae := NewAutomationExecutor(openTestDB(t), fp.panels, NewNotifier(push.NewRecordingProvider(false), nil, nil, nil, 0, nil, clk), NewConsoleBuffer(50), 4, 0, true, true, clk)
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	srv2Arrived := make(chan struct{})
+	var srv1Calls int
+	fp.mu.Lock()
+	fp.handler = func(w http.ResponseWriter, r *http.Request) {
+		server := strings.Split(r.URL.Path, "/")[4]
+		mu.Lock()
+		events = append(events, "start "+server)
+		first := server == "srv-1" && srv1Calls == 0
+		if server == "srv-1" {
+			srv1Calls++
+		}
+		mu.Unlock()
+
+		switch {
+		case server == "srv-2":
+			close(srv2Arrived)
+		case first:
+			// The slow first command holds srv-1's queue; srv-2 must not wait behind it
+			select {
+			case <-srv2Arrived:
+			case <-time.After(5 * time.Second):
+				t.Error("srv-2's command waited for srv-1's queue")
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		mu.Lock()
+		events = append(events, "end "+server)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}
+	fp.mu.Unlock()
+
+	command := func(id, serverID, cmd string) models.AutomationRule {
+		rule := cpuRule(id, models.ActionCommand, map[string]interface{}{"command": cmd})
+		rule.ServerID = serverID
+		rule.Cooldown = 300
+		return rule
+	}
+	srv1Rules := []models.AutomationRule{command("save", "srv-1", "save-all"), command("stop", "srv-1", "stop")}
+	srv2 := testSnapshot(clk, 95)
+	srv2.ServerID = "srv-2"
+
+	for i := 0; i < 2; i++ {
+		ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), srv1Rules)
+		ae.Evaluate(context.Background(), testUser(), "key", srv2, []models.AutomationRule{command("say", "srv-2", "say hi")})
+		clk.Advance(30 * time.Second)
+	}
+	ae.Drain(context.Background())
+
+	// srv-1's commands never overlap and keep rule order; the second cycle is on cooldown
+	var srv1 []string
+	mu.Lock()
+	for _, e := range events {
+		if strings.HasSuffix(e, "srv-1") {
+			srv1 = append(srv1, e)
+		}
+	}
+	mu.Unlock()
+	if got := strings.Join(srv1, ", "); got != "start srv-1, end srv-1, start srv-1, end srv-1" {
+		t.Fatalf("srv-1 events = %s, want two sequential commands", got)
+	}
+	var commands []string
+	reqs, bodies := fp.Requests(), fp.Bodies()
+	for i, r := range reqs {
+		if r == "POST /api/client/servers/srv-1/command" {
+			commands = append(commands, bodies[i])
+		}
+	}
+	if len(commands) != 2 || !strings.Contains(commands[0], "save-all") || !strings.Contains(commands[1], `"stop"`) {
+		t.Fatalf("srv-1 commands = %q, want save-all then stop", commands)
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// cpuRule is an enabled srv-1 automation running action above 90% CPU.
+func cpuRule(id string, action models.ActionType, config map[string]interface{}) models.AutomationRule {
+	return models.AutomationRule{
+		ID:            id,
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		TriggerType:   models.TriggerCPU,
+		TriggerConfig: map[string]interface{}{"threshold": 90.0},
+		Action:        action,
+		ActionConfig:  config,
+		Enabled:       true,
+	}
+}
+
+func TestKillActionSendsKillSignal(t *testing.T) {
+	for _, rule := range []models.AutomationRule{
+		cpuRule("kill", models.ActionKill, nil),
+		cpuRule("power-kill", models.ActionPower, map[string]interface{}{"signal": "kill"}),
+	} {
+		t.Run(rule.ID, func(t *testing.T) {
+			clk := clock.NewFake(testStart)
+			ae, fp, rec := newTestExecutor(t, clk)
+			ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 99), []models.AutomationRule{rule})
+
+			if got := fp.Requests(); len(got) != 1 || got[0] != "POST /api/client/servers/srv-1/power" {
+				t.Fatalf("requests %v, want one power call", got)
+			}
+			if got := fp.Bodies()[0]; got != `{"signal":"kill"}` {
+				t.Errorf("body %s, want kill signal", got)
+			}
+			pushes := rec.Drain()
+			if len(pushes) != 1 || !strings.HasPrefix(pushes[0].Payload.Body, "Force-killed") {
+				t.Errorf("pushes %+v, want a force-kill notice", pushes)
+			}
+		})
+	}
+}
+
+func TestReinstallRequiresConfirm(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"confirmed", map[string]interface{}{"confirm": true}, false},
+		{"no config", nil, true},
+		{"confirm false", map[string]interface{}{"confirm": false}, true},
+		{"confirm string", map[string]interface{}{"confirm": "true"}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(testStart)
+			ae, fp, _ := newTestExecutor(t, clk)
+
+			err := ae.executeAction(context.Background(), fp.panels.Default(), "key", cpuRule("reinstall", models.ActionReinstall, tc.config))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, want error: %v", err, tc.wantErr)
+			}
+			var want []string
+			if !tc.wantErr {
+				want = []string{"POST /api/client/servers/srv-1/settings/reinstall"}
+			}
+			if got := fp.Requests(); strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Fatalf("requests %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPowerActionSignals(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   models.AutomationRule
+		signal string // "" = rejected without a panel call
+	}{
+		{"power start", cpuRule("p", models.ActionPower, map[string]interface{}{"signal": "start"}), "start"},
+		{"power stop", cpuRule("p", models.ActionPower, map[string]interface{}{"signal": "stop"}), "stop"},
+		{"power restart", cpuRule("p", models.ActionPower, map[string]interface{}{"signal": "restart"}), "restart"},
+		{"power kill", cpuRule("p", models.ActionPower, map[string]interface{}{"signal": "kill"}), "kill"},
+		{"power freeze", cpuRule("p", models.ActionPower, map[string]interface{}{"signal": "freeze"}), ""},
+		{"power uppercase", cpuRule("p", models.ActionPower, map[string]interface{}{"signal": "RESTART"}), ""},
+		{"power non-string", cpuRule("p", models.ActionPower, map[string]interface{}{"signal": 1}), ""},
+		{"power no config", cpuRule("p", models.ActionPower, nil), ""},
+		{"named restart", cpuRule("r", models.ActionRestart, nil), "restart"},
+		{"named stop", cpuRule("s", models.ActionStop, nil), "stop"},
+		{"named start", cpuRule("s", models.ActionStart, nil), "start"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(testStart)
+			ae, fp, _ := newTestExecutor(t, clk)
+
+			err := ae.executeAction(context.Background(), fp.panels.Default(), "key", tc.rule)
+			if tc.signal == "" {
+				if err == nil || len(fp.Requests()) != 0 {
+					t.Fatalf("err = %v, requests %v, want the signal rejected before any call", err, fp.Requests())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fp.Bodies(); len(got) != 1 || got[0] != `{"signal":"`+tc.signal+`"}` {
+				t.Fatalf("bodies %v, want one %s signal", got, tc.signal)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// Schedules that include and exclude testStart, a Monday at 12:00 UTC.
+const (
+	businessHours = "* 9-17 * * mon-fri"
+	nightOnly     = "* 0-6 * * *"
+)
+
+func TestAlertActiveCron(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		cron  string
+		at    time.Duration // after testStart
+		fires bool
+	}{
+		{"no schedule", "", 0, true},
+		{"inside schedule", businessHours, 0, true},
+		{"outside schedule", nightOnly, 0, false},
+		{"after hours", businessHours, 7 * time.Hour, false},
+		{"weekend", businessHours, 5 * 24 * time.Hour, false},
+		{"invalid schedule", "not a cron", 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(testStart.Add(tc.at))
+			ae, rec := newTestEvaluator(t, clk)
+			rule := cpuAlert(80, 0, 0)
+			rule.ActiveCron = tc.cron
+
+			ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), []models.AlertRule{rule})
+			if got := len(rec.Drain()) == 1; got != tc.fires {
+				t.Fatalf("fired = %t, want %t", got, tc.fires)
+			}
+		})
+	}
+}
+
+func TestAutomationActiveCron(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cron string
+		at   time.Duration
+		runs bool
+	}{
+		{"inside schedule", businessHours, 0, true},
+		{"outside schedule", nightOnly, 0, false},
+		{"after hours", businessHours, 7 * time.Hour, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(testStart.Add(tc.at))
+			ae, fp, _ := newTestExecutor(t, clk)
+			rule := cpuRule("restart-hot", models.ActionRestart, nil)
+			rule.ActiveCron = tc.cron
+
+			ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 99), []models.AutomationRule{rule})
+			if got := powerCalls(fp, "srv-1") == 1; got != tc.runs {
+				t.Fatalf("ran = %t, want %t", got, tc.runs)
+			}
+		})
+	}
+}
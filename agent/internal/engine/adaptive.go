@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// AdaptiveSampling slows polling for servers that stay idle.
+type AdaptiveSampling struct {
+	IdleCycles int     // stable cycles before slowing down, 0 = off
+	SlowEvery  int     // once idle, sample every this many cycles
+	IdleCPU    float64 // CPU percent below which a running server counts as idle
+}
+
+// sampleRate tracks how long a server has been stable and when it is next due.
+type sampleRate struct {
+	powerState   string
+	stableCycles int
+	nextCycle    uint64
+}
+
+// dueForSample reports whether a server should be polled in this cycle.
+func (m *Monitor) dueForSample(serverID string, cycle uint64) bool {
+	m.rateMu.Lock()
+	defer m.rateMu.Unlock()
+
+	r, ok := m.rates[serverID]
+	return !ok || cycle >= r.nextCycle
+}
+
+// recordRate updates a server's sampling rate from its latest snapshot,
+// snapping back to every cycle as soon as the server changes state or gets busy.
+func (m *Monitor) recordRate(serverID string, cycle uint64, snap *models.ResourceSnapshot) {
+	if m.adaptive.IdleCycles <= 0 || m.adaptive.SlowEvery <= 1 {
+		return
+	}
+
+	m.rateMu.Lock()
+	defer m.rateMu.Unlock()
+
+	r, ok := m.rates[serverID]
+	if !ok {
+		r = &sampleRate{}
+		m.rates[serverID] = r
+	}
+
+	idle := snap.PowerState == "offline" || (snap.PowerState == "running" && snap.CPUPercent < m.adaptive.IdleCPU)
+	if !idle || snap.PowerState != r.powerState {
+		if r.stableCycles >= m.adaptive.IdleCycles {
+			logging.Info("Server %s changed (%s), resuming full-rate sampling", serverID, snap.PowerState)
+		}
+		r.powerState = snap.PowerState
+		r.stableCycles = 0
+		r.nextCycle = cycle + 1
+		return
+	}
+
+	r.stableCycles++
+	if r.stableCycles < m.adaptive.IdleCycles {
+		r.nextCycle = cycle + 1
+		return
+	}
+	if r.stableCycles == m.adaptive.IdleCycles {
+		logging.Info("Server %s idle for %d cycles, sampling every %d cycles", serverID, r.stableCycles, m.adaptive.SlowEvery)
+	}
+	r.nextCycle = cycle + uint64(m.adaptive.SlowEvery)
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+)
+
+func TestAdaptiveSamplingSlowsIdleServers(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	tm.adaptive = AdaptiveSampling{IdleCycles: 3, SlowEvery: 4, IdleCPU: 2}
+	var busy atomic.Bool
+	tm.panel.serveResources(func(id string) (int, string) {
+		if id == "srv-1" && !busy.Load() {
+			return http.StatusOK, resourcesBody("offline", 0, false)
+		}
+		return http.StatusOK, resourcesBody("running", 50, false)
+	})
+
+	var polled []int
+	cycle := 0
+	run := func(n int) {
+		for i := 0; i < n; i++ {
+			cycle++
+			before := tm.panel.resourceCalls("srv-1")
+			tm.sample()
+			if tm.panel.resourceCalls("srv-1") > before {
+				polled = append(polled, cycle)
+			}
+		}
+	}
+
+	// Full rate until stable for 3 cycles, then every 4th
+	run(12)
+	if want := []int{1, 2, 3, 4, 8, 12}; !reflect.DeepEqual(polled, want) {
+		t.Fatalf("idle server polled in cycles %v, want %v", polled, want)
+	}
+	if n := tm.panel.resourceCalls("srv-2"); n != 12 {
+		t.Errorf("busy server polled %d times in 12 cycles, want 12", n)
+	}
+
+	// A change seen at the next slow poll snaps back to full rate
+	busy.Store(true)
+	polled = nil
+	run(6)
+	if want := []int{16, 17, 18}; !reflect.DeepEqual(polled, want) {
+		t.Fatalf("after becoming busy polled in cycles %v, want %v", polled, want)
+	}
+}
+
+func TestAdaptiveSamplingOff(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	tm.panel.serveResources(func(id string) (int, string) {
+		return http.StatusOK, resourcesBody("offline", 0, false)
+	})
+	for i := 0; i < 10; i++ {
+		tm.sample()
+	}
+	if n := tm.panel.resourceCalls("srv-1"); n != 10 {
+		t.Fatalf("polled %d times in 10 cycles with adaptive sampling off, want 10", n)
+	}
+}
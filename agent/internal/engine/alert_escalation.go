@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// defaultEscalateWindow applies when a rule sets escalate_after but no escalate_window.
+const defaultEscalateWindow = time.Hour
+
+// triggerStreak counts a rule's triggers since it last recovered or went quiet.
+type triggerStreak struct {
+	count int
+	last  time.Time
+}
+
+// recordTrigger counts a trigger and returns the streak length, or 0 when the
+// rule does not escalate. A gap longer than the window starts a new streak.
+func (ae *AlertEvaluator) recordTrigger(rule models.AlertRule, now time.Time) int {
+	if rule.EscalateAfter <= 0 {
+		return 0
+	}
+	window := time.Duration(rule.EscalateWindow) * time.Second
+	if window <= 0 {
+		window = defaultEscalateWindow
+	}
+
+	key := stateKey(rule.ID, rule.ServerID)
+	s, ok := ae.streaks[key]
+	if !ok || now.Sub(s.last) > window {
+		s = &triggerStreak{}
+		ae.streaks[key] = s
+	}
+	s.count++
+	s.last = now
+	return s.count
+}
+
+// escalate raises severity one level once a rule has triggered escalate_after
+// times in a row, returning the new severity and a title suffix.
+func escalate(rule models.AlertRule, severity string, streak int) (string, string) {
+	if rule.EscalateAfter <= 0 || streak < rule.EscalateAfter {
+		return severity, ""
+	}
+	switch severity {
+	case "info":
+		severity = "warning"
+	case "warning":
+		severity = "critical"
+	}
+	return severity, fmt.Sprintf(" (escalated, %s time)", ordinal(streak))
+}
+
+// ordinal formats n as "1st", "2nd", "3rd", "4th", ...
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 100 {
+	case 11, 12, 13:
+	default:
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
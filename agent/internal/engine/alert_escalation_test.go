@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+// escalationRun evaluates rule once per CPU reading, step apart, and returns each
+// reading's push (nil when nothing was sent).
+func escalationRun(t *testing.T, rule models.AlertRule, step time.Duration, cpus ...float64) []*push.Payload {
+	t.Helper()
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	out := make([]*push.Payload, len(cpus))
+	for i, cpu := range cpus {
+		ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, cpu), []models.AlertRule{rule})
+		if d := rec.Drain(); len(d) > 0 {
+			out[i] = &d[0].Payload
+		}
+		clk.Advance(step)
+	}
+	return out
+}
+
+func TestAlertEscalatesAfterRepeatedTriggers(t *testing.T) {
+	rule := cpuAlert(80, 0, 0)
+	rule.EscalateAfter = 3
+
+	pushes := escalationRun(t, rule, time.Minute, 95, 95, 95, 95)
+	for i, p := range pushes {
+		if p == nil {
+			t.Fatalf("trigger %d sent nothing", i+1)
+		}
+	}
+	for _, p := range pushes[:2] {
+		if p.Severity != "warning" || strings.Contains(p.Title, "escalated") {
+			t.Fatalf("early trigger escalated: %q (%s)", p.Title, p.Severity)
+		}
+	}
+	if p := pushes[2]; p.Severity != "critical" || !strings.HasPrefix(p.Title, "[CRITICAL] ") || !strings.HasSuffix(p.Title, "(escalated, 3rd time)") {
+		t.Fatalf("3rd trigger = %q (%s), want it escalated to critical", p.Title, p.Severity)
+	}
+	if p := pushes[3]; !strings.HasSuffix(p.Title, "(escalated, 4th time)") {
+		t.Fatalf("4th trigger = %q, want it still escalated", p.Title)
+	}
+}
+
+func TestAlertEscalationInfoRaisedToWarning(t *testing.T) {
+	rule := cpuAlert(80, 0, 0)
+	rule.EscalateAfter = 2
+	rule.Severity = "info"
+
+	pushes := escalationRun(t, rule, time.Minute, 95, 95)
+	if pushes[0].Severity != "info" || pushes[1].Severity != "warning" {
+		t.Fatalf("severities %s then %s, want info raised to warning", pushes[0].Severity, pushes[1].Severity)
+	}
+}
+
+func TestAlertEscalationResetsOnRecovery(t *testing.T) {
+	rule := cpuAlert(80, 0, 0)
+	rule.EscalateAfter = 3
+
+	pushes := escalationRun(t, rule, time.Minute, 95, 95, 20, 95, 95, 95)
+	for i, p := range pushes[:5] {
+		if p != nil && strings.Contains(p.Title, "escalated") {
+			t.Fatalf("reading %d escalated across a recovery: %q", i+1, p.Title)
+		}
+	}
+	if !strings.HasSuffix(pushes[5].Title, "(escalated, 3rd time)") {
+		t.Fatalf("third trigger after recovery = %q, want it escalated", pushes[5].Title)
+	}
+}
+
+func TestAlertEscalationResetsAfterQuietGap(t *testing.T) {
+	rule := cpuAlert(80, 0, 0)
+	rule.EscalateAfter = 2
+	rule.EscalateWindow = 600
+
+	// Triggers 15 minutes apart never make a streak within the 10 minute window
+	for i, p := range escalationRun(t, rule, 15*time.Minute, 95, 95, 95) {
+		if strings.Contains(p.Title, "escalated") {
+			t.Fatalf("trigger %d escalated after a quiet gap: %q", i+1, p.Title)
+		}
+	}
+	// Five minutes apart they do
+	if p := escalationRun(t, rule, 5*time.Minute, 95, 95)[1]; !strings.Contains(p.Title, "escalated") {
+		t.Fatalf("second trigger within the window = %q, want it escalated", p.Title)
+	}
+}
+
+func TestAlertWithoutEscalateAfterNeverEscalates(t *testing.T) {
+	for i, p := range escalationRun(t, cpuAlert(80, 0, 0), time.Minute, 95, 95, 95, 95, 95) {
+		if p.Severity != "warning" || strings.Contains(p.Title, "escalated") {
+			t.Fatalf("trigger %d = %q (%s), want no escalation", i+1, p.Title, p.Severity)
+		}
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	for n, want := range map[int]string{1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 13: "13th", 21: "21st", 22: "22nd", 101: "101st", 111: "111th"} {
+		if got := ordinal(n); got != want {
+			t.Errorf("ordinal(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
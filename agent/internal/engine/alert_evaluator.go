@@ -3,196 +3,677 @@ package engine
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/xyidactyl/agent/internal/clock"
 	"github.com/xyidactyl/agent/internal/database"
 	"github.com/xyidactyl/agent/internal/logging"
 	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/pterodactyl"
 	"github.com/xyidactyl/agent/internal/push"
+	"github.com/xyidactyl/agent/internal/status"
+)
+
+const (
+	defaultCaptureWait = 3 * time.Second
+	maxCaptureWait     = 30 * time.Second
+	maxCaptureChars    = 1000 // keeps the push well under the 4KB APNs payload limit
+
+	diskTrendSamples    = 30 // recent snapshots fitted by disk_trend
+	diskTrendMinSamples = 5
 )
 
 // AlertEvaluator checks alert rules against resource snapshots
 // and triggers push notifications when conditions are met.
 type AlertEvaluator struct {
-	db           *database.DB
-	pushProvider push.Provider
+	db       *database.DB
+	panels   *pterodactyl.Panels
+	notify   *Notifier
+	coalesce bool // batch a user's alerts from one sampling pass into a single push
+	clock    clock.Clock
 
 	// In-memory state for duration-based tracking and cooldowns
-	mu               sync.Mutex
-	firstExceededAt  map[string]time.Time // rule_id -> when condition first became true
-	lastTriggeredAt  map[string]time.Time // rule_id -> last trigger time
-	previousStates   map[string]string    // server_id -> last known power state
-	restartTracker   map[string][]time.Time // server_id -> list of recent restart timestamps
+	mu              sync.Mutex
+	firstExceededAt map[string]time.Time                // rule_id|server_id -> when condition first became true
+	lastTriggeredAt map[string]time.Time                // rule_id|server_id -> last trigger time
+	ema             map[string]float64                  // rule_id|server_id -> smoothed value (Smoothing > 0 only)
+	streaks         map[string]*triggerStreak           // rule_id|server_id -> repeated triggers (EscalateAfter > 0 only)
+	serverStates    map[string]*models.ServerState      // server_id -> last known power state, persisted in server_state
+	dirtyStates     map[string]bool                     // server_ids whose state changed since the last Flush
+	previousSnaps   map[string]*models.ResourceSnapshot // server_id -> previous snapshot
+	restartTracker  map[string][]time.Time              // server_id -> list of recent restart timestamps
+	pending         map[string]*pendingAlerts           // user_uuid -> alerts awaiting Flush (coalesce only)
+	captures        sync.WaitGroup                      // alerts still capturing console output, see Drain
+	draining        bool                                // set by Drain; new alerts skip the capture
+	replayed        *replayHistory                      // set by Simulate, replaces database reads
+}
+
+// pendingAlerts are one user's alerts buffered during a sampling pass.
+type pendingAlerts struct {
+	user     models.ControlUser
+	payloads []push.Payload
 }
 
 // NewAlertEvaluator creates a new alert evaluator.
-func NewAlertEvaluator(db *database.DB, pushProvider push.Provider) *AlertEvaluator {
+func NewAlertEvaluator(db *database.DB, panels *pterodactyl.Panels, notifier *Notifier, coalesce bool, clk clock.Clock) *AlertEvaluator {
+	ae := newAlertState(clk)
+	ae.db = db
+	ae.panels = panels
+	ae.notify = notifier
+	ae.coalesce = coalesce
+
+	// Restore power states so transitions across an agent restart are still detected
+	states, err := db.GetServerStates()
+	if err != nil {
+		logging.Warn("Failed to load server states, transitions start fresh: %v", err)
+	}
+	for i := range states {
+		ae.serverStates[states[i].ServerID] = &states[i]
+	}
+	return ae
+}
+
+// newAlertState creates an evaluator with empty rule state and no outputs.
+func newAlertState(clk clock.Clock) *AlertEvaluator {
 	return &AlertEvaluator{
-		db:              db,
-		pushProvider:    pushProvider,
+		clock:           clock.OrReal(clk),
 		firstExceededAt: make(map[string]time.Time),
 		lastTriggeredAt: make(map[string]time.Time),
-		previousStates:  make(map[string]string),
+		ema:             make(map[string]float64),
+		streaks:         make(map[string]*triggerStreak),
+		serverStates:    make(map[string]*models.ServerState),
+		dirtyStates:     make(map[string]bool),
+		previousSnaps:   make(map[string]*models.ResourceSnapshot),
 		restartTracker:  make(map[string][]time.Time),
+		pending:         make(map[string]*pendingAlerts),
 	}
 }
 
 // Evaluate checks all alert rules for a specific server snapshot.
-func (ae *AlertEvaluator) Evaluate(ctx context.Context, user models.ControlUser, snapshot *models.ResourceSnapshot, rules []models.AlertRule) {
+// apiKey is the user's decrypted panel key, used for rules that capture console output.
+func (ae *AlertEvaluator) Evaluate(ctx context.Context, user models.ControlUser, apiKey string, snapshot *models.ResourceSnapshot, rules []models.AlertRule) {
 	ae.mu.Lock()
 	defer ae.mu.Unlock()
 
 	// Read previous state BEFORE updating it
-	prevState := ae.previousStates[snapshot.ServerID]
+	prevState := ae.previousState(snapshot.ServerID)
 
 	for _, rule := range rules {
-		ae.evaluateRule(ctx, user, snapshot, rule)
+		ae.evaluateRule(ctx, user, apiKey, snapshot, rule)
 	}
 
+	ae.advance(snapshot, prevState)
+}
+
+// EvaluateStale checks a server's data_stale rules against its last successful
+// snapshot when this cycle produced none (panel errors or backoff). Nothing else is
+// evaluated or recorded, since the snapshot is old data.
+func (ae *AlertEvaluator) EvaluateStale(ctx context.Context, user models.ControlUser, apiKey string, last *models.ResourceSnapshot, rules []models.AlertRule) {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	for _, rule := range rules {
+		if rule.ConditionType == models.ConditionDataStale {
+			ae.evaluateRule(ctx, user, apiKey, last, rule)
+		}
+	}
+}
+
+// advance records a snapshot as the previous sample once all rules have seen it.
+func (ae *AlertEvaluator) advance(snapshot *models.ResourceSnapshot, prevState string) {
 	// Track restarts (transition from offline/stopped to running)
 	if (prevState == "offline" || prevState == "stopped") && snapshot.PowerState == "running" {
-		ae.restartTracker[snapshot.ServerID] = append(ae.restartTracker[snapshot.ServerID], time.Now())
+		ae.restartTracker[snapshot.ServerID] = append(ae.restartTracker[snapshot.ServerID], ae.clock.Now())
 	}
 
 	// Update previous state for next cycle
-	ae.previousStates[snapshot.ServerID] = snapshot.PowerState
+	ae.recordState(snapshot)
+	ae.previousSnaps[snapshot.ServerID] = snapshot
 }
 
-func (ae *AlertEvaluator) evaluateRule(ctx context.Context, user models.ControlUser, snapshot *models.ResourceSnapshot, rule models.AlertRule) {
-	// Check cooldown
-	if lastTrigger, ok := ae.lastTriggeredAt[rule.ID]; ok {
-		if time.Since(lastTrigger) < time.Duration(rule.Cooldown)*time.Second {
-			return
-		}
-	}
-
-	triggered := false
-	var currentValue float64
-
-	switch rule.ConditionType {
-	case "cpu_threshold":
-		currentValue = snapshot.CPUPercent
-		triggered = currentValue > rule.Threshold
+// firing describes a rule that triggered on a snapshot.
+type firing struct {
+	value     float64
+	detail    string
+	severity  string
+	escalated string // title suffix when escalated, see escalate
+}
 
-	case "ram_threshold":
-		if snapshot.MemLimit > 0 {
-			currentValue = float64(snapshot.MemBytes) / float64(snapshot.MemLimit) * 100
-		}
-		triggered = currentValue > rule.Threshold
+func (ae *AlertEvaluator) evaluateRule(ctx context.Context, user models.ControlUser, apiKey string, snapshot *models.ResourceSnapshot, rule models.AlertRule) {
+	f, ok := ae.check(rule, snapshot)
+	if !ok {
+		return
+	}
+	ae.fire(ctx, user, apiKey, snapshot, rule, f)
+}
 
-	case "disk_threshold":
-		if snapshot.DiskLimit > 0 {
-			currentValue = float64(snapshot.DiskBytes) / float64(snapshot.DiskLimit) * 100
-		}
-		triggered = currentValue > rule.Threshold
+// check advances a rule's cooldown, smoothing, duration and escalation state for a
+// snapshot and reports whether it fires. It has no side effects beyond that state,
+// so Simulate can replay history through it.
+func (ae *AlertEvaluator) check(rule models.AlertRule, snapshot *models.ResourceSnapshot) (firing, bool) {
+	scheduled, err := withinCron(rule.ActiveCron, ae.clock.Now())
+	if err != nil {
+		logging.Warn("Alert %s: invalid active_cron, skipping: %v", rule.ID, err)
+		return firing{}, false
+	}
+	if !scheduled {
+		return firing{}, false
+	}
 
-	case "power_state_change":
-		prevState := ae.previousStates[snapshot.ServerID]
-		if prevState != "" && prevState != snapshot.PowerState {
-			triggered = true
-			currentValue = 0
+	// Check cooldown
+	if lastTrigger, ok := ae.lastTriggeredAt[stateKey(rule.ID, rule.ServerID)]; ok {
+		if ae.clock.Now().Sub(lastTrigger) < time.Duration(rule.Cooldown)*time.Second {
+			// Keep the average moving so it is current when the cooldown ends
+			if usesSmoothing(rule) {
+				if value, _, known := ae.measure(rule.ConditionType, rule.Threshold, snapshot); known {
+					ae.smooth(rule, value)
+				}
+			}
+			return firing{}, false
 		}
+	}
 
-	case "offline_duration":
-		if snapshot.PowerState == "offline" || snapshot.PowerState == "stopped" {
-			triggered = true
-			currentValue = 0
+	var (
+		currentValue float64
+		triggered    bool
+		detail       string
+	)
+
+	if rule.ConditionType == models.ConditionComposite {
+		currentValue, triggered, detail = ae.evaluateComposite(rule, snapshot)
+	} else {
+		var known bool
+		currentValue, triggered, known = ae.measure(rule.ConditionType, rule.Threshold, snapshot)
+		if !known {
+			logging.Warn("Unknown alert condition type: %s", rule.ConditionType)
+			return firing{}, false
 		}
-
-	case "restart_loop":
-		// Check for 3+ restarts in 5 minutes
-		recentRestarts := ae.getRecentRestarts(snapshot.ServerID, 5*time.Minute)
-		if len(recentRestarts) >= 3 {
-			triggered = true
-			currentValue = float64(len(recentRestarts))
+		if smoothed, ok := ae.smooth(rule, currentValue); ok {
+			currentValue = smoothed
+			triggered = smoothed > rule.Threshold
 		}
-
-	default:
-		logging.Warn("Unknown alert condition type: %s", rule.ConditionType)
-		return
 	}
 
 	if !triggered {
-		// Condition not met, reset duration tracker
-		delete(ae.firstExceededAt, rule.ID)
-		return
+		// Condition not met, reset duration tracker and escalation streak
+		delete(ae.firstExceededAt, stateKey(rule.ID, rule.ServerID))
+		delete(ae.streaks, stateKey(rule.ID, rule.ServerID))
+		return firing{}, false
 	}
 
 	// Duration-based check: condition must hold for `duration` seconds
-	if rule.Duration > 0 && rule.ConditionType != "power_state_change" && rule.ConditionType != "restart_loop" {
-		firstExceeded, exists := ae.firstExceededAt[rule.ID]
+	if rule.Duration > 0 && !isInstantCondition(rule.ConditionType) {
+		firstExceeded, exists := ae.firstExceededAt[stateKey(rule.ID, rule.ServerID)]
 		if !exists {
-			ae.firstExceededAt[rule.ID] = time.Now()
-			return // Start tracking, don't trigger yet
+			firstExceeded = ae.holdStart(rule, snapshot)
+			ae.firstExceededAt[stateKey(rule.ID, rule.ServerID)] = firstExceeded
 		}
 
-		if time.Since(firstExceeded) < time.Duration(rule.Duration)*time.Second {
-			return // Not held long enough
+		if ae.clock.Now().Sub(firstExceeded) < time.Duration(rule.Duration)*time.Second {
+			return firing{}, false // Not held long enough
 		}
 	}
 
 	// TRIGGER!
-	ae.lastTriggeredAt[rule.ID] = time.Now()
-	delete(ae.firstExceededAt, rule.ID) // Reset duration tracker
+	ae.lastTriggeredAt[stateKey(rule.ID, rule.ServerID)] = ae.clock.Now()
+	delete(ae.firstExceededAt, stateKey(rule.ID, rule.ServerID)) // Reset duration tracker
+
+	severity, escalated := escalate(rule, alertSeverity(rule), ae.recordTrigger(rule, ae.clock.Now()))
+	return firing{value: currentValue, detail: detail, severity: severity, escalated: escalated}, true
+}
+
+// fire records a triggered alert and notifies the user.
+func (ae *AlertEvaluator) fire(ctx context.Context, user models.ControlUser, apiKey string, snapshot *models.ResourceSnapshot, rule models.AlertRule, f firing) {
+	currentValue, detail, severity, escalated := f.value, f.detail, f.severity, f.escalated
 
 	logging.Info("🔔 Alert triggered: rule=%s type=%s server=%s value=%.1f threshold=%.1f",
 		rule.ID, rule.ConditionType, rule.ServerID, currentValue, rule.Threshold)
+	if escalated != "" {
+		logging.Info("Alert %s on %s escalated to %s%s", rule.ID, rule.ServerID, severity, escalated)
+	}
 
 	// Log to database
 	ae.db.InsertAlertHistory(models.AlertHistoryEntry{
 		RuleID:    rule.ID,
 		UserUUID:  rule.UserUUID,
 		ServerID:  rule.ServerID,
-		Condition: rule.ConditionType,
+		Condition: string(rule.ConditionType),
+		Severity:  severity,
 		Value:     currentValue,
 	})
 
 	// Build and send push notification
-	title, body := ae.buildNotificationText(rule, currentValue, snapshot)
+	title, body := ae.buildNotificationText(rule, currentValue, detail, snapshot)
+	title, body = renderAlertText(rule, alertTemplateData{
+		Value:      currentValue,
+		Threshold:  rule.Threshold,
+		ServerID:   rule.ServerID,
+		PowerState: snapshot.PowerState,
+		Condition:  string(rule.ConditionType),
+		Severity:   severity,
+		Detail:     detail,
+	}, title, body)
+	title += escalated
+	switch severity {
+	case "critical":
+		title = "[CRITICAL] " + title
+	case "info":
+		title = "[INFO] " + title
+	}
+
 	payload := push.Payload{
 		Title:     title,
 		Body:      body,
 		UserUUID:  rule.UserUUID,
 		ServerID:  rule.ServerID,
 		EventType: "alert",
-		Timestamp: time.Now().Format(time.RFC3339),
+		Severity:  severity,
+		Timestamp: ae.clock.Now().Format(time.RFC3339),
+		Sound:     rule.Sound,
+		Badge:     rule.Badge,
+	}
+	if rule.Collapse {
+		payload.CollapseID = "alert:" + stateKey(rule.ID, rule.ServerID)
 	}
 
-	for _, token := range user.DeviceTokens {
-		if err := ae.pushProvider.Send(ctx, token, payload); err != nil {
-			truncLen := len(token)
-			if truncLen > 16 {
-				truncLen = 16
-			}
-			logging.Error("Failed to send push for alert %s to token %s: %v", rule.ID, token[:truncLen], err)
+	ae.notify.notifyWebhook(ctx, user, webhookEvent{
+		EventType: payload.EventType,
+		UserUUID:  rule.UserUUID,
+		ServerID:  rule.ServerID,
+		RuleID:    rule.ID,
+		Title:     payload.Title,
+		Body:      payload.Body,
+		Timestamp: payload.Timestamp,
+		Condition: string(rule.ConditionType),
+		Severity:  severity,
+		Value:     &currentValue,
+		Threshold: &rule.Threshold,
+	})
+
+	if rule.CaptureCommand != "" && !ae.draining {
+		// Capturing console output takes seconds; don't hold up evaluation of other servers
+		ae.captures.Add(1)
+		go func() {
+			defer ae.captures.Done()
+			ae.sendWithCapture(ctx, user, apiKey, rule, payload)
+		}()
+		return
+	}
+
+	ae.deliver(ctx, user, payload)
+}
+
+// deliver pushes an alert, or buffers it for Flush when coalescing. Callers hold ae.mu.
+func (ae *AlertEvaluator) deliver(ctx context.Context, user models.ControlUser, payload push.Payload) {
+	if ae.coalesce {
+		p, ok := ae.pending[user.UserUUID]
+		if !ok {
+			p = &pendingAlerts{user: user}
+			ae.pending[user.UserUUID] = p
+		}
+		p.payloads = append(p.payloads, payload)
+		return
+	}
+
+	ae.notify.send(ctx, user, payload)
+}
+
+// Flush sends the alerts buffered during a sampling pass, one push per user.
+// It is a no-op unless coalescing is enabled.
+func (ae *AlertEvaluator) Flush(ctx context.Context) {
+	ae.mu.Lock()
+	pending := ae.pending
+	ae.pending = make(map[string]*pendingAlerts)
+	states := make([]models.ServerState, 0, len(ae.dirtyStates))
+	for id := range ae.dirtyStates {
+		states = append(states, *ae.serverStates[id])
+	}
+	ae.dirtyStates = make(map[string]bool)
+	ae.mu.Unlock()
+
+	if err := ae.db.UpsertServerStates(states); err != nil {
+		logging.Error("Failed to store %d server states: %v", len(states), err)
+	}
+
+	for _, p := range pending {
+		ae.notify.send(ctx, p.user, coalescePayloads(p.payloads, ae.clock.Now()))
+	}
+
+	ae.notify.releaseQuietHeld(ctx)
+}
+
+// Drain waits for alerts still capturing console output, then flushes them with any
+// other buffered alerts so a shutdown does not drop them. Alerts fired afterwards are
+// sent without a capture. If ctx expires first, the remaining captures are abandoned.
+func (ae *AlertEvaluator) Drain(ctx context.Context) {
+	ae.mu.Lock()
+	ae.draining = true
+	ae.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		ae.captures.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logging.Warn("Abandoning alerts still capturing console output at shutdown")
+	}
+
+	ae.Flush(ctx)
+}
+
+// coalescePayloads merges several alert payloads into one summary push sent at now.
+func coalescePayloads(payloads []push.Payload, now time.Time) push.Payload {
+	if len(payloads) == 1 {
+		return payloads[0]
+	}
+
+	servers := make(map[string]bool)
+	severity := "info"
+	lead := payloads[0] // most severe alert, whose sound and badge the summary uses
+	lines := make([]string, 0, len(payloads))
+	for _, p := range payloads {
+		servers[p.ServerID] = true
+		if severityRank(p.Severity) > severityRank(severity) {
+			severity = p.Severity
+			lead = p
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s — %s", p.ServerID, p.Title, p.Body))
+	}
+
+	title := fmt.Sprintf("⚠️ %d alerts", len(payloads))
+	if len(servers) > 1 {
+		title = fmt.Sprintf("⚠️ %d servers alerting", len(servers))
+	}
+	if severity == "critical" {
+		title = "[CRITICAL] " + title
+	}
+
+	serverID := ""
+	if len(servers) == 1 {
+		serverID = payloads[0].ServerID
+	}
+
+	return push.Payload{
+		Title:     title,
+		Body:      strings.Join(lines, "\n"),
+		UserUUID:  payloads[0].UserUUID,
+		ServerID:  serverID,
+		EventType: "alert",
+		Severity:  severity,
+		Timestamp: now.Format(time.RFC3339),
+		Sound:     lead.Sound,
+		Badge:     lead.Badge,
+	}
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case "critical":
+		return 2
+	case "warning":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// sendWithCapture runs the rule's diagnostic command, appends its output to the body and
+// delivers the push like any other alert.
+func (ae *AlertEvaluator) sendWithCapture(ctx context.Context, user models.ControlUser, apiKey string, rule models.AlertRule, payload push.Payload) {
+	wait := time.Duration(rule.CaptureWait) * time.Second
+	if wait <= 0 {
+		wait = defaultCaptureWait
+	}
+	if wait > maxCaptureWait {
+		wait = maxCaptureWait
+	}
+
+	var output string
+	client, err := ae.panels.For(user.PanelURL)
+	if err == nil {
+		output, err = client.SendCommandAndCapture(ctx, apiKey, rule.ServerID, rule.CaptureCommand, wait)
+	}
+	if err != nil {
+		logging.Warn("Alert %s: failed to capture output of %q: %v", rule.ID, rule.CaptureCommand, err)
+	}
+	if output != "" {
+		payload.Body += "\n\n" + truncateOutput(output, maxCaptureChars)
+	}
+
+	// The alert still goes out if a shutdown cut the capture short
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+	ae.deliver(context.WithoutCancel(ctx), user, payload)
+}
+
+// measure computes the current value of a single condition and whether it is met.
+// known is false for unrecognized condition types.
+func (ae *AlertEvaluator) measure(conditionType models.ConditionType, threshold float64, snapshot *models.ResourceSnapshot) (currentValue float64, triggered bool, known bool) {
+	switch conditionType {
+	case models.ConditionCPU:
+		currentValue = snapshot.CPUPercent
+		triggered = currentValue > threshold
+
+	case models.ConditionCPUNormalized:
+		currentValue = snapshot.CPUNormalized
+		triggered = currentValue > threshold
+
+	case models.ConditionRAM:
+		if snapshot.MemLimit > 0 {
+			currentValue = float64(snapshot.MemBytes) / float64(snapshot.MemLimit) * 100
+		}
+		triggered = currentValue > threshold
+
+	case models.ConditionDisk:
+		if snapshot.DiskLimit > 0 {
+			currentValue = float64(snapshot.DiskBytes) / float64(snapshot.DiskLimit) * 100
+		}
+		triggered = currentValue > threshold
+
+	case models.ConditionRAMBytes:
+		// Threshold is in bytes, independent of the memory limit
+		currentValue = float64(snapshot.MemBytes)
+		triggered = currentValue > threshold
+
+	case models.ConditionDiskBytes:
+		// Threshold is in bytes, independent of the disk limit
+		currentValue = float64(snapshot.DiskBytes)
+		triggered = currentValue > threshold
+
+	case models.ConditionNetwork:
+		// Threshold is in MB/s of combined rx+tx
+		currentValue = networkRate(ae.previousSnaps[snapshot.ServerID], snapshot)
+		triggered = currentValue > threshold
+
+	case models.ConditionCPUSpike:
+		// Threshold is the increase in percentage points between consecutive samples.
+		// Ignored on the first sample and across power transitions (startup spikes).
+		prev := ae.previousSnaps[snapshot.ServerID]
+		if prev != nil && prev.PowerState == "running" && snapshot.PowerState == "running" {
+			currentValue = snapshot.CPUPercent - prev.CPUPercent
+			triggered = currentValue > threshold
+		}
+
+	case models.ConditionUptime:
+		// Threshold is in hours of continuous uptime
+		currentValue = float64(snapshot.UptimeMs) / float64(time.Hour/time.Millisecond)
+		triggered = snapshot.PowerState == "running" && currentValue > threshold
+
+	case models.ConditionDiskTrend:
+		// Threshold is in hours; triggers when disk usage is projected to hit the limit sooner
+		hours, ok := ae.diskHoursToFull(snapshot)
+		if ok {
+			currentValue = hours
+			triggered = hours < threshold
+		}
+
+	case models.ConditionUptimeReset:
+		// Uptime going backwards while running in both samples means a silent restart
+		prev := ae.previousSnaps[snapshot.ServerID]
+		if prev != nil && prev.PowerState == "running" && snapshot.PowerState == "running" &&
+			snapshot.UptimeMs < prev.UptimeMs {
+			triggered = true
+			currentValue = float64(prev.UptimeMs) / float64(time.Hour/time.Millisecond)
+		}
+
+	case models.ConditionPowerStateChange:
+		prevState := ae.previousState(snapshot.ServerID)
+		if prevState != "" && prevState != snapshot.PowerState {
+			triggered = true
+			currentValue = 0
+		}
+
+	case models.ConditionSuspended:
+		// Fires on the transition into suspension, not while it lasts
+		prev := ae.previousSnaps[snapshot.ServerID]
+		if prev != nil && !prev.IsSuspended && snapshot.IsSuspended {
+			triggered = true
+			currentValue = 1
+		}
+
+	case models.ConditionOfflineDuration:
+		if snapshot.PowerState == "offline" || snapshot.PowerState == "stopped" {
+			triggered = true
+			currentValue = 0
+		}
+
+	case models.ConditionDataStale:
+		// Threshold is in seconds since the last successful sample, see EvaluateStale
+		currentValue = ae.clock.Now().Sub(snapshot.Timestamp).Seconds()
+		triggered = currentValue > threshold
+
+	case models.ConditionRestartLoop:
+		// Check for 3+ restarts in 5 minutes
+		recentRestarts := ae.getRecentRestarts(snapshot.ServerID, 5*time.Minute)
+		if len(recentRestarts) >= 3 {
+			triggered = true
+			currentValue = float64(len(recentRestarts))
 		}
+
+	default:
+		return 0, false, false
+	}
+
+	return currentValue, triggered, true
+}
+
+// smooth folds value into the rule's exponential moving average and returns it.
+// ok is false when the rule has no smoothing or its condition is not a plain
+// "value > threshold" check, in which case the raw value applies.
+func (ae *AlertEvaluator) smooth(rule models.AlertRule, value float64) (float64, bool) {
+	if !usesSmoothing(rule) {
+		return 0, false
+	}
+	key := stateKey(rule.ID, rule.ServerID)
+	prev, ok := ae.ema[key]
+	if !ok {
+		prev = value // Seed with the first reading
 	}
+	avg := rule.Smoothing*prev + (1-rule.Smoothing)*value
+	ae.ema[key] = avg
+	return avg, true
 }
 
-func (ae *AlertEvaluator) buildNotificationText(rule models.AlertRule, value float64, snapshot *models.ResourceSnapshot) (string, string) {
+// usesSmoothing reports whether the rule keeps a moving average of its condition's value.
+func usesSmoothing(rule models.AlertRule) bool {
+	return rule.Smoothing > 0 && isSmoothable(rule.ConditionType)
+}
+
+// isSmoothable reports whether a condition compares a continuous metric against its threshold.
+func isSmoothable(conditionType models.ConditionType) bool {
+	switch conditionType {
+	case models.ConditionCPU, models.ConditionCPUNormalized, models.ConditionRAM, models.ConditionDisk,
+		models.ConditionRAMBytes, models.ConditionDiskBytes, models.ConditionNetwork:
+		return true
+	default:
+		return false
+	}
+}
+
+// evaluateComposite combines the rule's sub-conditions with its AND/OR operator.
+// The value is the number of sub-conditions met; detail summarizes them for the notification.
+func (ae *AlertEvaluator) evaluateComposite(rule models.AlertRule, snapshot *models.ResourceSnapshot) (float64, bool, string) {
+	var met []string
+	for _, c := range rule.Conditions {
+		value, ok, known := ae.measure(c.ConditionType, c.Threshold, snapshot)
+		if !known {
+			logging.Warn("Alert %s: unknown composite sub-condition type: %s", rule.ID, c.ConditionType)
+			continue
+		}
+		if ok {
+			met = append(met, describeCondition(c.ConditionType, value, c.Threshold))
+		}
+	}
+
+	triggered := len(met) > 0
+	if strings.EqualFold(rule.Operator, "and") {
+		triggered = len(met) == len(rule.Conditions)
+	}
+	return float64(len(met)), triggered, strings.Join(met, ", ")
+}
+
+func (ae *AlertEvaluator) buildNotificationText(rule models.AlertRule, value float64, detail string, snapshot *models.ResourceSnapshot) (string, string) {
 	title := "Server Alert"
 	var body string
 
 	switch rule.ConditionType {
-	case "cpu_threshold":
+	case models.ConditionCPU:
 		title = "⚠️ CPU Alert"
 		body = fmt.Sprintf("CPU usage at %.0f%% (threshold: %.0f%%)", value, rule.Threshold)
-	case "ram_threshold":
+	case models.ConditionCPUNormalized:
+		title = "⚠️ CPU Alert"
+		body = fmt.Sprintf("CPU usage at %.0f%% of the server's limit (threshold: %.0f%%)", value, rule.Threshold)
+	case models.ConditionRAM:
 		title = "⚠️ Memory Alert"
 		body = fmt.Sprintf("Memory usage at %.0f%% (threshold: %.0f%%)", value, rule.Threshold)
-	case "disk_threshold":
+	case models.ConditionDisk:
 		title = "💾 Disk Alert"
 		body = fmt.Sprintf("Disk usage at %.0f%% (threshold: %.0f%%)", value, rule.Threshold)
-	case "power_state_change":
+	case models.ConditionRAMBytes:
+		title = "⚠️ Memory Alert"
+		body = fmt.Sprintf("Memory usage at %s (threshold: %s)", formatBytes(value), formatBytes(rule.Threshold))
+	case models.ConditionDiskBytes:
+		title = "💾 Disk Alert"
+		body = fmt.Sprintf("Disk usage at %s (threshold: %s)", formatBytes(value), formatBytes(rule.Threshold))
+	case models.ConditionNetwork:
+		title = "🌐 Network Alert"
+		body = fmt.Sprintf("Network throughput at %.1f MB/s (threshold: %.1f MB/s)", value, rule.Threshold)
+	case models.ConditionCPUSpike:
+		title = "📈 CPU Spike"
+		body = fmt.Sprintf("CPU jumped %.0f points to %.0f%% (threshold: %.0f points)", value, snapshot.CPUPercent, rule.Threshold)
+	case models.ConditionUptime:
+		title = "⏱️ Uptime Alert"
+		body = fmt.Sprintf("Server has been up for %.1f hours (threshold: %.0f hours)", value, rule.Threshold)
+	case models.ConditionDiskTrend:
+		title = "💾 Disk Filling Up"
+		body = fmt.Sprintf("Disk projected to be full in %.1f hours at the current rate (threshold: %.0f hours)", value, rule.Threshold)
+	case models.ConditionUptimeReset:
+		title = "🔁 Unexpected Restart"
+		body = fmt.Sprintf("Uptime reset after %.1f hours while the server stayed running", value)
+	case models.ConditionPowerStateChange:
 		title = "🔄 Power State Changed"
 		body = fmt.Sprintf("Server is now: %s", snapshot.PowerState)
-	case "offline_duration":
+	case models.ConditionSuspended:
+		title = "⛔ Server Suspended"
+		body = "The server was suspended by the panel. Check billing or contact your host."
+	case models.ConditionOfflineDuration:
 		title = "🔴 Server Offline"
 		body = fmt.Sprintf("Server has been offline for %d+ seconds", rule.Duration)
-	case "restart_loop":
+	case models.ConditionDataStale:
+		title = "📡 No Data"
+		body = fmt.Sprintf("No data from the panel for %s (threshold: %.0f seconds)",
+			(time.Duration(value) * time.Second).String(), rule.Threshold)
+	case models.ConditionRestartLoop:
 		title = "🔁 Restart Loop Detected"
 		body = fmt.Sprintf("%.0f restarts detected in 5 minutes", value)
+	case models.ConditionComposite:
+		title = "⚠️ Server Alert"
+		body = fmt.Sprintf("Conditions met: %s", detail)
 	default:
 		body = fmt.Sprintf("Condition %s triggered (value: %.1f)", rule.ConditionType, value)
 	}
@@ -200,9 +681,45 @@ func (ae *AlertEvaluator) buildNotificationText(rule models.AlertRule, value flo
 	return title, body
 }
 
+// diskHoursToFull projects time-to-full from the stored history plus the current snapshot,
+// which has not been inserted yet when rules are evaluated.
+func (ae *AlertEvaluator) diskHoursToFull(snapshot *models.ResourceSnapshot) (float64, bool) {
+	if ae.replayed != nil {
+		return hoursToFull(ae.replayed.recent(diskTrendSamples))
+	}
+	snaps, err := ae.db.GetRecentSnapshots(snapshot.ServerID, diskTrendSamples-1)
+	if err != nil {
+		logging.Warn("Failed to read snapshots for disk trend on %s: %v", snapshot.ServerID, err)
+		return 0, false
+	}
+	if n := len(snaps); n == 0 || snaps[n-1].Timestamp.Before(snapshot.Timestamp) {
+		snaps = append(snaps, *snapshot)
+	}
+	return hoursToFull(snaps)
+}
+
+// isInstantCondition reports whether a condition describes a single event
+// rather than a state, so the duration hold does not apply.
+func isInstantCondition(conditionType models.ConditionType) bool {
+	switch conditionType {
+	case models.ConditionPowerStateChange, models.ConditionRestartLoop, models.ConditionCPUSpike, models.ConditionUptimeReset, models.ConditionSuspended:
+		return true
+	default:
+		return false
+	}
+}
+
+// alertSeverity returns the rule's severity, defaulting to "warning".
+func alertSeverity(rule models.AlertRule) string {
+	if rule.Severity == "" {
+		return "warning"
+	}
+	return rule.Severity
+}
+
 func (ae *AlertEvaluator) getRecentRestarts(serverID string, window time.Duration) []time.Time {
 	restarts := ae.restartTracker[serverID]
-	cutoff := time.Now().Add(-window)
+	cutoff := ae.clock.Now().Add(-window)
 
 	var recent []time.Time
 	for _, t := range restarts {
@@ -215,3 +732,60 @@ func (ae *AlertEvaluator) getRecentRestarts(serverID string, window time.Duratio
 	ae.restartTracker[serverID] = recent
 	return recent
 }
+
+// holdStart returns when a rule's condition started holding. Offline time is measured
+// from the persisted state change, so it survives agent restarts and sampling gaps.
+func (ae *AlertEvaluator) holdStart(rule models.AlertRule, snapshot *models.ResourceSnapshot) time.Time {
+	if rule.ConditionType == models.ConditionOfflineDuration {
+		if st, ok := ae.serverStates[snapshot.ServerID]; ok && st.PowerState == snapshot.PowerState && !st.ChangedAt.IsZero() {
+			return st.ChangedAt
+		}
+	}
+	return ae.clock.Now()
+}
+
+// previousState returns the last known power state of a server ("" if never seen).
+func (ae *AlertEvaluator) previousState(serverID string) string {
+	if st, ok := ae.serverStates[serverID]; ok {
+		return st.PowerState
+	}
+	return ""
+}
+
+// recordState updates a server's last known power state; it is persisted on Flush.
+func (ae *AlertEvaluator) recordState(snapshot *models.ResourceSnapshot) {
+	st, ok := ae.serverStates[snapshot.ServerID]
+	if !ok {
+		st = &models.ServerState{ServerID: snapshot.ServerID}
+		ae.serverStates[snapshot.ServerID] = st
+	}
+	if st.PowerState != snapshot.PowerState {
+		st.PowerState = snapshot.PowerState
+		st.ChangedAt = snapshot.Timestamp
+	}
+	st.LastSeenAt = snapshot.Timestamp
+	ae.dirtyStates[snapshot.ServerID] = true
+}
+
+// offlineServers lists the given servers that were last seen offline or stopped, and since when.
+func (ae *AlertEvaluator) offlineServers(serverIDs []string) []status.OfflineServer {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	var out []status.OfflineServer
+	seen := make(map[string]bool)
+	for _, id := range serverIDs {
+		st, ok := ae.serverStates[id]
+		if !ok || seen[id] || (st.PowerState != "offline" && st.PowerState != "stopped") {
+			continue
+		}
+		seen[id] = true
+		out = append(out, status.OfflineServer{
+			ServerID:   id,
+			PowerState: st.PowerState,
+			Since:      st.ChangedAt.UTC().Format(time.RFC3339),
+			LastSeenAt: st.LastSeenAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return out
+}
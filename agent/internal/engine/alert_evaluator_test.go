@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestAlertDeliveredToEveryDevice(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	user := testUser()
+	user.DeviceTokens = []string{"token-1", "token-2"}
+	rule := cpuAlert(80, 0, 0)
+
+	ae.Evaluate(context.Background(), user, "key", testSnapshot(clk, 50), []models.AlertRule{rule})
+	if got := rec.Drain(); len(got) != 0 {
+		t.Fatalf("fired below the threshold: %+v", got)
+	}
+
+	ae.Evaluate(context.Background(), user, "key", testSnapshot(clk, 95), []models.AlertRule{rule})
+	got := rec.Drain()
+	if len(got) != 2 {
+		t.Fatalf("%d deliveries, want one per device", len(got))
+	}
+	for i, d := range got {
+		if d.Token != user.DeviceTokens[i] {
+			t.Errorf("delivery %d went to %q, want %q", i, d.Token, user.DeviceTokens[i])
+		}
+		if d.Payload.EventType != "alert" || d.Payload.ServerID != "srv-1" || d.Payload.UserUUID != "user-1" {
+			t.Errorf("delivery %d payload %+v", i, d.Payload)
+		}
+	}
+}
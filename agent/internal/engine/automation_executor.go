@@ -3,9 +3,12 @@ package engine
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/xyidactyl/agent/internal/clock"
 	"github.com/xyidactyl/agent/internal/database"
 	"github.com/xyidactyl/agent/internal/logging"
 	"github.com/xyidactyl/agent/internal/models"
@@ -15,24 +18,63 @@ import (
 
 // AutomationExecutor evaluates automation rules and executes actions.
 type AutomationExecutor struct {
-	db           *database.DB
-	pteroClient  *pterodactyl.Client
-	pushProvider push.Provider
-	maxConcurrent int
-
-	mu              sync.Mutex
-	lastExecutedAt  map[string]time.Time // rule_id -> last execution time
+	db             *database.DB
+	panels         *pterodactyl.Panels
+	notify         *Notifier
+	consoles       *ConsoleBuffer
+	maxConcurrent  int
+	actionCooldown time.Duration // min gap between the same action on a server across rules, 0 = off
+	defaultEnabled bool          // AUTOMATIONS_ENABLED, unless overridden in agent_state
+	enabled        atomic.Bool
+	ordered        bool // run a server's actions one at a time in rule order (see dispatch)
+	clock          clock.Clock
+
+	mu             sync.Mutex
+	lastExecutedAt map[string]time.Time                // rule_id|server_id -> last execution time
+	escalations    map[string]*escalationState         // rule_id|server_id -> position in escalation chain
+	previousSnaps  map[string]*models.ResourceSnapshot // server_id -> previous snapshot
+	lastActionAt   map[string]time.Time                // server_id|action -> last execution time
+	crashed        map[string]bool                     // server_ids that crashed and are still offline
+
+	// In-flight actions, tracked so shutdown can wait for them (see Drain)
+	actionCtx     context.Context
+	cancelActions context.CancelFunc
+	inflightMu    sync.Mutex
+	inflightWG    sync.WaitGroup
+	inflight      map[int64]string // id -> description
+	inflightSeq   int64
+	draining      bool
+
+	queueMu sync.Mutex
+	queues  map[string]chan queuedAction // server_id -> pending actions, ordered mode only
 }
 
 // NewAutomationExecutor creates a new automation executor.
-func NewAutomationExecutor(db *database.DB, pteroClient *pterodactyl.Client, pushProvider push.Provider, maxConcurrent int) *AutomationExecutor {
-	return &AutomationExecutor{
+func NewAutomationExecutor(db *database.DB, panels *pterodactyl.Panels, notifier *Notifier, consoles *ConsoleBuffer, maxConcurrent int, actionCooldown time.Duration, enabled, ordered bool, clk clock.Clock) *AutomationExecutor {
+	actionCtx, cancelActions := context.WithCancel(context.Background())
+	ae := &AutomationExecutor{
 		db:             db,
-		pteroClient:    pteroClient,
-		pushProvider:   pushProvider,
+		panels:         panels,
+		notify:         notifier,
+		consoles:       consoles,
 		maxConcurrent:  maxConcurrent,
+		actionCooldown: actionCooldown,
+		defaultEnabled: enabled,
+		ordered:        ordered,
+		clock:          clock.OrReal(clk),
 		lastExecutedAt: make(map[string]time.Time),
+		escalations:    make(map[string]*escalationState),
+		previousSnaps:  make(map[string]*models.ResourceSnapshot),
+		lastActionAt:   make(map[string]time.Time),
+		crashed:        make(map[string]bool),
+		actionCtx:      actionCtx,
+		cancelActions:  cancelActions,
+		inflight:       make(map[int64]string),
+		queues:         make(map[string]chan queuedAction),
 	}
+	ae.enabled.Store(enabled)
+	ae.refreshEnabled()
+	return ae
 }
 
 // Evaluate checks automation rules for a server and executes triggered actions.
@@ -40,15 +82,35 @@ func (ae *AutomationExecutor) Evaluate(ctx context.Context, user models.ControlU
 	ae.mu.Lock()
 	defer ae.mu.Unlock()
 
+	if snapshot.PowerState != "offline" {
+		delete(ae.crashed, snapshot.ServerID)
+	}
+
+	if !ae.Enabled() {
+		if len(rules) > 0 {
+			logging.Debug("Automations disabled, suppressing %d rules for server %s", len(rules), snapshot.ServerID)
+		}
+		ae.previousSnaps[snapshot.ServerID] = snapshot
+		return
+	}
+
 	for _, rule := range rules {
 		ae.evaluateRule(ctx, user, apiKey, snapshot, rule)
 	}
+
+	ae.previousSnaps[snapshot.ServerID] = snapshot
 }
 
 func (ae *AutomationExecutor) evaluateRule(ctx context.Context, user models.ControlUser, apiKey string, snapshot *models.ResourceSnapshot, rule models.AutomationRule) {
+	// Escalation chains pace themselves with per-step waits instead of the rule cooldown
+	if steps, ok := parseEscalation(rule.ActionConfig); ok {
+		ae.evaluateEscalation(ctx, user, apiKey, snapshot, rule, steps)
+		return
+	}
+
 	// Check cooldown
-	if lastExec, ok := ae.lastExecutedAt[rule.ID]; ok {
-		if time.Since(lastExec) < time.Duration(rule.Cooldown)*time.Second {
+	if lastExec, ok := ae.lastExecutedAt[stateKey(rule.ID, rule.ServerID)]; ok {
+		if ae.clock.Now().Sub(lastExec) < time.Duration(rule.Cooldown)*time.Second {
 			return
 		}
 	}
@@ -66,11 +128,49 @@ func (ae *AutomationExecutor) evaluateRule(ctx context.Context, user models.Cont
 		return
 	}
 
+	if !ae.checkActiveHours(rule) {
+		return
+	}
+
+	if ae.actionOnCooldown(rule) {
+		return
+	}
+
 	// Execute action
 	logging.Info("⚡ Automation triggered: rule=%s trigger=%s action=%s server=%s",
 		rule.ID, rule.TriggerType, rule.Action, rule.ServerID)
 
-	err := ae.executeAction(ctx, apiKey, rule)
+	ae.dispatch(user, apiKey, rule, 0)
+}
+
+// dispatch runs the rule's action inline, or queues it behind the server's earlier
+// actions in ordered mode. step is the 1-based escalation step, or 0 for a plain rule.
+// The action runs on the executor's own context so a shutdown waits for it instead of
+// cutting it off. Cooldowns are stamped here, so a queued action already counts.
+func (ae *AutomationExecutor) dispatch(user models.ControlUser, apiKey string, rule models.AutomationRule, step int) {
+	ctx, done, ok := ae.beginAction(rule, step)
+	if !ok {
+		logging.Warn("Automation %s: agent is shutting down, not running %s on %s", rule.ID, rule.Action, rule.ServerID)
+		return
+	}
+
+	ae.lastExecutedAt[stateKey(rule.ID, rule.ServerID)] = ae.clock.Now()
+	ae.lastActionAt[rule.ServerID+"|"+actionName(rule)] = ae.clock.Now()
+
+	if ae.ordered {
+		ae.enqueue(queuedAction{ctx: ctx, done: done, user: user, apiKey: apiKey, rule: rule, step: step})
+		return
+	}
+	defer done()
+	ae.runAction(ctx, user, apiKey, rule, step)
+}
+
+// runAction executes the rule's action, records it in automation_log and notifies the user.
+func (ae *AutomationExecutor) runAction(ctx context.Context, user models.ControlUser, apiKey string, rule models.AutomationRule, step int) {
+	client, err := ae.panels.For(user.PanelURL)
+	if err == nil {
+		err = ae.executeAction(ctx, client, apiKey, rule)
+	}
 
 	// Log execution
 	result := "success"
@@ -81,13 +181,23 @@ func (ae *AutomationExecutor) evaluateRule(ctx context.Context, user models.Cont
 		logging.Error("Automation %s failed: %v", rule.ID, err)
 	}
 
-	ae.lastExecutedAt[rule.ID] = time.Now()
+	// Crash reports carry the console output leading up to the crash
+	var consoleTail, reason string
+	if rule.TriggerType == models.TriggerCrash {
+		lines := ae.consoles.Recent(rule.ServerID)
+		reason = crashReason(lines)
+		consoleTail = truncateOutput(strings.Join(lines, "\n"), maxCaptureChars)
+		if consoleTail != "" {
+			errMsg = strings.TrimSpace(errMsg + "\n--- console ---\n" + consoleTail)
+		}
+	}
 
 	ae.db.InsertAutomationLog(models.AutomationLogEntry{
 		RuleID:   rule.ID,
 		UserUUID: rule.UserUUID,
 		ServerID: rule.ServerID,
-		Action:   rule.Action,
+		Action:   string(rule.Action),
+		Step:     step,
 		Result:   result,
 		ErrorMsg: errMsg,
 	})
@@ -95,9 +205,21 @@ func (ae *AutomationExecutor) evaluateRule(ctx context.Context, user models.Cont
 	// Send push notification about automation
 	title := fmt.Sprintf("⚡ Automation: %s", rule.Action)
 	body := fmt.Sprintf("Executed '%s' on server (trigger: %s)", rule.Action, rule.TriggerType)
+	if actionName(rule) == "kill" {
+		body = fmt.Sprintf("Force-killed server (trigger: %s)", rule.TriggerType)
+	}
 	if err != nil {
 		body = fmt.Sprintf("Failed to execute '%s': %s", rule.Action, errMsg)
 	}
+	if step > 0 {
+		title = fmt.Sprintf("%s (step %d)", title, step)
+	}
+	if reason != "" {
+		body += fmt.Sprintf("\nLikely cause: %s", reason)
+	}
+	if consoleTail != "" {
+		body += "\n\n" + consoleTail
+	}
 
 	payload := push.Payload{
 		Title:     title,
@@ -105,26 +227,78 @@ func (ae *AutomationExecutor) evaluateRule(ctx context.Context, user models.Cont
 		UserUUID:  rule.UserUUID,
 		ServerID:  rule.ServerID,
 		EventType: "automation",
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: ae.clock.Now().Format(time.RFC3339),
+		Sound:     rule.Sound,
+		Badge:     rule.Badge,
 	}
 
-	for _, token := range user.DeviceTokens {
-		if pushErr := ae.pushProvider.Send(ctx, token, payload); pushErr != nil {
-			logging.Error("Failed to send automation push to token: %v", pushErr)
-		}
+	ae.notify.notifyWebhook(ctx, user, webhookEvent{
+		EventType: payload.EventType,
+		UserUUID:  rule.UserUUID,
+		ServerID:  rule.ServerID,
+		RuleID:    rule.ID,
+		Title:     payload.Title,
+		Body:      payload.Body,
+		Timestamp: payload.Timestamp,
+		Action:    actionName(rule),
+		Trigger:   string(rule.TriggerType),
+		Step:      step,
+		Result:    result,
+		Error:     errMsg,
+	})
+
+	ae.notify.send(ctx, user, payload)
+}
+
+// actionOnCooldown reports whether the rule's action already ran on its server
+// within the action cooldown, possibly triggered by another rule.
+func (ae *AutomationExecutor) actionOnCooldown(rule models.AutomationRule) bool {
+	if ae.actionCooldown <= 0 {
+		return false
+	}
+	last, ok := ae.lastActionAt[rule.ServerID+"|"+actionName(rule)]
+	if !ok || ae.clock.Now().Sub(last) >= ae.actionCooldown {
+		return false
 	}
+	logging.Info("Automation %s: %s on server %s already ran %s ago, skipping",
+		rule.ID, rule.Action, rule.ServerID, ae.clock.Now().Sub(last).Round(time.Second))
+	return true
+}
+
+// checkActiveHours reports whether the rule may run now according to its active_cron
+// schedule and active_hours window.
+func (ae *AutomationExecutor) checkActiveHours(rule models.AutomationRule) bool {
+	scheduled, err := withinCron(rule.ActiveCron, ae.clock.Now())
+	if err != nil {
+		logging.Warn("Automation %s: invalid active_cron, skipping: %v", rule.ID, err)
+		return false
+	}
+	if !scheduled {
+		logging.Debug("Automation %s: outside active_cron schedule, skipping", rule.ID)
+		return false
+	}
+
+	active, err := withinActiveHours(rule.TriggerConfig, ae.clock.Now())
+	if err != nil {
+		logging.Warn("Automation %s: invalid active_hours, skipping: %v", rule.ID, err)
+		return false
+	}
+	if !active {
+		logging.Debug("Automation %s: outside active hours, skipping", rule.ID)
+	}
+	return active
 }
 
 func (ae *AutomationExecutor) evaluateTrigger(rule models.AutomationRule, snapshot *models.ResourceSnapshot) bool {
 	switch rule.TriggerType {
-	case "cpu_threshold":
+	case models.TriggerCPU:
 		threshold, ok := getFloat(rule.TriggerConfig, "threshold")
 		if !ok {
 			return false
 		}
 		return snapshot.CPUPercent > threshold
 
-	case "ram_threshold":
+	case models.TriggerRAM:
 		threshold, ok := getFloat(rule.TriggerConfig, "threshold")
 		if !ok || snapshot.MemLimit == 0 {
 			return false
@@ -132,7 +306,7 @@ func (ae *AutomationExecutor) evaluateTrigger(rule models.AutomationRule, snapsh
 		memPercent := float64(snapshot.MemBytes) / float64(snapshot.MemLimit) * 100
 		return memPercent > threshold
 
-	case "disk_threshold":
+	case models.TriggerDisk:
 		threshold, ok := getFloat(rule.TriggerConfig, "threshold")
 		if !ok || snapshot.DiskLimit == 0 {
 			return false
@@ -140,11 +314,38 @@ func (ae *AutomationExecutor) evaluateTrigger(rule models.AutomationRule, snapsh
 		diskPercent := float64(snapshot.DiskBytes) / float64(snapshot.DiskLimit) * 100
 		return diskPercent > threshold
 
-	case "server_offline":
+	case models.TriggerRAMBytes:
+		threshold, ok := getFloat(rule.TriggerConfig, "threshold")
+		if !ok {
+			return false
+		}
+		return float64(snapshot.MemBytes) > threshold
+
+	case models.TriggerDiskBytes:
+		threshold, ok := getFloat(rule.TriggerConfig, "threshold")
+		if !ok {
+			return false
+		}
+		return float64(snapshot.DiskBytes) > threshold
+
+	case models.TriggerNetwork:
+		// Threshold is in MB/s of combined rx+tx
+		threshold, ok := getFloat(rule.TriggerConfig, "threshold")
+		if !ok {
+			return false
+		}
+		return networkRate(ae.previousSnaps[snapshot.ServerID], snapshot) > threshold
+
+	case models.TriggerOffline:
 		return snapshot.PowerState == "offline" || snapshot.PowerState == "stopped"
 
-	case "server_crash":
-		return snapshot.PowerState == "offline" // Distinguish from "stopped" (intentional)
+	case models.TriggerCrash:
+		return ae.isCrash(rule, snapshot)
+
+	case models.TriggerSuspended:
+		// Only the transition into suspension, so the action runs once per suspension
+		prev := ae.previousSnaps[snapshot.ServerID]
+		return prev != nil && !prev.IsSuspended && snapshot.IsSuspended
 
 	default:
 		logging.Warn("Unknown automation trigger type: %s", rule.TriggerType)
@@ -152,32 +353,64 @@ func (ae *AutomationExecutor) evaluateTrigger(rule models.AutomationRule, snapsh
 	}
 }
 
-func (ae *AutomationExecutor) executeAction(ctx context.Context, apiKey string, rule models.AutomationRule) error {
+func (ae *AutomationExecutor) executeAction(ctx context.Context, client *pterodactyl.Client, apiKey string, rule models.AutomationRule) error {
 	switch rule.Action {
-	case "restart":
-		return ae.pteroClient.SendPowerSignal(apiKey, rule.ServerID, "restart")
+	case models.ActionRestart:
+		return client.SendPowerSignal(ctx, apiKey, rule.ServerID, "restart")
 
-	case "stop":
-		return ae.pteroClient.SendPowerSignal(apiKey, rule.ServerID, "stop")
+	case models.ActionStop:
+		return client.SendPowerSignal(ctx, apiKey, rule.ServerID, "stop")
 
-	case "start":
-		return ae.pteroClient.SendPowerSignal(apiKey, rule.ServerID, "start")
+	case models.ActionStart:
+		return client.SendPowerSignal(ctx, apiKey, rule.ServerID, "start")
 
-	case "command":
+	case models.ActionKill:
+		// Hard kill for servers that hang on a graceful stop/restart
+		return client.SendPowerSignal(ctx, apiKey, rule.ServerID, "kill")
+
+	case models.ActionPower:
+		signal, _ := rule.ActionConfig["signal"].(string)
+		if !pterodactyl.IsPowerSignal(signal) {
+			return fmt.Errorf("invalid power signal %q in action_config", signal)
+		}
+		return client.SendPowerSignal(ctx, apiKey, rule.ServerID, signal)
+
+	case models.ActionCommand:
 		cmd, ok := rule.ActionConfig["command"].(string)
 		if !ok || cmd == "" {
 			return fmt.Errorf("missing command in action_config")
 		}
-		return ae.pteroClient.SendCommand(apiKey, rule.ServerID, cmd)
+		return client.SendCommand(ctx, apiKey, rule.ServerID, cmd)
+
+	case models.ActionBackup:
+		if err := ae.rotateBackups(ctx, client, apiKey, rule); err != nil {
+			return err
+		}
+		return client.CreateBackup(ctx, apiKey, rule.ServerID, backupName(rule, ae.clock.Now()))
 
-	case "backup":
-		return ae.pteroClient.CreateBackup(apiKey, rule.ServerID)
+	case models.ActionReinstall:
+		// Destructive: only run when the rule explicitly opts in
+		if confirm, _ := rule.ActionConfig["confirm"].(bool); !confirm {
+			return fmt.Errorf("reinstall requires \"confirm\": true in action_config")
+		}
+		return client.ReinstallServer(ctx, apiKey, rule.ServerID)
 
 	default:
 		return fmt.Errorf("unknown action: %s", rule.Action)
 	}
 }
 
+// actionName is the effective action of a rule: the signal for generic "power"
+// actions, so "power" with signal "kill" shares the action cooldown with "kill".
+func actionName(rule models.AutomationRule) string {
+	if rule.Action == models.ActionPower {
+		if signal, _ := rule.ActionConfig["signal"].(string); signal != "" {
+			return signal
+		}
+	}
+	return string(rule.Action)
+}
+
 func isServerAllowed(user models.ControlUser, serverID string) bool {
 	for _, s := range user.AllowedServers {
 		if s == serverID {
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+)
+
+func TestFailingServerBacksOff(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	var failing atomic.Bool
+	failing.Store(true)
+	tm.panel.serveResources(func(id string) (int, string) {
+		if id == "srv-1" && failing.Load() {
+			return http.StatusBadGateway, `{"errors":[]}`
+		}
+		return http.StatusOK, resourcesBody("running", 5, false)
+	})
+
+	// Skips double after each failure: 1, 2, 4 cycles
+	var polled []int
+	for cycle := 1; cycle <= 11; cycle++ {
+		before := tm.panel.resourceCalls("srv-1")
+		tm.sample()
+		if tm.panel.resourceCalls("srv-1") > before {
+			polled = append(polled, cycle)
+		}
+	}
+	if want := []int{1, 3, 6, 11}; !reflect.DeepEqual(polled, want) {
+		t.Fatalf("failing server polled in cycles %v, want %v", polled, want)
+	}
+	if n := tm.panel.resourceCalls("srv-2"); n != 11 {
+		t.Errorf("healthy server polled %d times in 11 cycles, want 11", n)
+	}
+
+	// Recovery clears the backoff
+	failing.Store(false)
+	for i := 0; i < 8; i++ { // the fourth failure skips 8 cycles
+		tm.sample()
+	}
+	before := tm.panel.resourceCalls("srv-1")
+	tm.sample()
+	tm.sample()
+	if n := tm.panel.resourceCalls("srv-1") - before; n != 2 {
+		t.Errorf("recovered server polled %d times in 2 cycles, want 2", n)
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	for i := 0; i < 10; i++ {
+		tm.recordFailure("srv-1", "user-1", http.ErrHandlerTimeout)
+	}
+	if skip := tm.backoff["srv-1"].skipLeft; skip != maxBackoffCycles {
+		t.Fatalf("skip after 10 failures = %d, want the cap of %d", skip, maxBackoffCycles)
+	}
+}
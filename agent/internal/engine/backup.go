@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/pterodactyl"
+)
+
+// rotateBackups makes room for a new backup when action_config has "rotate": true:
+// while the server holds max_backups or more, the oldest unlocked backup is deleted.
+func (ae *AutomationExecutor) rotateBackups(ctx context.Context, client *pterodactyl.Client, apiKey string, rule models.AutomationRule) error {
+	if rotate, _ := rule.ActionConfig["rotate"].(bool); !rotate {
+		return nil
+	}
+	max, ok := getFloat(rule.ActionConfig, "max_backups")
+	if !ok || max < 1 {
+		return fmt.Errorf("rotate requires max_backups >= 1 in action_config")
+	}
+
+	backups, err := client.ListBackups(ctx, apiKey, rule.ServerID)
+	if err != nil {
+		return fmt.Errorf("list backups: %w", err)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.Before(backups[j].CreatedAt)
+	})
+
+	count := len(backups)
+	for _, b := range backups {
+		if count < int(max) {
+			break
+		}
+		if b.IsLocked {
+			continue
+		}
+
+		err := client.DeleteBackup(ctx, apiKey, rule.ServerID, b.UUID)
+
+		result := "success"
+		errMsg := ""
+		if err != nil {
+			result = "failure"
+			errMsg = err.Error()
+		}
+		ae.db.InsertAutomationLog(models.AutomationLogEntry{
+			RuleID:   rule.ID,
+			UserUUID: rule.UserUUID,
+			ServerID: rule.ServerID,
+			Action:   "backup_delete",
+			Result:   result,
+			ErrorMsg: errMsg,
+		})
+		if err != nil {
+			return fmt.Errorf("delete backup %s: %w", b.UUID, err)
+		}
+
+		logging.Info("Automation %s: deleted oldest backup %s (%s) to stay under %d backups",
+			rule.ID, b.Name, b.UUID, int(max))
+		count--
+	}
+
+	if count >= int(max) {
+		return fmt.Errorf("backup limit of %d reached and all remaining backups are locked", int(max))
+	}
+	return nil
+}
+
+// backupNameData is what action_config "name_template" can reference,
+// e.g. "crash-{{.ServerID}}-{{.Timestamp}}".
+type backupNameData struct {
+	ServerID  string
+	RuleID    string
+	Trigger   string
+	Timestamp string // UTC, 20060102-150405
+}
+
+// backupName renders the rule's name_template at now, or "" to let the panel name the backup.
+func backupName(rule models.AutomationRule, now time.Time) string {
+	tmpl, _ := rule.ActionConfig["name_template"].(string)
+	if tmpl == "" {
+		return ""
+	}
+
+	name, err := renderTemplate(tmpl, backupNameData{
+		ServerID:  rule.ServerID,
+		RuleID:    rule.ID,
+		Trigger:   string(rule.TriggerType),
+		Timestamp: now.UTC().Format("20060102-150405"),
+	})
+	if err != nil {
+		logging.Warn("Automation %s: name_template failed, creating unnamed backup: %v", rule.ID, err)
+		return ""
+	}
+	return name
+}
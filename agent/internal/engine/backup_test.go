@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// serveBackups makes the fake panel list the given backups ("uuid:created" pairs,
+// locked when the uuid ends in "!") and accept deletes and new backups.
+func (fp *fakePanel) serveBackups(backups ...string) {
+	var data []string
+	for _, b := range backups {
+		uuid, created, _ := strings.Cut(b, ":")
+		locked := strings.HasSuffix(uuid, "!")
+		uuid = strings.TrimSuffix(uuid, "!")
+		data = append(data, fmt.Sprintf(`{"attributes":{"uuid":%q,"name":%q,"is_locked":%t,"created_at":%q}}`, uuid, "backup "+uuid, locked, created))
+	}
+	list := fmt.Sprintf(`{"data":[%s],"meta":{"pagination":{"total_pages":1}}}`, strings.Join(data, ","))
+
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.handler = func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/backups") {
+			fmt.Fprint(w, list)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// rotatingBackupRule backs up srv-1 keeping at most max backups.
+func rotatingBackupRule(max float64) models.AutomationRule {
+	return cpuRule("backup", models.ActionBackup, map[string]interface{}{"rotate": true, "max_backups": max})
+}
+
+func TestBackupRotationDeletesOldestUnlocked(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, _ := newTestExecutor(t, clk)
+	fp.serveBackups(
+		"b-new:2026-01-04T00:00:00Z",
+		"b-locked!:2026-01-01T00:00:00Z",
+		"b-old:2026-01-02T00:00:00Z",
+	)
+
+	if err := ae.executeAction(context.Background(), fp.panels.Default(), "key", rotatingBackupRule(3)); err != nil {
+		t.Fatalf("executeAction: %v", err)
+	}
+
+	want := []string{
+		"GET /api/client/servers/srv-1/backups",
+		"DELETE /api/client/servers/srv-1/backups/b-old",
+		"POST /api/client/servers/srv-1/backups",
+	}
+	if got := fp.Requests(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("requests %v, want %v", got, want)
+	}
+
+	log, err := ae.db.GetAutomationLog("user-1", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 1 || log[0].Action != "backup_delete" || log[0].Result != "success" {
+		t.Fatalf("automation log %+v, want one backup_delete entry", log)
+	}
+}
+
+func TestBackupRotationBelowLimit(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, _ := newTestExecutor(t, clk)
+	fp.serveBackups("b-1:2026-01-01T00:00:00Z")
+
+	if err := ae.executeAction(context.Background(), fp.panels.Default(), "key", rotatingBackupRule(3)); err != nil {
+		t.Fatalf("executeAction: %v", err)
+	}
+	for _, r := range fp.Requests() {
+		if strings.HasPrefix(r, "DELETE") {
+			t.Fatalf("deleted a backup below the limit: %v", fp.Requests())
+		}
+	}
+}
+
+func TestBackupRotationAllLocked(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, _ := newTestExecutor(t, clk)
+	fp.serveBackups("a!:2026-01-01T00:00:00Z", "b!:2026-01-02T00:00:00Z")
+
+	err := ae.executeAction(context.Background(), fp.panels.Default(), "key", rotatingBackupRule(2))
+	if err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Fatalf("err = %v, want the limit reached with only locked backups", err)
+	}
+	for _, r := range fp.Requests() {
+		if strings.HasPrefix(r, "DELETE") || strings.HasPrefix(r, "POST") {
+			t.Fatalf("requests %v, want no delete or create", fp.Requests())
+		}
+	}
+}
+
+func TestBackupRotationRequiresMax(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, _ := newTestExecutor(t, clk)
+	rule := cpuRule("backup", models.ActionBackup, map[string]interface{}{"rotate": true})
+
+	if err := ae.executeAction(context.Background(), fp.panels.Default(), "key", rule); err == nil {
+		t.Fatal("want an error for rotate without max_backups")
+	}
+	if got := fp.Requests(); len(got) != 0 {
+		t.Fatalf("requests %v, want none", got)
+	}
+}
+
+func TestBackupNameTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template interface{}
+		wantBody string
+	}{
+		{"templated", "crash-{{.ServerID}}-{{.Timestamp}}", `{"name":"crash-srv-1-20260105-120000"}`},
+		{"no template", nil, `{}`},
+		{"malformed template", "crash-{{.ServerID", `{}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(testStart)
+			ae, fp, _ := newTestExecutor(t, clk)
+			config := map[string]interface{}{}
+			if tc.template != nil {
+				config["name_template"] = tc.template
+			}
+
+			if err := ae.executeAction(context.Background(), fp.panels.Default(), "key", cpuRule("backup", models.ActionBackup, config)); err != nil {
+				t.Fatalf("executeAction: %v", err)
+			}
+			if got := fp.Requests(); len(got) != 1 || got[0] != "POST /api/client/servers/srv-1/backups" {
+				t.Fatalf("requests %v, want one backup", got)
+			}
+			if got := fp.Bodies()[0]; got != tc.wantBody {
+				t.Fatalf("body %s, want %s", got, tc.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestFormatBytes(t *testing.T) {
+	for _, tt := range []struct {
+		in   float64
+		want string
+	}{
+		{0, "0 B"},
+		{512, "512 B"},
+		{1536, "1.5 KB"},
+		{3.2 * 1024 * 1024 * 1024, "3.2 GB"},
+		{5 << 40, "5.0 TB"},
+		{3 << 50, "3072.0 TB"},
+	} {
+		if got := formatBytes(tt.in); got != tt.want {
+			t.Errorf("formatBytes(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBytesAlertsIgnoreLimits(t *testing.T) {
+	for _, tt := range []struct {
+		condition models.ConditionType
+		threshold float64
+		wantBody  string
+	}{
+		{models.ConditionRAMBytes, 2 << 30, "Memory usage at 3.0 GB (threshold: 2.0 GB)"},
+		{models.ConditionDiskBytes, 10 << 30, "Disk usage at 20.0 GB (threshold: 10.0 GB)"},
+	} {
+		t.Run(string(tt.condition), func(t *testing.T) {
+			clk := clock.NewFake(testStart)
+			ae, rec := newTestEvaluator(t, clk)
+			snap := testSnapshot(clk, 5)
+			snap.MemBytes, snap.MemLimit = 3<<30, 0 // unlimited server
+			snap.DiskBytes, snap.DiskLimit = 20<<30, 0
+			rule := cpuAlert(tt.threshold, 0, 0)
+			rule.ConditionType = tt.condition
+
+			ae.Evaluate(context.Background(), testUser(), "key", snap, []models.AlertRule{rule})
+			got := rec.Drain()
+			if len(got) != 1 || got[0].Payload.Body != tt.wantBody {
+				t.Fatalf("pushes %+v, want body %q", got, tt.wantBody)
+			}
+			if _, triggered, _ := ae.measure(tt.condition, tt.threshold*4, snap); triggered {
+				t.Error("triggered below the threshold")
+			}
+		})
+	}
+}
+
+func TestBytesTriggers(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, _, _ := newTestExecutor(t, clk)
+	snap := testSnapshot(clk, 5)
+	snap.MemBytes, snap.MemLimit = 3<<30, 0
+	snap.DiskBytes, snap.DiskLimit = 20<<30, 0
+
+	for _, tt := range []struct {
+		trigger   models.TriggerType
+		threshold float64
+		want      bool
+	}{
+		{models.TriggerRAMBytes, 2 << 30, true},
+		{models.TriggerRAMBytes, 4 << 30, false},
+		{models.TriggerDiskBytes, 10 << 30, true},
+		{models.TriggerDiskBytes, 30 << 30, false},
+	} {
+		rule := models.AutomationRule{TriggerType: tt.trigger, TriggerConfig: map[string]interface{}{"threshold": tt.threshold}}
+		if got := ae.evaluateTrigger(rule, snap); got != tt.want {
+			t.Errorf("%s at %s = %t, want %t", tt.trigger, formatBytes(tt.threshold), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+// newCaptureEvaluator creates an evaluator whose console captures fail after delay.
+func newCaptureEvaluator(t *testing.T, clk clock.Clock, coalesce bool, delay time.Duration) (*AlertEvaluator, *push.RecordingProvider) {
+	t.Helper()
+	fp := newFakePanel(t)
+	fp.handler = func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(http.StatusNotFound)
+	}
+	rec := push.NewRecordingProvider(false)
+	ae := NewAlertEvaluator(openTestDB(t), fp.panels, NewNotifier(rec, nil, nil, nil, 0, nil, clk), coalesce, clk)
+	return ae, rec
+}
+
+// captureAlert is cpuAlert with a diagnostic console command.
+func captureAlert() models.AlertRule {
+	rule := cpuAlert(90, 0, 0)
+	rule.ID = "cpu-capture"
+	rule.CaptureCommand = "status"
+	return rule
+}
+
+func TestDrainWaitsForCaptures(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newCaptureEvaluator(t, clk, false, 200*time.Millisecond)
+
+	ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), []models.AlertRule{captureAlert()})
+	ae.Drain(context.Background())
+
+	if d := rec.Drain(); len(d) != 1 || d[0].Payload.UserUUID != "user-1" {
+		t.Fatalf("deliveries after Drain %+v, want the captured alert", d)
+	}
+}
+
+func TestCapturedAlertsAreCoalesced(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newCaptureEvaluator(t, clk, true, 50*time.Millisecond)
+
+	rules := []models.AlertRule{captureAlert(), cpuAlert(90, 0, 0)}
+	ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), rules)
+	if d := rec.Drain(); len(d) != 0 {
+		t.Fatalf("%d pushes before Flush, want the alerts buffered", len(d))
+	}
+	ae.Drain(context.Background())
+
+	d := rec.Drain()
+	if len(d) != 1 {
+		t.Fatalf("%d pushes, want one summary", len(d))
+	}
+	if strings.Count(d[0].Payload.Body, "srv-1:") != 2 {
+		t.Fatalf("summary body %q, want both alerts", d[0].Payload.Body)
+	}
+}
+
+func TestDrainSkipsNewCaptures(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newCaptureEvaluator(t, clk, false, 5*time.Second)
+	ae.Drain(context.Background())
+
+	// Sent at once instead of waiting on a console that would hold up shutdown
+	ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), []models.AlertRule{captureAlert()})
+	if d := rec.Drain(); len(d) != 1 {
+		t.Fatalf("%d pushes, want the alert sent without a capture", len(d))
+	}
+}
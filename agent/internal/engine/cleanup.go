@@ -7,6 +7,12 @@ import (
 	"github.com/xyidactyl/agent/internal/logging"
 )
 
+// maintenanceInterval is how often the database is vacuumed after cleanup.
+const maintenanceInterval = 7 * 24 * time.Hour
+
+// lastMaintenanceKey is the agent_state key holding the last maintenance time (RFC3339).
+const lastMaintenanceKey = "last_maintenance_at"
+
 // Cleanup runs the data retention cleanup job.
 type Cleanup struct {
 	db            *database.DB
@@ -61,4 +67,34 @@ func (c *Cleanup) run() {
 	} else {
 		logging.Debug("Cleanup: no records to delete")
 	}
+
+	c.maybeMaintain()
+}
+
+// maybeMaintain vacuums the database if the last maintenance was more than a week ago.
+func (c *Cleanup) maybeMaintain() {
+	last, err := c.db.GetState(lastMaintenanceKey)
+	if err != nil {
+		logging.Warn("Failed to read last maintenance time: %v", err)
+		return
+	}
+	if last != "" {
+		if t, err := time.Parse(time.RFC3339, last); err == nil && time.Since(t) < maintenanceInterval {
+			return
+		}
+	}
+
+	before := c.db.SizeBytes()
+	start := time.Now()
+	if err := c.db.Maintenance(); err != nil {
+		logging.Error("Database maintenance failed: %v", err)
+		return
+	}
+	after := c.db.SizeBytes()
+
+	logging.Info("🧹 Database maintenance: %d KB -> %d KB (%s)", before/1024, after/1024, time.Since(start).Round(time.Millisecond))
+
+	if err := c.db.SetState(lastMaintenanceKey, time.Now().Format(time.RFC3339)); err != nil {
+		logging.Warn("Failed to record maintenance time: %v", err)
+	}
 }
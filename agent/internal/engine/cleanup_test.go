@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCleanupMaintainsWeekly(t *testing.T) {
+	db := openTestDB(t)
+	c := NewCleanup(db, 7)
+
+	c.run()
+	first, err := db.GetState(lastMaintenanceKey)
+	if err != nil || first == "" {
+		t.Fatalf("last maintenance = %q, %v; want it recorded after the first run", first, err)
+	}
+
+	// A recent maintenance is not repeated
+	recent := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	if err := db.SetState(lastMaintenanceKey, recent); err != nil {
+		t.Fatal(err)
+	}
+	c.run()
+	if got, _ := db.GetState(lastMaintenanceKey); got != recent {
+		t.Fatalf("last maintenance = %q, want it left at %q", got, recent)
+	}
+
+	// One older than a week is
+	stale := time.Now().Add(-maintenanceInterval - time.Hour).Format(time.RFC3339)
+	if err := db.SetState(lastMaintenanceKey, stale); err != nil {
+		t.Fatal(err)
+	}
+	c.run()
+	if got, _ := db.GetState(lastMaintenanceKey); got == stale {
+		t.Fatal("maintenance older than a week was not repeated")
+	}
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+func TestAutomationCooldownFakeClock(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, _ := newTestExecutor(t, clk)
+	rule := models.AutomationRule{
+		ID:            "restart-hot",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		TriggerType:   models.TriggerCPU,
+		TriggerConfig: map[string]interface{}{"threshold": 90.0},
+		Action:        models.ActionRestart,
+		Cooldown:      300,
+		Enabled:       true,
+	}
+	run := func() int {
+		ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 99), []models.AutomationRule{rule})
+		return len(fp.Requests())
+	}
+
+	if n := run(); n != 1 {
+		t.Fatalf("first trigger: %d actions, want 1", n)
+	}
+	clk.Advance(299 * time.Second)
+	if n := run(); n != 1 {
+		t.Fatalf("within cooldown: %d actions, want 1", n)
+	}
+	clk.Advance(2 * time.Second)
+	if n := run(); n != 2 {
+		t.Fatalf("after cooldown: %d actions, want 2", n)
+	}
+}
+
+func TestAlertCooldownFakeClock(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	rules := []models.AlertRule{cpuAlert(90, 0, 600)}
+	pushes := 0
+	run := func() int {
+		ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), rules)
+		pushes += len(rec.Drain())
+		return pushes
+	}
+
+	if n := run(); n != 1 {
+		t.Fatalf("first trigger: %d pushes, want 1", n)
+	}
+	clk.Advance(5 * time.Minute)
+	if n := run(); n != 1 {
+		t.Fatalf("within cooldown: %d pushes, want 1", n)
+	}
+	clk.Advance(5*time.Minute + time.Second)
+	if n := run(); n != 2 {
+		t.Fatalf("after cooldown: %d pushes, want 2", n)
+	}
+}
+
+func TestAlertDurationHoldFakeClock(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	rules := []models.AlertRule{cpuAlert(90, 120, 600)}
+
+	for i := 0; i < 2; i++ {
+		ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), rules)
+		if got := rec.Drain(); len(got) != 0 {
+			t.Fatalf("fired after %s, before the 120s hold", time.Duration(i)*time.Minute)
+		}
+		clk.Advance(time.Minute)
+	}
+	clk.Advance(time.Second) // 121s past the first breach
+	ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), rules)
+	if got := rec.Drain(); len(got) != 1 {
+		t.Fatalf("%d pushes after the hold, want 1", len(got))
+	}
+}
+
+func TestSnoozeFollowsClock(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	rec := push.NewRecordingProvider(false)
+	n := NewNotifier(rec, nil, nil, nil, 0, nil, clk)
+	user := testUser()
+	user.SnoozeUntil = testStart.Add(time.Hour).Unix()
+
+	n.send(context.Background(), user, push.Payload{Title: "t", EventType: "alert"})
+	if got := rec.Drain(); len(got) != 0 {
+		t.Fatal("push delivered while snoozed")
+	}
+	clk.Advance(time.Hour)
+	n.send(context.Background(), user, push.Payload{Title: "t", EventType: "alert"})
+	if got := rec.Drain(); len(got) != 1 {
+		t.Fatalf("%d pushes after the snooze ended, want 1", len(got))
+	}
+}
+
+func TestCoalescedTimestampFollowsClock(t *testing.T) {
+	now := testStart.Add(42 * time.Minute)
+	p := coalescePayloads([]push.Payload{{Title: "a"}, {Title: "b"}}, now)
+	if p.Timestamp != now.Format(time.RFC3339) {
+		t.Fatalf("timestamp %s, want %s", p.Timestamp, now.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+func TestCoalesceSimultaneousAlerts(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	fp := newFakePanel(t)
+	rec := push.NewRecordingProvider(false)
+	db := openTestDB(t)
+	ae := NewAlertEvaluator(db, fp.panels, NewNotifier(rec, nil, nil, nil, 0, nil, clk), true, clk)
+
+	rule1 := cpuAlert(80, 0, 0)
+	rule2 := cpuAlert(80, 0, 0)
+	rule2.ID = "cpu-high-2"
+	rule2.ServerID = "srv-2"
+	snap2 := testSnapshot(clk, 95)
+	snap2.ServerID = "srv-2"
+
+	ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), []models.AlertRule{rule1})
+	ae.Evaluate(context.Background(), testUser(), "key", snap2, []models.AlertRule{rule2})
+	if got := rec.Drain(); len(got) != 0 {
+		t.Fatalf("pushed %d before Flush, want none", len(got))
+	}
+
+	ae.Flush(context.Background())
+	got := rec.Drain()
+	if len(got) != 1 {
+		t.Fatalf("%d pushes after Flush, want one summary", len(got))
+	}
+	p := got[0].Payload
+	if p.Title != "⚠️ 2 servers alerting" || !strings.Contains(p.Body, "srv-1: ") || !strings.Contains(p.Body, "srv-2: ") {
+		t.Fatalf("summary %q / %q", p.Title, p.Body)
+	}
+	if p.ServerID != "" {
+		t.Errorf("summary server_id = %q, want empty for several servers", p.ServerID)
+	}
+
+	history, err := db.GetAlertHistory("user-1", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("%d alert_history rows, want one per alert", len(history))
+	}
+
+	// Nothing is left buffered for the next pass
+	ae.Flush(context.Background())
+	if got := rec.Drain(); len(got) != 0 {
+		t.Fatalf("second Flush pushed %+v", got)
+	}
+}
+
+func TestCoalescePayloads(t *testing.T) {
+	now := testStart.Add(time.Minute)
+	single := push.Payload{Title: "CPU", Body: "high", ServerID: "srv-1", Severity: "warning"}
+	if got := coalescePayloads([]push.Payload{single}, now); got != single {
+		t.Errorf("single payload changed: %+v", got)
+	}
+
+	got := coalescePayloads([]push.Payload{
+		{Title: "CPU", Body: "high", ServerID: "srv-1", Severity: "warning"},
+		{Title: "Disk", Body: "full", ServerID: "srv-1", Severity: "critical"},
+	}, now)
+	if got.Title != "[CRITICAL] ⚠️ 2 alerts" {
+		t.Errorf("title %q, want the critical two-alert summary", got.Title)
+	}
+	if got.ServerID != "srv-1" || got.Severity != "critical" {
+		t.Errorf("server %q severity %q, want srv-1 and critical", got.ServerID, got.Severity)
+	}
+}
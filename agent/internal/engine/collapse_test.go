@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestAlertCollapseIDOptIn(t *testing.T) {
+	for _, collapse := range []bool{false, true} {
+		clk := clock.NewFake(testStart)
+		ae, rec := newTestEvaluator(t, clk)
+		rule := cpuAlert(80, 0, 0)
+		rule.Collapse = collapse
+
+		ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), []models.AlertRule{rule})
+		pushes := rec.Drain()
+		if len(pushes) != 1 {
+			t.Fatalf("collapse %t: %d pushes, want 1", collapse, len(pushes))
+		}
+		want := ""
+		if collapse {
+			want = "alert:" + stateKey("cpu-high", "srv-1")
+		}
+		if got := pushes[0].Payload.CollapseID; got != want {
+			t.Fatalf("collapse %t: collapse ID %q, want %q", collapse, got, want)
+		}
+	}
+}
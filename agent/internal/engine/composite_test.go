@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// compositeAlert is CPU > 90% <op> memory > 80% on srv-1.
+func compositeAlert(op string, duration int) models.AlertRule {
+	rule := cpuAlert(0, duration, 0)
+	rule.ID = "cpu-and-ram"
+	rule.ConditionType = models.ConditionComposite
+	rule.Operator = op
+	rule.Conditions = []models.SubCondition{
+		{ConditionType: models.ConditionCPU, Threshold: 90},
+		{ConditionType: models.ConditionRAM, Threshold: 80},
+	}
+	return rule
+}
+
+// compositeSnapshot is srv-1 at the given CPU and memory percentages.
+func compositeSnapshot(clk clock.Clock, cpu, memPercent float64) *models.ResourceSnapshot {
+	s := testSnapshot(clk, cpu)
+	s.MemLimit = 1000
+	s.MemBytes = int64(memPercent * 10)
+	return s
+}
+
+func TestCompositeSemantics(t *testing.T) {
+	for _, tt := range []struct {
+		op        string
+		cpu, mem  float64
+		wantFires bool
+	}{
+		{"and", 95, 85, true},
+		{"and", 95, 50, false},
+		{"and", 50, 85, false},
+		{"AND", 95, 85, true},
+		{"or", 95, 50, true},
+		{"or", 50, 85, true},
+		{"or", 50, 50, false},
+	} {
+		clk := clock.NewFake(testStart)
+		ae, rec := newTestEvaluator(t, clk)
+		ae.Evaluate(context.Background(), testUser(), "key", compositeSnapshot(clk, tt.cpu, tt.mem), []models.AlertRule{compositeAlert(tt.op, 0)})
+		if fired := len(rec.Drain()) == 1; fired != tt.wantFires {
+			t.Errorf("%s with CPU %.0f%% and memory %.0f%%: fired=%t, want %t", tt.op, tt.cpu, tt.mem, fired, tt.wantFires)
+		}
+	}
+}
+
+func TestCompositeBodyListsMetConditions(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	ae.Evaluate(context.Background(), testUser(), "key", compositeSnapshot(clk, 95, 50), []models.AlertRule{compositeAlert("or", 0)})
+
+	got := rec.Drain()
+	if len(got) != 1 || got[0].Payload.Body != "Conditions met: CPU 95% > 90%" {
+		t.Fatalf("pushes %+v, want only the CPU condition listed", got)
+	}
+}
+
+func TestCompositeHonorsDuration(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	rules := []models.AlertRule{compositeAlert("and", 300)}
+	eval := func(cpu, mem float64) int {
+		ae.Evaluate(context.Background(), testUser(), "key", compositeSnapshot(clk, cpu, mem), rules)
+		return len(rec.Drain())
+	}
+
+	eval(95, 85)
+	clk.Advance(4 * time.Minute)
+	eval(95, 50) // one condition dropped: the hold restarts
+	clk.Advance(2 * time.Minute)
+	if n := eval(95, 85); n != 0 {
+		t.Fatal("fired although both conditions held for only 2 minutes")
+	}
+	clk.Advance(5*time.Minute + time.Second)
+	if n := eval(95, 85); n != 1 {
+		t.Fatalf("%d pushes after 5 minutes, want 1", n)
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+)
+
+func TestSampleRunsServersConcurrently(t *testing.T) {
+	const servers, latency = 8, 100 * time.Millisecond
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	cf := tm.source.Get()
+	cf.Users[0].AllowedServers = nil
+	for i := 1; i <= servers; i++ {
+		cf.Users[0].AllowedServers = append(cf.Users[0].AllowedServers, fmt.Sprintf("srv-%d", i))
+	}
+	tm.source.Set(cf)
+
+	var inFlight, peak atomic.Int32
+	tm.panel.serveResources(func(string) (int, string) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(latency)
+		return http.StatusOK, resourcesBody("running", 5, false)
+	})
+
+	start := time.Now()
+	tm.sample()
+	elapsed := time.Since(start)
+
+	// concurrency is 4: two rounds of requests instead of eight
+	if serial := servers * latency; elapsed > serial/2 {
+		t.Errorf("cycle took %s, serial sampling would take %s", elapsed, serial)
+	}
+	if p := peak.Load(); p > 4 {
+		t.Errorf("%d requests in flight, want at most the concurrency of 4", p)
+	}
+	for i := 1; i <= servers; i++ {
+		if tm.LatestSnapshot(fmt.Sprintf("srv-%d", i)) == nil {
+			t.Errorf("srv-%d not sampled", i)
+		}
+	}
+}
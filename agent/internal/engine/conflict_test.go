@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+)
+
+func TestMonitorRecordsInstallingServer(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	tm := newTestMonitor(t, clk, nil, nil)
+	tm.panel.serveResources(func(id string) (int, string) {
+		if id == "srv-1" {
+			return http.StatusConflict, `{"errors":[{"code":"ConflictingServerStateException","status":"409","detail":"This server has not yet completed its installation process, please try again later."}]}`
+		}
+		return http.StatusOK, resourcesBody("running", 5, false)
+	})
+
+	for i := 0; i < 3; i++ {
+		tm.sample()
+		clk.Advance(30 * time.Second)
+	}
+
+	snaps, err := tm.db.GetRecentSnapshots("srv-1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 3 {
+		t.Fatalf("stored %d srv-1 snapshots, want one per cycle", len(snaps))
+	}
+	for _, s := range snaps {
+		if s.PowerState != "installing" || s.CPUPercent != 0 {
+			t.Fatalf("snapshot = %+v, want a zero-usage installing snapshot", s)
+		}
+	}
+
+	// A 409 is the server's state, not a failure: no backoff, polled every cycle
+	if n := tm.panel.resourceCalls("srv-1"); n != 3 {
+		t.Fatalf("srv-1 polled %d times, want 3", n)
+	}
+	tm.backoffMu.Lock()
+	_, backingOff := tm.backoff["srv-1"]
+	tm.backoffMu.Unlock()
+	if backingOff {
+		t.Fatal("srv-1 is in error backoff after a 409")
+	}
+}
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/pterodactyl"
+)
+
+// ConsoleBuffer keeps the most recent console lines of servers that have crash
+// automations, so a crash report can explain what the server printed before dying.
+type ConsoleBuffer struct {
+	maxLines int
+
+	mu      sync.Mutex
+	streams map[string]*consoleStream // server_id -> live stream
+}
+
+// consoleTarget is how to reach a server's console: its panel and a user key.
+type consoleTarget struct {
+	client *pterodactyl.Client
+	apiKey string
+}
+
+type consoleStream struct {
+	target consoleTarget
+	cancel context.CancelFunc
+	lines  []string
+}
+
+// NewConsoleBuffer creates a console buffer keeping maxLines per server (0 disables it).
+func NewConsoleBuffer(maxLines int) *ConsoleBuffer {
+	return &ConsoleBuffer{
+		maxLines: maxLines,
+		streams:  make(map[string]*consoleStream),
+	}
+}
+
+// Sync starts streams for the wanted servers and stops all others.
+func (cb *ConsoleBuffer) Sync(wanted map[string]consoleTarget) {
+	if cb.maxLines == 0 {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	for serverID, s := range cb.streams {
+		if t, ok := wanted[serverID]; !ok || t != s.target {
+			s.cancel()
+			delete(cb.streams, serverID)
+		}
+	}
+
+	for serverID, target := range wanted {
+		if _, ok := cb.streams[serverID]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		s := &consoleStream{target: target, cancel: cancel}
+		cb.streams[serverID] = s
+		go cb.run(ctx, serverID, s)
+	}
+}
+
+func (cb *ConsoleBuffer) run(ctx context.Context, serverID string, s *consoleStream) {
+	lines, err := s.target.client.StreamConsole(ctx, s.target.apiKey, serverID)
+	if err != nil {
+		logging.Warn("Console stream for server %s failed: %v", serverID, err)
+		// Forget the stream so the next Sync retries it
+		cb.mu.Lock()
+		if cb.streams[serverID] == s {
+			delete(cb.streams, serverID)
+		}
+		cb.mu.Unlock()
+		s.cancel()
+		return
+	}
+
+	logging.Debug("Console stream for server %s connected", serverID)
+	for line := range lines {
+		cb.mu.Lock()
+		s.lines = append(s.lines, line)
+		if len(s.lines) > cb.maxLines {
+			s.lines = s.lines[len(s.lines)-cb.maxLines:]
+		}
+		cb.mu.Unlock()
+	}
+}
+
+// Recent returns a copy of the buffered console lines for a server.
+func (cb *ConsoleBuffer) Recent(serverID string) []string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	s, ok := cb.streams[serverID]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), s.lines...)
+}
+
+// Stop closes all console streams.
+func (cb *ConsoleBuffer) Stop() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	for serverID, s := range cb.streams {
+		s.cancel()
+		delete(cb.streams, serverID)
+	}
+}
+
+// crashPatterns map console substrings (lowercased) to a likely crash reason, most specific first.
+var crashPatterns = []struct {
+	match  string
+	reason string
+}{
+	{"outofmemoryerror", "out of memory"},
+	{"out of memory", "out of memory"},
+	{"killed", "killed by the OS (likely out of memory)"},
+	{"segmentation fault", "segmentation fault"},
+	{"sigsegv", "segmentation fault"},
+	{"address already in use", "port already in use"},
+	{"failed to bind", "port already in use"},
+	{"no space left on device", "disk full"},
+	{"eula", "EULA not accepted"},
+	{"exception", "unhandled exception"},
+}
+
+// crashReason guesses why a server crashed from its last console lines, or "" if unclear.
+// Lines are scanned newest first so the final error wins over earlier noise.
+func crashReason(lines []string) string {
+	for _, p := range crashPatterns {
+		for i := len(lines) - 1; i >= 0; i-- {
+			if strings.Contains(strings.ToLower(lines[i]), p.match) {
+				return p.reason
+			}
+		}
+	}
+	return ""
+}
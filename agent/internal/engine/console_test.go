@@ -0,0 +1,31 @@
+package engine
+
+import "testing"
+
+func TestCrashReason(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"empty", nil, ""},
+		{"no match", []string{"Saving chunks", "Stopping server"}, ""},
+		{"oom", []string{"java.lang.OutOfMemoryError: Java heap space"}, "out of memory"},
+		{"port", []string{"**** FAILED TO BIND TO PORT!"}, "port already in use"},
+		{"disk", []string{"write failed: No space left on device"}, "disk full"},
+		{"specific beats generic", []string{"Exception in thread main", "java.lang.OutOfMemoryError"}, "out of memory"},
+	}
+	for _, tc := range tests {
+		if got := crashReason(tc.lines); got != tc.want {
+			t.Errorf("%s: crashReason = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestConsoleBufferDisabled(t *testing.T) {
+	cb := NewConsoleBuffer(0)
+	cb.Sync(map[string]consoleTarget{"srv-1": {}})
+	if got := cb.Recent("srv-1"); got != nil {
+		t.Fatalf("disabled buffer returned %q", got)
+	}
+}
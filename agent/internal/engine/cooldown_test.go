@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+// powerCalls counts the power requests made for a server.
+func powerCalls(fp *fakePanel, serverID string) int {
+	n := 0
+	for _, r := range fp.Requests() {
+		if r == "POST /api/client/servers/"+serverID+"/power" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestActionCooldownAcrossRules(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	fp := newFakePanel(t)
+	rec := push.NewRecordingProvider(false)
+	ae := NewAutomationExecutor(openTestDB(t), fp.panels, NewNotifier(rec, nil, nil, nil, 0, nil, clk), NewConsoleBuffer(50), 4, 5*time.Minute, true, false, clk)
+
+	rules := []models.AutomationRule{
+		cpuRule("restart-a", models.ActionRestart, nil),
+		cpuRule("restart-b", models.ActionRestart, nil),
+	}
+	ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 99), rules)
+	if n := powerCalls(fp, "srv-1"); n != 1 {
+		t.Fatalf("%d restarts from two rules on one server, want 1", n)
+	}
+
+	// Another server is not held back
+	other := testSnapshot(clk, 99)
+	other.ServerID = "srv-2"
+	rule := cpuRule("restart-c", models.ActionRestart, nil)
+	rule.ServerID = "srv-2"
+	ae.Evaluate(context.Background(), testUser(), "key", other, []models.AutomationRule{rule})
+	if n := powerCalls(fp, "srv-2"); n != 1 {
+		t.Fatalf("%d restarts of srv-2, want 1", n)
+	}
+
+	clk.Advance(5 * time.Minute)
+	ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 99), rules[1:])
+	if n := powerCalls(fp, "srv-1"); n != 2 {
+		t.Fatalf("%d restarts after the cooldown, want 2", n)
+	}
+}
+
+func TestActionCooldownOff(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, _ := newTestExecutor(t, clk) // no action cooldown
+
+	ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 99), []models.AutomationRule{
+		cpuRule("restart-a", models.ActionRestart, nil),
+		cpuRule("restart-b", models.ActionRestart, nil),
+	})
+	if n := powerCalls(fp, "srv-1"); n != 2 {
+		t.Fatalf("%d restarts without an action cooldown, want one per rule", n)
+	}
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestNormalizeCPU(t *testing.T) {
+	for _, tc := range []struct{ absolute, cores, want float64 }{
+		{380, 4, 95},
+		{100, 1, 100},
+		{50, 0.5, 100},
+		{380, 0, 380}, // unlimited keeps the absolute value
+	} {
+		if got := normalizeCPU(tc.absolute, tc.cores); got != tc.want {
+			t.Errorf("normalizeCPU(%g, %g) = %g, want %g", tc.absolute, tc.cores, got, tc.want)
+		}
+	}
+}
+
+func TestMonitorNormalizesFourCoreServer(t *testing.T) {
+	normalized := func(id string, threshold float64) models.AlertRule {
+		r := cpuAlert(threshold, 0, 0)
+		r.ID, r.ConditionType = id, models.ConditionCPUNormalized
+		return r
+	}
+	tm := newTestMonitor(t, clock.NewFake(testStart), []models.AlertRule{
+		normalized("normalized-90", 90),
+		normalized("normalized-96", 96),
+	}, nil)
+	tm.panel.mu.Lock()
+	tm.panel.handler = func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/client":
+			fmt.Fprint(w, `{"data":[{"attributes":{"identifier":"srv-1","name":"big","limits":{"memory":4096,"disk":10240,"cpu":400}}},{"attributes":{"identifier":"srv-2","name":"open","limits":{"memory":0,"disk":0,"cpu":0}}}],"meta":{"pagination":{"total":2,"current_page":1,"total_pages":1}}}`)
+		case "/api/client/servers/srv-1/resources":
+			fmt.Fprint(w, resourcesBody("running", 380, false))
+		default:
+			fmt.Fprint(w, resourcesBody("running", 150, false))
+		}
+	}
+	tm.panel.mu.Unlock()
+
+	// The first pass starts the access check that learns the core limits
+	tm.sample()
+	waitChecked(t, tm.access, "user-1")
+	tm.push.Drain()
+	tm.sample()
+
+	for id, want := range map[string][2]float64{"srv-1": {380, 95}, "srv-2": {150, 150}} {
+		snaps, err := tm.db.GetRecentSnapshots(id, 1)
+		if err != nil || len(snaps) != 1 {
+			t.Fatalf("%s: %d snapshots (%v)", id, len(snaps), err)
+		}
+		if s := snaps[0]; s.CPUPercent != want[0] || s.CPUNormalized != want[1] {
+			t.Fatalf("%s: cpu %g, normalized %g; want %g and %g", id, s.CPUPercent, s.CPUNormalized, want[0], want[1])
+		}
+	}
+
+	// 95% of four cores crosses 90 but not 96
+	if d := tm.push.Drain(); len(d) != 1 {
+		t.Fatalf("%d pushes, want only the 90%% normalized alert", len(d))
+	}
+	if info, _ := tm.db.GetServerInfo([]string{"srv-1"}); info["srv-1"].CPUCores != 4 {
+		t.Fatalf("stored cpu_cores = %g, want 4", info["srv-1"].CPUCores)
+	}
+}
+
+func TestStoredCoresApplyBeforePanelAnswers(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.UpsertServerInfo("srv-1", "big", 4<<30, 10<<30, 4); err != nil {
+		t.Fatal(err)
+	}
+	fp := newFakePanel(t)
+	fp.handler = func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusForbidden) }
+	r := newAccessReconciler(db, clock.NewFake(testStart))
+
+	r.maybeCheck(context.Background(), fp.panels.Default(), testUser(), "key")
+	if got := r.cpuCores("srv-1"); got != 4 {
+		t.Fatalf("cpuCores = %g, want the stored 4", got)
+	}
+	if got := r.cpuCores("srv-2"); got != 0 {
+		t.Fatalf("cpuCores for an unknown server = %g, want 0", got)
+	}
+}
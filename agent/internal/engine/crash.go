@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// defaultCrashStopGrace is how long after the agent itself stopped, killed or
+// restarted a server that going offline is not treated as a crash.
+const defaultCrashStopGrace = 2 * time.Minute
+
+// isCrash reports whether a server is down after a crash: it went straight from
+// running to offline, without passing through "stopping" and without a recent
+// power action from the agent, and has stayed offline since. trigger_config may set
+// "stop_grace" (seconds) and "require_running": false to treat any offline sample
+// as a crash, the old behavior.
+func (ae *AutomationExecutor) isCrash(rule models.AutomationRule, snapshot *models.ResourceSnapshot) bool {
+	if snapshot.PowerState != "offline" {
+		return false
+	}
+	if requireRunning, ok := rule.TriggerConfig["require_running"].(bool); ok && !requireRunning {
+		return true
+	}
+	if ae.crashed[snapshot.ServerID] {
+		return true // Still down, so retries and escalation steps keep firing
+	}
+
+	prev := ae.previousSnaps[snapshot.ServerID]
+	if prev == nil || prev.PowerState != "running" {
+		return false // First sample, already down, or a graceful stop seen mid-way
+	}
+
+	grace := defaultCrashStopGrace
+	if v, ok := getFloat(rule.TriggerConfig, "stop_grace"); ok && v >= 0 {
+		grace = time.Duration(v) * time.Second
+	}
+	for _, action := range []string{"stop", "kill", "restart"} {
+		if last, ok := ae.lastActionAt[snapshot.ServerID+"|"+action]; ok && ae.clock.Now().Sub(last) < grace {
+			return false
+		}
+	}
+	ae.crashed[snapshot.ServerID] = true
+	return true
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// crashRule restarts srv-1 when it crashes.
+func crashRule(config map[string]interface{}) models.AutomationRule {
+	return models.AutomationRule{
+		ID:            "restart-crash",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		TriggerType:   models.TriggerCrash,
+		TriggerConfig: config,
+		Action:        models.ActionRestart,
+		Enabled:       true,
+	}
+}
+
+// feedStates evaluates rules against srv-1 in each power state, a minute apart.
+func feedStates(ae *AutomationExecutor, clk *clock.Fake, rules []models.AutomationRule, states ...string) {
+	for _, state := range states {
+		snap := testSnapshot(clk, 10)
+		snap.PowerState = state
+		ae.Evaluate(context.Background(), testUser(), "key", snap, rules)
+		clk.Advance(time.Minute)
+	}
+}
+
+// restarts counts the restart signals sent to the fake panel.
+func restarts(fp *fakePanel) int {
+	n := 0
+	for _, b := range fp.Bodies() {
+		if b == `{"signal":"restart"}` {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCrashVersusGracefulStop(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		states []string
+		want   int
+	}{
+		{"crash", []string{"running", "offline"}, 1},
+		{"graceful stop", []string{"running", "stopping", "offline"}, 0},
+		{"offline from the start", []string{"offline", "offline"}, 0},
+		{"starting then offline", []string{"starting", "offline"}, 0},
+		{"crash, recovery, crash", []string{"running", "offline", "running", "offline"}, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(testStart)
+			ae, fp, _ := newTestExecutor(t, clk)
+			feedStates(ae, clk, []models.AutomationRule{crashRule(nil)}, tc.states...)
+			if got := restarts(fp); got != tc.want {
+				t.Fatalf("%d restarts for %v, want %d", got, tc.states, tc.want)
+			}
+		})
+	}
+}
+
+func TestCrashStaysTriggeredWhileDown(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, _ := newTestExecutor(t, clk)
+
+	// Without a cooldown the restart is retried every sample while the server stays down
+	feedStates(ae, clk, []models.AutomationRule{crashRule(nil)}, "running", "offline", "offline", "offline")
+	if got := restarts(fp); got != 3 {
+		t.Fatalf("%d restarts, want one per offline sample after the crash", got)
+	}
+}
+
+func TestAgentStopIsNotACrash(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, _ := newTestExecutor(t, clk)
+	stopHot := cpuRule("stop-hot", models.ActionStop, nil)
+	crash := crashRule(map[string]interface{}{"stop_grace": 300.0})
+
+	// The agent stops the server, which goes straight to offline: not a crash
+	ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 99), []models.AutomationRule{stopHot, crash})
+	if got := fp.Bodies(); len(got) != 1 || got[0] != `{"signal":"stop"}` {
+		t.Fatalf("bodies %v, want the agent's stop", got)
+	}
+	clk.Advance(time.Minute)
+	feedStates(ae, clk, []models.AutomationRule{crash}, "offline")
+	if got := restarts(fp); got != 0 {
+		t.Fatalf("%d restarts after the agent's own stop, want 0", got)
+	}
+
+	// Once the grace period is over, the same transition is a crash again
+	clk.Advance(10 * time.Minute)
+	feedStates(ae, clk, []models.AutomationRule{crash}, "running", "offline")
+	if got := restarts(fp); got != 1 {
+		t.Fatalf("%d restarts after the grace period, want 1", got)
+	}
+}
+
+func TestCrashRequireRunningOff(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, _ := newTestExecutor(t, clk)
+	rule := crashRule(map[string]interface{}{"require_running": false})
+
+	feedStates(ae, clk, []models.AutomationRule{rule}, "stopping", "offline")
+	if got := restarts(fp); got != 1 {
+		t.Fatalf("%d restarts, want any offline sample treated as a crash", got)
+	}
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"math"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// DeltaStore skips storing snapshots that barely differ from the last stored one.
+// Charts step-interpolate across the gaps; Heartbeat bounds how long a gap can get.
+type DeltaStore struct {
+	Heartbeat  time.Duration // always store at least this often, 0 = store every snapshot
+	CPUEpsilon float64       // CPU percent points treated as unchanged
+	MemEpsilon int64         // memory/disk bytes treated as unchanged
+}
+
+// changed reports whether snap differs meaningfully from prev.
+func (d DeltaStore) changed(prev, snap *models.ResourceSnapshot) bool {
+	return snap.PowerState != prev.PowerState ||
+		snap.IsSuspended != prev.IsSuspended ||
+		math.Abs(snap.CPUPercent-prev.CPUPercent) > d.CPUEpsilon ||
+		absInt64(snap.MemBytes-prev.MemBytes) > d.MemEpsilon ||
+		absInt64(snap.DiskBytes-prev.DiskBytes) > d.MemEpsilon ||
+		snap.MemLimit != prev.MemLimit ||
+		snap.DiskLimit != prev.DiskLimit
+}
+
+// deltaFilter returns the snapshots of a cycle worth storing, remembering
+// them as the new baseline. Only called from the loop goroutine.
+func (m *Monitor) deltaFilter(batch []models.ResourceSnapshot) []models.ResourceSnapshot {
+	if m.delta.Heartbeat <= 0 {
+		return batch
+	}
+
+	keep := batch[:0:0]
+	for i := range batch {
+		snap := &batch[i]
+		prev, ok := m.lastStored[snap.ServerID]
+		if ok && !m.delta.changed(prev, snap) && snap.Timestamp.Sub(prev.Timestamp) < m.delta.Heartbeat {
+			continue
+		}
+		stored := *snap
+		m.lastStored[snap.ServerID] = &stored
+		keep = append(keep, *snap)
+	}
+	return keep
+}
+
+func absInt64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestDeltaStoreChanged(t *testing.T) {
+	d := DeltaStore{Heartbeat: time.Minute, CPUEpsilon: 1, MemEpsilon: 16 << 20}
+	base := models.ResourceSnapshot{CPUPercent: 10, MemBytes: 512 << 20, MemLimit: 1 << 30, DiskBytes: 1 << 30, PowerState: "running"}
+
+	for _, tc := range []struct {
+		name   string
+		mutate func(s *models.ResourceSnapshot)
+		want   bool
+	}{
+		{"identical", func(s *models.ResourceSnapshot) {}, false},
+		{"cpu within epsilon", func(s *models.ResourceSnapshot) { s.CPUPercent = 10.9 }, false},
+		{"cpu moved", func(s *models.ResourceSnapshot) { s.CPUPercent = 11.5 }, true},
+		{"memory within epsilon", func(s *models.ResourceSnapshot) { s.MemBytes += 8 << 20 }, false},
+		{"memory moved", func(s *models.ResourceSnapshot) { s.MemBytes -= 32 << 20 }, true},
+		{"disk moved", func(s *models.ResourceSnapshot) { s.DiskBytes += 32 << 20 }, true},
+		{"state", func(s *models.ResourceSnapshot) { s.PowerState = "offline" }, true},
+		{"suspended", func(s *models.ResourceSnapshot) { s.IsSuspended = true }, true},
+		{"limit", func(s *models.ResourceSnapshot) { s.MemLimit = 2 << 30 }, true},
+	} {
+		snap := base
+		tc.mutate(&snap)
+		if got := d.changed(&base, &snap); got != tc.want {
+			t.Errorf("%s: changed = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDeltaStoreCollapsesIdenticalSamples(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	tm := newTestMonitor(t, clk, nil, nil)
+	tm.delta = DeltaStore{Heartbeat: 2 * time.Minute, CPUEpsilon: 1, MemEpsilon: 16 << 20}
+	cpu := 12.5
+	tm.panel.serveResources(func(string) (int, string) {
+		return http.StatusOK, resourcesBody("offline", cpu, false)
+	})
+
+	// Ten identical samples 30s apart: stored at 0, 2m and 4m as heartbeats
+	for i := 0; i < 10; i++ {
+		tm.sample()
+		clk.Advance(30 * time.Second)
+	}
+	snaps, err := tm.db.GetRecentSnapshots("srv-1", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 3 {
+		t.Fatalf("stored %d identical snapshots, want 3 heartbeats", len(snaps))
+	}
+	for i := 1; i < len(snaps); i++ {
+		if gap := snaps[i-1].Timestamp.Sub(snaps[i].Timestamp).Abs(); gap != 2*time.Minute {
+			t.Fatalf("heartbeat gap %s, want 2m", gap)
+		}
+	}
+
+	// A real change is stored straight away
+	cpu = 80
+	tm.sample()
+	if snaps, _ = tm.db.GetRecentSnapshots("srv-1", 100); len(snaps) != 4 {
+		t.Fatalf("stored %d snapshots after a change, want 4", len(snaps))
+	}
+}
+
+func TestDeltaStoreOffByDefault(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	tm := newTestMonitor(t, clk, nil, nil)
+
+	for i := 0; i < 5; i++ {
+		tm.sample()
+		clk.Advance(30 * time.Second)
+	}
+	if snaps, _ := tm.db.GetRecentSnapshots("srv-1", 100); len(snaps) != 5 {
+		t.Fatalf("stored %d snapshots, want every sample", len(snaps))
+	}
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// diskSeries returns n hourly srv-1 snapshots ending at end, with disk usage from
+// startBytes changing by stepBytes per hour against a 10 GiB limit.
+func diskSeries(end time.Time, n int, startBytes, stepBytes int64) []models.ResourceSnapshot {
+	snaps := make([]models.ResourceSnapshot, n)
+	for i := range snaps {
+		snaps[i] = models.ResourceSnapshot{
+			ServerID:   "srv-1",
+			Timestamp:  end.Add(-time.Duration(n-1-i) * time.Hour),
+			PowerState: "running",
+			DiskBytes:  startBytes + int64(i)*stepBytes,
+			DiskLimit:  10 << 30,
+		}
+	}
+	return snaps
+}
+
+func TestHoursToFull(t *testing.T) {
+	tests := []struct {
+		name  string
+		snaps []models.ResourceSnapshot
+		hours float64
+		ok    bool
+	}{
+		{"rising", diskSeries(testStart, 6, 3<<30, 1<<30), 2, true}, // 8 GiB used, 1 GiB/h
+		{"already full", diskSeries(testStart, 6, 5<<30, 1<<30), 0, true},
+		{"flat", diskSeries(testStart, 6, 5<<30, 0), 0, false},
+		{"shrinking", diskSeries(testStart, 6, 8<<30, -(1 << 28)), 0, false},
+		{"too few points", diskSeries(testStart, diskTrendMinSamples-1, 3<<30, 1<<30), 0, false},
+		{"unlimited disk", func() []models.ResourceSnapshot {
+			snaps := diskSeries(testStart, 6, 3<<30, 1<<30)
+			for i := range snaps {
+				snaps[i].DiskLimit = 0
+			}
+			return snaps
+		}(), 0, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hours, ok := hoursToFull(tc.snaps)
+			if ok != tc.ok || math.Abs(hours-tc.hours) > 1e-6 {
+				t.Fatalf("hoursToFull = %v, %v, want %v, %v", hours, ok, tc.hours, tc.ok)
+			}
+		})
+	}
+}
+
+func TestDiskTrendAlert(t *testing.T) {
+	rule := models.AlertRule{
+		ID:            "disk-trend",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		ConditionType: models.ConditionDiskTrend,
+		Threshold:     6, // hours
+		Enabled:       true,
+	}
+	for _, tc := range []struct {
+		name      string
+		stepBytes int64
+		fires     bool
+	}{
+		{"rising fast", 1 << 30, true},    // full in 2h
+		{"rising slowly", 1 << 26, false}, // 64 MiB/h, days left
+		{"flat", 0, false},
+		{"shrinking", -(1 << 26), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(testStart)
+			ae, rec := newTestEvaluator(t, clk)
+			series := diskSeries(clk.Now(), 6, 8<<30-5*tc.stepBytes, tc.stepBytes)
+			if err := ae.db.InsertSnapshots(series[:5]); err != nil {
+				t.Fatal(err)
+			}
+
+			// The current snapshot is evaluated before it is stored
+			current := series[5]
+			ae.Evaluate(context.Background(), testUser(), "key", &current, []models.AlertRule{rule})
+
+			pushes := rec.Drain()
+			if fired := len(pushes) > 0; fired != tc.fires {
+				t.Fatalf("fired = %v, want %v", fired, tc.fires)
+			}
+			if tc.fires && !strings.Contains(pushes[0].Payload.Body, "full in 2.0 hours") {
+				t.Fatalf("body = %q, want the projected 2 hours", pushes[0].Payload.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// drainGrace is how long Drain waits for abandoned actions to record their failure.
+const drainGrace = 2 * time.Second
+
+// beginAction registers an in-flight action. The returned context is independent of
+// the sampling loop so a shutdown does not cut the panel request short; it is only
+// cancelled when Drain gives up. ok is false once draining has started.
+func (ae *AutomationExecutor) beginAction(rule models.AutomationRule, step int) (ctx context.Context, done func(), ok bool) {
+	ae.inflightMu.Lock()
+	defer ae.inflightMu.Unlock()
+
+	if ae.draining {
+		return nil, nil, false
+	}
+
+	ae.inflightSeq++
+	id := ae.inflightSeq
+	desc := fmt.Sprintf("%s on %s (rule %s)", rule.Action, rule.ServerID, rule.ID)
+	if step > 0 {
+		desc = fmt.Sprintf("%s on %s (rule %s, step %d)", rule.Action, rule.ServerID, rule.ID, step)
+	}
+	ae.inflight[id] = desc
+	ae.inflightWG.Add(1)
+
+	return ae.actionCtx, func() {
+		ae.inflightMu.Lock()
+		delete(ae.inflight, id)
+		ae.inflightMu.Unlock()
+		ae.inflightWG.Done()
+	}, true
+}
+
+// Drain stops new actions from starting and waits for in-flight ones to finish.
+// If ctx expires first, the remaining actions are logged and cancelled.
+func (ae *AutomationExecutor) Drain(ctx context.Context) {
+	ae.inflightMu.Lock()
+	ae.draining = true
+	n := len(ae.inflight)
+	ae.inflightMu.Unlock()
+
+	if n > 0 {
+		logging.Info("Waiting for %d in-flight automation actions to finish...", n)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ae.inflightWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	ae.inflightMu.Lock()
+	abandoned := make([]string, 0, len(ae.inflight))
+	for _, desc := range ae.inflight {
+		abandoned = append(abandoned, desc)
+	}
+	ae.inflightMu.Unlock()
+	sort.Strings(abandoned)
+
+	logging.Warn("Abandoning %d automation actions still running at shutdown: %s", len(abandoned), strings.Join(abandoned, "; "))
+	ae.cancelActions()
+
+	// Give cancelled actions a moment to record their failure in automation_log
+	select {
+	case <-done:
+	case <-time.After(drainGrace):
+	}
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// slowPower makes the fake panel hold power requests until release is closed, signalling
+// started as each one arrives. Held requests also end when the client gives up.
+func slowPower(fp *fakePanel) (started chan struct{}, release chan struct{}) {
+	started, release = make(chan struct{}, 4), make(chan struct{})
+	fp.mu.Lock()
+	fp.handler = func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+	fp.mu.Unlock()
+	return started, release
+}
+
+// automationResults returns the results logged for srv-1, newest first.
+func automationResults(t *testing.T, ae *AutomationExecutor) []string {
+	t.Helper()
+	entries, err := ae.db.GetAutomationLogForServer("srv-1", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []string
+	for _, e := range entries {
+		results = append(results, e.Result)
+	}
+	return results
+}
+
+func TestDrainWaitsForSlowAction(t *testing.T) {
+	ae, fp, _ := newTestExecutor(t, clock.NewFake(testStart))
+	started, release := slowPower(fp)
+
+	go ae.dispatch(testUser(), "key", cpuRule("restart", models.ActionRestart, nil), 0)
+	<-started
+
+	drained := make(chan struct{})
+	go func() {
+		ae.Drain(context.Background())
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		t.Fatal("Drain returned while an action was still running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-drained:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Drain did not return after the action finished")
+	}
+	if got := automationResults(t, ae); len(got) != 1 || got[0] != "success" {
+		t.Fatalf("automation_log results = %v, want one success", got)
+	}
+
+	// Nothing new starts once draining
+	ae.dispatch(testUser(), "key", cpuRule("stop", models.ActionStop, nil), 0)
+	if n := len(fp.Requests()); n != 1 {
+		t.Fatalf("panel requests = %v, want only the drained action", fp.Requests())
+	}
+}
+
+func TestDrainAbandonsActionsAfterTimeout(t *testing.T) {
+	ae, fp, _ := newTestExecutor(t, clock.NewFake(testStart))
+	started, release := slowPower(fp)
+	defer close(release)
+
+	go ae.dispatch(testUser(), "key", cpuRule("restart", models.ActionRestart, nil), 0)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	ae.Drain(ctx)
+	if elapsed := time.Since(begin); elapsed > drainGrace {
+		t.Fatalf("Drain took %s, want the abandoned action cancelled promptly", elapsed)
+	}
+
+	// The cancelled action records its failure before Drain returns
+	if got := automationResults(t, ae); len(got) != 1 || got[0] != "failure" {
+		t.Fatalf("automation_log results = %v, want one failure", got)
+	}
+}
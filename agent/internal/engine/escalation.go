@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// escalationStep is one entry of ActionConfig["escalation"], e.g.
+// {"action": "restart", "wait": 120} or {"action": "command", "command": "save-all"}.
+type escalationStep struct {
+	Action models.ActionType
+	Wait   int                    // seconds before the next step may run
+	Config map[string]interface{} // the step's other keys, passed to the action as its action_config
+}
+
+// escalationState tracks how far a rule has progressed through its chain.
+type escalationState struct {
+	next   int       // index of the next step to run
+	nextAt time.Time // earliest time the next step may run
+}
+
+// parseEscalation reads the optional escalation chain from an action config.
+// Returns false when the rule has no (valid) chain and should run its plain action.
+func parseEscalation(cfg map[string]interface{}) ([]escalationStep, bool) {
+	raw, ok := cfg["escalation"].([]interface{})
+	if !ok || len(raw) == 0 {
+		return nil, false
+	}
+
+	steps := make([]escalationStep, 0, len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		action, _ := m["action"].(string)
+		if action == "" {
+			return nil, false
+		}
+		wait, _ := getFloat(m, "wait")
+		config := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			if k != "action" && k != "wait" {
+				config[k] = v
+			}
+		}
+		steps = append(steps, escalationStep{Action: models.ActionType(action), Wait: int(wait), Config: config})
+	}
+	return steps, true
+}
+
+// evaluateEscalation advances a rule's escalation chain by at most one step per call.
+// The chain resets as soon as the trigger stops matching (e.g. the server is back online).
+func (ae *AutomationExecutor) evaluateEscalation(ctx context.Context, user models.ControlUser, apiKey string, snapshot *models.ResourceSnapshot, rule models.AutomationRule, steps []escalationStep) {
+	if !ae.evaluateTrigger(rule, snapshot) {
+		if _, ok := ae.escalations[stateKey(rule.ID, rule.ServerID)]; ok {
+			logging.Info("Automation %s: trigger cleared, resetting escalation", rule.ID)
+			delete(ae.escalations, stateKey(rule.ID, rule.ServerID))
+		}
+		return
+	}
+
+	if !isServerAllowed(user, rule.ServerID) {
+		logging.Warn("Automation %s: server %s not in user %s allowed_servers, skipping",
+			rule.ID, rule.ServerID, user.UserUUID)
+		return
+	}
+
+	if !ae.checkActiveHours(rule) {
+		return
+	}
+
+	state, ok := ae.escalations[stateKey(rule.ID, rule.ServerID)]
+	if !ok {
+		state = &escalationState{}
+		ae.escalations[stateKey(rule.ID, rule.ServerID)] = state
+	}
+
+	if state.next >= len(steps) {
+		logging.Debug("Automation %s: escalation chain exhausted, waiting for recovery", rule.ID)
+		return
+	}
+	if ae.clock.Now().Before(state.nextAt) {
+		return
+	}
+
+	step := steps[state.next]
+	stepRule := rule
+	stepRule.Action = step.Action
+	stepRule.ActionConfig = step.Config
+
+	if ae.actionOnCooldown(stepRule) {
+		return // Retried next cycle without advancing the chain
+	}
+
+	logging.Info("⚡ Automation escalation: rule=%s step=%d/%d action=%s server=%s",
+		rule.ID, state.next+1, len(steps), step.Action, rule.ServerID)
+
+	ae.dispatch(user, apiKey, stepRule, state.next+1)
+
+	state.next++
+	state.nextAt = ae.clock.Now().Add(time.Duration(step.Wait) * time.Second)
+}
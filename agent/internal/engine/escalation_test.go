@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestEscalationAdvancesWhileOffline(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, _ := newTestExecutor(t, clk)
+	rule := models.AutomationRule{
+		ID:          "recover",
+		UserUUID:    "user-1",
+		ServerID:    "srv-1",
+		TriggerType: models.TriggerOffline,
+		Action:      models.ActionStart,
+		ActionConfig: map[string]interface{}{"escalation": []interface{}{
+			map[string]interface{}{"action": "start", "wait": 60.0},
+			map[string]interface{}{"action": "restart", "wait": 120.0},
+			map[string]interface{}{"action": "command", "command": "say recovering"},
+		}},
+		Enabled: true,
+	}
+	sample := func(state string, advance time.Duration) []string {
+		clk.Advance(advance)
+		snap := testSnapshot(clk, 0)
+		snap.PowerState = state
+		ae.Evaluate(context.Background(), testUser(), "key", snap, []models.AutomationRule{rule})
+		return fp.Bodies()
+	}
+
+	steps := []struct {
+		state   string
+		advance time.Duration
+		want    string // body of the newest request, "" = no new request
+	}{
+		{"offline", 0, `{"signal":"start"}`},
+		{"offline", 30 * time.Second, ""}, // waiting out step 1
+		{"offline", 31 * time.Second, `{"signal":"restart"}`},
+		{"offline", 60 * time.Second, ""}, // waiting out step 2
+		{"offline", 61 * time.Second, `{"command":"say recovering"}`},
+		{"offline", 10 * time.Minute, ""}, // chain exhausted
+		{"running", time.Minute, ""},      // recovery resets the chain
+		{"offline", time.Minute, `{"signal":"start"}`},
+	}
+	seen := 0
+	for i, s := range steps {
+		bodies := sample(s.state, s.advance)
+		switch {
+		case s.want == "" && len(bodies) != seen:
+			t.Fatalf("sample %d (%s): unexpected request %s", i, s.state, bodies[len(bodies)-1])
+		case s.want != "" && (len(bodies) != seen+1 || bodies[seen] != s.want):
+			t.Fatalf("sample %d (%s): requests %v, want new %s", i, s.state, bodies[seen:], s.want)
+		}
+		seen = len(bodies)
+	}
+
+	log, err := ae.db.GetAutomationLogForServer("srv-1", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for i := len(log) - 1; i >= 0; i-- {
+		got = append(got, fmt.Sprintf("%d:%s", log[i].Step, log[i].Action))
+	}
+	if want := "1:start 2:restart 3:command 1:start"; strings.Join(got, " ") != want {
+		t.Errorf("automation_log steps %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestEscalationStepsKeepTheirConfig(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, _ := newTestExecutor(t, clk)
+	fp.serveBackups("b-old:2026-01-01T00:00:00Z", "b-new:2026-01-04T00:00:00Z")
+	rule := models.AutomationRule{
+		ID:          "recover",
+		UserUUID:    "user-1",
+		ServerID:    "srv-1",
+		TriggerType: models.TriggerOffline,
+		Action:      models.ActionStart,
+		ActionConfig: map[string]interface{}{"escalation": []interface{}{
+			map[string]interface{}{"action": "backup", "wait": 60.0, "rotate": true, "max_backups": 2.0, "name_template": "pre-reinstall-{{.ServerID}}"},
+			map[string]interface{}{"action": "reinstall", "confirm": true},
+		}},
+		Enabled: true,
+	}
+	for _, advance := range []time.Duration{0, 61 * time.Second} {
+		clk.Advance(advance)
+		snap := testSnapshot(clk, 0)
+		snap.PowerState = "offline"
+		ae.Evaluate(context.Background(), testUser(), "key", snap, []models.AutomationRule{rule})
+	}
+
+	want := []string{
+		"GET /api/client/servers/srv-1/backups",
+		"DELETE /api/client/servers/srv-1/backups/b-old",
+		"POST /api/client/servers/srv-1/backups",
+		"POST /api/client/servers/srv-1/settings/reinstall",
+	}
+	if got := fp.Requests(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("requests %v, want %v", got, want)
+	}
+	if got := fp.Bodies()[2]; got != `{"name":"pre-reinstall-srv-1"}` {
+		t.Fatalf("backup body %s, want the step's name_template", got)
+	}
+
+	log, err := ae.db.GetAutomationLogForServer("srv-1", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range log {
+		if e.Result != "success" {
+			t.Errorf("%s step %d: %s (%s)", e.Action, e.Step, e.Result, e.ErrorMsg)
+		}
+	}
+}
+
+func TestParseEscalationRejectsMalformedChains(t *testing.T) {
+	for name, cfg := range map[string]map[string]interface{}{
+		"missing":        nil,
+		"empty":          {"escalation": []interface{}{}},
+		"not a list":     {"escalation": "start"},
+		"step not a map": {"escalation": []interface{}{"start"}},
+		"no action":      {"escalation": []interface{}{map[string]interface{}{"wait": 10.0}}},
+	} {
+		if _, ok := parseEscalation(cfg); ok {
+			t.Errorf("%s: parsed as an escalation chain", name)
+		}
+	}
+}
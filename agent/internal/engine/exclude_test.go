@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// setUser replaces testUser in the monitor's control file with the result of mutate.
+func (tm *testMonitor) setUser(mutate func(u *models.ControlUser)) {
+	cf := tm.source.Get()
+	users := append([]models.ControlUser(nil), cf.Users...)
+	mutate(&users[0])
+	cf.Users = users
+	tm.source.Set(cf)
+}
+
+func TestMonitoredServers(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		excluded []string
+		disabled bool
+		want     []string
+	}{
+		{"none excluded", nil, false, []string{"srv-1", "srv-2"}},
+		{"one excluded", []string{"srv-2"}, false, []string{"srv-1"}},
+		{"all excluded", []string{"srv-1", "srv-2"}, false, nil},
+		{"monitoring disabled", nil, true, nil},
+	} {
+		u := testUser()
+		u.ExcludedServers = tc.excluded
+		u.MonitoringDisabled = tc.disabled
+		if got := monitoredServers(u); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: monitored %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExcludedServerNeverSampled(t *testing.T) {
+	alert := cpuAlert(10, 0, 0)
+	alert.ServerID = "srv-2"
+	tm := newTestMonitor(t, clock.NewFake(testStart), []models.AlertRule{alert}, nil)
+	tm.setUser(func(u *models.ControlUser) { u.ExcludedServers = []string{"srv-2"} })
+
+	for i := 0; i < 3; i++ {
+		tm.sample()
+	}
+	if n := tm.panel.resourceCalls("srv-2"); n != 0 {
+		t.Fatalf("excluded srv-2 sampled %d times", n)
+	}
+	if n := tm.panel.resourceCalls("srv-1"); n != 3 {
+		t.Fatalf("srv-1 sampled %d times, want once per pass", n)
+	}
+	if snaps, _ := tm.db.GetRecentSnapshots("srv-2", 10); len(snaps) != 0 {
+		t.Fatalf("%d snapshots stored for excluded srv-2", len(snaps))
+	}
+	if d := tm.push.Drain(); len(d) != 0 {
+		t.Fatalf("alert on excluded srv-2 pushed %d times", len(d))
+	}
+}
+
+func TestMonitoringDisabledSamplesNothing(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	tm.setUser(func(u *models.ControlUser) { u.MonitoringDisabled = true })
+
+	tm.sample()
+	if n := resourceRequests(tm.panel); n != 0 {
+		t.Fatalf("%d resource requests with monitoring disabled", n)
+	}
+
+	// Re-enabling resumes sampling on the next pass
+	tm.setUser(func(u *models.ControlUser) { u.MonitoringDisabled = false })
+	tm.sample()
+	if tm.panel.resourceCalls("srv-1") != 1 || tm.panel.resourceCalls("srv-2") != 1 {
+		t.Fatalf("requests after re-enabling: %v", tm.panel.Requests())
+	}
+}
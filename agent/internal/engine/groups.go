@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// RuleServers returns the concrete servers a rule's server_id targets: the server
+// itself, or the members of the referenced group.
+func RuleServers(cf *models.ControlFile, target string) []string {
+	groupID, ok := strings.CutPrefix(target, models.GroupPrefix)
+	if !ok {
+		return []string{target}
+	}
+	for _, g := range cf.Groups {
+		if g.ID == groupID {
+			return g.ServerIDs
+		}
+	}
+	return nil
+}
+
+// ruleTargets reports whether a rule's server_id covers serverID.
+func ruleTargets(cf *models.ControlFile, target, serverID string) bool {
+	for _, s := range RuleServers(cf, target) {
+		if s == serverID {
+			return true
+		}
+	}
+	return false
+}
+
+// stateKey keys per-rule state by the concrete server, so each member of a group
+// rule keeps its own cooldown and duration tracking.
+func stateKey(ruleID, serverID string) string {
+	return ruleID + "|" + serverID
+}
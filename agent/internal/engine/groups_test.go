@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestGroupRulesExpand(t *testing.T) {
+	cf := &models.ControlFile{
+		Groups: []models.ServerGroup{{ID: "web", ServerIDs: []string{"srv-1", "srv-2"}}},
+		Alerts: []models.AlertRule{
+			{ID: "group", UserUUID: "user-1", ServerID: "group:web", Enabled: true},
+			{ID: "single", UserUUID: "user-1", ServerID: "srv-2", Enabled: true},
+			{ID: "missing", UserUUID: "user-1", ServerID: "group:gone", Enabled: true},
+		},
+	}
+
+	if got := RuleServers(cf, "group:web"); !reflect.DeepEqual(got, []string{"srv-1", "srv-2"}) {
+		t.Errorf("RuleServers(group:web) = %v", got)
+	}
+	if got := RuleServers(cf, "srv-3"); !reflect.DeepEqual(got, []string{"srv-3"}) {
+		t.Errorf("RuleServers(srv-3) = %v", got)
+	}
+	if got := RuleServers(cf, "group:gone"); got != nil {
+		t.Errorf("RuleServers(group:gone) = %v, want none", got)
+	}
+
+	// Each member sees the group rule with its own concrete server_id
+	for serverID, want := range map[string][]string{
+		"srv-1": {"group"},
+		"srv-2": {"group", "single"},
+		"srv-3": nil,
+	} {
+		var ids []string
+		for _, a := range filterAlerts(cf, "user-1", serverID) {
+			if a.ServerID != serverID {
+				t.Errorf("%s: rule %s has server_id %q", serverID, a.ID, a.ServerID)
+			}
+			ids = append(ids, a.ID)
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("%s: rules %v, want %v", serverID, ids, want)
+		}
+	}
+}
+
+func TestGroupRuleCooldownPerServer(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	alert := cpuAlert(50, 0, 600)
+	alert.ServerID = "group:all"
+	tm := newTestMonitor(t, clk, []models.AlertRule{alert}, nil)
+	cf := tm.source.Get()
+	cf.Groups = []models.ServerGroup{{ID: "all", ServerIDs: []string{"srv-1", "srv-2"}}}
+	tm.source.Set(cf)
+
+	hot := map[string]bool{"srv-1": true}
+	tm.panel.serveResources(func(id string) (int, string) {
+		cpu := 10.0
+		if hot[id] {
+			cpu = 90
+		}
+		return http.StatusOK, resourcesBody("running", cpu, false)
+	})
+	servers := func() []string {
+		var ids []string
+		for _, p := range tm.push.Drain() {
+			ids = append(ids, p.Payload.ServerID)
+		}
+		return ids
+	}
+
+	tm.sample()
+	if got := servers(); !reflect.DeepEqual(got, []string{"srv-1"}) {
+		t.Fatalf("first cycle alerted %v, want srv-1", got)
+	}
+
+	// srv-1's cooldown does not hold back srv-2 under the same rule
+	hot["srv-2"] = true
+	clk.Advance(time.Minute)
+	tm.sample()
+	if got := servers(); !reflect.DeepEqual(got, []string{"srv-2"}) {
+		t.Fatalf("second cycle alerted %v, want only srv-2", got)
+	}
+
+	clk.Advance(10 * time.Minute)
+	tm.sample()
+	if got := servers(); len(got) != 2 {
+		t.Fatalf("after the cooldown alerted %v, want both servers", got)
+	}
+}
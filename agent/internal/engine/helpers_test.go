@@ -0,0 +1,298 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/control"
+	"github.com/xyidactyl/agent/internal/database"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/pterodactyl"
+	"github.com/xyidactyl/agent/internal/push"
+	"github.com/xyidactyl/agent/internal/security"
+	"github.com/xyidactyl/agent/internal/status"
+)
+
+// testStart is the fake clock's starting time in engine tests.
+var testStart = time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+
+// openTestDB opens a fresh database in a temp dir, closed when the test ends.
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "agent.db"), false)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// fakePanel is a panel API that records "METHOD path" and the body of every request. By
+// default it lists srv-1 and srv-2, reports every server running at 12.5% CPU and accepts
+// all actions.
+type fakePanel struct {
+	srv    *httptest.Server
+	panels *pterodactyl.Panels
+
+	mu       sync.Mutex
+	requests []string
+	bodies   []string
+	handler  http.HandlerFunc // optional override, called after recording
+}
+
+func newFakePanel(t *testing.T) *fakePanel {
+	t.Helper()
+	fp := &fakePanel{}
+	fp.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fp.mu.Lock()
+		fp.requests = append(fp.requests, r.Method+" "+r.URL.Path)
+		fp.bodies = append(fp.bodies, string(body))
+		h := fp.handler
+		fp.mu.Unlock()
+		if h != nil {
+			h(w, r)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/client":
+			fmt.Fprint(w, `{"data":[{"attributes":{"identifier":"srv-1","name":"one"}},{"attributes":{"identifier":"srv-2","name":"two"}}],"meta":{"pagination":{"total":2,"current_page":1,"total_pages":1}}}`)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/resources"):
+			fmt.Fprint(w, `{"attributes":{"current_state":"running","resources":{"memory_bytes":536870912,"cpu_absolute":12.5,"disk_bytes":1073741824}}}`)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(fp.srv.Close)
+
+	panels, err := pterodactyl.NewPanels(fp.srv.URL, pterodactyl.ClientOptions{})
+	if err != nil {
+		t.Fatalf("new panels: %v", err)
+	}
+	fp.panels = panels
+	return fp
+}
+
+// Requests returns the requests received so far.
+func (fp *fakePanel) Requests() []string {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return append([]string(nil), fp.requests...)
+}
+
+// serveResources answers each server's /resources call with fn(serverID), keeping the
+// default server list and action responses.
+func (fp *fakePanel) serveResources(fn func(serverID string) (code int, body string)) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.handler = func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/client":
+			fmt.Fprint(w, `{"data":[{"attributes":{"identifier":"srv-1","name":"one"}},{"attributes":{"identifier":"srv-2","name":"two"}}],"meta":{"pagination":{"total":2,"current_page":1,"total_pages":1}}}`)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/resources"):
+			code, body := fn(strings.Split(r.URL.Path, "/")[4])
+			w.WriteHeader(code)
+			fmt.Fprint(w, body)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+// resourcesBody is a /resources response with the given state and CPU.
+func resourcesBody(state string, cpu float64, suspended bool) string {
+	return fmt.Sprintf(`{"attributes":{"current_state":%q,"is_suspended":%t,"resources":{"memory_bytes":536870912,"cpu_absolute":%g,"disk_bytes":1073741824}}}`, state, suspended, cpu)
+}
+
+// resourceCalls counts the /resources requests made for a server.
+func (fp *fakePanel) resourceCalls(serverID string) int {
+	n := 0
+	for _, r := range fp.Requests() {
+		if r == "GET /api/client/servers/"+serverID+"/resources" {
+			n++
+		}
+	}
+	return n
+}
+
+// Bodies returns the request bodies received so far, in the order of Requests.
+func (fp *fakePanel) Bodies() []string {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return append([]string(nil), fp.bodies...)
+}
+
+// newTestExecutor creates an enabled, unordered executor against a fake panel.
+func newTestExecutor(t *testing.T, clk clock.Clock) (*AutomationExecutor, *fakePanel, *push.RecordingProvider) {
+	t.Helper()
+	fp := newFakePanel(t)
+	rec := push.NewRecordingProvider(false)
+	ae := NewAutomationExecutor(openTestDB(t), fp.panels, NewNotifier(rec, nil, nil, nil, 0, nil, clk), NewConsoleBuffer(50), 4, 0, true, false, clk)
+	return ae, fp, rec
+}
+
+// testUser is a user allowed on srv-1 and srv-2 with one device token.
+func testUser() models.ControlUser {
+	return models.ControlUser{
+		UserUUID:       "user-1",
+		AllowedServers: []string{"srv-1", "srv-2"},
+		DeviceTokens:   []string{"token-1"},
+	}
+}
+
+// testSnapshot is a running srv-1 at the given CPU percent, timestamped now.
+func testSnapshot(clk clock.Clock, cpu float64) *models.ResourceSnapshot {
+	return &models.ResourceSnapshot{
+		ServerID:   "srv-1",
+		CPUPercent: cpu,
+		MemBytes:   512 << 20,
+		MemLimit:   1024 << 20,
+		DiskBytes:  1 << 30,
+		DiskLimit:  10 << 30,
+		PowerState: "running",
+		Timestamp:  clk.Now(),
+	}
+}
+
+// staticSource is a control.Source serving a fixed control file.
+type staticSource struct {
+	mu sync.Mutex
+	cf *models.ControlFile
+}
+
+var _ control.Source = (*staticSource)(nil)
+
+func (s *staticSource) LoadInitial() error             { return nil }
+func (s *staticSource) Start()                         {}
+func (s *staticSource) Stop()                          {}
+func (s *staticSource) PollInterval() time.Duration    { return time.Minute }
+func (s *staticSource) OnReload(fn func())             {}
+func (s *staticSource) Reload() error                  { return nil }
+func (s *staticSource) LastError() (string, time.Time) { return "", time.Time{} }
+
+func (s *staticSource) Get() *models.ControlFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cf := *s.cf
+	return &cf
+}
+
+func (s *staticSource) Version() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cf.Version
+}
+
+// Set replaces the served control file.
+func (s *staticSource) Set(cf *models.ControlFile) {
+	s.mu.Lock()
+	s.cf = cf
+	s.mu.Unlock()
+}
+
+// newTestCrypto returns a Crypto with a fixed test secret.
+func newTestCrypto(t *testing.T) *security.Crypto {
+	t.Helper()
+	c, err := security.NewCrypto("test-agent-secret-0123456789abcdef", "", "test-salt", "test-info")
+	if err != nil {
+		t.Fatalf("new crypto: %v", err)
+	}
+	return c
+}
+
+// testMonitor bundles a Monitor with the fakes behind it.
+type testMonitor struct {
+	*Monitor
+	panel   *fakePanel
+	source  *staticSource
+	push    *push.RecordingProvider
+	dataDir string
+}
+
+// newTestMonitor creates a monitor over a fake panel, serving testUser with its API
+// key encrypted, plus the given alerts and automations.
+func newTestMonitor(t *testing.T, clk clock.Clock, alerts []models.AlertRule, automations []models.AutomationRule) *testMonitor {
+	t.Helper()
+	crypto := newTestCrypto(t)
+	key, err := crypto.Encrypt("ptlc_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := testUser()
+	user.APIKeyEncrypted = key
+
+	db := openTestDB(t)
+	fp := newFakePanel(t)
+	rec := push.NewRecordingProvider(false)
+	dataDir := t.TempDir()
+	src := &staticSource{cf: &models.ControlFile{
+		Version:     1,
+		Users:       []models.ControlUser{user},
+		Alerts:      alerts,
+		Automations: automations,
+	}}
+	deadTokens := status.NewDeadTokenWriter(dataDir)
+	consoles := NewConsoleBuffer(50)
+	notifier := NewNotifier(rec, deadTokens, status.NewTokenReconciler(dataDir, clk), nil, 0, nil, clk)
+	alertEval := NewAlertEvaluator(db, fp.panels, notifier, false, clk)
+	autoExec := NewAutomationExecutor(db, fp.panels, notifier, consoles, 4, 0, true, false, clk)
+	m := NewMonitor(1, fp.panels, db, src, crypto, alertEval, autoExec,
+		status.NewWriter(dataDir, nil),
+		status.NewMetricsWriter(dataDir, db, nil, "", false, false, 0),
+		consoles, 4, false, AdaptiveSampling{}, DeltaStore{}, clk)
+	t.Cleanup(func() {
+		select {
+		case <-m.stopCh:
+		default:
+			m.Stop()
+		}
+	})
+	return &testMonitor{Monitor: m, panel: fp, source: src, push: rec, dataDir: dataDir}
+}
+
+// readStatus parses the status.json the monitor last wrote.
+func (tm *testMonitor) readStatus(t *testing.T) status.AgentStatus {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(tm.dataDir, "status.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st status.AgentStatus
+	if err := json.Unmarshal(data, &st); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+// newTestEvaluator creates an alert evaluator that records its pushes.
+func newTestEvaluator(t *testing.T, clk clock.Clock) (*AlertEvaluator, *push.RecordingProvider) {
+	t.Helper()
+	fp := newFakePanel(t)
+	rec := push.NewRecordingProvider(false)
+	ae := NewAlertEvaluator(openTestDB(t), fp.panels, NewNotifier(rec, nil, nil, nil, 0, nil, clk), false, clk)
+	return ae, rec
+}
+
+// cpuAlert is an enabled srv-1 CPU alert for testUser.
+func cpuAlert(threshold float64, duration, cooldown int) models.AlertRule {
+	return models.AlertRule{
+		ID:            "cpu-high",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		ConditionType: models.ConditionCPU,
+		Threshold:     threshold,
+		Duration:      duration,
+		Cooldown:      cooldown,
+		Enabled:       true,
+	}
+}
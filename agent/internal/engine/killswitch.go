@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"strconv"
+
+	"github.com/xyidactyl/agent/internal/logging"
+)
+
+// AutomationsEnabledKey is the agent_state key that overrides AUTOMATIONS_ENABLED at runtime.
+const AutomationsEnabledKey = "automations_enabled"
+
+// refreshEnabled re-reads the runtime kill-switch, falling back to the configured default.
+func (ae *AutomationExecutor) refreshEnabled() {
+	enabled := ae.defaultEnabled
+	val, err := ae.db.GetState(AutomationsEnabledKey)
+	if err != nil {
+		logging.Warn("Failed to read automation kill-switch, keeping current state: %v", err)
+		return
+	}
+	if val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			enabled = b
+		}
+	}
+
+	if ae.enabled.Swap(enabled) != enabled {
+		if enabled {
+			logging.Info("Automations enabled")
+		} else {
+			logging.Warn("Automations disabled by kill-switch; alerts still fire but no actions will run")
+		}
+	}
+}
+
+// Enabled reports whether automations may currently run actions.
+func (ae *AutomationExecutor) Enabled() bool {
+	return ae.enabled.Load()
+}
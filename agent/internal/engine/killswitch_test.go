@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestKillSwitchSuppressesActions(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, rec := newTestExecutor(t, clk)
+	rule := models.AutomationRule{
+		ID:            "restart-hot",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		TriggerType:   models.TriggerCPU,
+		TriggerConfig: map[string]interface{}{"threshold": 90.0},
+		Action:        models.ActionRestart,
+		Enabled:       true,
+	}
+
+	if err := ae.db.SetState(AutomationsEnabledKey, "false"); err != nil {
+		t.Fatal(err)
+	}
+	ae.refreshEnabled()
+	if ae.Enabled() {
+		t.Fatal("kill-switch off, but executor still enabled")
+	}
+	ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 99), []models.AutomationRule{rule})
+	if got := fp.Requests(); len(got) != 0 {
+		t.Fatalf("actions ran while disabled: %v", got)
+	}
+	if got := rec.Drain(); len(got) != 0 {
+		t.Fatalf("automation pushes while disabled: %d", len(got))
+	}
+
+	if err := ae.db.SetState(AutomationsEnabledKey, "true"); err != nil {
+		t.Fatal(err)
+	}
+	ae.refreshEnabled()
+	clk.Advance(time.Minute)
+	ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 99), []models.AutomationRule{rule})
+	got := fp.Requests()
+	if len(got) != 1 || got[0] != "POST /api/client/servers/srv-1/power" {
+		t.Fatalf("after re-enabling, requests = %v, want one power call", got)
+	}
+}
+
+func TestKillSwitchFallsBackToDefault(t *testing.T) {
+	ae, _, _ := newTestExecutor(t, clock.NewFake(testStart))
+	if err := ae.db.SetState(AutomationsEnabledKey, "not-a-bool"); err != nil {
+		t.Fatal(err)
+	}
+	ae.refreshEnabled()
+	if !ae.Enabled() {
+		t.Fatal("unparseable override should fall back to AUTOMATIONS_ENABLED=true")
+	}
+}
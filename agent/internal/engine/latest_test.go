@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+)
+
+func TestLatestSnapshot(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	tm := newTestMonitor(t, clk, nil, nil)
+	cpu := 10.0
+	tm.panel.serveResources(func(id string) (int, string) {
+		return http.StatusOK, resourcesBody("running", cpu, false)
+	})
+
+	if snap := tm.LatestSnapshot("srv-1"); snap != nil {
+		t.Fatalf("snapshot before any sample: %+v", snap)
+	}
+
+	tm.sample()
+	cpu = 75
+	clk.Advance(time.Minute)
+	tm.sample()
+
+	snap := tm.LatestSnapshot("srv-1")
+	if snap == nil || snap.CPUPercent != 75 || !snap.Timestamp.Equal(clk.Now()) {
+		t.Fatalf("latest srv-1 = %+v, want the second cycle's 75%%", snap)
+	}
+	// Callers get a copy
+	snap.CPUPercent = 0
+	if again := tm.LatestSnapshot("srv-1"); again.CPUPercent != 75 {
+		t.Fatalf("mutating the returned snapshot changed the cache to %v", again.CPUPercent)
+	}
+	if snap := tm.LatestSnapshot("srv-9"); snap != nil {
+		t.Fatalf("snapshot for an unknown server: %+v", snap)
+	}
+}
@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/database"
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+// agentNotifyThrottle keeps restart loops from sending a push on every start or stop.
+const agentNotifyThrottle = 10 * time.Minute
+
+// NotifyAgentStatus sends an "agent_status" push to every user's devices.
+// Each kind (e.g. "start", "stop") is sent at most once per agentNotifyThrottle.
+func NotifyAgentStatus(ctx context.Context, db *database.DB, notifier *Notifier, cf *models.ControlFile, kind, title, body string) {
+	if cf == nil || len(cf.Users) == 0 {
+		return
+	}
+
+	now := notifier.clock.Now()
+	key := "last_agent_notify_" + kind
+	if last, err := db.GetState(key); err != nil {
+		logging.Warn("Failed to read %s: %v", key, err)
+	} else if ts, err := strconv.ParseInt(last, 10, 64); err == nil && now.Sub(time.Unix(ts, 0)) < agentNotifyThrottle {
+		logging.Info("Skipping agent %s notification, one was sent %s ago", kind, now.Sub(time.Unix(ts, 0)).Round(time.Second))
+		return
+	}
+	if err := db.SetState(key, strconv.FormatInt(now.Unix(), 10)); err != nil {
+		logging.Warn("Failed to record %s: %v", key, err)
+	}
+
+	for _, user := range cf.Users {
+		notifier.send(ctx, user, push.Payload{
+			Title:     title,
+			Body:      body,
+			UserUUID:  user.UserUUID,
+			EventType: "agent_status",
+			Timestamp: now.Format(time.RFC3339),
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+func TestNotifyAgentStatus(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	db := openTestDB(t)
+	rec := push.NewRecordingProvider(false)
+	notifier := NewNotifier(rec, nil, nil, nil, 0, nil, clk)
+	second := models.ControlUser{UserUUID: "user-2", DeviceTokens: []string{"token-2", "token-3"}}
+	cf := &models.ControlFile{Users: []models.ControlUser{testUser(), second}}
+	start := func() []push.Delivery {
+		NotifyAgentStatus(context.Background(), db, notifier, cf, "start", "Agent online", "Monitoring resumed")
+		return rec.Drain()
+	}
+
+	got := start()
+	if len(got) != 3 {
+		t.Fatalf("%d startup pushes, want one per device", len(got))
+	}
+	for _, d := range got {
+		p := d.Payload
+		if p.EventType != "agent_status" || p.Title != "Agent online" || p.Timestamp != testStart.Format(time.RFC3339) {
+			t.Errorf("payload %+v", p)
+		}
+	}
+	if got[0].Payload.UserUUID != "user-1" || got[2].Payload.UserUUID != "user-2" {
+		t.Errorf("pushes went to %q and %q", got[0].Payload.UserUUID, got[2].Payload.UserUUID)
+	}
+
+	// A restart loop is throttled, per kind
+	clk.Advance(time.Minute)
+	if got := start(); len(got) != 0 {
+		t.Fatalf("%d pushes on a restart within the throttle, want none", len(got))
+	}
+	NotifyAgentStatus(context.Background(), db, notifier, cf, "stop", "Agent shutting down", "")
+	if got := rec.Drain(); len(got) != 3 {
+		t.Fatalf("%d shutdown pushes, want 3", len(got))
+	}
+
+	clk.Advance(agentNotifyThrottle)
+	if got := start(); len(got) != 3 {
+		t.Fatalf("%d pushes after the throttle window, want 3", len(got))
+	}
+}
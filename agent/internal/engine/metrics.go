@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// networkRate returns combined rx+tx throughput in MB/s between two snapshots.
+// Returns 0 without a previous sample or when the counters reset (e.g. server restart).
+func networkRate(prev, cur *models.ResourceSnapshot) float64 {
+	if prev == nil {
+		return 0
+	}
+
+	elapsed := cur.Timestamp.Sub(prev.Timestamp).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+
+	prevTotal := prev.NetRx + prev.NetTx
+	curTotal := cur.NetRx + cur.NetTx
+	if curTotal < prevTotal {
+		return 0 // Counter reset
+	}
+
+	return float64(curTotal-prevTotal) / elapsed / (1024 * 1024)
+}
+
+// hoursToFull fits a least-squares line through disk usage over snaps (oldest first)
+// and returns the projected hours until DiskLimit is reached. ok is false without a
+// disk limit, with too few points, or when usage is flat or shrinking.
+func hoursToFull(snaps []models.ResourceSnapshot) (hours float64, ok bool) {
+	if len(snaps) < diskTrendMinSamples {
+		return 0, false
+	}
+	last := snaps[len(snaps)-1]
+	if last.DiskLimit <= 0 {
+		return 0, false
+	}
+
+	// x is hours since the first sample, y is bytes used
+	t0 := snaps[0].Timestamp
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range snaps {
+		x := s.Timestamp.Sub(t0).Hours()
+		y := float64(s.DiskBytes)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(snaps))
+	denom := n*sumXX - sumX*sumX
+	if denom <= 0 {
+		return 0, false
+	}
+	slope := (n*sumXY - sumX*sumY) / denom // bytes per hour
+	if slope <= 0 {
+		return 0, false
+	}
+
+	remaining := float64(last.DiskLimit - last.DiskBytes)
+	if remaining <= 0 {
+		return 0, true
+	}
+	return remaining / slope, true
+}
+
+// formatBytes renders a byte count in human-readable form, e.g. "3.2 GB".
+func formatBytes(b float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	i := 0
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", b, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", b, units[i])
+}
+
+// describeCondition renders a met condition for notification text, e.g. "CPU 93% > 90%".
+func describeCondition(conditionType models.ConditionType, value, threshold float64) string {
+	switch conditionType {
+	case models.ConditionCPU:
+		return fmt.Sprintf("CPU %.0f%% > %.0f%%", value, threshold)
+	case models.ConditionCPUNormalized:
+		return fmt.Sprintf("CPU %.0f%% of limit > %.0f%%", value, threshold)
+	case models.ConditionRAM:
+		return fmt.Sprintf("Memory %.0f%% > %.0f%%", value, threshold)
+	case models.ConditionDisk:
+		return fmt.Sprintf("Disk %.0f%% > %.0f%%", value, threshold)
+	case models.ConditionRAMBytes:
+		return fmt.Sprintf("Memory %s > %s", formatBytes(value), formatBytes(threshold))
+	case models.ConditionDiskBytes:
+		return fmt.Sprintf("Disk %s > %s", formatBytes(value), formatBytes(threshold))
+	case models.ConditionNetwork:
+		return fmt.Sprintf("Network %.1f MB/s > %.1f MB/s", value, threshold)
+	case models.ConditionCPUSpike:
+		return fmt.Sprintf("CPU +%.0f points > %.0f", value, threshold)
+	case models.ConditionUptime:
+		return fmt.Sprintf("Uptime %.1fh > %.0fh", value, threshold)
+	case models.ConditionSuspended:
+		return "Server suspended"
+	case models.ConditionDiskTrend:
+		return fmt.Sprintf("Disk full in %.1fh < %.0fh", value, threshold)
+	case models.ConditionDataStale:
+		return fmt.Sprintf("No data for %.0fs > %.0fs", value, threshold)
+	default:
+		return string(conditionType)
+	}
+}
+
+// truncateOutput keeps the tail of console output within max characters.
+func truncateOutput(output string, max int) string {
+	if len(output) <= max {
+		return output
+	}
+	return "…" + output[len(output)-max:]
+}
@@ -2,11 +2,15 @@ package engine
 
 import (
 	"context"
-	"strings"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/xyidactyl/agent/internal/clock"
 	"github.com/xyidactyl/agent/internal/control"
 	"github.com/xyidactyl/agent/internal/database"
 	"github.com/xyidactyl/agent/internal/logging"
@@ -16,20 +20,68 @@ import (
 	"github.com/xyidactyl/agent/internal/status"
 )
 
+// suspendedRecheckCycles is how often a known-suspended server is polled again.
+const suspendedRecheckCycles = 10
+
+// sampleNowDebounce coalesces bursts of SampleNow calls into one out-of-band pass.
+const sampleNowDebounce = 2 * time.Second
+
+// panelStatsInterval is how often panel call metrics are summarized in the log.
+const panelStatsInterval = 10 * time.Minute
+
+// maxBackoffCycles caps how many cycles a repeatedly failing server is skipped.
+const maxBackoffCycles = 16
+
 // Monitor runs the main sampling loop: polls Pterodactyl for server resources,
 // stores snapshots, and triggers alert/automation evaluation.
 type Monitor struct {
 	interval       time.Duration
-	pteroClient    *pterodactyl.Client
+	panels         *pterodactyl.Panels
 	db             *database.DB
-	controlLoader  *control.Loader
+	controlLoader  control.Source
 	crypto         *security.Crypto
 	alertEvaluator *AlertEvaluator
 	autoExecutor   *AutomationExecutor
 	statusWriter   *status.Writer
 	metricsWriter  *status.MetricsWriter
+	consoles       *ConsoleBuffer
+	access         *accessReconciler
+	clock          clock.Clock
 	stopCh         chan struct{}
+	sampleNowCh    chan struct{}   // see SampleNow
+	ctx            context.Context // cancelled on Stop to abort in-flight panel requests
+	cancel         context.CancelFunc
 	startTime      time.Time
+	concurrency    int          // max servers sampled in parallel
+	lastSampleAt   atomic.Int64 // unix nanos of the last completed sampling pass
+	paused         atomic.Bool  // see Pause
+	lastMonitored  atomic.Int32 // servers sampled in the last pass, reported by status updates between passes
+	lastStatsLog   time.Time    // last panel call summary, only touched by the loop goroutine
+
+	// Suspended servers are only re-probed every suspendedRecheckCycles unless monitorSuspended is set
+	monitorSuspended bool
+	suspendedMu      sync.Mutex
+	suspendedSkips   map[string]int // server_id -> cycles skipped since last probe
+
+	// Adaptive sampling: idle servers are polled every few cycles
+	adaptive AdaptiveSampling
+	cycle    uint64 // sampling passes so far, only touched by the loop goroutine
+	rateMu   sync.Mutex
+	rates    map[string]*sampleRate // server_id -> idle tracking
+
+	// Delta store: unchanged snapshots are not written, only touched by the loop goroutine
+	delta      DeltaStore
+	lastStored map[string]*models.ResourceSnapshot // server_id -> last snapshot written
+
+	// Most recent snapshot per server, for the live view
+	latestMu sync.RWMutex
+	latest   map[string]*models.ResourceSnapshot
+
+	testPushes testPushes // see SendTestPush
+
+	// Per-server error backoff
+	backoffMu sync.Mutex
+	backoff   map[string]*serverBackoff // server_id -> failure state
 
 	// Permission cache: user_uuid -> decrypted API key
 	mu                 sync.RWMutex
@@ -40,18 +92,25 @@ type Monitor struct {
 // NewMonitor creates a new monitoring engine.
 func NewMonitor(
 	intervalSec int,
-	pteroClient *pterodactyl.Client,
+	panels *pterodactyl.Panels,
 	db *database.DB,
-	controlLoader *control.Loader,
+	controlLoader control.Source,
 	crypto *security.Crypto,
 	alertEval *AlertEvaluator,
 	autoExec *AutomationExecutor,
 	sw *status.Writer,
 	mw *status.MetricsWriter,
+	consoles *ConsoleBuffer,
+	concurrency int,
+	monitorSuspended bool,
+	adaptive AdaptiveSampling,
+	delta DeltaStore,
+	clk clock.Clock,
 ) *Monitor {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Monitor{
 		interval:       time.Duration(intervalSec) * time.Second,
-		pteroClient:    pteroClient,
+		panels:         panels,
 		db:             db,
 		controlLoader:  controlLoader,
 		crypto:         crypto,
@@ -59,26 +118,110 @@ func NewMonitor(
 		autoExecutor:   autoExec,
 		statusWriter:   sw,
 		metricsWriter:  mw,
+		consoles:       consoles,
+		access:         newAccessReconciler(db, clk),
+		clock:          clock.OrReal(clk),
 		stopCh:         make(chan struct{}),
+		sampleNowCh:    make(chan struct{}, 1),
+		ctx:            ctx,
+		cancel:         cancel,
 		startTime:      time.Now(),
+		concurrency:    concurrency,
 		apiKeyCache:    make(map[string]string),
+
+		monitorSuspended: monitorSuspended,
+		suspendedSkips:   make(map[string]int),
+		backoff:          make(map[string]*serverBackoff),
+		adaptive:         adaptive,
+		rates:            make(map[string]*sampleRate),
+		delta:            delta,
+		lastStored:       make(map[string]*models.ResourceSnapshot),
+		latest:           make(map[string]*models.ResourceSnapshot),
 	}
 }
 
+// serverBackoff tracks consecutive collection failures for a server.
+type serverBackoff struct {
+	failures int // consecutive failures
+	skipLeft int // cycles to skip before the next attempt
+}
+
 // Start begins the monitoring loop.
 func (m *Monitor) Start() {
-	logging.Info("Monitoring engine started (interval: %s)", m.interval)
+	logging.Info("Monitoring engine started (interval: %s, concurrency: %d)", m.interval, m.concurrency)
+	if m.adaptive.IdleCycles > 0 && m.adaptive.SlowEvery > 1 {
+		logging.Info("Adaptive sampling: idle servers polled every %d cycles after %d stable cycles", m.adaptive.SlowEvery, m.adaptive.IdleCycles)
+	}
+	if m.delta.Heartbeat > 0 {
+		logging.Info("Delta store: unchanged snapshots skipped, heartbeat every %s", m.delta.Heartbeat)
+	}
 	go m.loop()
 }
 
 // Stop halts the monitoring loop.
 func (m *Monitor) Stop() {
 	close(m.stopCh)
+	m.cancel()
+	m.consoles.Stop()
+}
+
+// Interval returns the sampling interval.
+func (m *Monitor) Interval() time.Duration {
+	return m.interval
+}
+
+// AutomationsEnabled reports the state of the automation kill-switch.
+func (m *Monitor) AutomationsEnabled() bool {
+	return m.autoExecutor.Enabled()
+}
+
+// LatestSnapshot returns the most recent snapshot of a server from memory, or nil
+// if it has not been sampled since the agent started.
+func (m *Monitor) LatestSnapshot(serverID string) *models.ResourceSnapshot {
+	m.latestMu.RLock()
+	defer m.latestMu.RUnlock()
+	s, ok := m.latest[serverID]
+	if !ok {
+		return nil
+	}
+	snap := *s
+	return &snap
+}
+
+// storeLatest records a cycle's snapshots (oldest first) as the latest per server.
+func (m *Monitor) storeLatest(batch []models.ResourceSnapshot) {
+	m.latestMu.Lock()
+	defer m.latestMu.Unlock()
+	for i := range batch {
+		snap := batch[i]
+		m.latest[snap.ServerID] = &snap
+	}
+}
+
+// LastSampleAt returns when the last sampling pass completed (zero if none yet).
+func (m *Monitor) LastSampleAt() time.Time {
+	ns := m.lastSampleAt.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// SampleNow requests an immediate sampling pass, e.g. so a newly created alert is
+// evaluated right away. Requests within sampleNowDebounce collapse into one pass,
+// which runs on the loop goroutine so it never overlaps a ticker-driven one.
+func (m *Monitor) SampleNow() {
+	select {
+	case m.sampleNowCh <- struct{}{}:
+	default: // A pass is already pending
+	}
 }
 
 func (m *Monitor) loop() {
 	// Run immediately once, then on ticker
-	m.sample()
+	if !m.refreshPaused() {
+		m.sample()
+	}
 
 	ticker := time.NewTicker(m.interval)
 	defer ticker.Stop()
@@ -89,15 +232,40 @@ func (m *Monitor) loop() {
 			logging.Info("Monitoring engine stopped")
 			return
 		case <-ticker.C:
+			if m.refreshPaused() {
+				logging.Debug("Sampling paused, skipping cycle")
+				continue
+			}
+			m.sample()
+		case <-m.sampleNowCh:
+			select {
+			case <-m.stopCh:
+				logging.Info("Monitoring engine stopped")
+				return
+			case <-time.After(sampleNowDebounce):
+			}
+			// Requests made while debouncing are covered by this pass
+			select {
+			case <-m.sampleNowCh:
+			default:
+			}
+			if m.refreshPaused() {
+				continue
+			}
+			logging.Debug("Running on-demand sampling pass")
 			m.sample()
+			ticker.Reset(m.interval)
 		}
 	}
 }
 
 func (m *Monitor) sample() {
 	cf := m.controlLoader.Get()
+	m.alertEvaluator.notify.reconcileTokens(cf)
 	if cf == nil || len(cf.Users) == 0 {
 		logging.Debug("No users configured, skipping sample")
+		m.lastSampleAt.Store(m.clock.Now().UnixNano())
+		m.lastMonitored.Store(0)
 		m.updateStatus(cf, 0)
 		return
 	}
@@ -106,74 +274,139 @@ func (m *Monitor) sample() {
 	if cf.Version > m.lastControlVersion {
 		logging.Info("Control version changed (%d -> %d), invalidating API key cache", m.lastControlVersion, cf.Version)
 		m.InvalidateKeyCache()
+		m.access.invalidate()
+		m.access.prune(cf.Users)
 		m.lastControlVersion = cf.Version
 	}
 
+	m.syncConsoles(cf)
+	m.autoExecutor.refreshEnabled()
+	m.cycle++
+	cycle := m.cycle
+
+	// Snapshots are stored together after the pass to keep it to one transaction
+	var batchMu sync.Mutex
+	var batch []models.ResourceSnapshot
+	addSnapshot := func(s *models.ResourceSnapshot) {
+		batchMu.Lock()
+		batch = append(batch, *s)
+		batchMu.Unlock()
+	}
+
 	var serversMonitored int32
 	var wg sync.WaitGroup
+	sem := make(chan struct{}, m.concurrency) // bounds in-flight server samples
 
 	for _, user := range cf.Users {
+		if user.MonitoringDisabled {
+			continue
+		}
 		apiKey, err := m.getAPIKey(user)
 		if err != nil {
 			logging.Error("Failed to decrypt API key for user %s: %v", user.UserUUID, err)
 			continue
 		}
+		client, err := m.panels.For(user.PanelURL)
+		if err != nil {
+			logging.Error("No panel client for user %s: %v", user.UserUUID, err)
+			continue
+		}
+		m.access.maybeCheck(m.ctx, client, user, apiKey)
 
-		for _, serverID := range user.AllowedServers {
+		for _, serverID := range monitoredServers(user) {
 			wg.Add(1)
+			sem <- struct{}{}
 			go func(u models.ControlUser, key, sID string) {
 				defer wg.Done()
+				defer func() { <-sem }()
+
+				if m.skipSuspended(sID) {
+					logging.Debug("Server %s is suspended, skipping collection", sID)
+					addSnapshot(m.suspendedSnapshot(sID))
+					atomic.AddInt32(&serversMonitored, 1)
+					return
+				}
 
-				snapshot, runErr := m.collectServer(key, sID)
+				if m.inBackoff(sID) {
+					logging.Debug("Server %s is in error backoff, skipping this cycle", sID)
+					m.evaluateStale(cf, u, key, sID)
+					return
+				}
+
+				if !m.dueForSample(sID, cycle) {
+					logging.Debug("Server %s is idle, skipping this cycle", sID)
+					return
+				}
+
+				snapshot, runErr := m.collectServer(m.ctx, client, key, sID)
 				if runErr != nil {
-					if strings.Contains(runErr.Error(), "409") {
-						logging.Debug("Skipping server %s (409 Conflict): Recording zero-usage snapshot", sID)
-						// Create zero snapshot for suspended server
-						snapshot = &models.ResourceSnapshot{
-							ServerID:   sID,
-							Timestamp:  time.Now(),
-							PowerState: "suspended", // Or "offline"
-							CPUPercent: 0,
-							MemBytes:   0,
-							DiskBytes:  0,
-							NetRx:      0,
-							NetTx:      0,
-							UptimeMs:   0,
-						}
+					if state, ok := pterodactyl.ConflictState(runErr); ok {
+						// Installing, transferring etc.: record the state so the app can explain the gap
+						logging.Debug("Server %s is %s (409 Conflict), recording zero-usage snapshot", sID, state)
+						snapshot = m.stateSnapshot(sID, state)
 					} else {
-						logging.Warn("Failed to collect server %s for user %s: %v", sID, u.UserUUID, runErr)
+						if m.ctx.Err() != nil {
+							return // Shutting down, not a server failure
+						}
+						m.recordFailure(sID, u.UserUUID, runErr)
+						m.evaluateStale(cf, u, key, sID)
 						return
 					}
 				}
+				m.recordSuccess(sID)
+				snapshot.CPUNormalized = normalizeCPU(snapshot.CPUPercent, m.access.cpuCores(sID))
 
-				// Store snapshot
-				if storeErr := m.db.InsertSnapshot(*snapshot); storeErr != nil {
-					logging.Error("Failed to store snapshot for server %s: %v", sID, storeErr)
-					return
-				}
+				suspended := snapshot.PowerState == "suspended" && !m.monitorSuspended
+				m.setSuspended(sID, suspended)
+				m.recordRate(sID, cycle, snapshot)
 
+				addSnapshot(snapshot)
 				atomic.AddInt32(&serversMonitored, 1)
 
+				userAlerts := filterAlerts(cf, u.UserUUID, sID)
+				userAutos := filterAutomations(cf, u.UserUUID, sID)
+
+				// Suspended servers keep their snapshot but only suspension rules are evaluated
+				if suspended {
+					userAlerts, userAutos = suspensionRules(userAlerts, userAutos)
+				}
+
 				// Evaluate alerts for this server
-				userAlerts := filterAlerts(cf.Alerts, u.UserUUID, sID)
-				m.alertEvaluator.Evaluate(context.Background(), u, snapshot, userAlerts)
+				m.alertEvaluator.Evaluate(m.ctx, u, key, snapshot, userAlerts)
 
 				// Evaluate automations for this server
-				userAutos := filterAutomations(cf.Automations, u.UserUUID, sID)
-				m.autoExecutor.Evaluate(context.Background(), u, key, snapshot, userAutos)
+				m.autoExecutor.Evaluate(m.ctx, u, key, snapshot, userAutos)
 			}(user, apiKey, serverID)
 		}
 	}
 
 	wg.Wait()
 
+	sort.Slice(batch, func(i, j int) bool { return batch[i].Timestamp.Before(batch[j].Timestamp) })
+	m.storeLatest(batch)
+	if stored := m.deltaFilter(batch); len(stored) > 0 {
+		if err := m.db.InsertSnapshots(stored); err != nil {
+			logging.Error("Failed to store %d snapshots: %v", len(stored), err)
+		}
+	}
+	m.alertEvaluator.Flush(m.ctx)
+
 	logging.Debug("Sampling cycle complete: %d servers monitored", serversMonitored)
+	m.lastSampleAt.Store(m.clock.Now().UnixNano())
+	m.lastMonitored.Store(serversMonitored)
 	m.updateStatus(cf, int(serversMonitored))
 
+	if time.Since(m.lastStatsLog) >= panelStatsInterval {
+		if !m.lastStatsLog.IsZero() {
+			m.panels.LogStats()
+		}
+		m.lastStatsLog = time.Now()
+	}
+
 	// Export metrics to metrics.json (last 1 hour = 120 points at 30s)
 	uniqueServers := make(map[string]bool)
 	for _, user := range cf.Users {
-		for _, sid := range user.AllowedServers {
+		for _, sid := range monitoredServers(user) {
 			uniqueServers[sid] = true
 		}
 	}
@@ -189,27 +422,220 @@ func (m *Monitor) sample() {
 	}
 }
 
-func (m *Monitor) collectServer(apiKey, serverID string) (*models.ResourceSnapshot, error) {
-	res, err := m.pteroClient.FetchResources(apiKey, serverID)
+// syncConsoles keeps console streams open for servers with enabled crash automations.
+func (m *Monitor) syncConsoles(cf *models.ControlFile) {
+	wanted := make(map[string]consoleTarget)
+	for _, rule := range cf.Automations {
+		if !rule.Enabled || rule.TriggerType != models.TriggerCrash {
+			continue
+		}
+		for _, serverID := range RuleServers(cf, rule.ServerID) {
+			for _, user := range cf.Users {
+				if user.UserUUID != rule.UserUUID || !isServerMonitored(user, serverID) {
+					continue
+				}
+				apiKey, err := m.getAPIKey(user)
+				if err != nil {
+					continue
+				}
+				if client, err := m.panels.For(user.PanelURL); err == nil {
+					wanted[serverID] = consoleTarget{client: client, apiKey: apiKey}
+				}
+			}
+		}
+	}
+	m.consoles.Sync(wanted)
+}
+
+func (m *Monitor) collectServer(ctx context.Context, client *pterodactyl.Client, apiKey, serverID string) (*models.ResourceSnapshot, error) {
+	res, err := client.FetchResources(ctx, apiKey, serverID)
 	if err != nil {
 		return nil, err
 	}
 
+	if res.IsSuspended && !m.monitorSuspended {
+		return m.suspendedSnapshot(serverID), nil
+	}
+
 	return &models.ResourceSnapshot{
-		ServerID:   serverID,
-		Timestamp:  time.Now(),
-		PowerState: res.CurrentState,
-		CPUPercent: res.Resources.CPUAbsolute,
-		MemBytes:   res.Resources.MemoryBytes,
-		MemLimit:   0, // Will be populated from server attributes if available
-		DiskBytes:  res.Resources.DiskBytes,
-		DiskLimit:  0,
-		NetRx:      res.Resources.NetworkRxBytes,
-		NetTx:      res.Resources.NetworkTxBytes,
-		UptimeMs:   res.Resources.Uptime,
+		ServerID:    serverID,
+		Timestamp:   m.clock.Now(),
+		PowerState:  res.CurrentState,
+		CPUPercent:  res.Resources.CPUAbsolute,
+		MemBytes:    res.Resources.MemoryBytes,
+		MemLimit:    0, // Will be populated from server attributes if available
+		DiskBytes:   res.Resources.DiskBytes,
+		DiskLimit:   0,
+		NetRx:       res.Resources.NetworkRxBytes,
+		NetTx:       res.Resources.NetworkTxBytes,
+		UptimeMs:    res.Resources.Uptime,
+		IsSuspended: res.IsSuspended,
 	}, nil
 }
 
+// evaluateStale runs a server's data_stale alerts when it could not be sampled, against
+// its last stored snapshot so the alert sees how old the data is.
+func (m *Monitor) evaluateStale(cf *models.ControlFile, user models.ControlUser, apiKey, serverID string) {
+	last := m.LatestSnapshot(serverID)
+	if last == nil {
+		// Not sampled since the agent started; fall back to the database
+		snap, err := m.db.GetLatestSnapshot(serverID)
+		if err != nil || snap == nil {
+			return
+		}
+		last = snap
+	}
+	m.alertEvaluator.EvaluateStale(m.ctx, user, apiKey, last, filterAlerts(cf, user.UserUUID, serverID))
+}
+
+// normalizeCPU scales absolute CPU usage (100% per core) to the server's core limit,
+// so 100% means the limit is saturated. Unlimited servers (0 cores) keep the absolute value.
+func normalizeCPU(absolute, cores float64) float64 {
+	if cores <= 0 {
+		return absolute
+	}
+	return absolute / cores
+}
+
+// suspensionRules keeps only the rules that watch for suspension.
+func suspensionRules(alerts []models.AlertRule, autos []models.AutomationRule) ([]models.AlertRule, []models.AutomationRule) {
+	var a []models.AlertRule
+	for _, r := range alerts {
+		if r.ConditionType == models.ConditionSuspended {
+			a = append(a, r)
+		}
+	}
+	var t []models.AutomationRule
+	for _, r := range autos {
+		if r.TriggerType == models.TriggerSuspended {
+			t = append(t, r)
+		}
+	}
+	return a, t
+}
+
+// suspendedSnapshot returns the minimal zero-usage snapshot recorded for suspended servers.
+func (m *Monitor) suspendedSnapshot(serverID string) *models.ResourceSnapshot {
+	return m.stateSnapshot(serverID, "suspended")
+}
+
+// stateSnapshot returns a zero-usage snapshot for a server the panel can't report on,
+// e.g. while it is installing.
+func (m *Monitor) stateSnapshot(serverID, state string) *models.ResourceSnapshot {
+	return &models.ResourceSnapshot{
+		ServerID:    serverID,
+		Timestamp:   m.clock.Now(),
+		PowerState:  state,
+		IsSuspended: state == "suspended",
+	}
+}
+
+// skipSuspended reports whether a known-suspended server should skip this cycle's API call.
+func (m *Monitor) skipSuspended(serverID string) bool {
+	m.suspendedMu.Lock()
+	defer m.suspendedMu.Unlock()
+
+	skips, ok := m.suspendedSkips[serverID]
+	if !ok {
+		return false
+	}
+	if skips+1 >= suspendedRecheckCycles {
+		m.suspendedSkips[serverID] = 0
+		return false // Time to probe again
+	}
+	m.suspendedSkips[serverID] = skips + 1
+	return true
+}
+
+// setSuspended records whether a server was found suspended on its last probe.
+func (m *Monitor) setSuspended(serverID string, suspended bool) {
+	m.suspendedMu.Lock()
+	defer m.suspendedMu.Unlock()
+
+	_, known := m.suspendedSkips[serverID]
+	if suspended && !known {
+		logging.Info("Server %s is suspended, polling every %d cycles", serverID, suspendedRecheckCycles)
+		m.suspendedSkips[serverID] = 0
+	} else if !suspended && known {
+		logging.Info("Server %s is no longer suspended, resuming normal sampling", serverID)
+		delete(m.suspendedSkips, serverID)
+	}
+}
+
+// inBackoff reports whether a failing server should be skipped this cycle.
+func (m *Monitor) inBackoff(serverID string) bool {
+	m.backoffMu.Lock()
+	defer m.backoffMu.Unlock()
+
+	b, ok := m.backoff[serverID]
+	if !ok || b.skipLeft == 0 {
+		return false
+	}
+	b.skipLeft--
+	return true
+}
+
+// recordFailure increases a server's backoff exponentially, warning only when backoff begins.
+func (m *Monitor) recordFailure(serverID, userUUID string, err error) {
+	m.backoffMu.Lock()
+	defer m.backoffMu.Unlock()
+
+	b, ok := m.backoff[serverID]
+	if !ok {
+		b = &serverBackoff{}
+		m.backoff[serverID] = b
+	}
+	b.failures++
+
+	skip := maxBackoffCycles
+	if b.failures <= 4 {
+		skip = 1 << (b.failures - 1) // 1, 2, 4, 8 cycles
+	}
+	b.skipLeft = skip
+
+	if b.failures == 1 {
+		logging.Warn("Failed to collect server %s for user %s: %s (backing off, retrying with increasing delay)", serverID, userUUID, describePanelError(err))
+	} else {
+		logging.Debug("Server %s failed %d times in a row, skipping %d cycles: %v", serverID, b.failures, skip, err)
+	}
+}
+
+// describePanelError explains common panel failures in terms an operator can act on.
+func describePanelError(err error) string {
+	var rateLimited *pterodactyl.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return "rate limited by the panel; consider a longer SAMPLING_INTERVAL"
+	}
+
+	var apiErr *pterodactyl.APIError
+	if !errors.As(err, &apiErr) {
+		return err.Error()
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusUnauthorized:
+		return "the panel rejected the user's API key (401); it may have been revoked"
+	case apiErr.StatusCode == http.StatusForbidden:
+		return "the user's API key has no access to this server (403)"
+	case apiErr.StatusCode == http.StatusNotFound:
+		return "server not found on the panel (404); it may have been deleted"
+	case apiErr.StatusCode >= 500:
+		return fmt.Sprintf("panel error %d: %s", apiErr.StatusCode, apiErr.Body)
+	default:
+		return err.Error()
+	}
+}
+
+// recordSuccess clears a server's backoff state.
+func (m *Monitor) recordSuccess(serverID string) {
+	m.backoffMu.Lock()
+	defer m.backoffMu.Unlock()
+
+	if b, ok := m.backoff[serverID]; ok {
+		logging.Info("Server %s recovered after %d failed attempts", serverID, b.failures)
+		delete(m.backoff, serverID)
+	}
+}
+
 func (m *Monitor) getAPIKey(user models.ControlUser) (string, error) {
 	m.mu.RLock()
 	cached, ok := m.apiKeyCache[user.UserUUID]
@@ -243,10 +669,19 @@ func (m *Monitor) updateStatus(cf *models.ControlFile, serversMonitored int) {
 	usersCount := 0
 	alertCount := 0
 	autoCount := 0
+	var snoozes []status.Snooze
+	var serverIDs []string
+	controlErr, _ := m.controlLoader.LastError()
 
 	if cf != nil {
 		controlVersion = cf.Version
 		usersCount = len(cf.Users)
+		for _, u := range cf.Users {
+			if isSnoozed(u, m.clock.Now()) {
+				snoozes = append(snoozes, status.Snooze{UserUUID: u.UserUUID, Until: u.SnoozeUntil})
+			}
+			serverIDs = append(serverIDs, monitoredServers(u)...)
+		}
 		for _, a := range cf.Alerts {
 			if a.Enabled {
 				alertCount++
@@ -260,33 +695,95 @@ func (m *Monitor) updateStatus(cf *models.ControlFile, serversMonitored int) {
 	}
 
 	m.statusWriter.Update(status.AgentStatus{
-		AgentVersion:      "1.0.0",
-		UptimeSeconds:     int64(time.Since(m.startTime).Seconds()),
-		LastSampleAt:      time.Now().Format(time.RFC3339),
-		ControlVersion:    controlVersion,
-		UsersCount:        usersCount,
-		ActiveAlerts:      alertCount,
-		ActiveAutomations: autoCount,
-		ServersMonitored:  serversMonitored,
+		AgentVersion:       "1.0.0",
+		UptimeSeconds:      int64(time.Since(m.startTime).Seconds()),
+		LastSampleAt:       m.LastSampleAt().Format(time.RFC3339),
+		ControlVersion:     controlVersion,
+		UsersCount:         usersCount,
+		ActiveAlerts:       alertCount,
+		ActiveAutomations:  autoCount,
+		ServersMonitored:   serversMonitored,
+		DBSizeBytes:        m.db.SizeBytes(),
+		Errors:             logging.RecentErrors(),
+		ActiveSnoozes:      snoozes,
+		ControlError:       controlErr,
+		AccessIssues:       m.access.issues(),
+		AutomationsEnabled: m.autoExecutor.Enabled(),
+		Paused:             m.Paused(),
+		OfflineServers:     m.alertEvaluator.offlineServers(serverIDs),
+		ServerNames:        m.serverNames(serverIDs),
+		PanelCalls:         m.panels.Stats(),
+		LastTestPush:       m.lastTestPush(),
 	})
 }
 
-func filterAlerts(all []models.AlertRule, userUUID, serverID string) []models.AlertRule {
+// monitoredServers returns the user's allowed servers minus excluded_servers,
+// or none when the user has monitoring_disabled set.
+func monitoredServers(user models.ControlUser) []string {
+	if user.MonitoringDisabled {
+		return nil
+	}
+	if len(user.ExcludedServers) == 0 {
+		return user.AllowedServers
+	}
+	excluded := make(map[string]bool, len(user.ExcludedServers))
+	for _, s := range user.ExcludedServers {
+		excluded[s] = true
+	}
+	var out []string
+	for _, s := range user.AllowedServers {
+		if !excluded[s] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// isServerMonitored reports whether serverID is among the user's monitored servers.
+func isServerMonitored(user models.ControlUser, serverID string) bool {
+	for _, s := range monitoredServers(user) {
+		if s == serverID {
+			return true
+		}
+	}
+	return false
+}
+
+// filterAlerts returns the user's enabled alerts for serverID. Group rules are expanded
+// into a copy targeting serverID, so downstream code only sees concrete servers.
+func filterAlerts(cf *models.ControlFile, userUUID, serverID string) []models.AlertRule {
 	var result []models.AlertRule
-	for _, a := range all {
-		if a.UserUUID == userUUID && a.ServerID == serverID && a.Enabled {
+	for _, a := range cf.Alerts {
+		if a.UserUUID == userUUID && a.Enabled && ruleTargets(cf, a.ServerID, serverID) {
+			a.ServerID = serverID
 			result = append(result, a)
 		}
 	}
 	return result
 }
 
-func filterAutomations(all []models.AutomationRule, userUUID, serverID string) []models.AutomationRule {
+// filterAutomations is filterAlerts for automation rules.
+func filterAutomations(cf *models.ControlFile, userUUID, serverID string) []models.AutomationRule {
 	var result []models.AutomationRule
-	for _, a := range all {
-		if a.UserUUID == userUUID && a.ServerID == serverID && a.Enabled {
+	for _, a := range cf.Automations {
+		if a.UserUUID == userUUID && a.Enabled && ruleTargets(cf, a.ServerID, serverID) {
+			a.ServerID = serverID
 			result = append(result, a)
 		}
 	}
 	return result
 }
+
+// serverNames maps server IDs to their panel names for status.json.
+func (m *Monitor) serverNames(serverIDs []string) map[string]string {
+	info, err := m.db.GetServerInfo(serverIDs)
+	if err != nil {
+		logging.Warn("Failed to read server names: %v", err)
+		return nil
+	}
+	names := make(map[string]string, len(info))
+	for id, si := range info {
+		names[id] = si.Name
+	}
+	return names
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestUsersOnTwoPanels(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	eu := newFakePanel(t)
+	restart := cpuRule("restart-eu", models.ActionRestart, nil)
+	restart.UserUUID, restart.ServerID = "user-2", "srv-eu"
+	tm := newTestMonitor(t, clk, nil, []models.AutomationRule{restart})
+
+	key, err := newTestCrypto(t).Encrypt("ptlc_eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf := tm.source.Get()
+	cf.Users = append(cf.Users, models.ControlUser{
+		UserUUID:        "user-2",
+		APIKeyEncrypted: key,
+		AllowedServers:  []string{"srv-eu"},
+		DeviceTokens:    []string{"token-2"},
+		PanelURL:        eu.srv.URL,
+	})
+	tm.source.Set(cf)
+
+	tm.sample()
+	clk.Advance(time.Minute)
+
+	// Each user's servers are polled on their own panel only
+	if tm.panel.resourceCalls("srv-1") != 1 || tm.panel.resourceCalls("srv-eu") != 0 {
+		t.Fatalf("default panel requests: %v", tm.panel.Requests())
+	}
+	if eu.resourceCalls("srv-eu") != 1 || eu.resourceCalls("srv-1") != 0 {
+		t.Fatalf("second panel requests: %v", eu.Requests())
+	}
+	snaps, err := tm.db.GetRecentSnapshots("srv-eu", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 1 {
+		t.Fatalf("stored %d srv-eu snapshots, want 1", len(snaps))
+	}
+
+	// Actions for the second user go to the second panel
+	tm.autoExecutor.Evaluate(context.Background(), cf.Users[1], "ptlc_eu", &models.ResourceSnapshot{
+		ServerID:   "srv-eu",
+		CPUPercent: 99,
+		PowerState: "running",
+		Timestamp:  clk.Now(),
+	}, []models.AutomationRule{restart})
+	if n := powerCalls(eu, "srv-eu"); n != 1 {
+		t.Fatalf("%d power calls on the second panel, want 1: %v", n, eu.Requests())
+	}
+	if n := powerCalls(tm.panel, "srv-eu"); n != 0 {
+		t.Fatalf("%d power calls for srv-eu on the default panel, want 0", n)
+	}
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+const mb = 1024 * 1024
+
+// netSnapshot is a running srv-1 with the given cumulative traffic counters, timestamped now.
+func netSnapshot(clk clock.Clock, rx, tx int64) *models.ResourceSnapshot {
+	s := testSnapshot(clk, 5)
+	s.NetRx, s.NetTx = rx, tx
+	return s
+}
+
+func TestNetworkRate(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	prev := netSnapshot(clk, 100*mb, 50*mb)
+	clk.Advance(10 * time.Second)
+
+	if got := networkRate(nil, netSnapshot(clk, 0, 0)); got != 0 {
+		t.Errorf("rate without a previous snapshot = %v, want 0", got)
+	}
+	if got := networkRate(prev, netSnapshot(clk, 120*mb, 60*mb)); got != 3 {
+		t.Errorf("rate = %v MB/s, want 3", got)
+	}
+	if got := networkRate(prev, netSnapshot(clk, 1*mb, 0)); got != 0 {
+		t.Errorf("rate after a counter reset = %v, want 0", got)
+	}
+	if got := networkRate(prev, prev); got != 0 {
+		t.Errorf("rate over zero elapsed time = %v, want 0", got)
+	}
+}
+
+func TestNetworkAlertTwoSnapshots(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	rule := models.AlertRule{
+		ID:            "net",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		ConditionType: models.ConditionNetwork,
+		Threshold:     2,
+		Enabled:       true,
+	}
+	eval := func(rx, tx int64) {
+		ae.Evaluate(context.Background(), testUser(), "key", netSnapshot(clk, rx, tx), []models.AlertRule{rule})
+	}
+
+	eval(100*mb, 100*mb)
+	if got := rec.Drain(); len(got) != 0 {
+		t.Fatal("fired on the first snapshot, which has no rate")
+	}
+	clk.Advance(10 * time.Second)
+	eval(120*mb, 110*mb) // 30 MB in 10s
+	got := rec.Drain()
+	if len(got) != 1 || !strings.Contains(got[0].Payload.Body, "3.0 MB/s") {
+		t.Fatalf("pushes %+v, want one at 3.0 MB/s", got)
+	}
+
+	clk.Advance(10 * time.Second)
+	eval(0, 0) // counter reset, e.g. after a restart
+	if got := rec.Drain(); len(got) != 0 {
+		t.Fatal("fired on a counter reset")
+	}
+}
+
+func TestNetworkTriggerTwoSnapshots(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, fp, _ := newTestExecutor(t, clk)
+	rule := models.AutomationRule{
+		ID:            "net-stop",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		TriggerType:   models.TriggerNetwork,
+		TriggerConfig: map[string]interface{}{"threshold": 2.0},
+		Action:        models.ActionStop,
+		Enabled:       true,
+	}
+	eval := func(rx int64) {
+		ae.Evaluate(context.Background(), testUser(), "key", netSnapshot(clk, rx, 0), []models.AutomationRule{rule})
+	}
+
+	eval(100 * mb)
+	clk.Advance(10 * time.Second)
+	eval(110 * mb) // 1 MB/s
+	if got := fp.Requests(); len(got) != 0 {
+		t.Fatalf("ran below the threshold: %v", got)
+	}
+	clk.Advance(10 * time.Second)
+	eval(150 * mb) // 4 MB/s
+	if got := fp.Bodies(); len(got) != 1 || got[0] != `{"signal":"stop"}` {
+		t.Fatalf("requests %v, want one stop", got)
+	}
+}
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+	"github.com/xyidactyl/agent/internal/status"
+)
+
+// Notifier delivers pushes to users' devices after applying snooze, quiet hours and
+// the per-user rate limit. The alert evaluator and automation executor share one, so
+// the rate limit and the quiet-hours queue cover both.
+type Notifier struct {
+	provider   push.Provider
+	deadTokens *status.DeadTokenWriter // optional
+	tokenPrune *status.TokenReconciler // optional
+	webhooks   *push.EventPoster       // optional, delivers to users' webhook_url
+	clock      clock.Clock
+	limit      *pushLimiter
+	quiet      quietQueue
+}
+
+// NewNotifier creates a notifier. Each user gets at most rateLimit pushes per minute
+// (0 = unlimited); payloads whose severity or event type is in rateExempt, e.g.
+// "critical", always go through. Events are posted to users' webhook_url through
+// webhooks when it is set.
+func NewNotifier(provider push.Provider, deadTokens *status.DeadTokenWriter, tokenPrune *status.TokenReconciler, webhooks *push.EventPoster, rateLimit int, rateExempt []string, clk clock.Clock) *Notifier {
+	return &Notifier{
+		provider:   provider,
+		deadTokens: deadTokens,
+		tokenPrune: tokenPrune,
+		webhooks:   webhooks,
+		clock:      clock.OrReal(clk),
+		limit:      newPushLimiter(rateLimit, rateExempt),
+		quiet:      quietQueue{held: make(map[string]*heldPushes)},
+	}
+}
+
+// send delivers a payload to every device token of a user.
+// A failing token never prevents delivery to the user's other devices;
+// tokens the provider reports as invalid are recorded for pruning.
+// Pushes over the user's rate limit are dropped; callers record history first.
+func (n *Notifier) send(ctx context.Context, user models.ControlUser, payload push.Payload) {
+	now := n.clock.Now()
+	if isSnoozed(user, now) {
+		logging.Debug("User %s is snoozed, suppressing %s push: %s", user.UserUUID, payload.EventType, payload.Title)
+		return
+	}
+	if n.holdForQuietHours(user, payload, now) {
+		return
+	}
+	if !n.limit.allow(user.UserUUID, payload, now) {
+		return
+	}
+
+	for _, token := range user.DeviceTokens {
+		err := n.provider.Send(ctx, token, payload)
+		if err == nil {
+			continue
+		}
+
+		if errors.Is(err, push.ErrTokenInvalid) {
+			logging.Warn("Push token %s for user %s is invalid, reporting for removal", truncateToken(token), user.UserUUID)
+			n.reportDeadToken(user.UserUUID, token)
+			continue
+		}
+
+		logging.Error("Failed to send %s push to token %s: %v", payload.EventType, truncateToken(token), err)
+	}
+}
+
+// reportDeadToken records a token the provider rejected as permanently invalid.
+func (n *Notifier) reportDeadToken(userUUID, token string) {
+	if n.deadTokens != nil {
+		n.deadTokens.Add(userUUID, token)
+	}
+	if n.tokenPrune != nil {
+		n.tokenPrune.Add(userUUID, token)
+	}
+}
+
+// reconcileTokens drops reported tokens that are gone from control.json and writes
+// token_prune.json if it changed.
+func (n *Notifier) reconcileTokens(cf *models.ControlFile) {
+	if n.deadTokens != nil {
+		n.deadTokens.Reconcile(cf)
+	}
+	if n.tokenPrune != nil {
+		n.tokenPrune.Reconcile(cf)
+	}
+}
+
+// isSnoozed reports whether the user has muted notifications past now.
+func isSnoozed(user models.ControlUser, now time.Time) bool {
+	return user.SnoozeUntil > 0 && now.Unix() < user.SnoozeUntil
+}
+
+// truncateToken shortens a device token for log output.
+func truncateToken(token string) string {
+	if len(token) > 16 {
+		return token[:16]
+	}
+	return token
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+	"github.com/xyidactyl/agent/internal/status"
+)
+
+// rejectingProvider reports one token as permanently invalid and records the rest.
+type rejectingProvider struct {
+	*push.RecordingProvider
+	invalid string
+}
+
+func (p rejectingProvider) Send(ctx context.Context, token string, payload push.Payload) error {
+	if token == p.invalid {
+		return push.ErrTokenInvalid
+	}
+	return p.RecordingProvider.Send(ctx, token, payload)
+}
+
+func TestInvalidTokenDoesNotBlockOtherDevices(t *testing.T) {
+	rec := push.NewRecordingProvider(false)
+	dir := t.TempDir()
+	prune := status.NewTokenReconciler(dir, clock.NewFake(testStart))
+	n := NewNotifier(rejectingProvider{rec, "dead"}, status.NewDeadTokenWriter(dir), prune, nil, 0, nil, clock.NewFake(testStart))
+	user := testUser()
+	user.DeviceTokens = []string{"dead", "live"}
+
+	n.send(context.Background(), user, push.Payload{Title: "t", EventType: "alert"})
+	if got := rec.Drain(); len(got) != 1 || got[0].Token != "live" {
+		t.Fatalf("deliveries %+v, want only the live token", got)
+	}
+
+	n.reconcileTokens(&models.ControlFile{Users: []models.ControlUser{user}})
+	data, err := os.ReadFile(filepath.Join(dir, "token_prune.json"))
+	if err != nil || !strings.Contains(string(data), `"token": "dead"`) {
+		t.Fatalf("token_prune.json = %q, %v", data, err)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, "dead_tokens.json"))
+	if err != nil || !strings.Contains(string(data), `"token": "dead"`) {
+		t.Fatalf("dead_tokens.json = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestOfflineDurationWaitsFullDuration(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	rule := models.AlertRule{
+		ID:            "offline",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		ConditionType: models.ConditionOfflineDuration,
+		Duration:      600,
+		Enabled:       true,
+	}
+	sample := func(state string) int {
+		snap := testSnapshot(clk, 0)
+		snap.PowerState = state
+		ae.Evaluate(context.Background(), testUser(), "key", snap, []models.AlertRule{rule})
+		clk.Advance(time.Minute)
+		return len(rec.Drain())
+	}
+
+	sample("running")
+	for i := 0; i < 10; i++ {
+		if n := sample("offline"); n != 0 {
+			t.Fatalf("pushed after %d offline minutes, want nothing before 10", i)
+		}
+	}
+	if n := sample("offline"); n != 1 {
+		t.Fatalf("pushes after 10 offline minutes = %d, want 1", n)
+	}
+
+	// Recovering resets the hold, so the next outage waits the full duration again
+	sample("running")
+	for i := 0; i < 10; i++ {
+		if n := sample("stopped"); n != 0 {
+			t.Fatalf("pushed %d minutes into the second outage, want the hold to restart", i)
+		}
+	}
+	if n := sample("stopped"); n != 1 {
+		t.Fatalf("pushes after the second outage = %d, want 1", n)
+	}
+}
+
+func TestOfflineDurationBriefOutage(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	rule := models.AlertRule{
+		ID:            "offline",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		ConditionType: models.ConditionOfflineDuration,
+		Duration:      600,
+		Enabled:       true,
+	}
+
+	// Flapping offline for under the duration never alerts
+	for i := 0; i < 20; i++ {
+		snap := testSnapshot(clk, 0)
+		if i%5 != 4 {
+			snap.PowerState = "offline"
+		}
+		ae.Evaluate(context.Background(), testUser(), "key", snap, []models.AlertRule{rule})
+		clk.Advance(2 * time.Minute)
+	}
+	if pushes := rec.Drain(); len(pushes) != 0 {
+		t.Fatalf("pushes = %+v, want none for outages shorter than the duration", pushes)
+	}
+}
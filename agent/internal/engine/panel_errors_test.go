@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/pterodactyl"
+)
+
+func TestDescribePanelError(t *testing.T) {
+	wrap := func(code int) error {
+		return fmt.Errorf("fetch resources: %w", &pterodactyl.APIError{StatusCode: code, Body: "body"})
+	}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{wrap(http.StatusUnauthorized), "rejected the user's API key (401)"},
+		{wrap(http.StatusForbidden), "no access to this server (403)"},
+		{wrap(http.StatusNotFound), "server not found on the panel (404)"},
+		{wrap(http.StatusBadGateway), "panel error 502: body"},
+		{&pterodactyl.RateLimitedError{}, "rate limited by the panel"},
+		{wrap(http.StatusTeapot), "API error 418: body"},
+		{errors.New("dial tcp: connection refused"), "connection refused"},
+	}
+	for _, tc := range tests {
+		if got := describePanelError(tc.err); !strings.Contains(got, tc.want) {
+			t.Errorf("describePanelError(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+)
+
+func TestStatusReportsPanelCalls(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	tm := newTestMonitor(t, clk, nil, nil)
+	tm.panel.serveResources(func(id string) (int, string) {
+		if id == "srv-2" {
+			return http.StatusNotFound, `{"errors":[{"code":"NotFoundHttpException","status":"404","detail":"not found"}]}`
+		}
+		return http.StatusOK, resourcesBody("running", 5, false)
+	})
+
+	tm.sample()
+
+	for _, s := range tm.readStatus(t).PanelCalls {
+		if s.Endpoint == "GET /api/client/servers/:id/resources" {
+			if s.Calls != 2 || s.Errors != 1 {
+				t.Fatalf("resources: %d calls, %d errors, want 2 and 1", s.Calls, s.Errors)
+			}
+			return
+		}
+	}
+	t.Fatalf("status.json has no resources endpoint in %+v", tm.readStatus(t).PanelCalls)
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"strconv"
+
+	"github.com/xyidactyl/agent/internal/logging"
+)
+
+// MonitorPausedKey is the agent_state key holding whether sampling is paused.
+// It persists across restarts so a maintenance pause is not undone by a redeploy.
+const MonitorPausedKey = "monitor_paused"
+
+// Pause stops sampling until Resume. Cleanup and the control loader keep running,
+// and in-memory evaluator state is kept.
+func (m *Monitor) Pause() error {
+	return m.setPaused(true)
+}
+
+// Resume restarts sampling from the next tick.
+func (m *Monitor) Resume() error {
+	return m.setPaused(false)
+}
+
+// Paused reports whether sampling is paused.
+func (m *Monitor) Paused() bool {
+	return m.paused.Load()
+}
+
+func (m *Monitor) setPaused(paused bool) error {
+	if err := m.db.SetState(MonitorPausedKey, strconv.FormatBool(paused)); err != nil {
+		return err
+	}
+	m.applyPaused(paused)
+	return nil
+}
+
+// refreshPaused picks up pause changes written to agent_state by other processes.
+func (m *Monitor) refreshPaused() bool {
+	val, err := m.db.GetState(MonitorPausedKey)
+	if err != nil {
+		logging.Warn("Failed to read pause state, keeping current state: %v", err)
+		return m.Paused()
+	}
+	paused, _ := strconv.ParseBool(val) // missing or invalid = not paused
+	m.applyPaused(paused)
+	return paused
+}
+
+func (m *Monitor) applyPaused(paused bool) {
+	if m.paused.Swap(paused) == paused {
+		return
+	}
+	if paused {
+		logging.Warn("Sampling paused; alerts and automations are not evaluated until resumed")
+	} else {
+		logging.Info("Sampling resumed")
+	}
+	m.updateStatus(m.controlLoader.Get(), int(m.lastMonitored.Load()))
+}
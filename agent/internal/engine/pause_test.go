@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+)
+
+func resourceRequests(fp *fakePanel) int {
+	n := 0
+	for _, r := range fp.Requests() {
+		if strings.HasSuffix(r, "/resources") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPauseSkipsSampling(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	tm.interval = 10 * time.Millisecond
+
+	if err := tm.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	tm.Start()
+	time.Sleep(20 * tm.interval)
+
+	if !tm.LastSampleAt().IsZero() {
+		t.Fatal("a sampling pass ran while paused")
+	}
+	if n := resourceRequests(tm.panel); n != 0 {
+		t.Fatalf("%d resource requests while paused", n)
+	}
+
+	if err := tm.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for tm.LastSampleAt().IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("no sampling pass after resume")
+		}
+		time.Sleep(tm.interval)
+	}
+
+	// Let the loop wind down before the database is closed
+	tm.Stop()
+	time.Sleep(5 * tm.interval)
+}
+
+func TestPausePersistsAcrossRestarts(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	if err := tm.Pause(); err != nil {
+		t.Fatal(err)
+	}
+
+	tm.paused.Store(false) // as after a restart
+	if !tm.refreshPaused() || !tm.Paused() {
+		t.Fatal("pause state not restored from agent_state")
+	}
+}
+
+func TestPauseKeepsServersMonitored(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	tm.sample()
+
+	if err := tm.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	st := tm.readStatus(t)
+	if !st.Paused || st.ServersMonitored != 2 {
+		t.Fatalf("status after pause: paused=%t servers_monitored=%d, want true and 2", st.Paused, st.ServersMonitored)
+	}
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+// maxQuietHeld caps the pushes queued per user during quiet hours; older ones are dropped.
+const maxQuietHeld = 50
+
+// heldPushes are one user's pushes queued during quiet hours (mode "queue").
+type heldPushes struct {
+	user     models.ControlUser
+	payloads []push.Payload
+}
+
+// quietQueue holds pushes queued during quiet hours until their window ends.
+type quietQueue struct {
+	mu   sync.Mutex
+	held map[string]*heldPushes // user_uuid -> queued pushes
+}
+
+// inQuietHours reports whether now falls in the user's quiet window. The window is
+// compared in the user's wall-clock time, so it follows DST shifts. A malformed
+// window (the loader rejects these) fails open so alerts are never lost silently.
+func inQuietHours(user models.ControlUser, now time.Time) bool {
+	q := user.QuietHours
+	if q == nil {
+		return false
+	}
+	in, err := inTimeWindow(q.Start, q.End, q.TZ, now)
+	if err != nil {
+		logging.Warn("User %s: invalid quiet_hours, ignoring: %v", user.UserUUID, err)
+		return false
+	}
+	return in
+}
+
+// holdForQuietHours reports whether a push must not be sent now because of the
+// user's quiet hours, queueing it when the window is in "queue" mode. Critical
+// alerts always go through.
+func (n *Notifier) holdForQuietHours(user models.ControlUser, payload push.Payload, now time.Time) bool {
+	if payload.Severity == "critical" || !inQuietHours(user, now) {
+		return false
+	}
+
+	if user.QuietHours.Mode != "queue" {
+		logging.Debug("User %s is in quiet hours, dropping %s push: %s", user.UserUUID, payload.EventType, payload.Title)
+		return true
+	}
+
+	n.quiet.mu.Lock()
+	defer n.quiet.mu.Unlock()
+
+	h, ok := n.quiet.held[user.UserUUID]
+	if !ok {
+		h = &heldPushes{}
+		n.quiet.held[user.UserUUID] = h
+	}
+	h.user = user
+	h.payloads = append(h.payloads, payload)
+	if len(h.payloads) > maxQuietHeld {
+		h.payloads = h.payloads[len(h.payloads)-maxQuietHeld:]
+	}
+	logging.Debug("User %s is in quiet hours, queueing %s push: %s", user.UserUUID, payload.EventType, payload.Title)
+	return true
+}
+
+// releaseQuietHeld sends each user's queued pushes as one summary once their quiet
+// hours are over.
+func (n *Notifier) releaseQuietHeld(ctx context.Context) {
+	now := n.clock.Now()
+	n.quiet.mu.Lock()
+	var due []*heldPushes
+	for id, h := range n.quiet.held {
+		if !inQuietHours(h.user, now) {
+			due = append(due, h)
+			delete(n.quiet.held, id)
+		}
+	}
+	n.quiet.mu.Unlock()
+
+	for _, h := range due {
+		logging.Info("Quiet hours over for user %s, delivering %d queued notifications", h.user.UserUUID, len(h.payloads))
+		n.send(ctx, h.user, coalescePayloads(h.payloads, now))
+	}
+}
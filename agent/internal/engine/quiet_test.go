@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestInQuietHours(t *testing.T) {
+	utc := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	tests := []struct {
+		name string
+		q    *models.QuietHours
+		now  time.Time
+		want bool
+	}{
+		{"none", nil, utc("2026-01-05T03:00:00Z"), false},
+		{"overnight, after midnight", &models.QuietHours{Start: "22:00", End: "07:00"}, utc("2026-01-05T03:00:00Z"), true},
+		{"overnight, before midnight", &models.QuietHours{Start: "22:00", End: "07:00"}, utc("2026-01-05T23:30:00Z"), true},
+		{"overnight, daytime", &models.QuietHours{Start: "22:00", End: "07:00"}, utc("2026-01-05T12:00:00Z"), false},
+		{"end is exclusive", &models.QuietHours{Start: "22:00", End: "07:00"}, utc("2026-01-05T07:00:00Z"), false},
+		// 22:00-07:00 in New York is 03:00-12:00 UTC in winter and 02:00-11:00 UTC in summer
+		{"winter time", &models.QuietHours{Start: "22:00", End: "07:00", TZ: "America/New_York"}, utc("2026-01-05T11:30:00Z"), true},
+		{"summer time", &models.QuietHours{Start: "22:00", End: "07:00", TZ: "America/New_York"}, utc("2026-07-05T11:30:00Z"), false},
+		// Clocks spring forward from 02:00 to 03:00 on 2026-03-08; 02:00-03:00 never happens
+		{"skipped hour, before", &models.QuietHours{Start: "01:00", End: "03:00", TZ: "America/New_York"}, utc("2026-03-08T06:30:00Z"), true},
+		{"skipped hour, after", &models.QuietHours{Start: "01:00", End: "03:00", TZ: "America/New_York"}, utc("2026-03-08T07:30:00Z"), false},
+		{"malformed fails open", &models.QuietHours{Start: "25:00", End: "07:00"}, utc("2026-01-05T03:00:00Z"), false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			user := testUser()
+			user.QuietHours = tc.q
+			if got := inQuietHours(user, tc.now); got != tc.want {
+				t.Fatalf("inQuietHours = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// quietRules are a critical and a warning CPU alert on srv-1.
+func quietRules() []models.AlertRule {
+	critical := cpuAlert(50, 0, 0)
+	critical.ID = "cpu-critical"
+	critical.Severity = "critical"
+	warning := cpuAlert(50, 0, 0)
+	warning.ID = "cpu-warning"
+	warning.Severity = "warning"
+	return []models.AlertRule{critical, warning}
+}
+
+func TestQuietHoursDeliverOnlyCritical(t *testing.T) {
+	clk := clock.NewFake(testStart) // 12:00 UTC
+	ae, rec := newTestEvaluator(t, clk)
+	user := testUser()
+	user.QuietHours = &models.QuietHours{Start: "11:00", End: "13:00"}
+
+	ae.Evaluate(context.Background(), user, "key", testSnapshot(clk, 90), quietRules())
+	ae.Flush(context.Background())
+
+	pushes := rec.Drain()
+	if len(pushes) != 1 || pushes[0].Payload.Severity != "critical" {
+		t.Fatalf("pushes = %+v, want only the critical alert", pushes)
+	}
+	// The suppressed warning is still in the history
+	history, err := ae.db.GetAlertHistory("user-1", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("alert_history has %d entries, want both alerts", len(history))
+	}
+
+	// Outside the window the warning goes out as usual
+	clk.Advance(2 * time.Hour)
+	ae.Evaluate(context.Background(), user, "key", testSnapshot(clk, 90), quietRules())
+	if pushes := rec.Drain(); len(pushes) != 2 {
+		t.Fatalf("pushes after quiet hours = %+v, want both alerts", pushes)
+	}
+}
+
+func TestQuietHoursQueueMode(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	user := testUser()
+	user.QuietHours = &models.QuietHours{Start: "11:00", End: "13:00", Mode: "queue"}
+	warning := quietRules()[1:]
+
+	for i := 0; i < 3; i++ {
+		ae.Evaluate(context.Background(), user, "key", testSnapshot(clk, 90), warning)
+		ae.Flush(context.Background())
+		clk.Advance(10 * time.Minute)
+	}
+	if pushes := rec.Drain(); len(pushes) != 0 {
+		t.Fatalf("pushes during quiet hours = %+v, want them queued", pushes)
+	}
+
+	// The first flush after the window delivers one summary
+	clk.Advance(time.Hour)
+	ae.Flush(context.Background())
+	pushes := rec.Drain()
+	if len(pushes) != 1 || pushes[0].Payload.Title != "⚠️ 3 alerts" {
+		t.Fatalf("pushes after quiet hours = %+v, want one summary of 3 alerts", pushes)
+	}
+	ae.Flush(context.Background())
+	if pushes := rec.Drain(); len(pushes) != 0 {
+		t.Fatalf("summary delivered twice: %+v", pushes)
+	}
+}
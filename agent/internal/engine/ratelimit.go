@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+// pushBucket is one user's token bucket.
+type pushBucket struct {
+	tokens  float64
+	updated time.Time
+	dropped int // pushes dropped since the last one allowed, for logging
+}
+
+// pushLimiter caps outbound pushes per user so a flapping rule cannot flood devices.
+type pushLimiter struct {
+	mu        sync.Mutex
+	perMinute int             // bucket size and refill per minute, 0 = unlimited
+	exempt    map[string]bool // severities and event types that bypass the limit
+	buckets   map[string]*pushBucket
+}
+
+// newPushLimiter limits each user to perMinute pushes per minute (0 = unlimited).
+// Payloads whose severity or event type is listed in exempt always go through.
+func newPushLimiter(perMinute int, exempt []string) *pushLimiter {
+	l := &pushLimiter{
+		perMinute: perMinute,
+		exempt:    make(map[string]bool, len(exempt)),
+		buckets:   make(map[string]*pushBucket),
+	}
+	for _, e := range exempt {
+		l.exempt[e] = true
+	}
+	return l
+}
+
+// allow reports whether a push to userUUID may be sent now, consuming a token if so.
+func (l *pushLimiter) allow(userUUID string, payload push.Payload, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.perMinute <= 0 || l.exempt[payload.Severity] || l.exempt[payload.EventType] {
+		return true
+	}
+
+	capacity := float64(l.perMinute)
+	b, ok := l.buckets[userUUID]
+	if !ok {
+		b = &pushBucket{tokens: capacity, updated: now}
+		l.buckets[userUUID] = b
+	}
+	b.tokens += now.Sub(b.updated).Minutes() * capacity
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	b.updated = now
+
+	if b.tokens < 1 {
+		if b.dropped == 0 {
+			logging.Warn("User %s exceeded %d pushes/minute, dropping pushes until the rate falls", userUUID, l.perMinute)
+		}
+		b.dropped++
+		logging.Debug("Rate limited %s push for user %s: %s", payload.EventType, userUUID, payload.Title)
+		return false
+	}
+	b.tokens--
+	if b.dropped > 0 {
+		logging.Info("User %s push rate back under limit, %d pushes were dropped", userUUID, b.dropped)
+		b.dropped = 0
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+func TestPushLimiterCapsFlood(t *testing.T) {
+	l := newPushLimiter(10, []string{"critical", "alert_recovery"})
+	alert := push.Payload{EventType: "alert", Severity: "warning"}
+
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		if l.allow("user-1", alert, testStart) {
+			allowed++
+		}
+	}
+	if allowed != 10 {
+		t.Fatalf("%d of 100 pushes allowed in one instant, want the cap of 10", allowed)
+	}
+
+	// Exempt severities and event types bypass an empty bucket
+	for _, p := range []push.Payload{
+		{EventType: "alert", Severity: "critical"},
+		{EventType: "alert_recovery", Severity: "info"},
+	} {
+		if !l.allow("user-1", p, testStart) {
+			t.Fatalf("exempt push %+v was rate limited", p)
+		}
+	}
+
+	// Other users have their own bucket
+	if !l.allow("user-2", alert, testStart) {
+		t.Fatal("user-2 limited by user-1's flood")
+	}
+
+	// The bucket refills at the cap per minute
+	allowed = 0
+	for i := 0; i < 100; i++ {
+		if l.allow("user-1", alert, testStart.Add(30*time.Second)) {
+			allowed++
+		}
+	}
+	if allowed != 5 {
+		t.Fatalf("%d pushes allowed after 30s, want 5", allowed)
+	}
+}
+
+func TestPushLimiterUnlimited(t *testing.T) {
+	l := newPushLimiter(0, nil)
+	for i := 0; i < 100; i++ {
+		if !l.allow("user-1", push.Payload{EventType: "alert"}, testStart) {
+			t.Fatalf("push %d limited with no limit configured", i)
+		}
+	}
+}
+
+func TestFlappingAlertRateLimited(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	fp := newFakePanel(t)
+	rec := push.NewRecordingProvider(false)
+	db := openTestDB(t)
+	ae := NewAlertEvaluator(db, fp.panels, NewNotifier(rec, nil, nil, nil, 10, []string{"critical"}, clk), false, clk)
+	flapping := cpuAlert(80, 0, 0)
+	critical := cpuAlert(80, 0, 0)
+	critical.ID, critical.Severity = "cpu-critical", "critical"
+
+	// 30 triggers of each rule within 30 seconds
+	for i := 0; i < 30; i++ {
+		ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), []models.AlertRule{flapping})
+		ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), []models.AlertRule{critical})
+		clk.Advance(time.Second)
+	}
+
+	counts := map[string]int{}
+	for _, d := range rec.Drain() {
+		counts[d.Payload.Severity]++
+	}
+	// 10 up front, plus one refilled token every 6 seconds
+	if counts["warning"] < 10 || counts["warning"] > 15 {
+		t.Fatalf("%d warning pushes delivered, want the cap to hold", counts["warning"])
+	}
+	if counts["critical"] != 30 {
+		t.Fatalf("%d critical pushes delivered, want all 30", counts["critical"])
+	}
+
+	// Dropped pushes are still recorded in history
+	history, err := db.GetAlertHistory("user-1", 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 60 {
+		t.Fatalf("%d history entries, want all 60 triggers", len(history))
+	}
+}
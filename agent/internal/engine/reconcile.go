@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/database"
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/pterodactyl"
+	"github.com/xyidactyl/agent/internal/status"
+)
+
+// accessRecheckInterval is how long a user's server access check stays valid
+// when control.json does not change.
+const accessRecheckInterval = time.Hour
+
+// accessReconciler checks that each user's allowed_servers are visible to their API key,
+// and records the names and limits of the visible ones in the servers table. CPU limits
+// are also kept in memory to normalize CPU usage while sampling.
+// Results are cached per user and refreshed on control.json reload or after accessRecheckInterval.
+type accessReconciler struct {
+	db    *database.DB
+	clock clock.Clock
+
+	mu        sync.Mutex
+	checkedAt map[string]time.Time // user_uuid -> last completed check
+	running   map[string]bool      // user_uuid -> check in flight
+	missing   map[string][]string  // user_uuid -> allowed servers the key can't see
+	cores     map[string]float64   // server_id -> CPU limit in cores, 0 = unlimited
+}
+
+func newAccessReconciler(db *database.DB, clk clock.Clock) *accessReconciler {
+	return &accessReconciler{
+		db:        db,
+		clock:     clock.OrReal(clk),
+		checkedAt: make(map[string]time.Time),
+		running:   make(map[string]bool),
+		missing:   make(map[string][]string),
+		cores:     make(map[string]float64),
+	}
+}
+
+// cpuCores returns a server's CPU limit in cores, 0 if unlimited or not yet known.
+func (r *accessReconciler) cpuCores(serverID string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cores[serverID]
+}
+
+// invalidate forces every user to be rechecked on the next maybeCheck.
+func (r *accessReconciler) invalidate() {
+	r.mu.Lock()
+	r.checkedAt = make(map[string]time.Time)
+	r.mu.Unlock()
+}
+
+// maybeCheck starts a background check for the user if its cached result is stale.
+func (r *accessReconciler) maybeCheck(ctx context.Context, client *pterodactyl.Client, user models.ControlUser, apiKey string) {
+	r.mu.Lock()
+	if r.running[user.UserUUID] || r.clock.Now().Sub(r.checkedAt[user.UserUUID]) < accessRecheckInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.running[user.UserUUID] = true
+	r.mu.Unlock()
+
+	// Limits stored by an earlier run apply until the panel answers
+	if info, err := r.db.GetServerInfo(user.AllowedServers); err == nil {
+		r.mu.Lock()
+		for id, si := range info {
+			if _, ok := r.cores[id]; !ok {
+				r.cores[id] = si.CPUCores
+			}
+		}
+		r.mu.Unlock()
+	}
+
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.running, user.UserUUID)
+			r.mu.Unlock()
+		}()
+
+		servers, err := client.ListServers(ctx, apiKey)
+		if err != nil {
+			logging.Warn("Access check for user %s failed: %v", user.UserUUID, err)
+			return // Retried on the next cycle
+		}
+
+		visible := make(map[string]pterodactyl.ServerListItem)
+		for _, s := range servers {
+			visible[s.Identifier] = s
+			visible[s.UUID] = s
+		}
+		var missing []string
+		for _, serverID := range user.AllowedServers {
+			s, ok := visible[serverID]
+			if !ok {
+				missing = append(missing, serverID)
+				continue
+			}
+			// Panel limits are in MiB, and CPU in percent of one core
+			cores := float64(s.Limits.CPU) / 100
+			if err := r.db.UpsertServerInfo(serverID, s.Name, s.Limits.Memory*1024*1024, s.Limits.Disk*1024*1024, cores); err != nil {
+				logging.Warn("Failed to store info for server %s: %v", serverID, err)
+			}
+			r.mu.Lock()
+			r.cores[serverID] = cores
+			r.mu.Unlock()
+		}
+
+		if len(missing) > 0 {
+			logging.Warn("User %s: API key cannot access allowed servers %v; they will keep failing until control.json is fixed",
+				user.UserUUID, missing)
+		}
+
+		r.mu.Lock()
+		r.checkedAt[user.UserUUID] = r.clock.Now()
+		if len(missing) > 0 {
+			r.missing[user.UserUUID] = missing
+		} else {
+			delete(r.missing, user.UserUUID)
+		}
+		r.mu.Unlock()
+	}()
+}
+
+// prune drops results for users no longer in control.json.
+func (r *accessReconciler) prune(users []models.ControlUser) {
+	keep := make(map[string]bool, len(users))
+	for _, u := range users {
+		keep[u.UserUUID] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for uuid := range r.missing {
+		if !keep[uuid] {
+			delete(r.missing, uuid)
+		}
+	}
+}
+
+// issues returns the current discrepancies for status.json, sorted by user.
+func (r *accessReconciler) issues() []status.AccessIssue {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]status.AccessIssue, 0, len(r.missing))
+	for uuid, servers := range r.missing {
+		out = append(out, status.AccessIssue{UserUUID: uuid, ServerIDs: servers})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UserUUID < out[j].UserUUID })
+	return out
+}
@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/status"
+)
+
+// waitChecked waits for the user's background access check to finish.
+func waitChecked(t *testing.T, r *accessReconciler, userUUID string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mu.Lock()
+		done := !r.running[userUUID] && !r.checkedAt[userUUID].IsZero()
+		r.mu.Unlock()
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("access check did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// listCalls counts the ListServers requests the fake panel received.
+func listCalls(fp *fakePanel) int {
+	n := 0
+	for _, r := range fp.Requests() {
+		if r == "GET /api/client" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestAccessReconcilerReportsMissingServers(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	fp := newFakePanel(t) // lists srv-1 and srv-2
+	r := newAccessReconciler(openTestDB(t), clk)
+	user := testUser()
+	user.AllowedServers = []string{"srv-1", "srv-3"}
+
+	r.maybeCheck(context.Background(), fp.panels.Default(), user, "key")
+	waitChecked(t, r, user.UserUUID)
+
+	want := []status.AccessIssue{{UserUUID: "user-1", ServerIDs: []string{"srv-3"}}}
+	if got := r.issues(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("issues = %+v, want %+v", got, want)
+	}
+
+	// Fixing control.json clears the issue once rechecked
+	user.AllowedServers = []string{"srv-1"}
+	r.invalidate()
+	r.maybeCheck(context.Background(), fp.panels.Default(), user, "key")
+	waitChecked(t, r, user.UserUUID)
+	if got := r.issues(); len(got) != 0 {
+		t.Fatalf("issues = %+v after fixing allowed_servers, want none", got)
+	}
+}
+
+func TestAccessReconcilerCachesResults(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	fp := newFakePanel(t)
+	r := newAccessReconciler(openTestDB(t), clk)
+	user := testUser()
+
+	r.maybeCheck(context.Background(), fp.panels.Default(), user, "key")
+	waitChecked(t, r, user.UserUUID)
+	r.maybeCheck(context.Background(), fp.panels.Default(), user, "key")
+	if n := listCalls(fp); n != 1 {
+		t.Fatalf("%d ListServers calls for two checks within the cache window, want 1", n)
+	}
+
+	clk.Advance(accessRecheckInterval)
+	r.maybeCheck(context.Background(), fp.panels.Default(), user, "key")
+	deadline := time.Now().Add(5 * time.Second)
+	for listCalls(fp) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d ListServers calls after the cache expired, want 2", listCalls(fp))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAccessReconcilerPrunesRemovedUsers(t *testing.T) {
+	r := newAccessReconciler(openTestDB(t), clock.NewFake(testStart))
+	r.missing["gone"] = []string{"srv-9"}
+	r.missing["user-1"] = []string{"srv-3"}
+
+	r.prune([]models.ControlUser{testUser()})
+	if got := r.issues(); len(got) != 1 || got[0].UserUUID != "user-1" {
+		t.Fatalf("issues = %+v, want only user-1", got)
+	}
+}
+
+func TestMonitorKeepsSamplingAccessibleServers(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	cf := tm.source.Get()
+	cf.Users[0].AllowedServers = []string{"srv-1", "srv-3"}
+	tm.source.Set(cf)
+
+	tm.sample()
+	waitChecked(t, tm.access, "user-1")
+	tm.sample()
+
+	if n := tm.panel.resourceCalls("srv-1"); n != 2 {
+		t.Errorf("srv-1 polled %d times, want 2", n)
+	}
+	st := tm.readStatus(t)
+	if len(st.AccessIssues) != 1 || !reflect.DeepEqual(st.AccessIssues[0].ServerIDs, []string{"srv-3"}) {
+		t.Fatalf("access_issues = %+v, want srv-3", st.AccessIssues)
+	}
+}
+
+func TestMonitorReportsServerNames(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	tm.panel.mu.Lock()
+	tm.panel.handler = func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/client" {
+			fmt.Fprint(w, `{"data":[{"attributes":{"identifier":"srv-1","name":"survival","limits":{"memory":2048,"disk":10240,"cpu":200}}},{"attributes":{"identifier":"srv-2","name":"creative","limits":{"memory":0,"disk":0,"cpu":0}}}],"meta":{"pagination":{"total":2,"current_page":1,"total_pages":1}}}`)
+			return
+		}
+		fmt.Fprint(w, resourcesBody("running", 10, false))
+	}
+	tm.panel.mu.Unlock()
+
+	tm.sample()
+	waitChecked(t, tm.access, "user-1")
+	tm.sample()
+
+	want := map[string]string{"srv-1": "survival", "srv-2": "creative"}
+	if got := tm.readStatus(t).ServerNames; !reflect.DeepEqual(got, want) {
+		t.Fatalf("server_names = %v, want %v", got, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tm.dataDir, "metrics.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var export status.MetricsExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		t.Fatal(err)
+	}
+	si := export.ServerInfo["srv-1"]
+	if si.Name != "survival" || si.MemLimit != 2048<<20 || si.DiskLimit != 10240<<20 || si.CPUCores != 2 {
+		t.Fatalf("metrics server_info[srv-1] = %+v", si)
+	}
+}
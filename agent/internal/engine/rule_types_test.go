@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// Every condition the loader accepts must be handled by measure (composite rules are
+// evaluated by evaluateComposite instead), so a renamed constant cannot silently fall
+// through to the unknown-condition branch.
+func TestMeasureKnowsEveryCondition(t *testing.T) {
+	ae := newAlertState(clock.NewFake(testStart))
+	ae.db = openTestDB(t) // disk_trend reads history
+	snap := testSnapshot(ae.clock, 50)
+	for _, c := range []models.ConditionType{
+		models.ConditionCPU, models.ConditionCPUNormalized, models.ConditionRAM, models.ConditionDisk,
+		models.ConditionRAMBytes, models.ConditionDiskBytes, models.ConditionNetwork, models.ConditionCPUSpike,
+		models.ConditionUptime, models.ConditionUptimeReset, models.ConditionDiskTrend, models.ConditionSuspended,
+		models.ConditionPowerStateChange, models.ConditionOfflineDuration, models.ConditionRestartLoop,
+		models.ConditionDataStale,
+	} {
+		if !c.Valid() {
+			t.Fatalf("%q not valid", c)
+		}
+		if _, _, known := ae.measure(c, 1, snap); !known {
+			t.Errorf("measure does not handle %q", c)
+		}
+	}
+	if _, _, known := ae.measure("cpu_treshold", 1, snap); known {
+		t.Error("measure accepted a typo")
+	}
+}
+
+func TestExecuteActionKnowsEveryAction(t *testing.T) {
+	ae, fp, _ := newTestExecutor(t, clock.NewFake(testStart))
+	client := fp.panels.Default()
+	for _, a := range []models.ActionType{
+		models.ActionRestart, models.ActionStop, models.ActionStart, models.ActionKill,
+		models.ActionCommand, models.ActionBackup, models.ActionReinstall, models.ActionPower,
+	} {
+		rule := models.AutomationRule{ID: "r", ServerID: "srv-1", Action: a, ActionConfig: map[string]interface{}{}}
+		err := ae.executeAction(context.Background(), client, "key", rule)
+		if err != nil && strings.HasPrefix(err.Error(), "unknown action") {
+			t.Errorf("executeAction does not handle %q", a)
+		}
+	}
+	rule := models.AutomationRule{ID: "r", ServerID: "srv-1", Action: "reboot"}
+	if err := ae.executeAction(context.Background(), client, "key", rule); err == nil {
+		t.Error("executeAction accepted a typo")
+	}
+}
+
+func TestEvaluateTriggerUsesTypedConstants(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, _, _ := newTestExecutor(t, clk)
+	snap := testSnapshot(clk, 95)
+	rule := models.AutomationRule{TriggerType: models.TriggerCPU, TriggerConfig: map[string]interface{}{"threshold": 90.0}}
+	if !ae.evaluateTrigger(rule, snap) {
+		t.Error("cpu trigger above threshold did not fire")
+	}
+	snap.PowerState = "offline"
+	if !ae.evaluateTrigger(models.AutomationRule{TriggerType: models.TriggerOffline}, snap) {
+		t.Error("offline trigger did not fire")
+	}
+	if ae.evaluateTrigger(models.AutomationRule{TriggerType: "server_ofline"}, snap) {
+		t.Error("typo trigger fired")
+	}
+}
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+)
+
+// waitResourceCalls waits until the fake panel has served n /resources calls for srv-1.
+func waitResourceCalls(t *testing.T, fp *fakePanel, n int, within time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(within)
+	for fp.resourceCalls("srv-1") < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("srv-1 polled %d times after %s, want %d", fp.resourceCalls("srv-1"), within, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSampleNowAfterVersionBump(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	tm.interval = time.Hour // only the initial and on-demand passes run
+	tm.Start()
+	waitResourceCalls(t, tm.panel, 1, 5*time.Second)
+
+	cf := tm.source.Get()
+	cf.Version = 2
+	tm.source.Set(cf)
+
+	// A burst of reloads collapses into one pass, run well before the next tick
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		tm.SampleNow()
+	}
+	waitResourceCalls(t, tm.panel, 2, sampleNowDebounce+5*time.Second)
+	if elapsed := time.Since(start); elapsed < sampleNowDebounce {
+		t.Fatalf("on-demand pass ran after %s, want it debounced by %s", elapsed, sampleNowDebounce)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if n := tm.panel.resourceCalls("srv-1"); n != 2 {
+		t.Fatalf("srv-1 polled %d times, want one on-demand pass for the burst", n)
+	}
+	// status.json is written once the pass finishes
+	deadline := time.Now().Add(5 * time.Second)
+	for tm.readStatus(t).ControlVersion != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("status.json does not report the bumped control version")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+func TestServerStateSurvivesEvaluatorRestart(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	db := openTestDB(t)
+	fp := newFakePanel(t)
+	rule := models.AlertRule{
+		ID:            "power",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		ConditionType: models.ConditionPowerStateChange,
+		Enabled:       true,
+	}
+
+	first := NewAlertEvaluator(db, fp.panels, NewNotifier(push.NewRecordingProvider(false), nil, nil, nil, 0, nil, clk), false, clk)
+	offline := testSnapshot(clk, 0)
+	offline.PowerState = "offline"
+	first.Evaluate(context.Background(), testUser(), "key", offline, []models.AlertRule{rule})
+	wentOffline := clk.Now()
+	clk.Advance(time.Minute)
+	offline = testSnapshot(clk, 0)
+	offline.PowerState = "offline"
+	first.Evaluate(context.Background(), testUser(), "key", offline, []models.AlertRule{rule})
+	first.Flush(context.Background())
+
+	// The agent restarts while srv-1 is still down
+	clk.Advance(time.Hour)
+	rec := push.NewRecordingProvider(false)
+	second := NewAlertEvaluator(db, fp.panels, NewNotifier(rec, nil, nil, nil, 0, nil, clk), false, clk)
+
+	got := second.offlineServers([]string{"srv-1", "srv-2"})
+	if len(got) != 1 || got[0].Since != wentOffline.Format(time.RFC3339) || got[0].LastSeenAt != wentOffline.Add(time.Minute).Format(time.RFC3339) {
+		t.Fatalf("offline servers after restart = %+v, want srv-1 offline since %s", got, wentOffline)
+	}
+
+	second.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 5), []models.AlertRule{rule})
+	pushes := rec.Drain()
+	if len(pushes) != 1 || !strings.Contains(pushes[0].Payload.Body, "running") {
+		t.Fatalf("pushes %+v, want the recovery detected across the restart", pushes)
+	}
+}
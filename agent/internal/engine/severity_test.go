@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestSeverityReachesPushAndHistory(t *testing.T) {
+	for _, tt := range []struct {
+		severity, want, titlePrefix string
+	}{
+		{"", "warning", "⚠️"},
+		{"warning", "warning", "⚠️"},
+		{"critical", "critical", "[CRITICAL] "},
+		{"info", "info", "[INFO] "},
+	} {
+		clk := clock.NewFake(testStart)
+		ae, rec := newTestEvaluator(t, clk)
+		rule := cpuAlert(90, 0, 0)
+		rule.Severity = tt.severity
+
+		ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), []models.AlertRule{rule})
+		got := rec.Drain()
+		if len(got) != 1 {
+			t.Fatalf("severity %q: %d pushes", tt.severity, len(got))
+		}
+		if p := got[0].Payload; p.Severity != tt.want || !strings.HasPrefix(p.Title, tt.titlePrefix) {
+			t.Errorf("severity %q: payload severity %q title %q", tt.severity, p.Severity, p.Title)
+		}
+		history, err := ae.db.GetAlertHistory("user-1", 0, 1)
+		if err != nil || len(history) != 1 || history[0].Severity != tt.want {
+			t.Errorf("severity %q: history %+v, %v", tt.severity, history, err)
+		}
+	}
+}
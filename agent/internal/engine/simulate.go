@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// replayHistory stands in for the snapshot table while Simulate replays a server.
+type replayHistory struct {
+	snaps []models.ResourceSnapshot
+}
+
+// recent returns up to the last n replayed snapshots, oldest first.
+func (h *replayHistory) recent(n int) []models.ResourceSnapshot {
+	if len(h.snaps) > n {
+		return h.snaps[len(h.snaps)-n:]
+	}
+	return h.snaps
+}
+
+// Simulate replays stored snapshots since the given time through alert rules and
+// returns the alerts that would have fired. Nothing is sent or written to history.
+// Rules are evaluated as if enabled; group targets use the current control file.
+func (m *Monitor) Simulate(rules []models.AlertRule, since time.Time) ([]models.AlertHistoryEntry, error) {
+	cf := m.controlLoader.Get()
+
+	byServer := make(map[string][]models.AlertRule)
+	for _, rule := range rules {
+		for _, id := range RuleServers(cf, rule.ServerID) {
+			r := rule
+			r.ServerID = id
+			byServer[id] = append(byServer[id], r)
+		}
+	}
+
+	var out []models.AlertHistoryEntry
+	for id, serverRules := range byServer {
+		snaps, err := m.db.GetSnapshotsSince(id, since)
+		if err != nil {
+			return nil, fmt.Errorf("read snapshots for %s: %w", id, err)
+		}
+		out = append(out, replayAlerts(serverRules, snaps)...)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].TriggeredAt.Before(out[j].TriggeredAt) })
+	return out, nil
+}
+
+// replayAlerts evaluates one server's rules against its snapshots (oldest first)
+// on a fresh evaluator whose clock follows the snapshot timestamps.
+func replayAlerts(rules []models.AlertRule, snaps []models.ResourceSnapshot) []models.AlertHistoryEntry {
+	fake := clock.NewFake(time.Time{})
+	ae := newAlertState(fake)
+	ae.replayed = &replayHistory{}
+
+	var out []models.AlertHistoryEntry
+	for i := range snaps {
+		snap := &snaps[i]
+		fake.Set(snap.Timestamp)
+		ae.replayed.snaps = append(ae.replayed.snaps, *snap)
+
+		prevState := ae.previousState(snap.ServerID)
+		for _, rule := range rules {
+			f, ok := ae.check(rule, snap)
+			if !ok {
+				continue
+			}
+			out = append(out, models.AlertHistoryEntry{
+				RuleID:      rule.ID,
+				UserUUID:    rule.UserUUID,
+				ServerID:    rule.ServerID,
+				Condition:   string(rule.ConditionType),
+				Severity:    f.severity,
+				Value:       f.value,
+				TriggeredAt: snap.Timestamp,
+			})
+		}
+		ae.advance(snap, prevState)
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// storeCPUSeries stores one srv-1 snapshot per minute from testStart with the given CPU.
+func storeCPUSeries(t *testing.T, tm *testMonitor, cpus ...float64) {
+	t.Helper()
+	clk := clock.NewFake(testStart)
+	var snaps []models.ResourceSnapshot
+	for _, cpu := range cpus {
+		snaps = append(snaps, *testSnapshot(clk, cpu))
+		clk.Advance(time.Minute)
+	}
+	if err := tm.db.InsertSnapshots(snaps); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSimulateReplaysKnownSeries(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart.Add(time.Hour)), nil, nil)
+	storeCPUSeries(t, tm, 20, 95, 95, 95, 20, 20, 20, 95, 95)
+
+	// A 5 minute cooldown covers minutes 2-3; the next spike at minute 7 fires again
+	rule := cpuAlert(80, 0, 300)
+	got, err := tm.Simulate([]models.AlertRule{rule}, testStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("%d simulated triggers, want 2: %+v", len(got), got)
+	}
+	for i, minute := range []int{1, 7} {
+		e := got[i]
+		if !e.TriggeredAt.Equal(testStart.Add(time.Duration(minute)*time.Minute)) || e.RuleID != "cpu-high" || e.Value != 95 {
+			t.Fatalf("trigger %d = %+v, want cpu-high at minute %d", i, e, minute)
+		}
+	}
+
+	// Only snapshots since the given time are replayed
+	if got, _ := tm.Simulate([]models.AlertRule{rule}, testStart.Add(5*time.Minute)); len(got) != 1 {
+		t.Fatalf("%d triggers since minute 5, want 1", len(got))
+	}
+
+	// A held condition needs the full duration within the series
+	held := cpuAlert(80, 120, 0)
+	got, err = tm.Simulate([]models.AlertRule{held}, testStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !got[0].TriggeredAt.Equal(testStart.Add(3*time.Minute)) {
+		t.Fatalf("held rule triggers = %+v, want one at minute 3", got)
+	}
+}
+
+func TestSimulateHasNoSideEffects(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart.Add(time.Hour)), nil, nil)
+	storeCPUSeries(t, tm, 95, 95, 95)
+
+	got, err := tm.Simulate([]models.AlertRule{cpuAlert(80, 0, 0)}, testStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("%d simulated triggers, want 3", len(got))
+	}
+	if d := tm.push.Drain(); len(d) != 0 {
+		t.Fatalf("simulation sent %d pushes", len(d))
+	}
+	if history, _ := tm.db.GetAlertHistory("user-1", 0, 10); len(history) != 0 {
+		t.Fatalf("simulation wrote %d history entries", len(history))
+	}
+}
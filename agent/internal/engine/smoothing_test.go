@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// alertsOver counts the pushes rule sends over a CPU series sampled a minute apart.
+func alertsOver(t *testing.T, rule models.AlertRule, series []float64) (int, []string) {
+	t.Helper()
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	var bodies []string
+	for _, cpu := range series {
+		ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, cpu), []models.AlertRule{rule})
+		for _, p := range rec.Drain() {
+			bodies = append(bodies, p.Payload.Body)
+		}
+		clk.Advance(time.Minute)
+	}
+	return len(bodies), bodies
+}
+
+func TestSmoothingSuppressesNoise(t *testing.T) {
+	// Spikes above the threshold every other sample, averaging well below it
+	var noisy []float64
+	for i := 0; i < 20; i++ {
+		noisy = append(noisy, []float64{40, 95}[i%2])
+	}
+
+	raw := cpuAlert(80, 0, 0)
+	if n, _ := alertsOver(t, raw, noisy); n != 10 {
+		t.Fatalf("raw rule fired %d times on 10 spikes, want 10", n)
+	}
+	smoothed := raw
+	smoothed.Smoothing = 0.8
+	if n, _ := alertsOver(t, smoothed, noisy); n != 0 {
+		t.Fatalf("smoothed rule fired %d times on noise, want 0", n)
+	}
+}
+
+func TestSmoothingFollowsSustainedLoad(t *testing.T) {
+	series := []float64{40, 40, 40, 95, 95, 95, 95, 95, 95, 95, 95, 95}
+	rule := cpuAlert(80, 0, 3600)
+	rule.Smoothing = 0.5
+
+	n, bodies := alertsOver(t, rule, series)
+	if n != 1 {
+		t.Fatalf("smoothed rule fired %d times on sustained load, want 1", n)
+	}
+	// The EMA is 40 -> 67.5 -> 81.25 on the third high sample, and that value is reported
+	if !strings.Contains(bodies[0], "81") {
+		t.Fatalf("body = %q, want the smoothed value", bodies[0])
+	}
+
+	// Without smoothing the first high sample fires
+	rule.Smoothing = 0
+	if n, bodies := alertsOver(t, rule, series[:4]); n != 1 || !strings.Contains(bodies[0], "95") {
+		t.Fatalf("raw rule: %d pushes %q, want one at 95%%", n, bodies)
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestActiveSnoozeMutesButKeepsHistory(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	user := testUser()
+	user.SnoozeUntil = testStart.Add(2 * time.Hour).Unix()
+
+	ae.Evaluate(context.Background(), user, "key", testSnapshot(clk, 95), []models.AlertRule{cpuAlert(90, 0, 0)})
+	ae.Flush(context.Background())
+
+	if got := rec.Drain(); len(got) != 0 {
+		t.Fatalf("%d pushes during an active snooze", len(got))
+	}
+	history, err := ae.db.GetAlertHistory("user-1", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].RuleID != "cpu-high" {
+		t.Fatalf("alert history %+v, want the muted alert recorded", history)
+	}
+}
+
+func TestExpiredSnoozeDelivers(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	user := testUser()
+	user.SnoozeUntil = testStart.Add(-time.Minute).Unix()
+
+	ae.Evaluate(context.Background(), user, "key", testSnapshot(clk, 95), []models.AlertRule{cpuAlert(90, 0, 0)})
+	ae.Flush(context.Background())
+
+	if got := rec.Drain(); len(got) != 1 {
+		t.Fatalf("%d pushes after the snooze expired, want 1", len(got))
+	}
+}
+
+func TestStatusListsActiveSnoozes(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	tm := newTestMonitor(t, clk, nil, nil)
+	cf := tm.source.Get()
+	active, expired := cf.Users[0], cf.Users[0]
+	active.SnoozeUntil = testStart.Add(time.Hour).Unix()
+	expired.UserUUID, expired.SnoozeUntil = "user-2", testStart.Add(-time.Hour).Unix()
+	cf.Users = []models.ControlUser{active, expired}
+	tm.source.Set(cf)
+
+	tm.sample()
+	st := tm.readStatus(t)
+	if len(st.ActiveSnoozes) != 1 || st.ActiveSnoozes[0].UserUUID != "user-1" || st.ActiveSnoozes[0].Until != active.SnoozeUntil {
+		t.Fatalf("active_snoozes %+v, want only user-1", st.ActiveSnoozes)
+	}
+
+	clk.Advance(time.Hour)
+	tm.sample()
+	if st := tm.readStatus(t); len(st.ActiveSnoozes) != 0 {
+		t.Fatalf("active_snoozes %+v after the snooze ended", st.ActiveSnoozes)
+	}
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestCPUSpikeAlert(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	rule := models.AlertRule{
+		ID:            "spike",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		ConditionType: models.ConditionCPUSpike,
+		Threshold:     50,
+		Enabled:       true,
+	}
+	eval := func(state string, cpu float64) []string {
+		snap := testSnapshot(clk, cpu)
+		snap.PowerState = state
+		ae.Evaluate(context.Background(), testUser(), "key", snap, []models.AlertRule{rule})
+		clk.Advance(10 * time.Second)
+		var bodies []string
+		for _, p := range rec.Drain() {
+			bodies = append(bodies, p.Payload.Body)
+		}
+		return bodies
+	}
+
+	if got := eval("running", 95); len(got) != 0 {
+		t.Fatalf("fired on the first sample: %q", got)
+	}
+	if got := eval("running", 20); len(got) != 0 {
+		t.Fatalf("fired on a drop: %q", got)
+	}
+	if got := eval("running", 60); len(got) != 0 {
+		t.Fatalf("fired on a 40 point rise below the threshold: %q", got)
+	}
+	eval("running", 10)
+	got := eval("running", 90)
+	if len(got) != 1 || !strings.Contains(got[0], "jumped 80 points to 90%") {
+		t.Fatalf("pushes %q, want one for an 80 point jump", got)
+	}
+}
+
+func TestCPUSpikeIgnoresStartup(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	rule := models.AlertRule{
+		ID:            "spike",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		ConditionType: models.ConditionCPUSpike,
+		Threshold:     50,
+		Enabled:       true,
+	}
+
+	for _, s := range []struct {
+		state string
+		cpu   float64
+	}{{"offline", 0}, {"starting", 5}, {"running", 100}} {
+		snap := testSnapshot(clk, s.cpu)
+		snap.PowerState = s.state
+		ae.Evaluate(context.Background(), testUser(), "key", snap, []models.AlertRule{rule})
+		clk.Advance(10 * time.Second)
+	}
+	if got := rec.Drain(); len(got) != 0 {
+		t.Fatalf("fired on a startup spike: %+v", got)
+	}
+}
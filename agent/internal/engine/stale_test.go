@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// staleAlert fires when srv-1 has had no fresh data for threshold seconds.
+func staleAlert(threshold float64) models.AlertRule {
+	return models.AlertRule{
+		ID:            "no-data",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		ConditionType: models.ConditionDataStale,
+		Threshold:     threshold,
+		Cooldown:      3600,
+		Enabled:       true,
+	}
+}
+
+func TestStaleAlertWhenServerStopsReporting(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	tm := newTestMonitor(t, clk, []models.AlertRule{staleAlert(150)}, nil)
+	var failing atomic.Bool
+	tm.panel.serveResources(func(id string) (int, string) {
+		if id == "srv-1" && failing.Load() {
+			return http.StatusNotFound, `{"errors":[{"code":"NotFoundHttpException","status":"404","detail":"not found"}]}`
+		}
+		return http.StatusOK, resourcesBody("running", 12.5, false)
+	})
+
+	// Reporting normally: data is always fresh
+	for i := 0; i < 5; i++ {
+		tm.sample()
+		clk.Advance(time.Minute)
+	}
+	if d := tm.push.Drain(); len(d) != 0 {
+		t.Fatalf("%d pushes while srv-1 reports, want none", len(d))
+	}
+
+	// The panel stops answering for srv-1; the last good sample was a minute ago.
+	// Failed and backed-off cycles alike check how old that sample is.
+	failing.Store(true)
+	var pushes int
+	for i := 0; i < 6; i++ {
+		tm.sample()
+		for _, d := range tm.push.Drain() {
+			pushes++
+			if d.Payload.ServerID != "srv-1" || !strings.Contains(d.Payload.Title, "No Data") {
+				t.Fatalf("push %+v, want a srv-1 no-data alert", d.Payload)
+			}
+			if age := clk.Now().Sub(testStart.Add(4 * time.Minute)); age <= 150*time.Second {
+				t.Fatalf("stale alert after %s, before the 150s threshold", age)
+			}
+		}
+		clk.Advance(time.Minute)
+	}
+	if pushes != 1 {
+		t.Fatalf("%d stale alerts, want 1", pushes)
+	}
+	if tm.panel.resourceCalls("srv-1") >= 11 {
+		t.Fatal("srv-1 was never backed off; the test should cover the backoff path too")
+	}
+}
+
+func TestStaleAlertNeedsStoredData(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	tm := newTestMonitor(t, clk, []models.AlertRule{staleAlert(60)}, nil)
+	tm.panel.serveResources(func(id string) (int, string) {
+		return http.StatusNotFound, `{"errors":[{"code":"NotFoundHttpException","status":"404","detail":"not found"}]}`
+	})
+
+	// Never sampled: there is no age to measure, so nothing fires
+	for i := 0; i < 5; i++ {
+		tm.sample()
+		clk.Advance(time.Minute)
+	}
+	if d := tm.push.Drain(); len(d) != 0 {
+		t.Fatalf("%d pushes for a server never sampled, want none", len(d))
+	}
+}
@@ -0,0 +1,35 @@
+package engine
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+)
+
+func TestStatusReportsErrorsAndDBSize(t *testing.T) {
+	tm := newTestMonitor(t, clock.NewFake(testStart), nil, nil)
+	tm.panel.serveResources(func(id string) (int, string) {
+		if id == "srv-1" {
+			return http.StatusBadGateway, `{"errors":[]}`
+		}
+		return http.StatusOK, resourcesBody("running", 5, false)
+	})
+
+	tm.sample()
+
+	st := tm.readStatus(t)
+	if st.DBSizeBytes <= 0 {
+		t.Errorf("db_size_bytes = %d, want the size of agent.db", st.DBSizeBytes)
+	}
+	found := false
+	for _, e := range st.Errors {
+		if strings.Contains(e, "Failed to collect server srv-1") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("errors = %q, want the srv-1 collection failure", st.Errors)
+	}
+}
@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestSuspendedServersAreSkipped(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	alert := cpuAlert(-1, 0, 0) // would fire on any snapshot it saw
+	alert.ServerID = "srv-2"
+	tm := newTestMonitor(t, clk, []models.AlertRule{alert}, nil)
+	tm.panel.serveResources(func(id string) (int, string) {
+		return http.StatusOK, resourcesBody("offline", 50, id == "srv-2")
+	})
+
+	for i := 0; i < 3; i++ {
+		tm.sample()
+		clk.Advance(time.Minute)
+	}
+
+	if n := tm.panel.resourceCalls("srv-2"); n != 1 {
+		t.Errorf("suspended server polled %d times in 3 cycles, want 1", n)
+	}
+	if n := tm.panel.resourceCalls("srv-1"); n != 3 {
+		t.Errorf("active server polled %d times, want 3", n)
+	}
+	if snap := tm.LatestSnapshot("srv-2"); snap == nil || snap.PowerState != "suspended" || snap.CPUPercent != 0 {
+		t.Errorf("latest srv-2 snapshot %+v, want a zero-usage suspended snapshot", snap)
+	}
+	if got := tm.push.Drain(); len(got) != 0 {
+		t.Errorf("CPU alert evaluated on a suspended server: %+v", got)
+	}
+}
+
+func TestMonitorSuspendedKeepsPolling(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	alert := cpuAlert(10, 0, 0)
+	alert.ServerID = "srv-2"
+	tm := newTestMonitor(t, clk, []models.AlertRule{alert}, nil)
+	tm.monitorSuspended = true
+	tm.panel.serveResources(func(id string) (int, string) {
+		return http.StatusOK, resourcesBody("running", 50, id == "srv-2")
+	})
+
+	for i := 0; i < 3; i++ {
+		tm.sample()
+		clk.Advance(time.Minute)
+	}
+
+	if n := tm.panel.resourceCalls("srv-2"); n != 3 {
+		t.Errorf("suspended server polled %d times with MONITOR_SUSPENDED, want 3", n)
+	}
+	if got := tm.push.Drain(); len(got) == 0 {
+		t.Error("CPU alert not evaluated with MONITOR_SUSPENDED")
+	}
+}
+
+func TestSuspensionTransition(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	alert := models.AlertRule{
+		ID:            "suspended",
+		UserUUID:      "user-1",
+		ServerID:      "srv-2",
+		ConditionType: models.ConditionSuspended,
+		Enabled:       true,
+	}
+	auto := cpuRule("stop-suspended", models.ActionStop, nil)
+	auto.ServerID = "srv-2"
+	auto.TriggerType = models.TriggerSuspended
+	auto.TriggerConfig = nil
+	tm := newTestMonitor(t, clk, []models.AlertRule{alert}, []models.AutomationRule{auto})
+
+	suspended := false
+	tm.panel.serveResources(func(id string) (int, string) {
+		return http.StatusOK, resourcesBody("offline", 0, id == "srv-2" && suspended)
+	})
+
+	var titles []string
+	cycle := func() {
+		tm.sample()
+		for _, p := range tm.push.Drain() {
+			titles = append(titles, p.Payload.Title)
+		}
+		clk.Advance(time.Minute)
+	}
+
+	cycle()
+	if len(titles) != 0 {
+		t.Fatalf("pushes before suspension: %q", titles)
+	}
+
+	// Only the transition notifies and runs the automation, not every suspended cycle
+	suspended = true
+	for i := 0; i < 3; i++ {
+		cycle()
+	}
+	if got := strings.Join(titles, ", "); got != "⛔ Server Suspended, ⚡ Automation: stop" {
+		t.Fatalf("pushes = %s, want one suspension alert and one automation", got)
+	}
+	if n := powerCalls(tm.panel, "srv-2"); n != 1 {
+		t.Fatalf("stop sent %d times, want 1", n)
+	}
+
+	snaps, err := tm.db.GetRecentSnapshots("srv-2", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(snaps); n != 4 || snaps[0].IsSuspended || !snaps[n-1].IsSuspended {
+		t.Fatalf("stored snapshots = %+v, want is_suspended from the second cycle on", snaps)
+	}
+}
+
+func TestSuspendedAlertNeedsTransition(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	rule := models.AlertRule{
+		ID:            "suspended",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		ConditionType: models.ConditionSuspended,
+		Enabled:       true,
+	}
+	evaluate := func(suspended bool) int {
+		snap := testSnapshot(clk, 0)
+		snap.IsSuspended = suspended
+		ae.Evaluate(context.Background(), testUser(), "key", snap, []models.AlertRule{rule})
+		clk.Advance(time.Minute)
+		return len(rec.Drain())
+	}
+
+	// Already suspended when first seen: no transition to report
+	if n := evaluate(true); n != 0 {
+		t.Fatalf("pushes on the first sample = %d, want 0", n)
+	}
+	if n := evaluate(false) + evaluate(true); n != 1 {
+		t.Fatalf("pushes on suspension = %d, want 1", n)
+	}
+	if n := evaluate(true); n != 0 {
+		t.Fatalf("pushes while still suspended = %d, want 0", n)
+	}
+}
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// alertTemplateData is what title_template/body_template can reference, e.g. {{.Value}}.
+type alertTemplateData struct {
+	Value      float64 // measured value for the condition
+	Threshold  float64
+	ServerID   string
+	PowerState string
+	Condition  string // condition_type
+	Severity   string
+	Detail     string // met sub-conditions for composite rules
+}
+
+// renderAlertText applies the rule's custom templates, keeping the built-in
+// title/body for any template that is absent or fails to render.
+func renderAlertText(rule models.AlertRule, data alertTemplateData, title, body string) (string, string) {
+	if rule.TitleTemplate != "" {
+		if out, err := renderTemplate(rule.TitleTemplate, data); err != nil {
+			logging.Warn("Alert %s: title_template failed, using default text: %v", rule.ID, err)
+		} else {
+			title = out
+		}
+	}
+	if rule.BodyTemplate != "" {
+		if out, err := renderTemplate(rule.BodyTemplate, data); err != nil {
+			logging.Warn("Alert %s: body_template failed, using default text: %v", rule.ID, err)
+		} else {
+			body = out
+		}
+	}
+	return title, body
+}
+
+func renderTemplate(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("alert").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
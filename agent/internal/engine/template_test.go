@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestRenderAlertText(t *testing.T) {
+	data := alertTemplateData{
+		Value:      93.5,
+		Threshold:  80,
+		ServerID:   "srv-1",
+		PowerState: "running",
+		Condition:  "cpu",
+	}
+
+	tests := []struct {
+		name                string
+		titleTmpl, bodyTmpl string
+		wantTitle, wantBody string
+	}{
+		{"no templates", "", "", "default title", "default body"},
+		{"custom", "CPU {{.ServerID}}", `{{printf "%.1f" .Value}}% > {{.Threshold}}% ({{.PowerState}})`, "CPU srv-1", "93.5% > 80% (running)"},
+		{"parse error", "{{.ServerID", "", "default title", "default body"},
+		{"execution error", "", "{{.Missing}}", "default title", "default body"},
+		{"only body", "", "{{.Condition}} on {{.ServerID}}", "default title", "cpu on srv-1"},
+	}
+	for _, tc := range tests {
+		rule := models.AlertRule{ID: "r", TitleTemplate: tc.titleTmpl, BodyTemplate: tc.bodyTmpl}
+		title, body := renderAlertText(rule, data, "default title", "default body")
+		if title != tc.wantTitle || body != tc.wantBody {
+			t.Errorf("%s: got %q / %q, want %q / %q", tc.name, title, body, tc.wantTitle, tc.wantBody)
+		}
+	}
+}
+
+func TestAlertUsesCustomTemplate(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	rule := cpuAlert(80, 0, 0)
+	rule.TitleTemplate = "Processeur {{.ServerID}}"
+	rule.BodyTemplate = "{{.Value}} > {{.Threshold}}"
+
+	ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 95), []models.AlertRule{rule})
+	got := rec.Drain()
+	if len(got) != 1 || got[0].Payload.Title != "Processeur srv-1" || got[0].Payload.Body != "95 > 80" {
+		t.Fatalf("pushes %+v, want the rendered template", got)
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+	"github.com/xyidactyl/agent/internal/status"
+)
+
+// testPushes remembers the latest test push result for status.json.
+type testPushes struct {
+	mu   sync.Mutex
+	last *status.TestPush
+}
+
+// SendTestPush sends a test notification to each of the user's devices and returns the
+// per-token outcome. Snooze and quiet hours are bypassed so the whole path is exercised.
+func (m *Monitor) SendTestPush(ctx context.Context, user models.ControlUser) status.TestPush {
+	provider := m.alertEvaluator.notify.provider
+	result := status.TestPush{
+		UserUUID: user.UserUUID,
+		Provider: provider.Name(),
+		SentAt:   m.clock.Now().Format(time.RFC3339),
+	}
+
+	payload := push.Payload{
+		Title:     "🔔 Test notification",
+		Body:      "Push notifications from your agent are working.",
+		UserUUID:  user.UserUUID,
+		EventType: "test",
+		Timestamp: m.clock.Now().Format(time.RFC3339),
+	}
+
+	for _, token := range user.DeviceTokens {
+		r := status.TestPushToken{Token: truncateToken(token), OK: true}
+		if err := provider.Send(ctx, token, payload); err != nil {
+			r.OK = false
+			r.Error = err.Error()
+			if errors.Is(err, push.ErrTokenInvalid) {
+				m.alertEvaluator.notify.reportDeadToken(user.UserUUID, token)
+			}
+			logging.Warn("Test push to token %s for user %s failed: %v", r.Token, user.UserUUID, err)
+		} else {
+			logging.Info("Test push to token %s for user %s delivered", r.Token, user.UserUUID)
+		}
+		result.Tokens = append(result.Tokens, r)
+	}
+	if len(user.DeviceTokens) == 0 {
+		logging.Info("Test push requested for user %s, but no device tokens are registered", user.UserUUID)
+	}
+
+	m.testPushes.mu.Lock()
+	m.testPushes.last = &result
+	m.testPushes.mu.Unlock()
+	return result
+}
+
+// lastTestPush returns the latest test push result, or nil if none was sent.
+func (m *Monitor) lastTestPush() *status.TestPush {
+	m.testPushes.mu.Lock()
+	defer m.testPushes.mu.Unlock()
+	return m.testPushes.last
+}
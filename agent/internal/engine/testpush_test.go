@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+)
+
+func TestSendTestPushReachesEveryToken(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	tm := newTestMonitor(t, clk, nil, nil)
+	user := testUser()
+	user.DeviceTokens = []string{"token-1", "token-2", "token-3-with-a-long-suffix"}
+
+	result := tm.SendTestPush(context.Background(), user)
+
+	deliveries := tm.push.Drain()
+	if len(deliveries) != len(user.DeviceTokens) {
+		t.Fatalf("%d deliveries, want one per token", len(deliveries))
+	}
+	for i, d := range deliveries {
+		if d.Token != user.DeviceTokens[i] || d.Payload.EventType != "test" || d.Payload.UserUUID != "user-1" {
+			t.Fatalf("delivery %d = %+v, want a test payload to %s", i, d, user.DeviceTokens[i])
+		}
+	}
+
+	if result.Provider != "recording" || result.UserUUID != "user-1" || len(result.Tokens) != 3 {
+		t.Fatalf("result = %+v, want three tokens through the recording provider", result)
+	}
+	for _, r := range result.Tokens {
+		if !r.OK || r.Error != "" {
+			t.Fatalf("token result %+v, want delivered", r)
+		}
+	}
+	if got := result.Tokens[2].Token; got != "token-3-with-a-l" {
+		t.Fatalf("token reported as %q, want it truncated", got)
+	}
+
+	// The latest result is reflected in status.json
+	tm.sample()
+	last := tm.readStatus(t).LastTestPush
+	if last == nil || len(last.Tokens) != 3 || last.SentAt != testStart.Format(time.RFC3339) {
+		t.Fatalf("status last_test_push = %+v, want the test push result", last)
+	}
+}
+
+func TestSendTestPushWithoutTokens(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	tm := newTestMonitor(t, clk, nil, nil)
+	user := testUser()
+	user.DeviceTokens = nil
+
+	result := tm.SendTestPush(context.Background(), user)
+	if len(result.Tokens) != 0 || len(tm.push.Drain()) != 0 {
+		t.Fatalf("result = %+v, want nothing sent", result)
+	}
+	if tm.lastTestPush() == nil {
+		t.Fatal("an empty test push should still be recorded")
+	}
+}
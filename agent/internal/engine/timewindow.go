@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/cron"
+)
+
+// inTimeWindow reports whether now falls within the daily [start, end) window given as "HH:MM"
+// in the named timezone (empty means UTC). Windows where end <= start cross midnight.
+func inTimeWindow(start, end, tz string, now time.Time) (bool, error) {
+	loc := time.UTC
+	if tz != "" {
+		var err error
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return false, fmt.Errorf("invalid timezone %q: %w", tz, err)
+		}
+	}
+
+	startMin, err := parseClock(start)
+	if err != nil {
+		return false, fmt.Errorf("invalid start: %w", err)
+	}
+	endMin, err := parseClock(end)
+	if err != nil {
+		return false, fmt.Errorf("invalid end: %w", err)
+	}
+
+	local := now.In(loc)
+	cur := local.Hour()*60 + local.Minute()
+
+	if startMin < endMin {
+		return cur >= startMin && cur < endMin, nil
+	}
+	// Crosses midnight, e.g. 22:00-06:00
+	return cur >= startMin || cur < endMin, nil
+}
+
+// parseClock converts "HH:MM" to minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// withinActiveHours checks the optional TriggerConfig["active_hours"] window of an automation.
+// Rules without a window are always active; malformed windows fail closed.
+func withinActiveHours(cfg map[string]interface{}, now time.Time) (bool, error) {
+	raw, ok := cfg["active_hours"]
+	if !ok || raw == nil {
+		return true, nil
+	}
+
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return false, fmt.Errorf("active_hours must be an object")
+	}
+
+	start, _ := m["start"].(string)
+	end, _ := m["end"].(string)
+	tz, _ := m["tz"].(string)
+	return inTimeWindow(start, end, tz, now)
+}
+
+// withinCron reports whether now matches a rule's active_cron schedule.
+// Rules without one are always active; malformed expressions fail closed.
+func withinCron(expr string, now time.Time) (bool, error) {
+	if expr == "" {
+		return true, nil
+	}
+	sched, err := cron.Parse(expr)
+	if err != nil {
+		return false, err
+	}
+	return sched.Matches(now), nil
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func TestInTimeWindow(t *testing.T) {
+	at := func(hhmm string) time.Time {
+		ts, err := time.Parse("2006-01-02 15:04", "2026-01-05 "+hhmm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	for _, tt := range []struct {
+		name       string
+		start, end string
+		tz         string
+		now        time.Time
+		want       bool
+	}{
+		{"in window", "02:00", "05:00", "", at("03:30"), true},
+		{"at start", "02:00", "05:00", "", at("02:00"), true},
+		{"at end", "02:00", "05:00", "", at("05:00"), false},
+		{"before window", "02:00", "05:00", "", at("01:59"), false},
+		{"after window", "02:00", "05:00", "", at("12:00"), false},
+		{"midnight crossing, late", "22:00", "06:00", "", at("23:15"), true},
+		{"midnight crossing, early", "22:00", "06:00", "", at("05:59"), true},
+		{"midnight crossing, outside", "22:00", "06:00", "", at("12:00"), false},
+		// 07:30 UTC is 02:30 in New York (EST, UTC-5)
+		{"timezone in window", "02:00", "05:00", "America/New_York", at("07:30"), true},
+		{"timezone out of window", "02:00", "05:00", "America/New_York", at("03:30"), false},
+	} {
+		got, err := inTimeWindow(tt.start, tt.end, tt.tz, tt.now)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestActiveHoursFailClosed(t *testing.T) {
+	for name, cfg := range map[string]map[string]interface{}{
+		"bad timezone": {"active_hours": map[string]interface{}{"start": "02:00", "end": "05:00", "tz": "Mars/Olympus_Mons"}},
+		"bad clock":    {"active_hours": map[string]interface{}{"start": "2am", "end": "05:00"}},
+		"not object":   {"active_hours": "02:00-05:00"},
+	} {
+		if ok, err := withinActiveHours(cfg, testStart); ok || err == nil {
+			t.Errorf("%s: active=%t err=%v, want inactive with an error", name, ok, err)
+		}
+	}
+	if ok, err := withinActiveHours(map[string]interface{}{}, testStart); !ok || err != nil {
+		t.Errorf("no window: active=%t err=%v, want always active", ok, err)
+	}
+}
+
+func TestAutomationSkippedOutsideActiveHours(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC))
+	ae, fp, _ := newTestExecutor(t, clk)
+	rule := cpuRule("night-restart", models.ActionRestart, nil)
+	rule.TriggerConfig["active_hours"] = map[string]interface{}{"start": "02:00", "end": "05:00"}
+	eval := func() int {
+		ae.Evaluate(context.Background(), testUser(), "key", testSnapshot(clk, 99), []models.AutomationRule{rule})
+		return len(fp.Requests())
+	}
+
+	if n := eval(); n != 0 {
+		t.Fatalf("ran at noon outside 02:00-05:00: %d requests", n)
+	}
+	clk.Set(time.Date(2026, 1, 6, 3, 0, 0, 0, time.UTC))
+	if n := eval(); n != 1 {
+		t.Fatalf("%d requests at 03:00, want 1", n)
+	}
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+const hourMs = int64(time.Hour / time.Millisecond)
+
+// uptimeSnapshot is srv-1 in the given state with the given uptime, timestamped now.
+func uptimeSnapshot(clk clock.Clock, state string, uptimeMs int64) *models.ResourceSnapshot {
+	s := testSnapshot(clk, 5)
+	s.PowerState = state
+	s.UptimeMs = uptimeMs
+	return s
+}
+
+func TestUptimeThresholdAlert(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	rule := models.AlertRule{
+		ID:            "uptime",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		ConditionType: models.ConditionUptime,
+		Threshold:     24,
+		Enabled:       true,
+	}
+	eval := func(snap *models.ResourceSnapshot) []string {
+		ae.Evaluate(context.Background(), testUser(), "key", snap, []models.AlertRule{rule})
+		clk.Advance(time.Minute)
+		var bodies []string
+		for _, p := range rec.Drain() {
+			bodies = append(bodies, p.Payload.Body)
+		}
+		return bodies
+	}
+
+	if got := eval(uptimeSnapshot(clk, "running", 23*hourMs)); len(got) != 0 {
+		t.Fatalf("fired below the threshold: %q", got)
+	}
+	if got := eval(uptimeSnapshot(clk, "offline", 30*hourMs)); len(got) != 0 {
+		t.Fatalf("fired while offline: %q", got)
+	}
+	got := eval(uptimeSnapshot(clk, "running", 30*hourMs))
+	if len(got) != 1 || !strings.Contains(got[0], "30.0 hours") {
+		t.Fatalf("pushes %q, want one at 30 hours", got)
+	}
+}
+
+func TestUptimeResetAlert(t *testing.T) {
+	clk := clock.NewFake(testStart)
+	ae, rec := newTestEvaluator(t, clk)
+	rule := models.AlertRule{
+		ID:            "restart",
+		UserUUID:      "user-1",
+		ServerID:      "srv-1",
+		ConditionType: models.ConditionUptimeReset,
+		Enabled:       true,
+	}
+	eval := func(snap *models.ResourceSnapshot) int {
+		ae.Evaluate(context.Background(), testUser(), "key", snap, []models.AlertRule{rule})
+		clk.Advance(time.Minute)
+		return len(rec.Drain())
+	}
+
+	if n := eval(uptimeSnapshot(clk, "running", 5*hourMs)); n != 0 {
+		t.Fatal("fired on the first sample")
+	}
+	if n := eval(uptimeSnapshot(clk, "running", 5*hourMs+60000)); n != 0 {
+		t.Fatal("fired while uptime grew")
+	}
+	if n := eval(uptimeSnapshot(clk, "running", 30000)); n != 1 {
+		t.Fatalf("%d pushes for a silent restart, want 1", n)
+	}
+
+	// A visible stop/start is not a silent restart
+	eval(uptimeSnapshot(clk, "running", 2*hourMs))
+	eval(uptimeSnapshot(clk, "offline", 0))
+	if n := eval(uptimeSnapshot(clk, "running", 30000)); n != 0 {
+		t.Fatal("fired after a visible stop and start")
+	}
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// webhookEvent is the JSON POSTed to a user's webhook_url when an alert or automation fires.
+type webhookEvent struct {
+	EventType string `json:"event_type"` // "alert" or "automation"
+	UserUUID  string `json:"user_uuid"`
+	ServerID  string `json:"server_id"`
+	RuleID    string `json:"rule_id"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Timestamp string `json:"timestamp"`
+
+	// Alerts
+	Condition string   `json:"condition,omitempty"`
+	Severity  string   `json:"severity,omitempty"`
+	Value     *float64 `json:"value,omitempty"`
+	Threshold *float64 `json:"threshold,omitempty"`
+
+	// Automations
+	Action  string `json:"action,omitempty"`
+	Trigger string `json:"trigger,omitempty"`
+	Step    int    `json:"step,omitempty"`
+	Result  string `json:"result,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// notifyWebhook posts an event to the user's webhook in the background. It ignores
+// snooze, quiet hours and the push rate limit, which only concern devices.
+func (n *Notifier) notifyWebhook(ctx context.Context, user models.ControlUser, event webhookEvent) {
+	if user.WebhookURL == "" || n.webhooks == nil {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		logging.Error("Failed to encode %s webhook for user %s: %v", event.EventType, user.UserUUID, err)
+		return
+	}
+	go func() {
+		if err := n.webhooks.Post(ctx, user.WebhookURL, body); err != nil {
+			logging.Warn("Failed to deliver %s webhook for user %s: %v", event.EventType, user.UserUUID, err)
+		}
+	}()
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/push"
+)
+
+func TestAlertWebhookPerNotifier(t *testing.T) {
+	received := make(chan *http.Request, 4)
+	bodies := make(chan []byte, 4)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	t.Cleanup(hook.Close)
+
+	clk := clock.NewFake(testStart)
+	user := testUser()
+	user.WebhookURL = hook.URL
+	fire := func(webhooks *push.EventPoster) {
+		fp := newFakePanel(t)
+		n := NewNotifier(push.NewRecordingProvider(false), nil, nil, webhooks, 0, nil, clk)
+		ae := NewAlertEvaluator(openTestDB(t), fp.panels, n, false, clk)
+		ae.Evaluate(context.Background(), user, "key", testSnapshot(clk, 95), []models.AlertRule{cpuAlert(90, 0, 0)})
+	}
+
+	// A notifier without a poster never posts, even next to one that does
+	fire(nil)
+	fire(push.NewEventPoster(func([]byte) string { return "sig" }))
+
+	select {
+	case r := <-received:
+		if got := r.Header.Get(push.SignatureHeader); got != "sha256=sig" {
+			t.Errorf("signature %q, want sha256=sig", got)
+		}
+		var event webhookEvent
+		if err := json.Unmarshal(<-bodies, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.EventType != "alert" || event.RuleID != "cpu-high" || event.ServerID != "srv-1" {
+			t.Errorf("event %+v, want the cpu-high alert on srv-1", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook delivered")
+	}
+	select {
+	case <-received:
+		t.Fatal("webhook delivered twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -35,6 +36,13 @@ func (l Level) String() string {
 	}
 }
 
+var levelNames = map[string]Level{
+	"debug": LevelDebug,
+	"info":  LevelInfo,
+	"warn":  LevelWarn,
+	"error": LevelError,
+}
+
 // ParseLevel converts a string to Level.
 func ParseLevel(s string) Level {
 	switch s {
@@ -58,13 +66,43 @@ type Logger struct {
 	file     *os.File
 	filePath string
 	maxSize  int64 // bytes
+	compress bool  // gzip rotated files
+	totalMax int64 // bytes across agent.log and its rotations, 0 = unlimited
 	stdout   *log.Logger
 }
 
 var defaultLogger *Logger
 
-// Init creates the global logger.
-func Init(dataDir string, level string) error {
+// maxRecentErrors is how many warning/error lines RecentErrors keeps.
+const maxRecentErrors = 10
+
+// recentErrors is a ring buffer of the latest warning/error lines, surfaced in status.json.
+var (
+	recentMu     sync.Mutex
+	recentErrors []string
+)
+
+// RecentErrors returns the most recent warning and error lines, oldest first.
+func RecentErrors() []string {
+	recentMu.Lock()
+	defer recentMu.Unlock()
+	out := make([]string, len(recentErrors))
+	copy(out, recentErrors)
+	return out
+}
+
+func recordError(line string) {
+	recentMu.Lock()
+	defer recentMu.Unlock()
+	if len(recentErrors) == maxRecentErrors {
+		recentErrors = append(recentErrors[:0], recentErrors[1:]...)
+	}
+	recentErrors = append(recentErrors, line)
+}
+
+// Init creates the global logger. Rotated files are gzipped when compress is set, and
+// totalMaxKB (0 = unlimited) caps the combined size of the log and its rotations.
+func Init(dataDir string, level string, compress bool, totalMaxKB int) error {
 	logDir := filepath.Join(dataDir, "logs")
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		return fmt.Errorf("create log dir: %w", err)
@@ -81,11 +119,62 @@ func Init(dataDir string, level string) error {
 		file:     f,
 		filePath: logPath,
 		maxSize:  128 * 1024, // 128KB (Safe for Pterodactyl Panel view)
+		compress: compress,
+		totalMax: int64(totalMaxKB) * 1024,
 		stdout:   log.New(os.Stdout, "", 0),
 	}
 	return nil
 }
 
+// SetLevel changes the minimum level that is logged.
+func SetLevel(level Level) {
+	if defaultLogger == nil {
+		return
+	}
+	defaultLogger.mu.Lock()
+	defaultLogger.level = level
+	defaultLogger.mu.Unlock()
+}
+
+// GetLevel returns the current minimum log level.
+func GetLevel() Level {
+	if defaultLogger == nil {
+		return LevelDebug
+	}
+	defaultLogger.mu.Lock()
+	defer defaultLogger.mu.Unlock()
+	return defaultLogger.level
+}
+
+// WatchLevelFile polls path (e.g. data/log_level) and applies the level written in it,
+// so verbosity can be changed without a restart. A missing file leaves the level alone.
+func WatchLevelFile(path string, interval time.Duration, stop <-chan struct{}) {
+	var lastMod time.Time
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if info, err := os.Stat(path); err == nil && info.ModTime() != lastMod {
+			lastMod = info.ModTime()
+			if data, err := os.ReadFile(path); err == nil {
+				name := strings.ToLower(strings.TrimSpace(string(data)))
+				if level, ok := levelNames[name]; !ok {
+					Warn("Ignoring invalid log level %q in %s", name, path)
+				} else if level != GetLevel() {
+					SetLevel(level)
+					Info("Log level changed to %s", level)
+				}
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // Close closes the log file.
 func Close() {
 	if defaultLogger != nil && defaultLogger.file != nil {
@@ -96,11 +185,14 @@ func Close() {
 func logMsg(level Level, format string, args ...interface{}) {
 	if defaultLogger == nil {
 		// Fallback to stdout before logger is initialized
-		msg := fmt.Sprintf(format, args...)
-		fmt.Printf("[%s] %s %s\n", level, time.Now().Format(time.RFC3339), msg)
+		line := fmt.Sprintf("[%s] %s %s", level, time.Now().Format(time.RFC3339), fmt.Sprintf(format, args...))
+		if level >= LevelWarn {
+			recordError(line)
+		}
+		fmt.Println(line)
 		return
 	}
-	if level < defaultLogger.level {
+	if level < GetLevel() {
 		return
 	}
 
@@ -108,6 +200,10 @@ func logMsg(level Level, format string, args ...interface{}) {
 	ts := time.Now().Format(time.RFC3339)
 	line := fmt.Sprintf("[%s] %s %s", level, ts, msg)
 
+	if level >= LevelWarn {
+		recordError(line)
+	}
+
 	// Always print to stdout (Pterodactyl console)
 	defaultLogger.stdout.Println(line)
 
@@ -121,30 +217,6 @@ func logMsg(level Level, format string, args ...interface{}) {
 	}
 }
 
-func (l *Logger) maybeRotate() {
-	info, err := l.file.Stat()
-	if err != nil || info.Size() < l.maxSize {
-		return
-	}
-
-	l.file.Close()
-
-	// Keep up to 5 rotated files
-	for i := 4; i >= 1; i-- {
-		old := fmt.Sprintf("%s.%d", l.filePath, i)
-		new := fmt.Sprintf("%s.%d", l.filePath, i+1)
-		os.Rename(old, new)
-	}
-	os.Rename(l.filePath, l.filePath+".1")
-
-	f, err := os.OpenFile(l.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		l.file = nil
-		return
-	}
-	l.file = f
-}
-
 // Writer returns an io.Writer that writes at the given level (for use with standard log).
 func Writer(level Level) io.Writer {
 	return &logWriter{level: level}
@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// initTestLogger initializes the global logger in a temp dir, reset when the test ends.
+func initTestLogger(t *testing.T, level string) {
+	t.Helper()
+	if err := Init(t.TempDir(), level, false, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		Close()
+		defaultLogger = nil
+	})
+}
+
+// logged returns the lines written to the log file so far.
+func logged(t *testing.T) string {
+	t.Helper()
+	lines, err := Tail(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestRecentErrorsKeepsLatestWarnings(t *testing.T) {
+	for i := 0; i < maxRecentErrors+3; i++ {
+		Warn("warning %d", i)
+	}
+	Info("not an error")
+
+	got := RecentErrors()
+	if len(got) != maxRecentErrors {
+		t.Fatalf("%d recent errors, want %d", len(got), maxRecentErrors)
+	}
+	for i, line := range got {
+		if want := fmt.Sprintf("warning %d", i+3); !strings.HasSuffix(line, want) {
+			t.Fatalf("recent error %d = %q, want it to end with %q", i, line, want)
+		}
+	}
+
+	Error("boom")
+	if got := RecentErrors(); !strings.HasSuffix(got[len(got)-1], "boom") || !strings.HasPrefix(got[len(got)-1], "[ERROR]") {
+		t.Fatalf("newest recent error = %q, want the ERROR line", got[len(got)-1])
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	initTestLogger(t, "info")
+
+	Debug("hidden debug")
+	Info("shown info")
+	SetLevel(LevelDebug)
+	Debug("shown debug")
+	SetLevel(LevelError)
+	Warn("hidden warning")
+	Error("shown error")
+
+	out := logged(t)
+	for _, want := range []string{"shown info", "shown debug", "shown error"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log is missing %q:\n%s", want, out)
+		}
+	}
+	for _, hidden := range []string{"hidden debug", "hidden warning"} {
+		if strings.Contains(out, hidden) {
+			t.Errorf("log contains filtered %q:\n%s", hidden, out)
+		}
+	}
+	if GetLevel() != LevelError {
+		t.Errorf("GetLevel() = %s, want ERROR", GetLevel())
+	}
+}
+
+func TestWatchLevelFile(t *testing.T) {
+	initTestLogger(t, "info")
+	path := filepath.Join(t.TempDir(), "log_level")
+	if err := os.WriteFile(path, []byte("debug\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		WatchLevelFile(path, 10*time.Millisecond, stop)
+		close(done)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for GetLevel() != LevelDebug {
+		if time.Now().After(deadline) {
+			t.Fatalf("level = %s after writing debug to the level file", GetLevel())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
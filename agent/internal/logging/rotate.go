@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+// maxRotations is how many rotated files (agent.log.1 .. agent.log.5) are kept.
+const maxRotations = 5
+
+// rotated returns the path of rotation n, with the .gz suffix if it was compressed.
+func (l *Logger) rotated(n int) (string, bool) {
+	path := fmt.Sprintf("%s.%d", l.filePath, n)
+	if _, err := os.Stat(path + ".gz"); err == nil {
+		return path + ".gz", true
+	}
+	return path, false
+}
+
+// maybeRotate moves agent.log to agent.log.1 once it reaches maxSize, shifting older
+// rotations up and dropping the oldest. It runs under l.mu, so no write can interleave.
+func (l *Logger) maybeRotate() {
+	info, err := l.file.Stat()
+	if err != nil || info.Size() < l.maxSize {
+		return
+	}
+
+	l.file.Close()
+
+	last := fmt.Sprintf("%s.%d", l.filePath, maxRotations)
+	os.Remove(last)
+	os.Remove(last + ".gz")
+	for i := maxRotations - 1; i >= 1; i-- {
+		old, gz := l.rotated(i)
+		new := fmt.Sprintf("%s.%d", l.filePath, i+1)
+		if gz {
+			new += ".gz"
+		}
+		os.Rename(old, new)
+	}
+	os.Rename(l.filePath, l.filePath+".1")
+
+	f, err := os.OpenFile(l.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		l.file = nil
+	} else {
+		l.file = f
+	}
+
+	if l.compress {
+		if err := gzipFile(l.filePath + ".1"); err != nil {
+			fmt.Fprintf(os.Stderr, "compress rotated log: %v\n", err)
+		}
+	}
+	if l.totalMax > 0 {
+		l.enforceBudget()
+	}
+}
+
+// gzipFile replaces path with path.gz. The archive is written to a temporary file
+// and renamed into place, so a crash never leaves a truncated .gz behind.
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// enforceBudget deletes the oldest rotations until they fit in totalMax alongside a
+// full agent.log, so the budget still holds as the live log grows to its next rotation.
+func (l *Logger) enforceBudget() {
+	total := l.maxSize
+	sizes := make([]int64, maxRotations+1)
+	for i := 1; i <= maxRotations; i++ {
+		path, _ := l.rotated(i)
+		if info, err := os.Stat(path); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+
+	for i := maxRotations; i >= 1 && total > l.totalMax; i-- {
+		if sizes[i] == 0 {
+			continue
+		}
+		path, _ := l.rotated(i)
+		if err := os.Remove(path); err == nil {
+			total -= sizes[i]
+		}
+	}
+}
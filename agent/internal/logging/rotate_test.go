@@ -0,0 +1,162 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// initRotatingLogger initializes the global logger with a small maxSize so tests can
+// rotate quickly, and returns the log file path.
+func initRotatingLogger(t *testing.T, maxSize int64, compress bool, totalMaxKB int) string {
+	t.Helper()
+	if err := Init(t.TempDir(), "info", compress, totalMaxKB); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		Close()
+		defaultLogger = nil
+	})
+	defaultLogger.maxSize = maxSize
+	defaultLogger.stdout = log.New(io.Discard, "", 0)
+	return defaultLogger.filePath
+}
+
+// readLog returns the lines of a log file, decompressing .gz rotations.
+func readLog(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		r = zr
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+}
+
+// lineNumber extracts N from a "... line NNNN ..." log line.
+func lineNumber(t *testing.T, line string) int {
+	t.Helper()
+	var n int
+	_, rest, _ := strings.Cut(line, " line ")
+	if _, err := fmt.Sscanf(rest, "%d", &n); err != nil {
+		t.Fatalf("no line number in %q", line)
+	}
+	return n
+}
+
+// logFiles lists the files next to the log, sorted by name.
+func logFiles(t *testing.T, path string) []string {
+	t.Helper()
+	files, err := filepath.Glob(path + "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestRotationsAreCompressed(t *testing.T) {
+	path := initRotatingLogger(t, 1024, true, 0)
+	for i := 0; i < 200; i++ {
+		Info("line %04d %s", i, strings.Repeat("x", 40))
+	}
+
+	for _, f := range logFiles(t, path) {
+		if f != path && !strings.HasSuffix(f, ".gz") {
+			t.Fatalf("rotation %s left uncompressed, files %v", f, logFiles(t, path))
+		}
+	}
+	for i := 1; i <= maxRotations; i++ {
+		if _, err := os.Stat(fmt.Sprintf("%s.%d.gz", path, i)); err != nil {
+			t.Fatalf("rotation %d missing: %v", i, err)
+		}
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s.%d.gz", path, maxRotations+1)); err == nil {
+		t.Fatalf("more than %d rotations kept", maxRotations)
+	}
+
+	// Newer rotations have lower numbers, and the live log has the latest lines
+	older, newer, live := readLog(t, path+".2.gz"), readLog(t, path+".1.gz"), readLog(t, path)
+	if lineNumber(t, older[len(older)-1])+1 != lineNumber(t, newer[0]) || lineNumber(t, newer[len(newer)-1])+1 != lineNumber(t, live[0]) {
+		t.Fatal("rotations are not contiguous and in order")
+	}
+	if lineNumber(t, live[len(live)-1]) != 199 {
+		t.Fatalf("last live line = %q, want line 0199", live[len(live)-1])
+	}
+}
+
+func TestRotationBudget(t *testing.T) {
+	const maxSize = 1024
+	path := initRotatingLogger(t, maxSize, false, 3)
+	for i := 0; i < 300; i++ {
+		Info("line %04d %s", i, strings.Repeat("x", 40))
+	}
+
+	var rotated int64
+	for _, f := range logFiles(t, path) {
+		if f == path {
+			continue
+		}
+		info, err := os.Stat(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rotated += info.Size()
+	}
+	if rotated+maxSize > 3*1024 {
+		t.Fatalf("rotations use %d bytes, over the 3KB budget with a full live log", rotated)
+	}
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("newest rotation deleted: %v", err)
+	}
+	if _, err := os.Stat(path + ".5"); err == nil {
+		t.Fatal("oldest rotation kept over budget")
+	}
+}
+
+func TestRotationUnderConcurrentWrites(t *testing.T) {
+	path := initRotatingLogger(t, 16*1024, true, 0)
+
+	const writers, lines = 20, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				Info("writer %02d line %02d %s", w, i, strings.Repeat("y", 20))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	seen := 0
+	for _, f := range logFiles(t, path) {
+		for _, line := range readLog(t, f) {
+			if !strings.HasPrefix(line, "[INFO] ") || !strings.HasSuffix(line, strings.Repeat("y", 20)) {
+				t.Fatalf("%s: torn line %q", f, line)
+			}
+			seen++
+		}
+	}
+	if seen != writers*lines {
+		t.Fatalf("%d lines across the log and its rotations, want %d", seen, writers*lines)
+	}
+}
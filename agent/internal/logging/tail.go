@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// tailChunk is how much of the file Tail reads per step, from the end backwards.
+const tailChunk = 16 * 1024
+
+// panelKeyPattern matches Pterodactyl client/application API keys. They are never
+// logged, but Tail redacts anything that looks like one as a safeguard.
+var panelKeyPattern = regexp.MustCompile(`ptl[ac]_[A-Za-z0-9]{20,}`)
+
+// Tail returns the last n lines of the current log file, oldest first.
+func Tail(n int) ([]string, error) {
+	if defaultLogger == nil {
+		return nil, errors.New("logger not initialized")
+	}
+	return TailFile(defaultLogger.filePath, n)
+}
+
+// TailFile returns the last n lines of the file at path, oldest first, reading
+// backwards so large files are never loaded whole. Panel API keys are redacted.
+func TailFile(path string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read chunks from the end until we have more than n newlines or hit the start
+	var buf []byte
+	offset := info.Size()
+	for offset > 0 && bytes.Count(buf, []byte{'\n'}) <= n {
+		size := int64(tailChunk)
+		if size > offset {
+			size = offset
+		}
+		offset -= size
+		chunk := make([]byte, size)
+		if _, err := f.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		buf = append(chunk, buf...)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(buf), "\n"), "\n")
+	if offset > 0 {
+		lines = lines[1:] // First line may be cut off mid-way
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil, nil
+	}
+	for i, l := range lines {
+		lines[i] = panelKeyPattern.ReplaceAllString(l, "[REDACTED]")
+	}
+	return lines, nil
+}
@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeLog writes lines to a log file in a temp dir and returns its path.
+func writeLog(t *testing.T, lines []string, trailingNewline bool) string {
+	t.Helper()
+	content := strings.Join(lines, "\n")
+	if trailingNewline && len(lines) > 0 {
+		content += "\n"
+	}
+	path := filepath.Join(t.TempDir(), "agent.log")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// numbered is n synthetic log lines, "[INFO] line 0" to "[INFO] line n-1".
+func numbered(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("[INFO] 2026-01-05T12:00:00Z line %d", i)
+	}
+	return lines
+}
+
+func TestTailFileReadsAcrossChunks(t *testing.T) {
+	// Well over tailChunk, so the tail spans several backwards reads
+	all := numbered(5000)
+	path := writeLog(t, all, true)
+
+	for _, n := range []int{1, 10, 999, 1000} {
+		got, err := TailFile(path, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := all[len(all)-n:]
+		if len(got) != n || got[0] != want[0] || got[n-1] != want[n-1] {
+			t.Fatalf("n=%d: got %d lines %q..%q, want %q..%q", n, len(got), got[0], got[len(got)-1], want[0], want[n-1])
+		}
+	}
+}
+
+func TestTailFileShortFile(t *testing.T) {
+	all := numbered(3)
+	for _, trailing := range []bool{true, false} {
+		got, err := TailFile(writeLog(t, all, trailing), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, "\n") != strings.Join(all, "\n") {
+			t.Fatalf("trailing newline %t: got %q, want the whole file", trailing, got)
+		}
+	}
+
+	if got, err := TailFile(writeLog(t, nil, false), 10); err != nil || got != nil {
+		t.Fatalf("empty file: got %q, %v, want nothing", got, err)
+	}
+	if got, err := TailFile(writeLog(t, all, true), 0); err != nil || got != nil {
+		t.Fatalf("n=0: got %q, %v, want nothing", got, err)
+	}
+	if _, err := TailFile(filepath.Join(t.TempDir(), "missing.log"), 10); err == nil {
+		t.Fatal("missing file: want an error")
+	}
+}
+
+func TestTailFileRedactsPanelKeys(t *testing.T) {
+	key := "ptlc_" + strings.Repeat("a1B2", 10)
+	path := writeLog(t, []string{
+		"[INFO] using key " + key,
+		"[WARN] app key ptla_" + strings.Repeat("Z9", 12) + " rejected",
+		"[INFO] ptlc_short is not a key",
+	}, true)
+
+	got, err := TailFile(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(got, "\n")
+	if strings.Contains(joined, key) || strings.Contains(joined, "ptla_Z9") {
+		t.Fatalf("tail leaked a panel key: %q", got)
+	}
+	if got[0] != "[INFO] using key [REDACTED]" || !strings.Contains(got[2], "ptlc_short") {
+		t.Fatalf("got %q, want only full-length keys redacted", got)
+	}
+}
@@ -9,7 +9,8 @@ type AutomationLogEntry struct {
 	UserUUID   string    `json:"user_uuid"`
 	ServerID   string    `json:"server_id"`
 	Action     string    `json:"action"`
-	Result     string    `json:"result"` // "success" or "failure"
+	Step       int       `json:"step,omitempty"` // 1-based escalation step, 0 for plain rules
+	Result     string    `json:"result"`         // "success" or "failure"
 	ErrorMsg   string    `json:"error_msg,omitempty"`
 	ExecutedAt time.Time `json:"executed_at"`
 }
@@ -21,6 +22,7 @@ type AlertHistoryEntry struct {
 	UserUUID    string    `json:"user_uuid"`
 	ServerID    string    `json:"server_id"`
 	Condition   string    `json:"condition"`
+	Severity    string    `json:"severity"`
 	Value       float64   `json:"value"`
 	TriggeredAt time.Time `json:"triggered_at"`
 }
@@ -8,38 +8,105 @@ type ControlFile struct {
 	Users       []ControlUser    `json:"users"`
 	Alerts      []AlertRule      `json:"alerts"`
 	Automations []AutomationRule `json:"automations"`
+	Groups      []ServerGroup    `json:"groups,omitempty"`
+	Signature   string           `json:"signature,omitempty"` // hex HMAC-SHA256, see security.SignControlFile
 }
 
 // ControlUser represents a registered user in the control plane.
 type ControlUser struct {
-	UserUUID        string   `json:"user_uuid"`
-	APIKeyEncrypted string   `json:"api_key_encrypted"`
-	IsAdmin         bool     `json:"is_admin"`
-	AllowedServers  []string `json:"allowed_servers"`
-	DeviceTokens    []string `json:"device_tokens"`
+	UserUUID           string   `json:"user_uuid"`
+	APIKeyEncrypted    string   `json:"api_key_encrypted"`
+	IsAdmin            bool     `json:"is_admin"`
+	AllowedServers     []string `json:"allowed_servers"`
+	ExcludedServers    []string `json:"excluded_servers,omitempty"`    // allowed servers the user does not want sampled
+	MonitoringDisabled bool     `json:"monitoring_disabled,omitempty"` // sample none of the user's servers
+	DeviceTokens       []string `json:"device_tokens"`
+	SnoozeUntil        int64    `json:"snooze_until,omitempty"` // unix seconds; pushes muted until then
+	PanelURL           string   `json:"panel_url,omitempty"`    // the user's panel, empty = PANEL_URL
+	WebhookURL         string   `json:"webhook_url,omitempty"`  // optional: fired alerts and automations are also POSTed here, signed
+
+	// Optional daily window in which only critical alerts are pushed
+	QuietHours *QuietHours `json:"quiet_hours,omitempty"`
+}
+
+// QuietHours is a daily do-not-disturb window given as "HH:MM" wall-clock times in TZ
+// (empty means UTC); end <= start crosses midnight. Mode "drop" (default) discards
+// non-critical pushes, "queue" delivers them as one summary when the window ends.
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	TZ    string `json:"tz,omitempty"`
+	Mode  string `json:"mode,omitempty"`
+}
+
+// GroupPrefix marks a rule server_id that targets a ServerGroup, e.g. "group:web".
+const GroupPrefix = "group:"
+
+// ServerGroup is a named set of servers that rules can target as "group:<id>".
+type ServerGroup struct {
+	ID        string   `json:"id"`
+	ServerIDs []string `json:"server_ids"`
 }
 
 // AlertRule defines a monitoring alert condition.
 type AlertRule struct {
-	ID            string  `json:"id"`
-	UserUUID      string  `json:"user_uuid"`
-	ServerID      string  `json:"server_id"`
-	ConditionType string  `json:"condition_type"` // cpu_threshold, ram_threshold, disk_threshold, power_state_change, offline_duration, restart_loop
-	Threshold     float64 `json:"threshold"`
-	Duration      int     `json:"duration"`  // seconds the condition must hold
-	Cooldown      int     `json:"cooldown"`  // seconds between triggers
-	Enabled       bool    `json:"enabled"`
+	ID            string        `json:"id"`
+	UserUUID      string        `json:"user_uuid"`
+	ServerID      string        `json:"server_id"` // a server ID or "group:<id>"
+	ConditionType ConditionType `json:"condition_type"`
+	Threshold     float64       `json:"threshold"`
+	Duration      int           `json:"duration"` // seconds the condition must hold
+	Cooldown      int           `json:"cooldown"` // seconds between triggers
+	Enabled       bool          `json:"enabled"`
+	Severity      string        `json:"severity,omitempty"`  // info, warning (default), critical
+	Smoothing     float64       `json:"smoothing,omitempty"` // 0-1 EMA weight of the previous value for threshold conditions, 0 = raw
+
+	// Optional escalation: after escalate_after triggers with no recovery and no gap longer
+	// than escalate_window seconds (default 3600), severity is raised one level
+	EscalateAfter  int `json:"escalate_after,omitempty"`
+	EscalateWindow int `json:"escalate_window,omitempty"`
+
+	// Optional console command whose output (captured for capture_wait seconds) is appended to the push
+	CaptureCommand string `json:"capture_command,omitempty"`
+	CaptureWait    int    `json:"capture_wait,omitempty"`
+
+	// Optional text/template overrides for the notification, see engine.alertTemplateData
+	TitleTemplate string `json:"title_template,omitempty"`
+	BodyTemplate  string `json:"body_template,omitempty"`
+
+	// Optional APNs sound ("" = silent, unset = default) and badge count
+	Sound *string `json:"sound,omitempty"`
+	Badge *int    `json:"badge,omitempty"`
+
+	// Optional: each trigger replaces the rule's previous notification for the server instead of stacking
+	Collapse bool `json:"collapse,omitempty"`
+
+	// Optional cron expression; an enabled rule only evaluates during minutes it matches
+	ActiveCron string `json:"active_cron,omitempty"`
+
+	// Composite rules (condition_type "composite") combine sub-conditions with "and"/"or"
+	Operator   string         `json:"operator,omitempty"`
+	Conditions []SubCondition `json:"conditions,omitempty"`
+}
+
+// SubCondition is one part of a composite alert rule.
+type SubCondition struct {
+	ConditionType ConditionType `json:"condition_type"`
+	Threshold     float64       `json:"threshold"`
 }
 
 // AutomationRule defines an automated action triggered by conditions.
 type AutomationRule struct {
 	ID            string                 `json:"id"`
 	UserUUID      string                 `json:"user_uuid"`
-	ServerID      string                 `json:"server_id"`
-	TriggerType   string                 `json:"trigger_type"`
-	TriggerConfig map[string]interface{} `json:"trigger_config"`
-	Action        string                 `json:"action"` // restart, stop, command, backup
-	ActionConfig  map[string]interface{} `json:"action_config"`
+	ServerID      string                 `json:"server_id"` // a server ID or "group:<id>"
+	TriggerType   TriggerType            `json:"trigger_type"`
+	TriggerConfig map[string]interface{} `json:"trigger_config"` // optional "active_hours": {start, end, tz}; server_crash: "stop_grace", "require_running"
+	Action        ActionType             `json:"action"`         // restart, stop, start, kill, command, backup, reinstall, power
+	ActionConfig  map[string]interface{} `json:"action_config"`  // optional "escalation": [{action, wait, command}, ...]; backup: "rotate", "max_backups", "name_template"; reinstall: "confirm"; power: "signal"
 	Cooldown      int                    `json:"cooldown"`
 	Enabled       bool                   `json:"enabled"`
+	Sound         *string                `json:"sound,omitempty"`       // APNs sound, "" = silent, unset = default
+	Badge         *int                   `json:"badge,omitempty"`       // APNs badge count
+	ActiveCron    string                 `json:"active_cron,omitempty"` // only evaluate during minutes this cron expression matches
 }
@@ -0,0 +1,88 @@
+package models
+
+// ConditionType is what an AlertRule watches for.
+type ConditionType string
+
+const (
+	ConditionCPU              ConditionType = "cpu_threshold"
+	ConditionCPUNormalized    ConditionType = "cpu_normalized_threshold" // percent of the server's core limit
+	ConditionRAM              ConditionType = "ram_threshold"
+	ConditionDisk             ConditionType = "disk_threshold"
+	ConditionRAMBytes         ConditionType = "ram_bytes_threshold"
+	ConditionDiskBytes        ConditionType = "disk_bytes_threshold"
+	ConditionNetwork          ConditionType = "network_threshold"
+	ConditionCPUSpike         ConditionType = "cpu_spike"
+	ConditionUptime           ConditionType = "uptime_threshold"
+	ConditionUptimeReset      ConditionType = "uptime_reset"
+	ConditionDiskTrend        ConditionType = "disk_trend"
+	ConditionSuspended        ConditionType = "server_suspended"
+	ConditionPowerStateChange ConditionType = "power_state_change"
+	ConditionOfflineDuration  ConditionType = "offline_duration"
+	ConditionRestartLoop      ConditionType = "restart_loop"
+	ConditionDataStale        ConditionType = "data_stale"
+	ConditionComposite        ConditionType = "composite"
+)
+
+// Valid reports whether c is a condition the alert evaluator understands.
+func (c ConditionType) Valid() bool {
+	switch c {
+	case ConditionCPU, ConditionCPUNormalized, ConditionRAM, ConditionDisk, ConditionRAMBytes, ConditionDiskBytes,
+		ConditionNetwork, ConditionCPUSpike, ConditionUptime, ConditionUptimeReset,
+		ConditionDiskTrend, ConditionSuspended, ConditionPowerStateChange,
+		ConditionOfflineDuration, ConditionRestartLoop, ConditionDataStale, ConditionComposite:
+		return true
+	default:
+		return false
+	}
+}
+
+// TriggerType is what starts an AutomationRule.
+type TriggerType string
+
+const (
+	TriggerCPU       TriggerType = "cpu_threshold"
+	TriggerRAM       TriggerType = "ram_threshold"
+	TriggerDisk      TriggerType = "disk_threshold"
+	TriggerRAMBytes  TriggerType = "ram_bytes_threshold"
+	TriggerDiskBytes TriggerType = "disk_bytes_threshold"
+	TriggerNetwork   TriggerType = "network_threshold"
+	TriggerOffline   TriggerType = "server_offline"
+	TriggerCrash     TriggerType = "server_crash"
+	TriggerSuspended TriggerType = "server_suspended"
+)
+
+// Valid reports whether t is a trigger the automation executor understands.
+func (t TriggerType) Valid() bool {
+	switch t {
+	case TriggerCPU, TriggerRAM, TriggerDisk, TriggerRAMBytes, TriggerDiskBytes,
+		TriggerNetwork, TriggerOffline, TriggerCrash, TriggerSuspended:
+		return true
+	default:
+		return false
+	}
+}
+
+// ActionType is what an AutomationRule (or one of its escalation steps) does.
+type ActionType string
+
+const (
+	ActionRestart   ActionType = "restart"
+	ActionStop      ActionType = "stop"
+	ActionStart     ActionType = "start"
+	ActionKill      ActionType = "kill"
+	ActionCommand   ActionType = "command"
+	ActionBackup    ActionType = "backup"
+	ActionReinstall ActionType = "reinstall"
+	ActionPower     ActionType = "power"
+)
+
+// Valid reports whether a is an action the automation executor can run.
+func (a ActionType) Valid() bool {
+	switch a {
+	case ActionRestart, ActionStop, ActionStart, ActionKill,
+		ActionCommand, ActionBackup, ActionReinstall, ActionPower:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConditionTypeValid(t *testing.T) {
+	for _, c := range []ConditionType{
+		ConditionCPU, ConditionCPUNormalized, ConditionRAM, ConditionDisk, ConditionRAMBytes,
+		ConditionDiskBytes, ConditionNetwork, ConditionCPUSpike, ConditionUptime, ConditionUptimeReset,
+		ConditionDiskTrend, ConditionSuspended, ConditionPowerStateChange, ConditionOfflineDuration,
+		ConditionRestartLoop, ConditionDataStale, ConditionComposite,
+	} {
+		if !c.Valid() {
+			t.Errorf("%q not valid", c)
+		}
+	}
+	for _, c := range []ConditionType{"", "cpu_treshold", "CPU_THRESHOLD", "cpu"} {
+		if c.Valid() {
+			t.Errorf("typo %q accepted", c)
+		}
+	}
+}
+
+func TestTriggerTypeValid(t *testing.T) {
+	for _, tt := range []TriggerType{
+		TriggerCPU, TriggerRAM, TriggerDisk, TriggerRAMBytes, TriggerDiskBytes,
+		TriggerNetwork, TriggerOffline, TriggerCrash, TriggerSuspended,
+	} {
+		if !tt.Valid() {
+			t.Errorf("%q not valid", tt)
+		}
+	}
+	for _, tt := range []TriggerType{"", "server_crashed", "cpu_spike"} {
+		if tt.Valid() {
+			t.Errorf("typo %q accepted", tt)
+		}
+	}
+}
+
+func TestActionTypeValid(t *testing.T) {
+	for _, a := range []ActionType{
+		ActionRestart, ActionStop, ActionStart, ActionKill,
+		ActionCommand, ActionBackup, ActionReinstall, ActionPower,
+	} {
+		if !a.Valid() {
+			t.Errorf("%q not valid", a)
+		}
+	}
+	for _, a := range []ActionType{"", "reboot", "Restart"} {
+		if a.Valid() {
+			t.Errorf("typo %q accepted", a)
+		}
+	}
+}
+
+func TestRuleTypesDecodeFromJSON(t *testing.T) {
+	var r AutomationRule
+	if err := json.Unmarshal([]byte(`{"trigger_type":"server_crash","action":"restart"}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.TriggerType != TriggerCrash || r.Action != ActionRestart {
+		t.Fatalf("decoded %q/%q", r.TriggerType, r.Action)
+	}
+}
@@ -4,16 +4,36 @@ import "time"
 
 // ResourceSnapshot represents a single point-in-time sample of server resources.
 type ResourceSnapshot struct {
-	ID         int64     `json:"id"`
+	ID            int64     `json:"id"`
+	ServerID      string    `json:"server_id"`
+	Timestamp     time.Time `json:"timestamp"`
+	PowerState    string    `json:"power_state"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	CPUNormalized float64   `json:"cpu_percent_normalized"` // CPUPercent divided by the core limit; equal to CPUPercent when unlimited
+	MemBytes      int64     `json:"mem_bytes"`
+	MemLimit      int64     `json:"mem_limit"`
+	DiskBytes     int64     `json:"disk_bytes"`
+	DiskLimit     int64     `json:"disk_limit"`
+	NetRx         int64     `json:"net_rx"`
+	NetTx         int64     `json:"net_tx"`
+	UptimeMs      int64     `json:"uptime_ms"`
+	IsSuspended   bool      `json:"is_suspended"`
+}
+
+// ServerState is the last known power state of a server, persisted across restarts.
+type ServerState struct {
 	ServerID   string    `json:"server_id"`
-	Timestamp  time.Time `json:"timestamp"`
 	PowerState string    `json:"power_state"`
-	CPUPercent float64   `json:"cpu_percent"`
-	MemBytes   int64     `json:"mem_bytes"`
-	MemLimit   int64     `json:"mem_limit"`
-	DiskBytes  int64     `json:"disk_bytes"`
-	DiskLimit  int64     `json:"disk_limit"`
-	NetRx      int64     `json:"net_rx"`
-	NetTx      int64     `json:"net_tx"`
-	UptimeMs   int64     `json:"uptime_ms"`
+	ChangedAt  time.Time `json:"changed_at"`   // when PowerState last changed
+	LastSeenAt time.Time `json:"last_seen_at"` // last successful sample
+}
+
+// ServerInfo holds descriptive panel attributes of a server, refreshed from the server list.
+type ServerInfo struct {
+	ServerID  string    `json:"server_id"`
+	Name      string    `json:"name"`
+	MemLimit  int64     `json:"mem_limit"`  // bytes, 0 = unlimited
+	DiskLimit int64     `json:"disk_limit"` // bytes, 0 = unlimited
+	CPUCores  float64   `json:"cpu_cores"`  // 0 = unlimited
+	UpdatedAt time.Time `json:"updated_at"`
 }
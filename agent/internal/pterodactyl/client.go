@@ -1,30 +1,159 @@
 package pterodactyl
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/xyidactyl/agent/internal/logging"
 )
 
+// maxRetryAfter caps how long a 429 Retry-After is honored.
+const maxRetryAfter = 30 * time.Second
+
 // Client communicates with the Pterodactyl API.
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
+	baseURL          string
+	httpClient       *http.Client
+	tlsConfig        *tls.Config                           // nil = system defaults
+	proxy            func(*http.Request) (*url.URL, error) // shared with the console websocket dialer
+	rateLimitRetries int                                   // retries after a 429 before giving up
+	retries          int                                   // retries of GETs after network errors or 5xx
+	retryDelay       time.Duration                         // base delay for those retries, doubled each time
+	stats            callStats                             // per-endpoint counters, see Stats
+}
+
+// ClientOptions configures transport behavior. The zero value matches the defaults.
+type ClientOptions struct {
+	Timeout            time.Duration // per-request timeout, default 25s
+	InsecureSkipVerify bool          // accept any panel certificate (self-signed setups)
+	CACertPath         string        // PEM bundle trusted in addition to the system roots
+	ProxyURL           string        // explicit proxy, otherwise HTTP(S)_PROXY from the environment
+	RateLimitRetries   int           // retries after a 429 before giving up
+	Retries            int           // retries of idempotent requests after network errors or 5xx
+	RetryDelay         time.Duration // base backoff for Retries, default 500ms
 }
 
 // NewClient creates a Pterodactyl API client.
-func NewClient(panelURL string) *Client {
-	url := strings.TrimRight(panelURL, "/")
+func NewClient(panelURL string, opts ClientOptions) (*Client, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 25 * time.Second
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = 500 * time.Millisecond
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	var tlsConfig *tls.Config
+	if opts.InsecureSkipVerify || opts.CACertPath != "" {
+		tlsConfig = &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+		if opts.CACertPath != "" {
+			pool, err := loadCertPool(opts.CACertPath)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if opts.ProxyURL != "" {
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
 	return &Client{
-		baseURL: url,
+		baseURL: strings.TrimRight(panelURL, "/"),
 		httpClient: &http.Client{
-			Timeout: 25 * time.Second,
+			Timeout:   opts.Timeout,
+			Transport: transport,
 		},
+		tlsConfig:        tlsConfig,
+		proxy:            transport.Proxy,
+		rateLimitRetries: opts.RateLimitRetries,
+		retries:          opts.Retries,
+		retryDelay:       opts.RetryDelay,
+	}, nil
+}
+
+// loadCertPool returns the system roots plus the certificates in a PEM file.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read CA cert: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// RateLimitedError is returned when the panel keeps answering 429 after all retries.
+type RateLimitedError struct {
+	RetryAfter time.Duration // wait requested by the last 429, capped at maxRetryAfter
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("API error 429: rate limited (retry after %s)", e.RetryAfter)
+	}
+	return "API error 429: rate limited"
+}
+
+// APIError is a non-2xx panel response other than a rate limit. Use errors.As to
+// branch on StatusCode, e.g. 401 (bad key), 404 (server gone) or 409 (state conflict).
+type APIError struct {
+	StatusCode int
+	Method     string
+	URL        string
+	Body       string // response body, truncated
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+// ConflictState maps a 409 from the panel to the state that blocks the server:
+// "installing", "transferring", "restoring_backup" or "suspended". ok is false for
+// other errors; an unrecognized 409 reports "conflict".
+func ConflictState(err error) (state string, ok bool) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		return "", false
+	}
+
+	body := strings.ToLower(apiErr.Body)
+	switch {
+	case strings.Contains(body, "install"):
+		return "installing", true
+	case strings.Contains(body, "transfer"):
+		return "transferring", true
+	case strings.Contains(body, "restor"):
+		return "restoring_backup", true
+	case strings.Contains(body, "suspend"):
+		return "suspended", true
+	default:
+		return "conflict", true
 	}
 }
 
@@ -54,6 +183,7 @@ type ServerListItem struct {
 	Limits     struct {
 		Memory int64 `json:"memory"`
 		Disk   int64 `json:"disk"`
+		CPU    int64 `json:"cpu"` // percent of one core, 0 = unlimited
 	} `json:"limits"`
 }
 
@@ -71,9 +201,9 @@ type serverListResponse struct {
 }
 
 // FetchResources gets resource usage for a specific server.
-func (c *Client) FetchResources(apiKey, serverID string) (*ServerResource, error) {
+func (c *Client) FetchResources(ctx context.Context, apiKey, serverID string) (*ServerResource, error) {
 	url := fmt.Sprintf("%s/api/client/servers/%s/resources", c.baseURL, serverID)
-	resp, err := c.doRequest("GET", url, apiKey, nil)
+	resp, err := c.doRequest(ctx, "GET", url, apiKey, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -87,13 +217,13 @@ func (c *Client) FetchResources(apiKey, serverID string) (*ServerResource, error
 }
 
 // ListServers gets all servers accessible by the given API key.
-func (c *Client) ListServers(apiKey string) ([]ServerListItem, error) {
+func (c *Client) ListServers(ctx context.Context, apiKey string) ([]ServerListItem, error) {
 	var allServers []ServerListItem
 	page := 1
 
 	for {
 		url := fmt.Sprintf("%s/api/client?page=%d", c.baseURL, page)
-		resp, err := c.doRequest("GET", url, apiKey, nil)
+		resp, err := c.doRequest(ctx, "GET", url, apiKey, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -118,11 +248,21 @@ func (c *Client) ListServers(apiKey string) ([]ServerListItem, error) {
 	return allServers, nil
 }
 
+// IsPowerSignal reports whether signal is one the panel's power endpoint accepts.
+func IsPowerSignal(signal string) bool {
+	switch signal {
+	case "start", "stop", "restart", "kill":
+		return true
+	default:
+		return false
+	}
+}
+
 // SendPowerSignal sends a power action to a server.
-func (c *Client) SendPowerSignal(apiKey, serverID, signal string) error {
+func (c *Client) SendPowerSignal(ctx context.Context, apiKey, serverID, signal string) error {
 	url := fmt.Sprintf("%s/api/client/servers/%s/power", c.baseURL, serverID)
 	body := fmt.Sprintf(`{"signal":"%s"}`, signal)
-	resp, err := c.doRequest("POST", url, apiKey, strings.NewReader(body))
+	resp, err := c.doRequest(ctx, "POST", url, apiKey, strings.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -131,10 +271,10 @@ func (c *Client) SendPowerSignal(apiKey, serverID, signal string) error {
 }
 
 // SendCommand sends a console command to a server.
-func (c *Client) SendCommand(apiKey, serverID, command string) error {
+func (c *Client) SendCommand(ctx context.Context, apiKey, serverID, command string) error {
 	url := fmt.Sprintf("%s/api/client/servers/%s/command", c.baseURL, serverID)
 	body := fmt.Sprintf(`{"command":"%s"}`, command)
-	resp, err := c.doRequest("POST", url, apiKey, strings.NewReader(body))
+	resp, err := c.doRequest(ctx, "POST", url, apiKey, strings.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -142,10 +282,18 @@ func (c *Client) SendCommand(apiKey, serverID, command string) error {
 	return nil
 }
 
-// CreateBackup triggers a backup for a server.
-func (c *Client) CreateBackup(apiKey, serverID string) error {
+// CreateBackup triggers a backup for a server. An empty name lets the panel pick one.
+func (c *Client) CreateBackup(ctx context.Context, apiKey, serverID, name string) error {
 	url := fmt.Sprintf("%s/api/client/servers/%s/backups", c.baseURL, serverID)
-	resp, err := c.doRequest("POST", url, apiKey, strings.NewReader("{}"))
+	req := map[string]string{}
+	if name != "" {
+		req["name"] = name
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal backup request: %w", err)
+	}
+	resp, err := c.doRequest(ctx, "POST", url, apiKey, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -153,39 +301,216 @@ func (c *Client) CreateBackup(apiKey, serverID string) error {
 	return nil
 }
 
-func (c *Client) doRequest(method, url, apiKey string, body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(method, url, body)
+// ReinstallServer reinstalls a server from its egg. This wipes server files.
+func (c *Client) ReinstallServer(ctx context.Context, apiKey, serverID string) error {
+	url := fmt.Sprintf("%s/api/client/servers/%s/settings/reinstall", c.baseURL, serverID)
+	resp, err := c.doRequest(ctx, "POST", url, apiKey, nil)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return err
+	}
+	defer resp.Body.Close()
+	return nil
+}
+
+// Backup is a server backup as returned by the panel.
+type Backup struct {
+	UUID         string    `json:"uuid"`
+	Name         string    `json:"name"`
+	IsSuccessful bool      `json:"is_successful"`
+	IsLocked     bool      `json:"is_locked"`
+	Bytes        int64     `json:"bytes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type backupListResponse struct {
+	Data []struct {
+		Attributes Backup `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			TotalPages int `json:"total_pages"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+// ListBackups gets all backups of a server.
+func (c *Client) ListBackups(ctx context.Context, apiKey, serverID string) ([]Backup, error) {
+	var backups []Backup
+	page := 1
+
+	for {
+		url := fmt.Sprintf("%s/api/client/servers/%s/backups?page=%d", c.baseURL, serverID, page)
+		resp, err := c.doRequest(ctx, "GET", url, apiKey, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result backupListResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("decode backup list: %w", err)
+		}
+		resp.Body.Close()
+
+		for _, d := range result.Data {
+			backups = append(backups, d.Attributes)
+		}
+
+		if page >= result.Meta.Pagination.TotalPages {
+			break
+		}
+		page++
 	}
 
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
+	return backups, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// DeleteBackup deletes a server backup.
+func (c *Client) DeleteBackup(ctx context.Context, apiKey, serverID, backupUUID string) error {
+	url := fmt.Sprintf("%s/api/client/servers/%s/backups/%s", c.baseURL, serverID, backupUUID)
+	resp, err := c.doRequest(ctx, "DELETE", url, apiKey, nil)
 	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
+		return err
 	}
+	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
+func (c *Client) doRequest(ctx context.Context, method, url, apiKey string, body io.Reader) (resp *http.Response, err error) {
+	start := time.Now()
+	var sent int64
+	defer func() {
+		var received int64
+		if resp != nil {
+			received = resp.ContentLength
+		}
+		c.stats.record(endpointName(method, url), time.Since(start), err != nil, sent, received)
+	}()
 
-		bodyStr := string(bodyBytes)
-		if len(bodyStr) > 500 {
-			bodyStr = bodyStr[:500] + "... (truncated)"
+	// Buffer the body so the request can be replayed after a 429
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = io.ReadAll(body); err != nil {
+			return nil, fmt.Errorf("read request body: %w", err)
 		}
+	}
+	sent = int64(len(payload))
+
+	// Power signals, commands and backups are not retried after a failure: the panel
+	// may have acted on the first attempt
+	idempotent := method == http.MethodGet || method == http.MethodHead
+	transient := 0 // network errors and 5xx retried so far
 
-		// 409 Conflict is common for servers in install/transfer states.
-		if resp.StatusCode == 409 {
-			logging.Debug("Pterodactyl API %s %s returned 409 (Conflict): %s", method, url, bodyStr)
-		} else {
-			logging.Warn("Pterodactyl API %s %s returned %d: %s", method, url, resp.StatusCode, bodyStr)
+	for attempt := 0; ; attempt++ {
+		var reqBody io.Reader
+		if payload != nil {
+			reqBody = bytes.NewReader(payload)
 		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			// Timeouts are not retried: the panel is slow, not flaky, and retrying would stall the cycle
+			if idempotent && transient < c.retries && ctx.Err() == nil && !os.IsTimeout(err) {
+				if err := c.backoff(ctx, method, url, transient, err.Error()); err != nil {
+					return nil, err
+				}
+				transient++
+				continue
+			}
+			return nil, fmt.Errorf("execute request: %w", err)
+		}
+
+		if resp.StatusCode >= 500 && idempotent && transient < c.retries {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if err := c.backoff(ctx, method, url, transient, resp.Status); err != nil {
+				return nil, err
+			}
+			transient++
+			continue
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
 
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, bodyStr)
+			wait := retryAfter(resp.Header.Get("Retry-After"), attempt)
+			if attempt >= c.rateLimitRetries {
+				logging.Warn("Pterodactyl API %s %s rate limited after %d retries", method, url, attempt)
+				return nil, &RateLimitedError{RetryAfter: wait}
+			}
+			logging.Debug("Pterodactyl API %s %s returned 429, retrying in %s", method, url, wait)
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("execute request: %w", ctx.Err())
+			case <-time.After(wait):
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 400 {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			bodyStr := string(bodyBytes)
+			if len(bodyStr) > 500 {
+				bodyStr = bodyStr[:500] + "... (truncated)"
+			}
+
+			// 409 Conflict is common for servers in install/transfer states.
+			if resp.StatusCode == 409 {
+				logging.Debug("Pterodactyl API %s %s returned 409 (Conflict): %s", method, url, bodyStr)
+			} else {
+				logging.Warn("Pterodactyl API %s %s returned %d: %s", method, url, resp.StatusCode, bodyStr)
+			}
+
+			return nil, &APIError{StatusCode: resp.StatusCode, Method: method, URL: url, Body: bodyStr}
+		}
+
+		return resp, nil
 	}
+}
 
-	return resp, nil
+// backoff waits before retry n (0-based) of a transient failure: retryDelay doubled
+// per retry plus up to 50% jitter, so many servers failing together don't retry in lockstep.
+func (c *Client) backoff(ctx context.Context, method, url string, n int, reason string) error {
+	wait := c.retryDelay << n
+	wait += time.Duration(rand.Int64N(int64(wait)/2 + 1))
+	logging.Debug("Pterodactyl API %s %s failed (%s), retrying in %s", method, url, reason, wait.Round(time.Millisecond))
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("execute request: %w", ctx.Err())
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// retryAfter parses a Retry-After header (seconds or HTTP date), falling back to
+// exponential backoff when absent, capped at maxRetryAfter.
+func retryAfter(header string, attempt int) time.Duration {
+	wait := maxRetryAfter
+	if attempt < 5 {
+		wait = time.Second << attempt
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		wait = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(header); err == nil {
+		wait = time.Until(t)
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return wait
 }
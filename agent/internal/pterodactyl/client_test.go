@@ -0,0 +1,158 @@
+package pterodactyl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client for a panel served by handler.
+func newTestClient(t *testing.T, opts ClientOptions, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(srv.URL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSendPowerSignalKill(t *testing.T) {
+	var method, path, body, auth string
+	c := newTestClient(t, ClientOptions{}, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		method, path, body, auth = r.Method, r.URL.Path, string(b), r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := c.SendPowerSignal(context.Background(), "ptlc_key", "abc123", "kill"); err != nil {
+		t.Fatalf("SendPowerSignal: %v", err)
+	}
+	if method != http.MethodPost || path != "/api/client/servers/abc123/power" {
+		t.Errorf("request %s %s", method, path)
+	}
+	if body != `{"signal":"kill"}` {
+		t.Errorf("body %s, want kill signal", body)
+	}
+	if auth != "Bearer ptlc_key" {
+		t.Errorf("Authorization %q", auth)
+	}
+}
+
+func TestIsPowerSignal(t *testing.T) {
+	for _, s := range []string{"start", "stop", "restart", "kill"} {
+		if !IsPowerSignal(s) {
+			t.Errorf("IsPowerSignal(%q) = false", s)
+		}
+	}
+	for _, s := range []string{"", "KILL", "freeze", "reinstall"} {
+		if IsPowerSignal(s) {
+			t.Errorf("IsPowerSignal(%q) = true", s)
+		}
+	}
+}
+
+func TestCancelMidRequest(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	c := newTestClient(t, ClientOptions{Retries: 2}, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	begin := time.Now()
+	_, err := c.FetchResources(ctx, "ptlc_key", "srv-1")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 2*time.Second {
+		t.Fatalf("cancelled request returned after %s", elapsed)
+	}
+}
+
+func TestConflictState(t *testing.T) {
+	tests := []struct {
+		err   error
+		state string
+		ok    bool
+	}{
+		{&APIError{StatusCode: 409, Body: `{"errors":[{"detail":"This server has not yet completed its installation process"}]}`}, "installing", true},
+		{&APIError{StatusCode: 409, Body: `{"errors":[{"detail":"This server is currently being transferred"}]}`}, "transferring", true},
+		{&APIError{StatusCode: 409, Body: `{"errors":[{"detail":"This server is currently restoring from a backup"}]}`}, "restoring_backup", true},
+		{&APIError{StatusCode: 409, Body: `{"errors":[{"detail":"This server is currently suspended"}]}`}, "suspended", true},
+		{&APIError{StatusCode: 409, Body: `{}`}, "conflict", true},
+		{fmt.Errorf("fetch resources: %w", &APIError{StatusCode: 409, Body: "installing"}), "installing", true},
+		{&APIError{StatusCode: 404, Body: "installing"}, "", false},
+		{errors.New("API error 409: installing"), "", false},
+		{nil, "", false},
+	}
+	for _, tc := range tests {
+		state, ok := ConflictState(tc.err)
+		if state != tc.state || ok != tc.ok {
+			t.Errorf("ConflictState(%v) = %q, %v, want %q, %v", tc.err, state, ok, tc.state, tc.ok)
+		}
+	}
+}
+
+func TestFetchResourcesConflict(t *testing.T) {
+	c := newTestClient(t, ClientOptions{}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"errors":[{"detail":"This server has not yet completed its installation process"}]}`)
+	})
+
+	_, err := c.FetchResources(context.Background(), "ptlc_key", "srv-1")
+	if state, ok := ConflictState(err); !ok || state != "installing" {
+		t.Fatalf("ConflictState(%v) = %q, %v, want installing", err, state, ok)
+	}
+}
+
+func TestAPIErrorStatusCode(t *testing.T) {
+	for _, code := range []int{http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError} {
+		c := newTestClient(t, ClientOptions{}, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+			fmt.Fprint(w, `{"errors":[{"detail":"nope"}]}`)
+		})
+
+		err := c.SendPowerSignal(context.Background(), "ptlc_key", "srv-1", "start")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("%d: err = %v, want an APIError", code, err)
+		}
+		if apiErr.StatusCode != code || apiErr.Method != http.MethodPost || !strings.HasSuffix(apiErr.URL, "/api/client/servers/srv-1/power") || !strings.Contains(apiErr.Body, "nope") {
+			t.Fatalf("%d: APIError = %+v", code, apiErr)
+		}
+		if !strings.Contains(err.Error(), fmt.Sprintf("API error %d", code)) {
+			t.Fatalf("%d: message %q lost the status code", code, err)
+		}
+	}
+}
+
+func TestAPIErrorBodyTruncated(t *testing.T) {
+	c := newTestClient(t, ClientOptions{}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, strings.Repeat("x", 2000))
+	})
+
+	_, err := c.FetchResources(context.Background(), "ptlc_key", "srv-1")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || len(apiErr.Body) > 600 || !strings.HasSuffix(apiErr.Body, "(truncated)") {
+		t.Fatalf("err = %v, want a truncated APIError body", err)
+	}
+}
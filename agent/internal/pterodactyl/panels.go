@@ -0,0 +1,92 @@
+package pterodactyl
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xyidactyl/agent/internal/logging"
+)
+
+// Panels hands out one Client per panel URL so users on different panels can be
+// monitored by the same agent. All clients share the transport options of the
+// default panel (PANEL_URL), which serves users without a panel_url.
+type Panels struct {
+	opts ClientOptions
+	def  *Client
+
+	mu      sync.Mutex
+	clients map[string]*Client // base URL -> client, besides the default
+}
+
+// NewPanels creates the client for the default panel.
+func NewPanels(defaultURL string, opts ClientOptions) (*Panels, error) {
+	def, err := NewClient(defaultURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Panels{opts: opts, def: def, clients: make(map[string]*Client)}, nil
+}
+
+// Default returns the client for PANEL_URL.
+func (p *Panels) Default() *Client {
+	return p.def
+}
+
+// For returns the client for panelURL, creating it on first use. An empty URL
+// selects the default panel.
+func (p *Panels) For(panelURL string) (*Client, error) {
+	base := strings.TrimRight(panelURL, "/")
+	if base == "" || base == p.def.baseURL {
+		return p.def, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if c, ok := p.clients[base]; ok {
+		return c, nil
+	}
+	c, err := NewClient(base, p.opts)
+	if err != nil {
+		return nil, fmt.Errorf("panel %s: %w", base, err)
+	}
+	p.clients[base] = c
+	logging.Info("Using additional panel %s", base)
+	return c, nil
+}
+
+// Stats returns the panel call metrics of every client. Endpoints of panels other
+// than the default are prefixed with their host.
+func (p *Panels) Stats() []EndpointStats {
+	out := p.def.Stats()
+
+	p.mu.Lock()
+	others := make(map[string]*Client, len(p.clients))
+	for base, c := range p.clients {
+		others[base] = c
+	}
+	p.mu.Unlock()
+
+	for base, c := range others {
+		host := base
+		if u, err := url.Parse(base); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		for _, s := range c.Stats() {
+			s.Endpoint = host + " " + s.Endpoint
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Endpoint < out[j].Endpoint })
+	return out
+}
+
+// LogStats writes a one-line summary per endpoint, to tell a slow panel from a slow agent.
+func (p *Panels) LogStats() {
+	for _, s := range p.Stats() {
+		logging.Info("Panel %s: %d calls, %d errors, avg %.0fms, max %dms", s.Endpoint, s.Calls, s.Errors, s.AvgMs, s.MaxMs)
+	}
+}
@@ -0,0 +1,65 @@
+package pterodactyl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPanelsCacheClientPerURL(t *testing.T) {
+	p, err := NewPanels("https://panel.example.com/", ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, u := range []string{"", "https://panel.example.com", "https://panel.example.com/"} {
+		if c, err := p.For(u); err != nil || c != p.Default() {
+			t.Fatalf("For(%q) = %p, %v, want the default client", u, c, err)
+		}
+	}
+
+	eu, err := p.For("https://panel-eu.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eu == p.Default() {
+		t.Fatal("a second panel got the default client")
+	}
+	if again, _ := p.For("https://panel-eu.example.com/"); again != eu {
+		t.Fatal("the same panel URL should reuse its client")
+	}
+}
+
+func TestPanelsStatsPrefixOtherHosts(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(resourcesJSON)))
+		fmt.Fprint(w, resourcesJSON)
+	}
+	def := newTestClient(t, ClientOptions{}, handler)
+	other := newTestClient(t, ClientOptions{}, handler)
+
+	p, err := NewPanels(def.baseURL, ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.For(other.baseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Default().FetchResources(context.Background(), "key", "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.FetchResources(context.Background(), "key", "abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := p.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("%d endpoints, want one per panel: %+v", len(stats), stats)
+	}
+	host := strings.TrimPrefix(other.baseURL, "http://")
+	statsFor(t, stats, "GET /api/client/servers/:id/resources")
+	statsFor(t, stats, host+" GET /api/client/servers/:id/resources")
+}
@@ -0,0 +1,165 @@
+package pterodactyl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const resourcesJSON = `{"attributes":{"current_state":"running","resources":{"cpu_absolute":12.5}}}`
+
+func TestRateLimitedThenOK(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, ClientOptions{RateLimitRetries: 1}, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, resourcesJSON)
+	})
+
+	res, err := c.FetchResources(context.Background(), "ptlc_key", "srv-1")
+	if err != nil {
+		t.Fatalf("FetchResources: %v", err)
+	}
+	if res.Resources.CPUAbsolute != 12.5 {
+		t.Fatalf("cpu = %v, want 12.5", res.Resources.CPUAbsolute)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("%d requests, want 2", n)
+	}
+}
+
+func TestRateLimitedError(t *testing.T) {
+	for _, retries := range []int{0, 2} {
+		var calls atomic.Int32
+		c := newTestClient(t, ClientOptions{RateLimitRetries: retries}, func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+
+		_, err := c.FetchResources(context.Background(), "ptlc_key", "srv-1")
+		var rl *RateLimitedError
+		if !errors.As(err, &rl) {
+			t.Fatalf("retries=%d: err = %v, want a RateLimitedError", retries, err)
+		}
+		if n := calls.Load(); int(n) != retries+1 {
+			t.Fatalf("retries=%d: %d requests, want %d", retries, n, retries+1)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header  string
+		attempt int
+		want    time.Duration
+	}{
+		{"", 0, time.Second},
+		{"", 2, 4 * time.Second},
+		{"", 10, maxRetryAfter},
+		{"5", 0, 5 * time.Second},
+		{"3600", 0, maxRetryAfter},
+		{"-1", 0, time.Second},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tc := range tests {
+		if got := retryAfter(tc.header, tc.attempt); got != tc.want {
+			t.Errorf("retryAfter(%q, %d) = %s, want %s", tc.header, tc.attempt, got, tc.want)
+		}
+	}
+}
+
+func TestTransientFailuresRetried(t *testing.T) {
+	for name, fail := range map[string]func(w http.ResponseWriter){
+		"5xx": func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+		"connection reset": func(w http.ResponseWriter) {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var calls atomic.Int32
+			c := newTestClient(t, ClientOptions{Retries: 2, RetryDelay: time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= 2 {
+					fail(w)
+					return
+				}
+				fmt.Fprint(w, resourcesJSON)
+			})
+
+			res, err := c.FetchResources(context.Background(), "ptlc_key", "srv-1")
+			if err != nil {
+				t.Fatalf("FetchResources: %v", err)
+			}
+			if res.Resources.CPUAbsolute != 12.5 {
+				t.Fatalf("cpu = %v, want 12.5", res.Resources.CPUAbsolute)
+			}
+			if n := calls.Load(); n != 3 {
+				t.Fatalf("%d requests, want 3", n)
+			}
+		})
+	}
+}
+
+func TestTransientRetriesExhausted(t *testing.T) {
+	for _, retries := range []int{0, 2} {
+		var calls atomic.Int32
+		c := newTestClient(t, ClientOptions{Retries: retries, RetryDelay: time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+
+		_, err := c.FetchResources(context.Background(), "ptlc_key", "srv-1")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("retries=%d: err = %v, want a 503 APIError", retries, err)
+		}
+		if n := calls.Load(); int(n) != retries+1 {
+			t.Fatalf("retries=%d: %d requests, want %d", retries, n, retries+1)
+		}
+	}
+}
+
+func TestPowerSignalNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, ClientOptions{Retries: 3, RetryDelay: time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	// The panel may have acted on the first attempt, so a POST is never replayed
+	if err := c.SendPowerSignal(context.Background(), "ptlc_key", "srv-1", "restart"); err == nil {
+		t.Fatal("want an error for a 502")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("%d requests, want 1", n)
+	}
+}
+
+func TestRetryBackoffStopsOnCancel(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, ClientOptions{Retries: 5, RetryDelay: time.Minute}, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.FetchResources(ctx, "ptlc_key", "srv-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the context deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("FetchResources took %s, want the backoff cut short", elapsed)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("%d requests, want 1", n)
+	}
+}
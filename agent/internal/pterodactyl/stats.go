@@ -0,0 +1,116 @@
+package pterodactyl
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds (ms) of the panel latency histogram; the last
+// bucket in EndpointStats.Buckets counts everything slower.
+var latencyBuckets = [...]int64{50, 100, 250, 500, 1000, 2500, 5000}
+
+// EndpointStats are cumulative counters for one panel endpoint, e.g. "GET /api/client/servers/:id/resources".
+type EndpointStats struct {
+	Endpoint      string  `json:"endpoint"`
+	Calls         int64   `json:"calls"`
+	Errors        int64   `json:"errors"`
+	AvgMs         float64 `json:"avg_ms"`
+	MaxMs         int64   `json:"max_ms"`
+	BytesSent     int64   `json:"bytes_sent"`
+	BytesReceived int64   `json:"bytes_received"`  // from Content-Length, when the panel sends one
+	Buckets       []int64 `json:"latency_buckets"` // counts per latencyBuckets bound, then > 5000ms
+}
+
+type endpointCounters struct {
+	calls, errors  int64
+	totalMs, maxMs int64
+	bytesOut       int64
+	bytesIn        int64
+	buckets        [len(latencyBuckets) + 1]int64
+}
+
+// callStats aggregates panel call metrics per endpoint.
+type callStats struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointCounters
+}
+
+func (s *callStats) record(endpoint string, d time.Duration, failed bool, sent, received int64) {
+	ms := d.Milliseconds()
+	bucket := len(latencyBuckets)
+	for i, bound := range latencyBuckets {
+		if ms <= bound {
+			bucket = i
+			break
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.endpoints == nil {
+		s.endpoints = make(map[string]*endpointCounters)
+	}
+	c, ok := s.endpoints[endpoint]
+	if !ok {
+		c = &endpointCounters{}
+		s.endpoints[endpoint] = c
+	}
+	c.calls++
+	if failed {
+		c.errors++
+	}
+	c.totalMs += ms
+	if ms > c.maxMs {
+		c.maxMs = ms
+	}
+	c.bytesOut += sent
+	if received > 0 {
+		c.bytesIn += received
+	}
+	c.buckets[bucket]++
+}
+
+// Stats returns the per-endpoint panel call metrics since startup, sorted by endpoint.
+func (c *Client) Stats() []EndpointStats {
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+
+	out := make([]EndpointStats, 0, len(c.stats.endpoints))
+	for name, e := range c.stats.endpoints {
+		out = append(out, EndpointStats{
+			Endpoint:      name,
+			Calls:         e.calls,
+			Errors:        e.errors,
+			AvgMs:         float64(e.totalMs) / float64(e.calls),
+			MaxMs:         e.maxMs,
+			BytesSent:     e.bytesOut,
+			BytesReceived: e.bytesIn,
+			Buckets:       append([]int64(nil), e.buckets[:]...),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Endpoint < out[j].Endpoint })
+	return out
+}
+
+// endpointName reduces a request URL to a low-cardinality key by replacing server
+// identifiers and backup UUIDs with placeholders.
+func endpointName(method, rawURL string) string {
+	path := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		path = u.Path
+	}
+
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		switch parts[i-1] {
+		case "servers":
+			parts[i] = ":id"
+		case "backups":
+			parts[i] = ":uuid"
+		}
+	}
+	return method + " " + strings.Join(parts, "/")
+}
@@ -0,0 +1,101 @@
+package pterodactyl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// statsFor returns the stats of one endpoint, failing the test if it has none.
+func statsFor(t *testing.T, stats []EndpointStats, endpoint string) EndpointStats {
+	t.Helper()
+	for _, s := range stats {
+		if s.Endpoint == endpoint {
+			return s
+		}
+	}
+	t.Fatalf("no stats for %q in %+v", endpoint, stats)
+	return EndpointStats{}
+}
+
+func TestStatsCountSuccessAndErrors(t *testing.T) {
+	c := newTestClient(t, ClientOptions{}, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/client/servers/missing"):
+			http.Error(w, "not found", http.StatusNotFound)
+		case strings.HasSuffix(r.URL.Path, "/resources"):
+			w.Header().Set("Content-Length", fmt.Sprint(len(resourcesJSON)))
+			fmt.Fprint(w, resourcesJSON)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	ctx := context.Background()
+
+	for _, id := range []string{"abc", "def", "ghi"} {
+		if _, err := c.FetchResources(ctx, "key", id); err != nil {
+			t.Fatalf("FetchResources(%s): %v", id, err)
+		}
+	}
+	if _, err := c.FetchResources(ctx, "key", "missing"); err == nil {
+		t.Fatal("FetchResources(missing): want an error")
+	}
+	if err := c.SendPowerSignal(ctx, "key", "abc", "restart"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := c.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("%d endpoints, want resources and power: %+v", len(stats), stats)
+	}
+
+	res := statsFor(t, stats, "GET /api/client/servers/:id/resources")
+	if res.Calls != 4 || res.Errors != 1 {
+		t.Fatalf("resources: %d calls, %d errors, want 4 and 1", res.Calls, res.Errors)
+	}
+	if res.BytesReceived < int64(3*len(resourcesJSON)) {
+		t.Fatalf("resources: %d bytes received, want at least %d", res.BytesReceived, 3*len(resourcesJSON))
+	}
+	var bucketed int64
+	for _, n := range res.Buckets {
+		bucketed += n
+	}
+	if len(res.Buckets) != len(latencyBuckets)+1 || bucketed != res.Calls {
+		t.Fatalf("resources: buckets %v, want %d buckets holding every call", res.Buckets, len(latencyBuckets)+1)
+	}
+
+	power := statsFor(t, stats, "POST /api/client/servers/:id/power")
+	if power.Calls != 1 || power.Errors != 0 || power.BytesSent != int64(len(`{"signal":"restart"}`)) {
+		t.Fatalf("power: %+v, want one successful call with the signal body", power)
+	}
+}
+
+func TestStatsCountConnectionErrors(t *testing.T) {
+	c, err := NewClient("http://127.0.0.1:1", ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SendPowerSignal(context.Background(), "key", "abc", "kill"); err == nil {
+		t.Fatal("want a connection error")
+	}
+
+	s := statsFor(t, c.Stats(), "POST /api/client/servers/:id/power")
+	if s.Calls != 1 || s.Errors != 1 || s.BytesReceived != 0 {
+		t.Fatalf("stats %+v, want one failed call", s)
+	}
+}
+
+func TestEndpointName(t *testing.T) {
+	for _, tc := range []struct{ method, url, want string }{
+		{"GET", "https://panel.test/api/client", "GET /api/client"},
+		{"GET", "https://panel.test/api/client/servers/abc123/resources", "GET /api/client/servers/:id/resources"},
+		{"GET", "https://panel.test/api/client/servers/abc123/backups?page=2", "GET /api/client/servers/:id/backups"},
+		{"DELETE", "https://panel.test/api/client/servers/abc123/backups/0b1c-uuid", "DELETE /api/client/servers/:id/backups/:uuid"},
+	} {
+		if got := endpointName(tc.method, tc.url); got != tc.want {
+			t.Errorf("endpointName(%s, %s) = %q, want %q", tc.method, tc.url, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package pterodactyl
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTLSPanel serves the resources endpoint over TLS with a self-signed certificate
+// and returns the server and the path of its CA in PEM form.
+func newTLSPanel(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, resourcesJSON)
+	}))
+	t.Cleanup(srv.Close)
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return srv, caPath
+}
+
+func TestTLSWithCustomCA(t *testing.T) {
+	srv, caPath := newTLSPanel(t)
+
+	tests := []struct {
+		name    string
+		opts    ClientOptions
+		wantErr bool
+	}{
+		{"system roots only", ClientOptions{}, true},
+		{"CA configured", ClientOptions{CACertPath: caPath}, false},
+		{"insecure skip verify", ClientOptions{InsecureSkipVerify: true}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(srv.URL, tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.FetchResources(context.Background(), "ptlc_key", "srv-1")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("FetchResources err = %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewClientRejectsBadOptions(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, opts := range map[string]ClientOptions{
+		"missing CA": {CACertPath: filepath.Join(t.TempDir(), "missing.pem")},
+		"CA not PEM": {CACertPath: empty},
+		"bad proxy":  {ProxyURL: "http://[::1"},
+	} {
+		if _, err := NewClient("https://panel.example.com", opts); err == nil {
+			t.Errorf("%s: NewClient succeeded, want an error", name)
+		}
+	}
+}
+
+func TestNewClientDefaults(t *testing.T) {
+	c, err := NewClient("https://panel.example.com/", ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.httpClient.Timeout != 25*time.Second {
+		t.Errorf("timeout = %s, want 25s", c.httpClient.Timeout)
+	}
+	if c.tlsConfig != nil {
+		t.Error("default client has a custom TLS config")
+	}
+	if c.baseURL != "https://panel.example.com" {
+		t.Errorf("baseURL = %q, want the trailing slash trimmed", c.baseURL)
+	}
+}
@@ -0,0 +1,275 @@
+package pterodactyl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/xyidactyl/agent/internal/logging"
+)
+
+// Console websocket auth flow (Pterodactyl client API → Wings):
+//
+//  1. GET /api/client/servers/{id}/websocket with the user's API key returns a short-lived
+//     JWT ("token") and the Wings socket URL ("socket").
+//  2. Dial the socket URL with an Origin header matching the panel URL (Wings rejects
+//     other origins).
+//  3. Send {"event":"auth","args":["<token>"]} and wait for {"event":"auth success"}.
+//  4. Wings then streams events such as "console output", "status" and "stats".
+//     Commands are sent as {"event":"send command","args":["<command>"]}.
+//  5. Before the JWT expires Wings sends "token expiring"; fetch a new token (step 1)
+//     and re-send "auth" on the same connection. "token expired" means it is too late.
+
+const consoleAuthTimeout = 10 * time.Second
+
+type websocketResponse struct {
+	Data struct {
+		Token  string `json:"token"`
+		Socket string `json:"socket"`
+	} `json:"data"`
+}
+
+// consoleEvent is a message on the Wings websocket.
+type consoleEvent struct {
+	Event string   `json:"event"`
+	Args  []string `json:"args,omitempty"`
+}
+
+// consoleConn is an authenticated Wings console websocket.
+type consoleConn struct {
+	client   *Client
+	apiKey   string
+	serverID string
+	conn     *websocket.Conn
+}
+
+// websocketCredentials fetches a console token and socket URL for a server.
+func (c *Client) websocketCredentials(ctx context.Context, apiKey, serverID string) (string, string, error) {
+	url := fmt.Sprintf("%s/api/client/servers/%s/websocket", c.baseURL, serverID)
+	resp, err := c.doRequest(ctx, "GET", url, apiKey, nil)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	var result websocketResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", "", fmt.Errorf("decode websocket credentials: %w", err)
+	}
+	if result.Data.Token == "" || result.Data.Socket == "" {
+		return "", "", errors.New("panel returned empty websocket credentials")
+	}
+	return result.Data.Token, result.Data.Socket, nil
+}
+
+// dialConsole connects to a server's console websocket and authenticates.
+func (c *Client) dialConsole(ctx context.Context, apiKey, serverID string) (*consoleConn, error) {
+	token, socket, err := c.websocketCredentials(ctx, apiKey, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Origin", c.baseURL)
+
+	dialer := websocket.Dialer{
+		HandshakeTimeout: consoleAuthTimeout,
+		TLSClientConfig:  c.tlsConfig,
+		Proxy:            c.proxy,
+	}
+	conn, _, err := dialer.DialContext(ctx, socket, header)
+	if err != nil {
+		return nil, fmt.Errorf("dial console websocket: %w", err)
+	}
+
+	cc := &consoleConn{client: c, apiKey: apiKey, serverID: serverID, conn: conn}
+	if err := cc.send("auth", token); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Wait for the auth acknowledgement
+	conn.SetReadDeadline(time.Now().Add(consoleAuthTimeout))
+	for {
+		ev, err := cc.read()
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("console auth: %w", err)
+		}
+		if ev.Event == "auth success" {
+			break
+		}
+		if ev.Event == "jwt error" {
+			conn.Close()
+			return nil, fmt.Errorf("console auth rejected: %s", strings.Join(ev.Args, " "))
+		}
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	return cc, nil
+}
+
+func (cc *consoleConn) send(event string, args ...string) error {
+	if err := cc.conn.WriteJSON(consoleEvent{Event: event, Args: args}); err != nil {
+		return fmt.Errorf("send %s: %w", event, err)
+	}
+	return nil
+}
+
+func (cc *consoleConn) read() (consoleEvent, error) {
+	var ev consoleEvent
+	err := cc.conn.ReadJSON(&ev)
+	return ev, err
+}
+
+// reauth fetches a fresh token and re-authenticates the open connection.
+func (cc *consoleConn) reauth(ctx context.Context) error {
+	token, _, err := cc.client.websocketCredentials(ctx, cc.apiKey, cc.serverID)
+	if err != nil {
+		return err
+	}
+	return cc.send("auth", token)
+}
+
+func (cc *consoleConn) close() {
+	cc.conn.Close()
+}
+
+// SendCommandAndCapture sends a console command and returns the console output
+// received during the following wait duration, one line per output event.
+func (c *Client) SendCommandAndCapture(ctx context.Context, apiKey, serverID, command string, wait time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, wait+2*consoleAuthTimeout)
+	defer cancel()
+
+	cc, err := c.dialConsole(ctx, apiKey, serverID)
+	if err != nil {
+		return "", err
+	}
+	defer cc.close()
+	// Abort the capture promptly on cancellation
+	stop := context.AfterFunc(ctx, cc.close)
+	defer stop()
+
+	if err := cc.send("send command", command); err != nil {
+		return "", err
+	}
+
+	var lines []string
+	cc.conn.SetReadDeadline(time.Now().Add(wait))
+	for {
+		ev, err := cc.read()
+		if err != nil {
+			var netErr interface{ Timeout() bool }
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break // Capture window over
+			}
+			return strings.Join(lines, "\n"), fmt.Errorf("read console: %w", err)
+		}
+
+		switch ev.Event {
+		case "console output":
+			lines = append(lines, ev.Args...)
+		case "token expiring":
+			if err := cc.reauth(ctx); err != nil {
+				return strings.Join(lines, "\n"), err
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+const (
+	consoleReconnectMin = time.Second
+	consoleReconnectMax = time.Minute
+)
+
+// StreamConsole connects to a server's console and streams output lines until ctx
+// is cancelled. Dropped connections are re-established with exponential backoff;
+// only the initial connection error is returned. The channel is closed when ctx ends.
+func (c *Client) StreamConsole(ctx context.Context, apiKey, serverID string) (<-chan string, error) {
+	cc, err := c.dialConsole(ctx, apiKey, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(chan string, 64)
+	go func() {
+		defer close(lines)
+
+		backoff := consoleReconnectMin
+		for {
+			connectedAt := time.Now()
+			err := cc.stream(ctx, lines)
+			cc.close()
+			if ctx.Err() != nil {
+				return
+			}
+			// Only a connection that stayed up for a while resets the backoff
+			if time.Since(connectedAt) > consoleReconnectMax {
+				backoff = consoleReconnectMin
+			}
+
+			for {
+				logging.Debug("Console stream for server %s dropped (%v), reconnecting in %s", serverID, err, backoff)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				if backoff *= 2; backoff > consoleReconnectMax {
+					backoff = consoleReconnectMax
+				}
+
+				if cc, err = c.dialConsole(ctx, apiKey, serverID); err == nil {
+					break
+				}
+			}
+		}
+	}()
+
+	return lines, nil
+}
+
+// stream forwards console output to lines until the connection fails or ctx ends.
+func (cc *consoleConn) stream(ctx context.Context, lines chan<- string) error {
+	// Unblock the read when ctx is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cc.conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		ev, err := cc.read()
+		if err != nil {
+			return err
+		}
+
+		switch ev.Event {
+		case "console output":
+			for _, line := range ev.Args {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		case "token expiring":
+			if err := cc.reauth(ctx); err != nil {
+				return err
+			}
+		case "token expired":
+			return errors.New("console token expired")
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package pterodactyl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeWings is a panel that hands out console credentials for a Wings websocket it
+// also serves. Each connection is authenticated, then handed to session.
+type fakeWings struct {
+	srv     *httptest.Server
+	session func(conn *websocket.Conn)
+
+	mu         sync.Mutex
+	issued     int      // credentials handed out
+	origins    []string // Origin header of each websocket connection
+	rejectAuth bool     // answer auth with a jwt error
+}
+
+func newFakeWings(t *testing.T, session func(conn *websocket.Conn)) (*Client, *fakeWings) {
+	t.Helper()
+	fw := &fakeWings{session: session}
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+	fw.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/websocket"):
+			fw.mu.Lock()
+			fw.issued++
+			token := fmt.Sprintf("jwt-%d", fw.issued)
+			fw.mu.Unlock()
+			socket := "ws" + strings.TrimPrefix(fw.srv.URL, "http") + "/ws"
+			fmt.Fprintf(w, `{"data":{"token":%q,"socket":%q}}`, token, socket)
+
+		case r.URL.Path == "/ws":
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			fw.mu.Lock()
+			fw.origins = append(fw.origins, r.Header.Get("Origin"))
+			reject := fw.rejectAuth
+			fw.mu.Unlock()
+
+			var ev consoleEvent
+			if err := conn.ReadJSON(&ev); err != nil || ev.Event != "auth" || len(ev.Args) != 1 || !strings.HasPrefix(ev.Args[0], "jwt-") {
+				return
+			}
+			if reject {
+				conn.WriteJSON(consoleEvent{Event: "jwt error", Args: []string{"signature is invalid"}})
+				return
+			}
+			conn.WriteJSON(consoleEvent{Event: "auth success"})
+			fw.session(conn)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(fw.srv.Close)
+
+	c, err := NewClient(fw.srv.URL, ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, fw
+}
+
+// output sends console output lines on conn.
+func output(conn *websocket.Conn, lines ...string) error {
+	return conn.WriteJSON(consoleEvent{Event: "console output", Args: lines})
+}
+
+func TestSendCommandAndCapture(t *testing.T) {
+	c, fw := newFakeWings(t, func(conn *websocket.Conn) {
+		var ev consoleEvent
+		if err := conn.ReadJSON(&ev); err != nil || ev.Event != "send command" || len(ev.Args) != 1 || ev.Args[0] != "list" {
+			return
+		}
+		output(conn, "There are 2 of a max of 20 players online:")
+		output(conn, "alice, bob")
+		// Hold the connection open past the capture window
+		conn.ReadJSON(&ev)
+	})
+
+	got, err := c.SendCommandAndCapture(context.Background(), "ptlc_key", "srv-1", "list", 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("SendCommandAndCapture: %v", err)
+	}
+	if want := "There are 2 of a max of 20 players online:\nalice, bob"; got != want {
+		t.Fatalf("captured %q, want %q", got, want)
+	}
+
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if len(fw.origins) != 1 || fw.origins[0] != fw.srv.URL {
+		t.Fatalf("Origin headers %q, want the panel URL", fw.origins)
+	}
+}
+
+func TestSendCommandAndCaptureReauthenticates(t *testing.T) {
+	c, fw := newFakeWings(t, func(conn *websocket.Conn) {
+		var ev consoleEvent
+		conn.ReadJSON(&ev) // the command
+		conn.WriteJSON(consoleEvent{Event: "token expiring"})
+		if err := conn.ReadJSON(&ev); err != nil || ev.Event != "auth" {
+			return
+		}
+		output(conn, "after reauth")
+		conn.ReadJSON(&ev)
+	})
+
+	got, err := c.SendCommandAndCapture(context.Background(), "ptlc_key", "srv-1", "tps", 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("SendCommandAndCapture: %v", err)
+	}
+	if got != "after reauth" {
+		t.Fatalf("captured %q, want the line sent after re-auth", got)
+	}
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.issued != 2 {
+		t.Fatalf("%d tokens issued, want a second one for re-auth", fw.issued)
+	}
+}
+
+func TestSendCommandAndCaptureAuthRejected(t *testing.T) {
+	c, fw := newFakeWings(t, func(conn *websocket.Conn) {})
+	fw.rejectAuth = true
+
+	_, err := c.SendCommandAndCapture(context.Background(), "ptlc_key", "srv-1", "list", 100*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "signature is invalid") {
+		t.Fatalf("err = %v, want the jwt error", err)
+	}
+}
+
+// recvLines reads n lines from ch, failing the test after timeout.
+func recvLines(t *testing.T, ch <-chan string, n int, timeout time.Duration) []string {
+	t.Helper()
+	var got []string
+	deadline := time.After(timeout)
+	for len(got) < n {
+		select {
+		case line, ok := <-ch:
+			if !ok {
+				t.Fatalf("stream closed after %q", got)
+			}
+			got = append(got, line)
+		case <-deadline:
+			t.Fatalf("got %q before timing out, want %d lines", got, n)
+		}
+	}
+	return got
+}
+
+func TestStreamConsole(t *testing.T) {
+	c, _ := newFakeWings(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(consoleEvent{Event: "status", Args: []string{"running"}})
+		output(conn, "[Server] Done (3.2s)!")
+		output(conn, "player joined", "player left")
+		var ev consoleEvent
+		conn.ReadJSON(&ev)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lines, err := c.StreamConsole(ctx, "ptlc_key", "srv-1")
+	if err != nil {
+		t.Fatalf("StreamConsole: %v", err)
+	}
+	got := recvLines(t, lines, 3, 5*time.Second)
+	if want := []string{"[Server] Done (3.2s)!", "player joined", "player left"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("lines %q, want %q", got, want)
+	}
+
+	cancel()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-lines:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("stream not closed after cancel")
+		}
+	}
+}
+
+func TestStreamConsoleReconnects(t *testing.T) {
+	var mu sync.Mutex
+	conns := 0
+	c, _ := newFakeWings(t, func(conn *websocket.Conn) {
+		mu.Lock()
+		conns++
+		n := conns
+		mu.Unlock()
+		output(conn, fmt.Sprintf("connection %d", n))
+		if n > 1 {
+			var ev consoleEvent
+			conn.ReadJSON(&ev)
+		}
+		// The first connection drops right away
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, err := c.StreamConsole(ctx, "ptlc_key", "srv-1")
+	if err != nil {
+		t.Fatalf("StreamConsole: %v", err)
+	}
+	got := recvLines(t, lines, 2, consoleReconnectMin+5*time.Second)
+	if got[0] != "connection 1" || got[1] != "connection 2" {
+		t.Fatalf("lines %q, want output from both connections", got)
+	}
+}
+
+func TestStreamConsoleInitialError(t *testing.T) {
+	c := newTestClient(t, ClientOptions{}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	if _, err := c.StreamConsole(context.Background(), "ptlc_key", "srv-1"); err == nil {
+		t.Fatal("want an error when the panel refuses console credentials")
+	}
+}
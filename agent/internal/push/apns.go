@@ -8,6 +8,7 @@ import (
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -16,25 +17,41 @@ import (
 	"sync"
 	"time"
 
+	"github.com/xyidactyl/agent/internal/clock"
 	"github.com/xyidactyl/agent/internal/logging"
 )
 
+const (
+	apnsProductionHost = "https://api.push.apple.com"
+	apnsSandboxHost    = "https://api.sandbox.push.apple.com"
+
+	// Apple rejects provider tokens older than an hour; we re-sign well before that.
+	apnsJWTLifetime = 45 * time.Minute
+)
+
 // APNsProvider sends push notifications via Apple Push Notification service.
 type APNsProvider struct {
+	host       string
 	keyID      string
 	teamID     string
 	bundleID   string
 	privateKey *ecdsa.PrivateKey
 	client     *http.Client
 
-	mu       sync.Mutex
-	jwtToken string
-	jwtExp   time.Time
+	mu            sync.Mutex
+	jwtToken      string
+	jwtExp        time.Time
+	refreshMargin time.Duration // re-sign this long before jwtExp
+	clock         clock.Clock
+	stop          chan struct{}
+	stopOnce      sync.Once
 }
 
 // NewAPNsProvider creates an APNs push provider.
 // keyBase64 is the base64-encoded contents of the .p8 file.
-func NewAPNsProvider(keyBase64, keyID, teamID, bundleID string) (*APNsProvider, error) {
+// environment is "sandbox" for development builds; anything else uses production.
+// refreshMargin is how long before expiry the provider token is re-signed.
+func NewAPNsProvider(keyBase64, keyID, teamID, bundleID, environment string, refreshMargin time.Duration, clk clock.Clock) (*APNsProvider, error) {
 	keyBytes, err := base64.StdEncoding.DecodeString(keyBase64)
 	if err != nil {
 		return nil, fmt.Errorf("decode APNs key: %w", err)
@@ -55,7 +72,12 @@ func NewAPNsProvider(keyBase64, keyID, teamID, bundleID string) (*APNsProvider,
 		return nil, fmt.Errorf("key is not ECDSA")
 	}
 
+	if refreshMargin < 0 || refreshMargin >= apnsJWTLifetime {
+		return nil, fmt.Errorf("JWT refresh margin must be between 0 and %s, got %s", apnsJWTLifetime, refreshMargin)
+	}
+
 	return &APNsProvider{
+		host:       apnsHost(environment),
 		keyID:      keyID,
 		teamID:     teamID,
 		bundleID:   bundleID,
@@ -63,19 +85,73 @@ func NewAPNsProvider(keyBase64, keyID, teamID, bundleID string) (*APNsProvider,
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		refreshMargin: refreshMargin,
+		clock:         clock.OrReal(clk),
+		stop:          make(chan struct{}),
 	}, nil
 }
 
+// Start signs the first provider token and keeps re-signing it refreshMargin
+// before expiry, so sends never pay the signing cost or use a stale token.
+func (a *APNsProvider) Start() {
+	go a.refreshLoop()
+}
+
+// Stop ends the background refresh. Safe to call more than once.
+func (a *APNsProvider) Stop() {
+	a.stopOnce.Do(func() { close(a.stop) })
+}
+
+func (a *APNsProvider) refreshLoop() {
+	for {
+		timer := time.NewTimer(a.untilRefresh())
+		select {
+		case <-a.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := a.refreshJWT(); err != nil {
+			logging.Warn("APNs JWT refresh failed, retrying in 1m: %v", err)
+			select {
+			case <-a.stop:
+				return
+			case <-time.After(time.Minute):
+			}
+		}
+	}
+}
+
+// untilRefresh is how long until the cached token enters its refresh margin.
+func (a *APNsProvider) untilRefresh() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.jwtToken == "" {
+		return 0
+	}
+	if d := a.jwtExp.Add(-a.refreshMargin).Sub(a.clock.Now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// refreshJWT re-signs the provider token unless it is still outside the refresh margin.
+func (a *APNsProvider) refreshJWT() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.fresh() {
+		return nil
+	}
+	return a.resign()
+}
+
 // Send delivers a push notification via APNs with retry.
 func (a *APNsProvider) Send(ctx context.Context, token string, payload Payload) error {
 	apnsPayload := map[string]interface{}{
-		"aps": map[string]interface{}{
-			"alert": map[string]string{
-				"title": payload.Title,
-				"body":  payload.Body,
-			},
-			"sound": "default",
-		},
+		"aps":        apsDict(payload),
 		"user_uuid":  payload.UserUUID,
 		"server_id":  payload.ServerID,
 		"event_type": payload.EventType,
@@ -100,7 +176,7 @@ func (a *APNsProvider) Send(ctx context.Context, token string, payload Payload)
 			}
 		}
 
-		statusCode, err := a.sendOnce(ctx, token, body)
+		statusCode, err := a.sendOnce(ctx, token, body, apnsPriority(payload.Severity), apnsCollapseID(payload.CollapseID))
 		if err != nil {
 			lastErr = err
 			logging.Warn("APNs attempt %d failed: %v", attempt+1, err)
@@ -116,8 +192,8 @@ func (a *APNsProvider) Send(ctx context.Context, token string, payload Payload)
 			if truncLen > 16 {
 				truncLen = 16
 			}
-			logging.Info("APNs token invalid (410 Gone), should remove: %s...", token[:truncLen])
-			return fmt.Errorf("token invalid (410)")
+			logging.Info("APNs token invalid (410 Gone), reporting for removal: %s...", token[:truncLen])
+			return fmt.Errorf("%w (410)", ErrTokenInvalid)
 		}
 
 		if statusCode >= 500 {
@@ -131,8 +207,8 @@ func (a *APNsProvider) Send(ctx context.Context, token string, payload Payload)
 	return fmt.Errorf("APNs send failed after retries: %w", lastErr)
 }
 
-func (a *APNsProvider) sendOnce(ctx context.Context, token string, body []byte) (int, error) {
-	url := fmt.Sprintf("https://api.push.apple.com/3/device/%s", token)
+func (a *APNsProvider) sendOnce(ctx context.Context, token string, body []byte, priority, collapseID string) (int, error) {
+	url := fmt.Sprintf("%s/3/device/%s", a.host, token)
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
@@ -147,7 +223,10 @@ func (a *APNsProvider) sendOnce(ctx context.Context, token string, body []byte)
 	req.Header.Set("authorization", "bearer "+jwt)
 	req.Header.Set("apns-topic", a.bundleID)
 	req.Header.Set("apns-push-type", "alert")
-	req.Header.Set("apns-priority", "10")
+	req.Header.Set("apns-priority", priority)
+	if collapseID != "" {
+		req.Header.Set("apns-collapse-id", collapseID)
+	}
 
 	resp, err := a.client.Do(req)
 	if err != nil {
@@ -159,23 +238,90 @@ func (a *APNsProvider) sendOnce(ctx context.Context, token string, body []byte)
 	return resp.StatusCode, nil
 }
 
+// apnsCollapseID fits an ID into the 64-byte apns-collapse-id limit,
+// replacing longer IDs with their SHA-256 hex digest.
+func apnsCollapseID(id string) string {
+	if len(id) <= 64 {
+		return id
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// apnsPriority maps alert severity to the apns-priority header.
+// Non-alert pushes (no severity) keep immediate delivery.
+func apnsPriority(severity string) string {
+	switch severity {
+	case "", "critical":
+		return "10"
+	default:
+		return "5"
+	}
+}
+
+// apsDict builds the "aps" dictionary of an APNs payload.
+func apsDict(payload Payload) map[string]interface{} {
+	aps := map[string]interface{}{
+		"alert": map[string]string{
+			"title": payload.Title,
+			"body":  payload.Body,
+		},
+		"sound": "default",
+	}
+	if payload.Sound != nil {
+		if *payload.Sound == "" {
+			delete(aps, "sound")
+		} else {
+			aps["sound"] = *payload.Sound
+		}
+	}
+	if payload.Badge != nil {
+		aps["badge"] = *payload.Badge
+	}
+	if payload.Severity == "critical" {
+		// "critical" needs a special Apple entitlement; time-sensitive breaks through Focus without it
+		aps["interruption-level"] = "time-sensitive"
+	}
+	return aps
+}
+
+// apnsHost returns the APNs endpoint for the given environment.
+func apnsHost(environment string) string {
+	if environment == "sandbox" {
+		return apnsSandboxHost
+	}
+	return apnsProductionHost
+}
+
 func (a *APNsProvider) getJWT() (string, error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	if a.jwtToken != "" && time.Now().Before(a.jwtExp) {
-		return a.jwtToken, nil
+	// Normally the refresher keeps the token fresh; this covers a stalled or unstarted one.
+	if !a.fresh() {
+		if err := a.resign(); err != nil {
+			return "", err
+		}
 	}
+	return a.jwtToken, nil
+}
+
+// fresh reports whether the cached token is outside its refresh margin. Callers hold a.mu.
+func (a *APNsProvider) fresh() bool {
+	return a.jwtToken != "" && a.clock.Now().Before(a.jwtExp.Add(-a.refreshMargin))
+}
 
-	now := time.Now()
+// resign signs a new provider token. Callers hold a.mu.
+func (a *APNsProvider) resign() error {
+	now := a.clock.Now()
 	token, err := a.signJWT(now)
 	if err != nil {
-		return "", err
+		return err
 	}
-
 	a.jwtToken = token
-	a.jwtExp = now.Add(45 * time.Minute)
-	return token, nil
+	a.jwtExp = now.Add(apnsJWTLifetime)
+	logging.Debug("APNs JWT re-signed, expires %s", a.jwtExp.Format(time.RFC3339))
+	return nil
 }
 
 func (a *APNsProvider) signJWT(now time.Time) (string, error) {
@@ -208,6 +354,23 @@ func (a *APNsProvider) signJWT(now time.Time) (string, error) {
 	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
 }
 
+// Validate confirms the key can sign a provider token and the identifiers look right.
+func (a *APNsProvider) Validate(ctx context.Context) error {
+	if len(a.keyID) != 10 {
+		return fmt.Errorf("APNs key ID must be 10 characters, got %q", a.keyID)
+	}
+	if len(a.teamID) != 10 {
+		return fmt.Errorf("APNs team ID must be 10 characters, got %q", a.teamID)
+	}
+	if a.privateKey.Curve.Params().BitSize != 256 {
+		return fmt.Errorf("APNs key must be a P-256 key, got %s", a.privateKey.Curve.Params().Name)
+	}
+	if _, err := a.signJWT(a.clock.Now()); err != nil {
+		return fmt.Errorf("sign test JWT: %w", err)
+	}
+	return nil
+}
+
 // Name returns the provider name.
 func (a *APNsProvider) Name() string {
 	return "apns"
@@ -0,0 +1,344 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+)
+
+func marshalAps(t *testing.T, p Payload) map[string]interface{} {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"aps": apsDict(p)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Aps map[string]interface{} `json:"aps"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out.Aps
+}
+
+func TestApsDictDefaults(t *testing.T) {
+	aps := marshalAps(t, Payload{Title: "CPU", Body: "high"})
+	if aps["sound"] != "default" {
+		t.Errorf("sound = %v, want default", aps["sound"])
+	}
+	if _, ok := aps["badge"]; ok {
+		t.Error("badge set without Payload.Badge")
+	}
+	alert, _ := aps["alert"].(map[string]interface{})
+	if alert["title"] != "CPU" || alert["body"] != "high" {
+		t.Errorf("alert = %v", aps["alert"])
+	}
+}
+
+func TestApsDictSoundAndBadge(t *testing.T) {
+	sound, badge := "chime.caf", 3
+	aps := marshalAps(t, Payload{Sound: &sound, Badge: &badge})
+	if aps["sound"] != "chime.caf" {
+		t.Errorf("sound = %v, want chime.caf", aps["sound"])
+	}
+	if aps["badge"] != float64(3) {
+		t.Errorf("badge = %v, want 3", aps["badge"])
+	}
+}
+
+func TestApsDictSilent(t *testing.T) {
+	silent := ""
+	aps := marshalAps(t, Payload{Sound: &silent})
+	if _, ok := aps["sound"]; ok {
+		t.Errorf("silent push still has sound %v", aps["sound"])
+	}
+}
+
+func TestApsDictCritical(t *testing.T) {
+	aps := marshalAps(t, Payload{Severity: "critical"})
+	if aps["interruption-level"] != "time-sensitive" {
+		t.Errorf("interruption-level = %v, want time-sensitive", aps["interruption-level"])
+	}
+}
+
+func TestApnsHost(t *testing.T) {
+	if apnsHost("sandbox") != apnsSandboxHost || apnsHost("production") != apnsProductionHost {
+		t.Fatal("wrong APNs host")
+	}
+}
+
+// testAPNsKey returns a freshly generated P-256 key as base64-encoded .p8 contents.
+func testAPNsKey(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestNewAPNsProviderEnvironment(t *testing.T) {
+	key := testAPNsKey(t)
+	for env, want := range map[string]string{
+		"production": apnsProductionHost,
+		"sandbox":    apnsSandboxHost,
+		"":           apnsProductionHost, // default preserves the old behavior
+		"staging":    apnsProductionHost,
+	} {
+		p, err := NewAPNsProvider(key, "ABCDEFGHIJ", "KLMNOPQRST", "com.example.app", env, 5*time.Minute, nil)
+		if err != nil {
+			t.Fatalf("%q: %v", env, err)
+		}
+		if p.host != want {
+			t.Errorf("environment %q: host %s, want %s", env, p.host, want)
+		}
+	}
+}
+
+// newTestAPNs returns a provider that posts to handler instead of Apple.
+func newTestAPNs(t *testing.T, handler http.HandlerFunc) *APNsProvider {
+	t.Helper()
+	p, err := NewAPNsProvider(testAPNsKey(t), "ABCDEFGHIJ", "KLMNOPQRST", "com.example.app", "sandbox", 5*time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	p.host = srv.URL
+	return p
+}
+
+func TestAPNsPriorityHeader(t *testing.T) {
+	var header http.Header
+	p := newTestAPNs(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	})
+
+	for severity, want := range map[string]string{
+		"critical": "10",
+		"warning":  "5",
+		"info":     "5",
+		"":         "10", // non-alert pushes
+	} {
+		if err := p.Send(context.Background(), "device-token", Payload{Title: "t", Severity: severity}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		if got := header.Get("apns-priority"); got != want {
+			t.Errorf("severity %q: apns-priority %q, want %q", severity, got, want)
+		}
+		if header.Get("apns-topic") != "com.example.app" || header.Get("apns-push-type") != "alert" {
+			t.Errorf("severity %q: headers %v", severity, header)
+		}
+	}
+}
+
+func TestNewAPNsProviderRejectsBadKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaDER, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"not base64":     "%%%",
+		"not PEM":        base64.StdEncoding.EncodeToString([]byte("hello")),
+		"garbage in PEM": base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("junk")})),
+		"RSA key":        base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rsaDER})),
+	}
+	for name, key := range tests {
+		if _, err := NewAPNsProvider(key, "ABCDEFGHIJ", "KLMNOPQRST", "com.example.app", "", 5*time.Minute, nil); err == nil {
+			t.Errorf("%s: NewAPNsProvider succeeded, want an error", name)
+		}
+	}
+}
+
+func TestAPNsValidate(t *testing.T) {
+	key := testAPNsKey(t)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384DER, err := x509.MarshalPKCS8PrivateKey(p384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Key := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: p384DER}))
+
+	tests := []struct {
+		name          string
+		key           string
+		keyID, teamID string
+		wantErr       bool
+	}{
+		{"good key", key, "ABCDEFGHIJ", "KLMNOPQRST", false},
+		{"short key ID", key, "ABC", "KLMNOPQRST", true},
+		{"short team ID", key, "ABCDEFGHIJ", "", true},
+		{"P-384 key", p384Key, "ABCDEFGHIJ", "KLMNOPQRST", true},
+	}
+	for _, tc := range tests {
+		p, err := NewAPNsProvider(tc.key, tc.keyID, tc.teamID, "com.example.app", "", 5*time.Minute, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if err := p.Validate(context.Background()); (err != nil) != tc.wantErr {
+			t.Errorf("%s: Validate = %v, want error: %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestAPNsRefreshMarginValidated(t *testing.T) {
+	key := testAPNsKey(t)
+	for _, margin := range []time.Duration{-time.Minute, apnsJWTLifetime, time.Hour} {
+		if _, err := NewAPNsProvider(key, "ABCDEFGHIJ", "KLMNOPQRST", "com.example.app", "", margin, nil); err == nil {
+			t.Errorf("margin %s: NewAPNsProvider succeeded, want an error", margin)
+		}
+	}
+}
+
+func TestAPNsSendsResignWithinMargin(t *testing.T) {
+	var auths []string
+	p := newTestAPNs(t, func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("authorization"))
+	})
+	clk := clock.NewFake(time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC))
+	p.clock = clk
+
+	send := func() {
+		t.Helper()
+		if err := p.Send(context.Background(), "device-token", Payload{Title: "t"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	send()
+	clk.Advance(39 * time.Minute) // still outside the 5 minute margin
+	send()
+	clk.Advance(2 * time.Minute) // 41 minutes: inside it
+	send()
+
+	if auths[0] != auths[1] {
+		t.Fatal("token re-signed before entering the refresh margin")
+	}
+	if auths[2] == auths[1] {
+		t.Fatal("token not re-signed inside the refresh margin")
+	}
+}
+
+func TestAPNsRefreshTiming(t *testing.T) {
+	start := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	p, err := NewAPNsProvider(testAPNsKey(t), "ABCDEFGHIJ", "KLMNOPQRST", "com.example.app", "", 5*time.Minute, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := p.untilRefresh(); d != 0 {
+		t.Fatalf("before the first token: refresh in %s, want now", d)
+	}
+	if err := p.refreshJWT(); err != nil {
+		t.Fatal(err)
+	}
+	first := p.jwtToken
+	if d := p.untilRefresh(); d != 40*time.Minute {
+		t.Fatalf("fresh token: refresh in %s, want 40m", d)
+	}
+
+	clk.Advance(39 * time.Minute)
+	if err := p.refreshJWT(); err != nil || p.jwtToken != first {
+		t.Fatalf("refresh outside the margin replaced the token (err %v)", err)
+	}
+	if d := p.untilRefresh(); d != time.Minute {
+		t.Fatalf("at 39m: refresh in %s, want 1m", d)
+	}
+
+	clk.Advance(2 * time.Minute)
+	if d := p.untilRefresh(); d != 0 {
+		t.Fatalf("inside the margin: refresh in %s, want now", d)
+	}
+	if err := p.refreshJWT(); err != nil {
+		t.Fatal(err)
+	}
+	if p.jwtToken == first || !p.jwtExp.Equal(start.Add(41*time.Minute+apnsJWTLifetime)) {
+		t.Fatalf("token not re-signed inside the margin, expires %s", p.jwtExp)
+	}
+}
+
+func TestAPNsRefreshLoopResignsProactively(t *testing.T) {
+	start := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	p, err := NewAPNsProvider(testAPNsKey(t), "ABCDEFGHIJ", "KLMNOPQRST", "com.example.app", "", 5*time.Minute, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.getJWT(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The token is now inside its margin; the loop re-signs it without any send
+	clk.Advance(42 * time.Minute)
+	p.Start()
+	defer p.Stop()
+
+	want := start.Add(42*time.Minute + apnsJWTLifetime)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		p.mu.Lock()
+		exp := p.jwtExp
+		p.mu.Unlock()
+		if exp.Equal(want) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("token expires %s, want it re-signed to expire %s", exp, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAPNsCollapseIDHeader(t *testing.T) {
+	var header http.Header
+	p := newTestAPNs(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	})
+	long := "alert:" + strings.Repeat("very-long-rule-id-", 5) + "|srv-1"
+	sum := sha256.Sum256([]byte(long))
+
+	for _, tc := range []struct{ id, want string }{
+		{"", ""},
+		{"alert:cpu-high|srv-1", "alert:cpu-high|srv-1"},
+		{strings.Repeat("x", 64), strings.Repeat("x", 64)},
+		{long, hex.EncodeToString(sum[:])},
+	} {
+		if err := p.Send(context.Background(), "device-token", Payload{Title: "t", CollapseID: tc.id}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		got, set := header.Get("apns-collapse-id"), len(header.Values("apns-collapse-id")) > 0
+		if got != tc.want || set != (tc.want != "") {
+			t.Errorf("collapse ID %q: header %q (set %t), want %q", tc.id, got, set, tc.want)
+		}
+		if len(got) > 64 {
+			t.Errorf("collapse ID %q: header is %d bytes, over the 64-byte limit", tc.id, len(got))
+		}
+	}
+}
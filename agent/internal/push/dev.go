@@ -32,3 +32,8 @@ func (d *DevProvider) Send(ctx context.Context, token string, payload Payload) e
 func (d *DevProvider) Name() string {
 	return "dev"
 }
+
+// Validate always succeeds; the dev provider has nothing to configure.
+func (d *DevProvider) Validate(ctx context.Context) error {
+	return nil
+}
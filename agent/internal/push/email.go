@@ -0,0 +1,166 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// EmailProvider sends notifications as email over SMTP with STARTTLS.
+// The device token is treated as the destination email address.
+type EmailProvider struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	timeout  time.Duration
+}
+
+// NewEmailProvider creates an SMTP email push provider.
+func NewEmailProvider(smtpHost string, port int, username, password, fromAddr string) *EmailProvider {
+	return &EmailProvider{
+		host:     smtpHost,
+		port:     port,
+		username: username,
+		password: password,
+		from:     fromAddr,
+		timeout:  15 * time.Second,
+	}
+}
+
+// Send emails the notification to the address in token.
+func (e *EmailProvider) Send(ctx context.Context, token string, payload Payload) error {
+	to, err := mail.ParseAddress(token)
+	if err != nil {
+		// Not an email address (e.g. an APNs token) — skip without reporting it as dead
+		return fmt.Errorf("token is not an email address: %w", err)
+	}
+
+	msg, err := e.buildMessage(to.Address, payload)
+	if err != nil {
+		return fmt.Errorf("build message: %w", err)
+	}
+
+	addr := net.JoinHostPort(e.host, strconv.Itoa(e.port))
+	dialer := &net.Dialer{Timeout: e.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(e.timeout))
+	}
+
+	c, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: e.host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	if e.username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted connection
+		if err := c.Auth(smtp.PlainAuth("", e.username, e.password, e.host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(e.from); err != nil {
+		return fmt.Errorf("MAIL FROM: %w", err)
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("RCPT TO: %w", err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("DATA: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return fmt.Errorf("write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("finish message: %w", err)
+	}
+
+	return c.Quit()
+}
+
+// buildMessage composes a multipart/alternative message with text and HTML parts.
+func (e *EmailProvider) buildMessage(to string, payload Payload) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	details := fmt.Sprintf("Server: %s\nEvent: %s\nTime: %s", payload.ServerID, payload.EventType, payload.Timestamp)
+
+	textPart, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=UTF-8"}})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(textPart, "%s\r\n\r\n%s\r\n", payload.Body, details)
+
+	htmlPart, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(htmlPart, "<h2>%s</h2>\r\n<p>%s</p>\r\n<p style=\"color:#666\">Server: %s<br>Event: %s<br>Time: %s</p>\r\n",
+		html.EscapeString(payload.Title), html.EscapeString(payload.Body),
+		html.EscapeString(payload.ServerID), html.EscapeString(payload.EventType), html.EscapeString(payload.Timestamp))
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", payload.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n", mw.Boundary())
+	fmt.Fprintf(&msg, "\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// Name returns the provider name.
+func (e *EmailProvider) Name() string {
+	return "email"
+}
+
+// Validate checks the sender address and that the SMTP server accepts connections.
+func (e *EmailProvider) Validate(ctx context.Context) error {
+	if _, err := mail.ParseAddress(e.from); err != nil {
+		return fmt.Errorf("invalid sender address %q: %w", e.from, err)
+	}
+
+	addr := net.JoinHostPort(e.host, strconv.Itoa(e.port))
+	dialer := net.Dialer{Timeout: e.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("SMTP server %s unreachable: %w", addr, err)
+	}
+	conn.Close()
+	return nil
+}
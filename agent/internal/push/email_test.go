@@ -0,0 +1,146 @@
+package push
+
+import (
+	"bufio"
+	"context"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// smtpSession is what the fake SMTP server received in one session.
+type smtpSession struct {
+	from, rcpt string
+	data       string
+}
+
+// fakeSMTP accepts one session on a local port and reports it on the returned channel.
+func fakeSMTP(t *testing.T) (host string, port int, got <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		var sess smtpSession
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				sess.from = strings.Trim(strings.TrimPrefix(cmd, "MAIL FROM:"), "<>")
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				sess.rcpt = strings.Trim(strings.TrimPrefix(cmd, "RCPT TO:"), "<>")
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				sess.data = data.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				ch <- sess
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, ch
+}
+
+func TestEmailProviderSend(t *testing.T) {
+	host, port, got := fakeSMTP(t)
+	e := NewEmailProvider(host, port, "", "", "agent@example.com")
+
+	err := e.Send(context.Background(), "Ops <ops@example.com>", Payload{
+		Title:     "CPU high on srv-1",
+		Body:      "CPU at 97%",
+		ServerID:  "srv-1",
+		EventType: "alert",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	sess := <-got
+
+	if sess.from != "agent@example.com" || sess.rcpt != "ops@example.com" {
+		t.Errorf("envelope from %q to %q", sess.from, sess.rcpt)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(sess.data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	for header, want := range map[string]string{
+		"From":         "agent@example.com",
+		"To":           "ops@example.com",
+		"Subject":      "CPU high on srv-1",
+		"Mime-Version": "1.0",
+	} {
+		if v := msg.Header.Get(header); v != want {
+			t.Errorf("%s = %q, want %q", header, v, want)
+		}
+	}
+	if ct := msg.Header.Get("Content-Type"); !strings.HasPrefix(ct, "multipart/alternative; boundary=") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(sess.data, "text/plain") || !strings.Contains(sess.data, "text/html") || !strings.Contains(sess.data, "CPU at 97%") {
+		t.Error("message lacks the text and HTML parts")
+	}
+}
+
+func TestEmailProviderRejectsNonAddressTokens(t *testing.T) {
+	e := NewEmailProvider("127.0.0.1", 1, "", "", "agent@example.com")
+	err := e.Send(context.Background(), "a1b2c3d4e5f6", Payload{Title: "t"})
+	if err == nil || !strings.Contains(err.Error(), "not an email address") {
+		t.Fatalf("Send to an APNs token: %v", err)
+	}
+}
+
+func TestEmailSubjectEncoding(t *testing.T) {
+	e := NewEmailProvider("localhost", 25, "", "", "agent@example.com")
+	msg, err := e.buildMessage("ops@example.com", Payload{Title: "⚠️ Disk full"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := mail.ReadMessage(strings.NewReader(string(msg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := m.Header.Get("Subject")
+	if !strings.HasPrefix(raw, "=?utf-8?q?") {
+		t.Errorf("Subject %q is not Q-encoded", raw)
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(raw); err != nil || decoded != "⚠️ Disk full" {
+		t.Errorf("decoded Subject %q, %v", decoded, err)
+	}
+}
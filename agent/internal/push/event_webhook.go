@@ -0,0 +1,82 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of an event webhook body, "sha256=<hex>".
+const SignatureHeader = "X-Agent-Signature"
+
+// EventPoster delivers signed event JSON to per-user webhook URLs. Unlike Provider
+// it is not tied to device tokens, so integrations receive events alongside pushes.
+type EventPoster struct {
+	sign   func(body []byte) string
+	client *http.Client
+}
+
+// NewEventPoster creates an EventPoster that signs each body with sign.
+func NewEventPoster(sign func(body []byte) string) *EventPoster {
+	return &EventPoster{
+		sign: sign,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Post sends body to target, retrying network and server errors with exponential backoff.
+func (p *EventPoster) Post(ctx context.Context, target string, body []byte) error {
+	signature := "sha256=" + p.sign(body)
+
+	delays := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}
+	var lastErr error
+
+	for attempt := 0; attempt <= len(delays); attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delays[attempt-1]):
+			}
+		}
+
+		statusCode, err := p.postOnce(ctx, target, body, signature)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if statusCode >= 200 && statusCode < 300 {
+			return nil
+		}
+		if statusCode >= 500 {
+			lastErr = fmt.Errorf("webhook server error: %d", statusCode)
+			continue
+		}
+		return fmt.Errorf("webhook error: %d", statusCode)
+	}
+
+	return fmt.Errorf("webhook delivery failed after retries: %w", lastErr)
+}
+
+func (p *EventPoster) postOnce(ctx context.Context, target string, body []byte, signature string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,62 @@
+package push
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEventPosterSignsBody(t *testing.T) {
+	var gotBody, gotSig, gotType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody, gotSig, gotType = string(b), r.Header.Get(SignatureHeader), r.Header.Get("Content-Type")
+	}))
+	defer srv.Close()
+
+	p := NewEventPoster(func(body []byte) string { return "sig-of-" + string(body) })
+	if err := p.Post(context.Background(), srv.URL, []byte(`{"a":1}`)); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if gotBody != `{"a":1}` || gotSig != `sha256=sig-of-{"a":1}` || gotType != "application/json" {
+		t.Errorf("body %q, signature %q, content type %q", gotBody, gotSig, gotType)
+	}
+}
+
+func TestEventPosterRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewEventPoster(func([]byte) string { return "x" })
+	if err := p.Post(context.Background(), srv.URL, []byte(`{}`)); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("%d attempts, want 2", calls.Load())
+	}
+}
+
+func TestEventPosterDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	p := NewEventPoster(func([]byte) string { return "x" })
+	if err := p.Post(context.Background(), srv.URL, []byte(`{}`)); err == nil {
+		t.Fatal("Post succeeded on 401")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("%d attempts, want 1", calls.Load())
+	}
+}
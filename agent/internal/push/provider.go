@@ -1,6 +1,13 @@
 package push
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrTokenInvalid is returned (possibly wrapped) by Send when the provider reports
+// that the device token is no longer valid and should be removed.
+var ErrTokenInvalid = errors.New("push token invalid")
 
 // Payload represents a push notification to send.
 type Payload struct {
@@ -8,14 +15,25 @@ type Payload struct {
 	Body      string `json:"body"`
 	UserUUID  string `json:"user_uuid"`
 	ServerID  string `json:"server_id"`
-	EventType string `json:"event_type"` // "alert" or "automation"
+	EventType string `json:"event_type"`         // "alert", "automation", "agent_status" or "test"
+	Severity  string `json:"severity,omitempty"` // alerts only: "info", "warning" or "critical"
 	Timestamp string `json:"timestamp"`
+
+	// APNs only: nil Sound plays the default sound and "" sends silently; nil Badge leaves it unchanged
+	Sound *string `json:"-"`
+	Badge *int    `json:"-"`
+
+	// APNs only: notifications sharing a collapse ID replace each other on the device
+	CollapseID string `json:"-"`
 }
 
 // Provider defines the interface for sending push notifications.
 type Provider interface {
 	// Send delivers a push notification to the given device token.
+	// Returns an error wrapping ErrTokenInvalid if the token is permanently dead.
 	Send(ctx context.Context, token string, payload Payload) error
 	// Name returns the provider name for logging.
 	Name() string
+	// Validate checks the provider configuration at startup so mistakes fail fast.
+	Validate(ctx context.Context) error
 }
@@ -0,0 +1,63 @@
+package push
+
+import (
+	"context"
+	"sync"
+)
+
+// Delivery is a single notification captured by a RecordingProvider.
+type Delivery struct {
+	Token   string
+	Payload Payload
+}
+
+// RecordingProvider stores every notification in memory instead of sending it,
+// so tests can assert exactly which alerts and automations fired.
+type RecordingProvider struct {
+	dev *DevProvider // nil unless logging is enabled
+
+	mu         sync.Mutex
+	deliveries []Delivery
+}
+
+// NewRecordingProvider creates a recording provider. If log is set, notifications
+// are also logged like the dev provider does.
+func NewRecordingProvider(log bool) *RecordingProvider {
+	r := &RecordingProvider{}
+	if log {
+		r.dev = NewDevProvider()
+	}
+	return r
+}
+
+// Send records the notification.
+func (r *RecordingProvider) Send(ctx context.Context, token string, payload Payload) error {
+	r.mu.Lock()
+	r.deliveries = append(r.deliveries, Delivery{Token: token, Payload: payload})
+	r.mu.Unlock()
+
+	if r.dev != nil {
+		return r.dev.Send(ctx, token, payload)
+	}
+	return nil
+}
+
+// Drain returns all recorded notifications in send order and clears the record.
+func (r *RecordingProvider) Drain() []Delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := r.deliveries
+	r.deliveries = nil
+	return out
+}
+
+// Name returns the provider name.
+func (r *RecordingProvider) Name() string {
+	return "recording"
+}
+
+// Validate always succeeds.
+func (r *RecordingProvider) Validate(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,42 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRecordingProviderDrain(t *testing.T) {
+	r := NewRecordingProvider(false)
+	ctx := context.Background()
+	r.Send(ctx, "token-1", Payload{Title: "first"})
+	r.Send(ctx, "token-2", Payload{Title: "second"})
+
+	got := r.Drain()
+	if len(got) != 2 || got[0].Token != "token-1" || got[0].Payload.Title != "first" || got[1].Payload.Title != "second" {
+		t.Fatalf("deliveries %+v, want both in send order", got)
+	}
+	if got := r.Drain(); len(got) != 0 {
+		t.Fatalf("second drain returned %+v, want nothing", got)
+	}
+	if r.Name() != "recording" {
+		t.Fatalf("Name() = %q", r.Name())
+	}
+}
+
+func TestRecordingProviderConcurrentSends(t *testing.T) {
+	r := NewRecordingProvider(false)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.Send(context.Background(), fmt.Sprintf("token-%d", i), Payload{})
+		}(i)
+	}
+	wg.Wait()
+	if got := r.Drain(); len(got) != 20 {
+		t.Fatalf("%d deliveries, want 20", len(got))
+	}
+}
@@ -0,0 +1,131 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/logging"
+)
+
+// WebhookProvider POSTs the notification payload as JSON to a URL.
+// A device token that is itself an http(s) URL overrides the default URL.
+type WebhookProvider struct {
+	url        string
+	authHeader string // sent as Authorization if set
+	client     *http.Client
+}
+
+// webhookBody is the JSON sent to the webhook.
+type webhookBody struct {
+	Payload
+	SentAt string `json:"sent_at"`
+}
+
+// NewWebhookProvider creates a generic JSON webhook provider.
+func NewWebhookProvider(webhookURL, authHeader string) *WebhookProvider {
+	return &WebhookProvider{
+		url:        webhookURL,
+		authHeader: authHeader,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Send posts the payload, retrying server errors with exponential backoff.
+func (w *WebhookProvider) Send(ctx context.Context, token string, payload Payload) error {
+	target := w.url
+	if isHTTPURL(token) {
+		target = token
+	}
+
+	body, err := json.Marshal(webhookBody{Payload: payload, SentAt: time.Now().UTC().Format(time.RFC3339)})
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	delays := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}
+	var lastErr error
+
+	for attempt := 0; attempt <= len(delays); attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delays[attempt-1]):
+			}
+		}
+
+		statusCode, err := w.sendOnce(ctx, target, body)
+		if err != nil {
+			lastErr = err
+			logging.Warn("Webhook attempt %d failed: %v", attempt+1, err)
+			continue
+		}
+
+		if statusCode >= 200 && statusCode < 300 {
+			return nil
+		}
+
+		if statusCode == http.StatusGone {
+			return fmt.Errorf("%w (410)", ErrTokenInvalid)
+		}
+
+		if statusCode >= 500 {
+			lastErr = fmt.Errorf("webhook server error: %d", statusCode)
+			continue
+		}
+
+		return fmt.Errorf("webhook error: %d", statusCode)
+	}
+
+	return fmt.Errorf("webhook send failed after retries: %w", lastErr)
+}
+
+func (w *WebhookProvider) sendOnce(ctx context.Context, target string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.authHeader != "" {
+		req.Header.Set("Authorization", w.authHeader)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode, nil
+}
+
+// Validate checks that the webhook URL is an absolute http(s) URL.
+func (w *WebhookProvider) Validate(ctx context.Context) error {
+	if !isHTTPURL(w.url) {
+		return fmt.Errorf("webhook URL must be an absolute http(s) URL, got %q", w.url)
+	}
+	return nil
+}
+
+// Name returns the provider name.
+func (w *WebhookProvider) Name() string {
+	return "webhook"
+}
+
+func isHTTPURL(s string) bool {
+	if !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && u.Host != ""
+}
@@ -0,0 +1,106 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookSendBody(t *testing.T) {
+	var got map[string]interface{}
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+
+	p := NewWebhookProvider(srv.URL, "Bearer hook-secret")
+	err := p.Send(context.Background(), "device-1", Payload{Title: "CPU", Body: "high", ServerID: "srv-1", EventType: "alert"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if auth != "Bearer hook-secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if got["title"] != "CPU" || got["body"] != "high" || got["server_id"] != "srv-1" || got["event_type"] != "alert" {
+		t.Errorf("body %v, want the payload fields", got)
+	}
+	if _, err := time.Parse(time.RFC3339, got["sent_at"].(string)); err != nil {
+		t.Errorf("sent_at %v: %v", got["sent_at"], err)
+	}
+}
+
+func TestWebhookTokenOverridesURL(t *testing.T) {
+	var hits atomic.Int32
+	override := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer override.Close()
+
+	p := NewWebhookProvider("http://127.0.0.1:1/unused", "")
+	if err := p.Send(context.Background(), override.URL, Payload{Title: "x"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("override URL hit %d times, want 1", hits.Load())
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantCalls int32
+		wantErr   error
+		wantOK    bool
+	}{
+		{"server error then ok", []int{http.StatusServiceUnavailable, http.StatusOK}, 2, nil, true},
+		{"client error", []int{http.StatusBadRequest}, 1, nil, false},
+		{"gone", []int{http.StatusGone}, 1, ErrTokenInvalid, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				w.WriteHeader(tc.statuses[int(n)-1])
+			}))
+			defer srv.Close()
+
+			err := NewWebhookProvider(srv.URL, "").Send(context.Background(), "device-1", Payload{})
+			if (err == nil) != tc.wantOK {
+				t.Fatalf("err = %v, want ok: %v", err, tc.wantOK)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if n := calls.Load(); n != tc.wantCalls {
+				t.Fatalf("%d calls, want %d", n, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWebhookValidate(t *testing.T) {
+	for url, ok := range map[string]bool{
+		"https://hooks.example.com/agent": true,
+		"http://10.0.0.5:8080/push":       true,
+		"":                                false,
+		"hooks.example.com/agent":         false,
+		"ftp://hooks.example.com":         false,
+		"https://":                        false,
+	} {
+		err := NewWebhookProvider(url, "").Validate(context.Background())
+		if (err == nil) != ok {
+			t.Errorf("Validate(%q) = %v, want ok: %v", url, err, ok)
+		}
+	}
+}
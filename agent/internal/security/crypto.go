@@ -8,80 +8,166 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"sync"
 
+	"github.com/xyidactyl/agent/internal/logging"
 	"golang.org/x/crypto/hkdf"
 )
 
+// Default HKDF parameters; deployments predating CRYPTO_SALT/CRYPTO_INFO use these.
+const (
+	DefaultSalt = "xyidactyl-salt"
+	DefaultInfo = "xyidactyl-api-key-encryption"
+)
+
 // Crypto provides AES-256-GCM encryption/decryption using a key derived from AGENT_SECRET.
 type Crypto struct {
-	key []byte
+	key         []byte
+	previousKey []byte // key from AGENT_SECRET_PREVIOUS during secret rotation, may be nil
+	signKey     []byte // HMAC key for control.json signatures
+	webhookKey  []byte // HMAC key for user webhook deliveries
+
+	fallbackOnce sync.Once
 }
 
 // NewCrypto creates a Crypto instance with a key derived from agentSecret via HKDF.
-func NewCrypto(agentSecret string) (*Crypto, error) {
+// previousSecret is optional; when set, Decrypt falls back to it for values
+// encrypted before a secret rotation. Empty salt or info select DefaultSalt/DefaultInfo;
+// setting them scopes the derived keys to one deployment even if secrets are shared.
+func NewCrypto(agentSecret, previousSecret, salt, info string) (*Crypto, error) {
 	if len(agentSecret) < 16 {
 		return nil, fmt.Errorf("agent secret too short (minimum 16 characters)")
 	}
+	if salt == "" {
+		salt = DefaultSalt
+	}
+	if info == "" {
+		info = DefaultInfo
+	}
+
+	key, err := deriveKey(agentSecret, salt, info)
+	if err != nil {
+		return nil, err
+	}
+
+	var previousKey []byte
+	if previousSecret != "" {
+		previousKey, err = deriveKey(previousSecret, salt, info)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	signKey, err := deriveKey(agentSecret, salt, "xyidactyl-control-signature")
+	if err != nil {
+		return nil, err
+	}
 
-	// Derive a 32-byte key using HKDF-SHA256
-	hkdfReader := hkdf.New(sha256.New, []byte(agentSecret), []byte("xyidactyl-salt"), []byte("xyidactyl-api-key-encryption"))
+	webhookKey, err := deriveKey(agentSecret, salt, "xyidactyl-webhook-signature")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Crypto{key: key, previousKey: previousKey, signKey: signKey, webhookKey: webhookKey}, nil
+}
+
+// deriveKey derives a 32-byte key from the agent secret using HKDF-SHA256.
+func deriveKey(agentSecret, salt, info string) ([]byte, error) {
+	hkdfReader := hkdf.New(sha256.New, []byte(agentSecret), []byte(salt), []byte(info))
 	key := make([]byte, 32)
 	if _, err := io.ReadFull(hkdfReader, key); err != nil {
 		return nil, fmt.Errorf("derive key: %w", err)
 	}
-
-	return &Crypto{key: key}, nil
+	return key, nil
 }
 
 // Encrypt encrypts plaintext and returns base64-encoded ciphertext.
 func (c *Crypto) Encrypt(plaintext string) (string, error) {
-	block, err := aes.NewCipher(c.key)
+	ciphertext, err := c.EncryptBytes([]byte(plaintext))
 	if err != nil {
-		return "", fmt.Errorf("create cipher: %w", err)
+		return "", err
 	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
 
-	aesGCM, err := cipher.NewGCM(block)
+// Decrypt decrypts base64-encoded ciphertext and returns plaintext.
+// If decryption with the current key fails, the previous key (if any) is tried.
+func (c *Crypto) Decrypt(encoded string) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
-		return "", fmt.Errorf("create GCM: %w", err)
+		return "", fmt.Errorf("decode base64: %w", err)
+	}
+
+	plaintext, err := c.DecryptBytes(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// EncryptBytes encrypts data with AES-256-GCM and returns the nonce followed by the ciphertext.
+func (c *Crypto) EncryptBytes(plaintext []byte) ([]byte, error) {
+	aesGCM, err := newGCM(c.key)
+	if err != nil {
+		return nil, err
 	}
 
 	nonce := make([]byte, aesGCM.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", fmt.Errorf("generate nonce: %w", err)
+		return nil, fmt.Errorf("generate nonce: %w", err)
 	}
 
 	// nonce is prepended to ciphertext
-	ciphertext := aesGCM.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	return aesGCM.Seal(nonce, nonce, plaintext, nil), nil
 }
 
-// Decrypt decrypts base64-encoded ciphertext and returns plaintext.
-func (c *Crypto) Decrypt(encoded string) (string, error) {
-	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
-	if err != nil {
-		return "", fmt.Errorf("decode base64: %w", err)
+// DecryptBytes reverses EncryptBytes, falling back to the previous key (if any).
+func (c *Crypto) DecryptBytes(ciphertext []byte) ([]byte, error) {
+	plaintext, err := decryptWithKey(c.key, ciphertext)
+	if err == nil || c.previousKey == nil {
+		return plaintext, err
 	}
 
-	block, err := aes.NewCipher(c.key)
+	plaintext, prevErr := decryptWithKey(c.previousKey, ciphertext)
+	if prevErr != nil {
+		return nil, err
+	}
+
+	c.fallbackOnce.Do(func() {
+		logging.Warn("Decrypted a value with AGENT_SECRET_PREVIOUS; re-encryption with the current secret is pending")
+	})
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", fmt.Errorf("create cipher: %w", err)
+		return nil, fmt.Errorf("create cipher: %w", err)
 	}
 
 	aesGCM, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", fmt.Errorf("create GCM: %w", err)
+		return nil, fmt.Errorf("create GCM: %w", err)
+	}
+	return aesGCM, nil
+}
+
+func decryptWithKey(key, ciphertext []byte) ([]byte, error) {
+	aesGCM, err := newGCM(key)
+	if err != nil {
+		return nil, err
 	}
 
 	nonceSize := aesGCM.NonceSize()
 	if len(ciphertext) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
+		return nil, fmt.Errorf("ciphertext too short")
 	}
 
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
 	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
-		return "", fmt.Errorf("decrypt: %w", err)
+		return nil, fmt.Errorf("decrypt: %w", err)
 	}
 
-	return string(plaintext), nil
+	return plaintext, nil
 }
@@ -0,0 +1,114 @@
+package security
+
+import "testing"
+
+const (
+	oldSecret = "old-agent-secret-0123456789"
+	newSecret = "new-agent-secret-0123456789"
+)
+
+func mustCrypto(t *testing.T, secret, previous string) *Crypto {
+	t.Helper()
+	c, err := NewCrypto(secret, previous, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestDecryptWithPreviousSecret(t *testing.T) {
+	before := mustCrypto(t, oldSecret, "")
+	oldCiphertext, err := before.Encrypt("ptlc_old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := mustCrypto(t, newSecret, oldSecret)
+	newCiphertext, err := rotated.Encrypt("ptlc_new")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := rotated.Decrypt(oldCiphertext); err != nil || got != "ptlc_old" {
+		t.Errorf("ciphertext from the previous secret: %q, %v", got, err)
+	}
+	if got, err := rotated.Decrypt(newCiphertext); err != nil || got != "ptlc_new" {
+		t.Errorf("ciphertext from the current secret: %q, %v", got, err)
+	}
+
+	// Encrypt uses the current key only
+	if _, err := before.Decrypt(newCiphertext); err == nil {
+		t.Error("new ciphertext decrypted with the old secret alone")
+	}
+}
+
+func TestDecryptWithoutPreviousSecretFails(t *testing.T) {
+	oldCiphertext, err := mustCrypto(t, oldSecret, "").Encrypt("ptlc_old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mustCrypto(t, newSecret, "").Decrypt(oldCiphertext); err == nil {
+		t.Fatal("old ciphertext decrypted after rotation without AGENT_SECRET_PREVIOUS")
+	}
+	if _, err := mustCrypto(t, newSecret, "unrelated-secret-0123456789").Decrypt(oldCiphertext); err == nil {
+		t.Fatal("old ciphertext decrypted with an unrelated previous secret")
+	}
+}
+
+func TestNewCryptoRejectsShortSecret(t *testing.T) {
+	if _, err := NewCrypto("short", "", "", ""); err == nil {
+		t.Fatal("accepted a 5-character secret")
+	}
+}
+
+func TestSaltScopesKeys(t *testing.T) {
+	a, err := NewCrypto(newSecret, "", "tenant-a", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewCrypto(newSecret, "", "tenant-b", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := a.Encrypt("ptlc_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Decrypt(ciphertext); err == nil {
+		t.Fatal("ciphertext decrypted under a different CRYPTO_SALT")
+	}
+	if got, err := a.Decrypt(ciphertext); err != nil || got != "ptlc_key" {
+		t.Fatalf("round trip: %q, %v", got, err)
+	}
+}
+
+func TestInfoScopesKeys(t *testing.T) {
+	a := mustCrypto(t, newSecret, "")
+	b, err := NewCrypto(newSecret, "", "", "other-field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := a.Encrypt("ptlc_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Decrypt(ciphertext); err == nil {
+		t.Fatal("ciphertext decrypted under a different CRYPTO_INFO")
+	}
+}
+
+// legacyCiphertext is "ptlc_key" encrypted under newSecret with the salt and info
+// hardcoded before CRYPTO_SALT/CRYPTO_INFO existed.
+const legacyCiphertext = "dd2oI91wtdLoy6YTPCoRsMgt0yIFw1evZyrSEPf/uFwadt7P"
+
+func TestDefaultsDecryptExistingCiphertext(t *testing.T) {
+	explicit, err := NewCrypto(newSecret, "", "xyidactyl-salt", "xyidactyl-api-key-encryption")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*Crypto{mustCrypto(t, newSecret, ""), explicit} {
+		if got, err := c.Decrypt(legacyCiphertext); err != nil || got != "ptlc_key" {
+			t.Fatalf("Decrypt(legacy) = %q, %v", got, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package security
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrSignatureMissing is returned when a control file has no signature field.
+	ErrSignatureMissing = errors.New("control file is not signed")
+	// ErrSignatureInvalid is returned when a control file's signature does not match its contents.
+	ErrSignatureInvalid = errors.New("control file signature mismatch")
+)
+
+// SignControlFile returns the hex HMAC-SHA256 signature for a control.json document.
+// Any existing "signature" field is ignored, so the result can be written back into the file.
+func (c *Crypto) SignControlFile(data []byte) (string, error) {
+	canonical, _, err := canonicalControlJSON(data)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, c.signKey)
+	mac.Write(canonical)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyControlFile checks the "signature" field of a control.json document.
+func (c *Crypto) VerifyControlFile(data []byte) error {
+	_, signature, err := canonicalControlJSON(data)
+	if err != nil {
+		return err
+	}
+	if signature == "" {
+		return ErrSignatureMissing
+	}
+
+	expected, err := c.SignControlFile(data)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// canonicalControlJSON returns the canonical form signed by SignControlFile: the document
+// without its "signature" field, object keys sorted, no insignificant whitespace, number
+// literals preserved as written and no HTML escaping.
+func canonicalControlJSON(data []byte) ([]byte, string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, "", fmt.Errorf("parse control file: %w", err)
+	}
+
+	signature, _ := doc["signature"].(string)
+	delete(doc, "signature")
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, "", fmt.Errorf("encode control file: %w", err)
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), signature, nil
+}
+
+// SignWebhook returns the hex HMAC-SHA256 of a webhook body. The key is
+// HKDF-SHA256(AGENT_SECRET, CRYPTO_SALT, info "xyidactyl-webhook-signature"); receivers
+// need that same derived key to verify deliveries, and the operator hands it to them out of
+// band. It is a shared secret that can also forge deliveries, but being a separate HKDF
+// output it does not expose AGENT_SECRET or the encryption and control-signing keys.
+func (c *Crypto) SignWebhook(body []byte) string {
+	mac := hmac.New(sha256.New, c.webhookKey)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
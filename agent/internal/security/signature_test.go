@@ -0,0 +1,61 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+func TestSignWebhookUsesDerivedKey(t *testing.T) {
+	c, err := NewCrypto("agent-secret-0123456789", "", "salt", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := []byte(`{"event_type":"alert"}`)
+
+	// A receiver holding only the derived key can reproduce the signature.
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte("agent-secret-0123456789"), []byte("salt"), []byte("xyidactyl-webhook-signature")), key); err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	if got, want := c.SignWebhook(body), hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Fatalf("SignWebhook = %s, want %s", got, want)
+	}
+
+	if c.SignWebhook([]byte(`{"event_type":"automation"}`)) == c.SignWebhook(body) {
+		t.Error("different bodies produced the same signature")
+	}
+}
+
+func TestControlFileSignature(t *testing.T) {
+	c, err := NewCrypto("agent-secret-0123456789", "", "salt", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := []byte(`{"version": 3, "users": [], "signature": "stale"}`)
+
+	sig, err := c.SignControlFile(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Whitespace and key order do not change the canonical form.
+	signed := []byte(`{"users":[],"version":3,"signature":"` + sig + `"}`)
+	if err := c.VerifyControlFile(signed); err != nil {
+		t.Fatalf("VerifyControlFile: %v", err)
+	}
+	if err := c.VerifyControlFile([]byte(`{"version":4,"users":[],"signature":"` + sig + `"}`)); err != ErrSignatureInvalid {
+		t.Errorf("tampered file: %v, want ErrSignatureInvalid", err)
+	}
+	if err := c.VerifyControlFile([]byte(`{"version":3}`)); err != ErrSignatureMissing {
+		t.Errorf("unsigned file: %v, want ErrSignatureMissing", err)
+	}
+	if c.SignWebhook(doc) == sig {
+		t.Error("webhook and control signatures share a key")
+	}
+}
@@ -0,0 +1,89 @@
+package status
+
+import (
+	"time"
+
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// CompactSeries is the columnar form of a server's snapshots used by the compact
+// metrics format: one array per field instead of one object per sample.
+type CompactSeries struct {
+	ID            []int64   `json:"id"`
+	Timestamp     []int64   `json:"timestamp"` // unix milliseconds
+	PowerState    []string  `json:"power_state"`
+	CPUPercent    []float64 `json:"cpu_percent"`
+	CPUNormalized []float64 `json:"cpu_percent_normalized"`
+	MemBytes      []int64   `json:"mem_bytes"`
+	MemLimit      []int64   `json:"mem_limit"`
+	DiskBytes     []int64   `json:"disk_bytes"`
+	DiskLimit     []int64   `json:"disk_limit"`
+	NetRx         []int64   `json:"net_rx"`
+	NetTx         []int64   `json:"net_tx"`
+	UptimeMs      []int64   `json:"uptime_ms"`
+	Suspended     []bool    `json:"is_suspended"`
+}
+
+// NewCompactSeries converts snapshots to columnar form.
+func NewCompactSeries(snaps []models.ResourceSnapshot) *CompactSeries {
+	n := len(snaps)
+	c := &CompactSeries{
+		ID:            make([]int64, n),
+		Timestamp:     make([]int64, n),
+		PowerState:    make([]string, n),
+		CPUPercent:    make([]float64, n),
+		CPUNormalized: make([]float64, n),
+		MemBytes:      make([]int64, n),
+		MemLimit:      make([]int64, n),
+		DiskBytes:     make([]int64, n),
+		DiskLimit:     make([]int64, n),
+		NetRx:         make([]int64, n),
+		NetTx:         make([]int64, n),
+		UptimeMs:      make([]int64, n),
+		Suspended:     make([]bool, n),
+	}
+	for i, s := range snaps {
+		c.ID[i] = s.ID
+		c.Timestamp[i] = s.Timestamp.UnixMilli()
+		c.PowerState[i] = s.PowerState
+		c.CPUPercent[i] = s.CPUPercent
+		c.CPUNormalized[i] = s.CPUNormalized
+		c.MemBytes[i] = s.MemBytes
+		c.MemLimit[i] = s.MemLimit
+		c.DiskBytes[i] = s.DiskBytes
+		c.DiskLimit[i] = s.DiskLimit
+		c.NetRx[i] = s.NetRx
+		c.NetTx[i] = s.NetTx
+		c.UptimeMs[i] = s.UptimeMs
+		c.Suspended[i] = s.IsSuspended
+	}
+	return c
+}
+
+// Snapshots converts the series back to snapshots (timestamps at millisecond precision).
+func (c *CompactSeries) Snapshots(serverID string) []models.ResourceSnapshot {
+	snaps := make([]models.ResourceSnapshot, len(c.Timestamp))
+	for i := range snaps {
+		snaps[i] = models.ResourceSnapshot{
+			ID:         c.ID[i],
+			ServerID:   serverID,
+			Timestamp:  time.UnixMilli(c.Timestamp[i]),
+			PowerState: c.PowerState[i],
+			CPUPercent: c.CPUPercent[i],
+			MemBytes:   c.MemBytes[i],
+			MemLimit:   c.MemLimit[i],
+			DiskBytes:  c.DiskBytes[i],
+			DiskLimit:  c.DiskLimit[i],
+			NetRx:      c.NetRx[i],
+			NetTx:      c.NetTx[i],
+			UptimeMs:   c.UptimeMs[i],
+		}
+		if i < len(c.Suspended) {
+			snaps[i].IsSuspended = c.Suspended[i]
+		}
+		if i < len(c.CPUNormalized) {
+			snaps[i].CPUNormalized = c.CPUNormalized[i]
+		}
+	}
+	return snaps
+}
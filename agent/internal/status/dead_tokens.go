@@ -0,0 +1,111 @@
+package status
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// DeadToken is a device token a push provider reported as permanently invalid.
+type DeadToken struct {
+	UserUUID   string `json:"user_uuid"`
+	Token      string `json:"token"`
+	ReportedAt string `json:"reported_at"`
+}
+
+// DeadTokenWriter maintains dead_tokens.json so the iOS app can prune tokens from control.json.
+// The agent never edits control.json itself; entries are dropped by Reconcile once the app
+// has removed the token.
+type DeadTokenWriter struct {
+	mu       sync.Mutex
+	filePath string
+	tokens   []DeadToken
+}
+
+// NewDeadTokenWriter creates a dead token writer, keeping any entries already on disk.
+func NewDeadTokenWriter(dataDir string) *DeadTokenWriter {
+	w := &DeadTokenWriter{
+		filePath: filepath.Join(dataDir, "dead_tokens.json"),
+	}
+
+	if data, err := os.ReadFile(w.filePath); err == nil {
+		if err := json.Unmarshal(data, &w.tokens); err != nil {
+			logging.Warn("Ignoring unreadable dead_tokens.json: %v", err)
+			w.tokens = nil
+		}
+	}
+	return w
+}
+
+// Add records a dead token for a user. Already-reported tokens are ignored.
+func (w *DeadTokenWriter) Add(userUUID, token string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, t := range w.tokens {
+		if t.UserUUID == userUUID && t.Token == token {
+			return
+		}
+	}
+
+	w.tokens = append(w.tokens, DeadToken{
+		UserUUID:   userUUID,
+		Token:      token,
+		ReportedAt: time.Now().Format(time.RFC3339),
+	})
+	w.write()
+}
+
+// Reconcile drops entries whose token is no longer listed for its user in control.json,
+// i.e. the app has pruned it.
+func (w *DeadTokenWriter) Reconcile(cf *models.ControlFile) {
+	if cf == nil {
+		return
+	}
+
+	current := make(map[string]bool)
+	for _, u := range cf.Users {
+		for _, t := range u.DeviceTokens {
+			current[u.UserUUID+"|"+t] = true
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	kept := w.tokens[:0]
+	for _, t := range w.tokens {
+		if current[t.UserUUID+"|"+t.Token] {
+			kept = append(kept, t)
+		}
+	}
+	if removed := len(w.tokens) - len(kept); removed > 0 {
+		logging.Info("%d dead push tokens were removed from control.json, clearing them from dead_tokens.json", removed)
+		w.tokens = kept
+		w.write()
+	}
+}
+
+func (w *DeadTokenWriter) write() {
+	data, err := json.MarshalIndent(w.tokens, "", "  ")
+	if err != nil {
+		logging.Error("Failed to marshal dead tokens: %v", err)
+		return
+	}
+
+	// Write to temp file then rename for atomicity
+	tmpPath := w.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		logging.Error("Failed to write dead_tokens.json: %v", err)
+		return
+	}
+
+	if err := os.Rename(tmpPath, w.filePath); err != nil {
+		logging.Error("Failed to rename dead_tokens.json: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package status
+
+import (
+	"time"
+
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// GapPowerState marks a synthetic snapshot inserted where sampling stopped,
+// e.g. while the agent was down. Charts should break the line at it.
+const GapPowerState = "gap"
+
+// markGaps returns snaps (oldest first) with a gap marker after every pair of
+// consecutive snapshots further apart than maxGap. maxGap <= 0 disables marking.
+func markGaps(snaps []models.ResourceSnapshot, maxGap time.Duration) []models.ResourceSnapshot {
+	if maxGap <= 0 || len(snaps) < 2 {
+		return snaps
+	}
+
+	out := make([]models.ResourceSnapshot, 0, len(snaps))
+	for i, snap := range snaps {
+		if i > 0 {
+			prev := snaps[i-1]
+			if snap.Timestamp.Sub(prev.Timestamp) > maxGap {
+				out = append(out, models.ResourceSnapshot{
+					ServerID:   prev.ServerID,
+					Timestamp:  prev.Timestamp.Add(maxGap / 2),
+					PowerState: GapPowerState,
+					MemLimit:   prev.MemLimit,
+					DiskLimit:  prev.DiskLimit,
+				})
+			}
+		}
+		out = append(out, snap)
+	}
+	return out
+}
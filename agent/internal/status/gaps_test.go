@@ -0,0 +1,90 @@
+package status
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/database"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// gappedSeries is a running server sampled every 30s, with the agent down for
+// ten minutes after the third sample.
+func gappedSeries() []models.ResourceSnapshot {
+	start := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	var snaps []models.ResourceSnapshot
+	for _, offset := range []time.Duration{0, 30 * time.Second, time.Minute, 11 * time.Minute, 11*time.Minute + 30*time.Second} {
+		snaps = append(snaps, models.ResourceSnapshot{
+			ServerID:   "srv-1",
+			Timestamp:  start.Add(offset),
+			PowerState: "running",
+			CPUPercent: 20,
+			MemLimit:   1 << 30,
+			DiskLimit:  10 << 30,
+		})
+	}
+	return snaps
+}
+
+func TestMarkGaps(t *testing.T) {
+	snaps := gappedSeries()
+
+	got := markGaps(snaps, time.Minute)
+	if len(got) != len(snaps)+1 {
+		t.Fatalf("%d snapshots, want one gap marker added", len(got))
+	}
+	gap := got[3]
+	if gap.PowerState != GapPowerState || gap.ServerID != "srv-1" || gap.CPUPercent != 0 {
+		t.Fatalf("snapshot 3 = %+v, want a gap marker", gap)
+	}
+	if !gap.Timestamp.After(snaps[2].Timestamp) || !gap.Timestamp.Before(snaps[3].Timestamp) {
+		t.Fatalf("gap marker at %s, want it between %s and %s", gap.Timestamp, snaps[2].Timestamp, snaps[3].Timestamp)
+	}
+	if gap.MemLimit != 1<<30 || gap.DiskLimit != 10<<30 {
+		t.Fatalf("gap marker limits %d/%d, want the previous snapshot's", gap.MemLimit, gap.DiskLimit)
+	}
+	for i, s := range append(got[:3:3], got[4:]...) {
+		if s.PowerState == GapPowerState {
+			t.Fatalf("snapshot %d is an unexpected gap marker", i)
+		}
+	}
+
+	if got := markGaps(snaps, 0); len(got) != len(snaps) {
+		t.Fatalf("gap marking disabled: %d snapshots, want %d", len(got), len(snaps))
+	}
+	if got := markGaps(snaps, 15*time.Minute); len(got) != len(snaps) {
+		t.Fatalf("no gap longer than 15m: %d snapshots, want %d", len(got), len(snaps))
+	}
+}
+
+func TestMetricsExportGapMarkers(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "agent.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InsertSnapshots(gappedSeries()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{MetricsFormatVerbose, MetricsFormatCompact} {
+		dir := t.TempDir()
+		NewMetricsWriter(dir, db, nil, format, false, false, time.Minute).Update([]string{"srv-1"}, 100)
+
+		export, _ := readMetrics(t, dir)
+		snaps := export.Servers["srv-1"]
+		if export.Series != nil {
+			snaps = export.Series["srv-1"].Snapshots("srv-1")
+		}
+		gaps := 0
+		for _, s := range snaps {
+			if s.PowerState == GapPowerState {
+				gaps++
+			}
+		}
+		if len(snaps) != 6 || gaps != 1 {
+			t.Fatalf("%s export: %d snapshots with %d gap markers, want 6 with 1", format, len(snaps), gaps)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/security"
+)
+
+// Health is the result of a liveness check.
+type Health struct {
+	Healthy       bool     `json:"healthy"`
+	LastSampleAt  string   `json:"last_sample_at,omitempty"`
+	MaxAgeSeconds int      `json:"max_age_seconds"`
+	Failed        []string `json:"failed,omitempty"` // descriptions of failed checks
+	Paused        bool     `json:"paused,omitempty"`
+}
+
+// CheckHealth reports the agent healthy if the last completed sample is no older than maxAge.
+// A paused agent is healthy regardless, so orchestrators don't restart it mid-maintenance.
+func CheckHealth(lastSample time.Time, maxAge time.Duration, paused bool) Health {
+	h := Health{
+		Healthy:       true,
+		MaxAgeSeconds: int(maxAge.Seconds()),
+		Paused:        paused,
+	}
+	if paused {
+		return h
+	}
+
+	if lastSample.IsZero() {
+		h.Healthy = false
+		h.Failed = append(h.Failed, "sampling: no sample completed yet")
+		return h
+	}
+
+	h.LastSampleAt = lastSample.Format(time.RFC3339)
+	if age := time.Since(lastSample); age > maxAge {
+		h.Healthy = false
+		h.Failed = append(h.Failed, fmt.Sprintf("sampling: last sample %s ago exceeds %s", age.Round(time.Second), maxAge))
+	}
+	return h
+}
+
+// ReadHealth checks health from the status.json written to dataDir,
+// for use by the healthcheck subcommand outside the running agent process.
+// crypto must be set when the agent encrypts its state files.
+func ReadHealth(dataDir string, maxAge time.Duration, crypto *security.Crypto) (Health, error) {
+	data, err := readStateFile(filepath.Join(dataDir, "status.json"), crypto)
+	if err != nil {
+		return Health{}, fmt.Errorf("read status.json: %w", err)
+	}
+
+	var s AgentStatus
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Health{}, fmt.Errorf("parse status.json: %w", err)
+	}
+
+	lastSample, err := time.Parse(time.RFC3339, s.LastSampleAt)
+	if err != nil {
+		return Health{}, fmt.Errorf("parse last_sample_at: %w", err)
+	}
+	return CheckHealth(lastSample, maxAge, s.Paused), nil
+}
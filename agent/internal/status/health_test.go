@@ -0,0 +1,64 @@
+package status
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckHealth(t *testing.T) {
+	maxAge := 3 * time.Minute
+	tests := []struct {
+		name       string
+		lastSample time.Time
+		paused     bool
+		healthy    bool
+	}{
+		{"fresh", time.Now().Add(-time.Minute), false, true},
+		{"stale", time.Now().Add(-10 * time.Minute), false, false},
+		{"never sampled", time.Time{}, false, false},
+		{"stale but paused", time.Now().Add(-10 * time.Minute), true, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := CheckHealth(tc.lastSample, maxAge, tc.paused)
+			if h.Healthy != tc.healthy {
+				t.Fatalf("healthy = %v, want %v (%+v)", h.Healthy, tc.healthy, h)
+			}
+			if !h.Healthy && len(h.Failed) == 0 {
+				t.Fatal("unhealthy without a failed check")
+			}
+			if h.MaxAgeSeconds != 180 {
+				t.Fatalf("max_age_seconds = %d, want 180", h.MaxAgeSeconds)
+			}
+		})
+	}
+}
+
+func TestReadHealth(t *testing.T) {
+	dir := t.TempDir()
+	w := NewWriter(dir, nil)
+
+	w.Update(AgentStatus{LastSampleAt: time.Now().Add(-30 * time.Second).Format(time.RFC3339)})
+	h, err := ReadHealth(dir, time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Healthy {
+		t.Fatalf("fresh status.json reported unhealthy: %+v", h)
+	}
+
+	w.Update(AgentStatus{LastSampleAt: time.Now().Add(-5 * time.Minute).Format(time.RFC3339)})
+	h, err = ReadHealth(dir, time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Healthy {
+		t.Fatalf("stale status.json reported healthy: %+v", h)
+	}
+}
+
+func TestReadHealthWithoutStatusFile(t *testing.T) {
+	if _, err := ReadHealth(t.TempDir(), time.Minute, nil); err == nil {
+		t.Fatal("want an error when status.json is missing")
+	}
+}
@@ -2,6 +2,7 @@ package status
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -10,12 +11,29 @@ import (
 	"github.com/xyidactyl/agent/internal/database"
 	"github.com/xyidactyl/agent/internal/logging"
 	"github.com/xyidactyl/agent/internal/models"
+	"github.com/xyidactyl/agent/internal/security"
+)
+
+// MetricsSchemaVersion is bumped on incompatible changes to MetricsExport.
+// Files without schema_version predate versioning and match version 1.
+// Version 3 adds gap markers (power_state "gap", see GapPowerState).
+const MetricsSchemaVersion = 3
+
+// Metrics formats: verbose lists snapshot objects under "servers", compact lists
+// columnar series under "series".
+const (
+	MetricsFormatVerbose = "verbose"
+	MetricsFormatCompact = "compact"
 )
 
 // MetricsExport represents the structure of the metrics.json file.
 type MetricsExport struct {
-	GeneratedAt time.Time                            `json:"generated_at"`
-	Servers     map[string][]models.ResourceSnapshot `json:"servers"` // server_id -> snapshots
+	SchemaVersion int                                  `json:"schema_version"`
+	Format        string                               `json:"format"`
+	GeneratedAt   time.Time                            `json:"generated_at"`
+	Servers       map[string][]models.ResourceSnapshot `json:"servers,omitempty"`     // verbose: server_id -> snapshots
+	Series        map[string]*CompactSeries            `json:"series,omitempty"`      // compact: server_id -> columns
+	ServerInfo    map[string]models.ServerInfo         `json:"server_info,omitempty"` // server_id -> name and limits
 }
 
 // MetricsWriter handles exporting recent metrics to a JSON file.
@@ -23,13 +41,34 @@ type MetricsWriter struct {
 	mu       sync.Mutex
 	filePath string
 	db       *database.DB
+	crypto   *security.Crypto // non-nil writes metrics.json.enc instead
+	format   string           // MetricsFormatVerbose or MetricsFormatCompact
+	gzip     bool             // write metrics.json.gz instead
+	gapAfter time.Duration    // mark a gap between snapshots further apart than this, 0 = off
+
+	// Per-server mode writes metrics/<server_id>.json, skipping unchanged servers
+	perServer bool
+	hashes    map[string][32]byte // server_id -> hash of the last written content
 }
 
-// NewMetricsWriter creates a new metrics writer.
-func NewMetricsWriter(dataDir string, db *database.DB) *MetricsWriter {
+// NewMetricsWriter creates a new metrics writer. crypto is optional; see writeStateFile.
+// With gzipped set the file is written as metrics.json.gz, which the app must decompress;
+// if crypto is also set, the compressed bytes are encrypted, so decrypt first.
+// perServer selects one file per server under metrics/ instead of a single metrics.json.
+// gapAfter is the longest expected spacing between stored snapshots; see markGaps.
+func NewMetricsWriter(dataDir string, db *database.DB, crypto *security.Crypto, format string, gzipped, perServer bool, gapAfter time.Duration) *MetricsWriter {
+	if format != MetricsFormatCompact {
+		format = MetricsFormatVerbose
+	}
 	return &MetricsWriter{
-		filePath: filepath.Join(dataDir, "metrics.json"),
-		db:       db,
+		filePath:  filepath.Join(dataDir, "metrics.json"),
+		db:        db,
+		crypto:    crypto,
+		format:    format,
+		gzip:      gzipped,
+		perServer: perServer,
+		gapAfter:  gapAfter,
+		hashes:    make(map[string][32]byte),
 	}
 }
 
@@ -39,9 +78,20 @@ func (w *MetricsWriter) Update(serverIDs []string, limit int) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	if w.perServer {
+		w.updatePerServer(serverIDs, limit)
+		return
+	}
+
 	export := MetricsExport{
-		GeneratedAt: time.Now(),
-		Servers:     make(map[string][]models.ResourceSnapshot),
+		SchemaVersion: MetricsSchemaVersion,
+		Format:        w.format,
+		GeneratedAt:   time.Now(),
+	}
+	if w.format == MetricsFormatCompact {
+		export.Series = make(map[string]*CompactSeries)
+	} else {
+		export.Servers = make(map[string][]models.ResourceSnapshot)
 	}
 
 	for _, id := range serverIDs {
@@ -50,8 +100,19 @@ func (w *MetricsWriter) Update(serverIDs []string, limit int) {
 			logging.Warn("Failed to get recent snapshots for %s: %v", id, err)
 			continue
 		}
-		export.Servers[id] = snaps
+		snaps = markGaps(snaps, w.gapAfter)
+		if export.Series != nil {
+			export.Series[id] = NewCompactSeries(snaps)
+		} else {
+			export.Servers[id] = snaps
+		}
+	}
+
+	info, err := w.db.GetServerInfo(serverIDs)
+	if err != nil {
+		logging.Warn("Failed to get server info: %v", err)
 	}
+	export.ServerInfo = info
 
 	data, err := json.Marshal(export)
 	if err != nil {
@@ -59,13 +120,35 @@ func (w *MetricsWriter) Update(serverIDs []string, limit int) {
 		return
 	}
 
-	// atomic write
-	tmpPath := w.filePath + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+	if err := w.writeExport(w.filePath, data); err != nil {
 		logging.Error("Failed to write metrics.json: %v", err)
-		return
 	}
-	if err := os.Rename(tmpPath, w.filePath); err != nil {
-		logging.Error("Failed to rename metrics.json: %v", err)
+}
+
+// writeExport writes a metrics file, compressing it first in gzip mode.
+func (w *MetricsWriter) writeExport(path string, data []byte) error {
+	if !w.gzip {
+		return writeStateFile(path, data, w.crypto)
+	}
+
+	data, err := gzipBytes(data)
+	if err != nil {
+		return fmt.Errorf("compress: %w", err)
+	}
+	// An uncompressed copy from before would otherwise go stale next to the .gz
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logging.Warn("Failed to remove old %s: %v", filepath.Base(path), err)
+	}
+	return writeStateFile(path+".gz", data, w.crypto)
+}
+
+// exportPath returns the name writeExport actually writes for path.
+func (w *MetricsWriter) exportPath(path string) string {
+	if w.gzip {
+		path += ".gz"
+	}
+	if w.crypto != nil {
+		path += encryptedSuffix
 	}
+	return path
 }
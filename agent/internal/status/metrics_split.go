@@ -0,0 +1,107 @@
+package status
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// ServerMetricsExport is the structure of metrics/<server_id>.json in per-server mode.
+type ServerMetricsExport struct {
+	SchemaVersion int                       `json:"schema_version"`
+	Format        string                    `json:"format"`
+	GeneratedAt   time.Time                 `json:"generated_at"`
+	ServerID      string                    `json:"server_id"`
+	Snapshots     []models.ResourceSnapshot `json:"snapshots,omitempty"` // verbose
+	Series        *CompactSeries            `json:"series,omitempty"`    // compact
+	Info          *models.ServerInfo        `json:"info,omitempty"`
+}
+
+// updatePerServer writes one file per server, skipping servers whose data is unchanged
+// since the last write, and removes files of servers no longer monitored.
+func (w *MetricsWriter) updatePerServer(serverIDs []string, limit int) {
+	dir := filepath.Join(filepath.Dir(w.filePath), "metrics")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logging.Error("Failed to create metrics directory: %v", err)
+		return
+	}
+
+	info, err := w.db.GetServerInfo(serverIDs)
+	if err != nil {
+		logging.Warn("Failed to get server info: %v", err)
+	}
+
+	keep := make(map[string]bool, len(serverIDs))
+	written := 0
+	for _, id := range serverIDs {
+		if id == "" || id != filepath.Base(id) || id == "." || id == ".." {
+			logging.Warn("Server ID %q is not a valid file name, skipping its metrics file", id)
+			continue
+		}
+		keep[id] = true
+
+		snaps, err := w.db.GetRecentSnapshots(id, limit)
+		if err != nil {
+			logging.Warn("Failed to get recent snapshots for %s: %v", id, err)
+			continue
+		}
+		snaps = markGaps(snaps, w.gapAfter)
+
+		export := ServerMetricsExport{
+			SchemaVersion: MetricsSchemaVersion,
+			Format:        w.format,
+			ServerID:      id,
+		}
+		if w.format == MetricsFormatCompact {
+			export.Series = NewCompactSeries(snaps)
+		} else {
+			export.Snapshots = snaps
+		}
+		if si, ok := info[id]; ok {
+			export.Info = &si
+		}
+
+		// Hash without generated_at so an unchanged server is not rewritten
+		content, err := json.Marshal(export)
+		if err != nil {
+			logging.Error("Failed to marshal metrics for %s: %v", id, err)
+			continue
+		}
+		sum := sha256.Sum256(content)
+		if prev, ok := w.hashes[id]; ok && prev == sum {
+			continue
+		}
+
+		export.GeneratedAt = time.Now()
+		data, err := json.Marshal(export)
+		if err != nil {
+			logging.Error("Failed to marshal metrics for %s: %v", id, err)
+			continue
+		}
+		if err := w.writeExport(filepath.Join(dir, id+".json"), data); err != nil {
+			logging.Error("Failed to write metrics for %s: %v", id, err)
+			continue
+		}
+		w.hashes[id] = sum
+		written++
+	}
+
+	for id := range w.hashes {
+		if keep[id] {
+			continue
+		}
+		path := w.exportPath(filepath.Join(dir, id+".json"))
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logging.Warn("Failed to remove metrics for %s: %v", id, err)
+			continue
+		}
+		delete(w.hashes, id)
+	}
+
+	logging.Debug("Metrics export: %d of %d server files rewritten", written, len(keep))
+}
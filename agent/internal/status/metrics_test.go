@@ -0,0 +1,204 @@
+package status
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/database"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// seededMetricsDB opens a database holding n varied snapshots for each server.
+func seededMetricsDB(t *testing.T, n int, serverIDs ...string) *database.DB {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "agent.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	start := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	var snaps []models.ResourceSnapshot
+	for _, id := range serverIDs {
+		for i := 0; i < n; i++ {
+			snaps = append(snaps, models.ResourceSnapshot{
+				ServerID:      id,
+				Timestamp:     start.Add(time.Duration(i) * 30 * time.Second),
+				PowerState:    []string{"running", "starting", "offline"}[i%3],
+				CPUPercent:    float64(i) * 1.5,
+				CPUNormalized: float64(i) * 0.75,
+				MemBytes:      int64(i) << 20,
+				MemLimit:      1 << 30,
+				DiskBytes:     int64(i) << 24,
+				DiskLimit:     10 << 30,
+				NetRx:         int64(i * 1000),
+				NetTx:         int64(i * 500),
+				UptimeMs:      int64(i * 30000),
+				IsSuspended:   i%7 == 0,
+			})
+		}
+	}
+	if err := db.InsertSnapshots(snaps); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// readMetrics parses the metrics.json written to dir.
+func readMetrics(t *testing.T, dir string) (MetricsExport, int) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "metrics.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var export MetricsExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		t.Fatal(err)
+	}
+	return export, len(data)
+}
+
+// utc normalizes snapshot timestamps so decoded series compare equal.
+func utc(snaps []models.ResourceSnapshot) []models.ResourceSnapshot {
+	for i := range snaps {
+		snaps[i].Timestamp = snaps[i].Timestamp.UTC()
+	}
+	return snaps
+}
+
+func TestCompactAndVerboseMetricsMatch(t *testing.T) {
+	db := seededMetricsDB(t, 200, "srv-1", "srv-2")
+	verboseDir, compactDir := t.TempDir(), t.TempDir()
+	NewMetricsWriter(verboseDir, db, nil, "", false, false, 0).Update([]string{"srv-1", "srv-2"}, 120)
+	NewMetricsWriter(compactDir, db, nil, MetricsFormatCompact, false, false, 0).Update([]string{"srv-1", "srv-2"}, 120)
+
+	verbose, verboseSize := readMetrics(t, verboseDir)
+	compact, compactSize := readMetrics(t, compactDir)
+	for _, export := range []MetricsExport{verbose, compact} {
+		if export.SchemaVersion != MetricsSchemaVersion {
+			t.Fatalf("schema_version = %d, want %d", export.SchemaVersion, MetricsSchemaVersion)
+		}
+	}
+	if verbose.Format != MetricsFormatVerbose || verbose.Series != nil {
+		t.Fatalf("default export is not verbose: format %q", verbose.Format)
+	}
+	if compact.Format != MetricsFormatCompact || compact.Servers != nil {
+		t.Fatalf("compact export format %q", compact.Format)
+	}
+
+	for _, id := range []string{"srv-1", "srv-2"} {
+		want := utc(verbose.Servers[id])
+		if len(want) != 120 {
+			t.Fatalf("%s: %d verbose snapshots, want 120", id, len(want))
+		}
+		if got := utc(compact.Series[id].Snapshots(id)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: compact decodes to\n%+v\nwant\n%+v", id, got[0], want[0])
+		}
+	}
+	if compactSize >= verboseSize/2 {
+		t.Fatalf("compact metrics.json is %d bytes, verbose %d; want under half", compactSize, verboseSize)
+	}
+}
+
+func TestCompactSeriesReadsOlderFiles(t *testing.T) {
+	// Series written before is_suspended and cpu_percent_normalized existed lack them
+	var c CompactSeries
+	if err := json.Unmarshal([]byte(`{"id":[1],"timestamp":[1767614400000],"power_state":["running"],"cpu_percent":[50],
+		"mem_bytes":[1],"mem_limit":[2],"disk_bytes":[3],"disk_limit":[4],"net_rx":[5],"net_tx":[6],"uptime_ms":[7]}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	snaps := c.Snapshots("srv-1")
+	if len(snaps) != 1 || snaps[0].CPUPercent != 50 || snaps[0].IsSuspended || snaps[0].CPUNormalized != 0 {
+		t.Fatalf("snapshots = %+v", snaps)
+	}
+}
+
+func TestGzipMetricsRoundTrip(t *testing.T) {
+	db := seededMetricsDB(t, 50, "srv-1")
+	dir := t.TempDir()
+	// An uncompressed file from before METRICS_GZIP was turned on is removed
+	NewMetricsWriter(dir, db, nil, "", false, false, 0).Update([]string{"srv-1"}, 40)
+	plain, _ := readMetrics(t, dir)
+
+	NewMetricsWriter(dir, db, nil, "", true, false, 0).Update([]string{"srv-1"}, 40)
+	if _, err := os.Stat(filepath.Join(dir, "metrics.json")); !os.IsNotExist(err) {
+		t.Fatalf("stale metrics.json next to metrics.json.gz: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "metrics.json.gz.tmp")); !os.IsNotExist(err) {
+		t.Fatalf("temp file left behind: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "metrics.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var export MetricsExport
+	if err := json.NewDecoder(zr).Decode(&export); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := utc(export.Servers["srv-1"]), utc(plain.Servers["srv-1"]); len(got) != 40 || !reflect.DeepEqual(got, want) {
+		t.Fatalf("decompressed %d snapshots, want the 40 written uncompressed", len(got))
+	}
+}
+
+func TestPerServerMetricsRewriteOnlyChanged(t *testing.T) {
+	db := seededMetricsDB(t, 10, "srv-1", "srv-2", "srv-3")
+	dir := t.TempDir()
+	w := NewMetricsWriter(dir, db, nil, "", false, true, 0)
+	path := func(id string) string { return filepath.Join(dir, "metrics", id+".json") }
+
+	w.Update([]string{"srv-1", "srv-2", "srv-3", "../escape"}, 20)
+	for _, id := range []string{"srv-1", "srv-2", "srv-3"} {
+		data, err := os.ReadFile(path(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var export ServerMetricsExport
+		if err := json.Unmarshal(data, &export); err != nil {
+			t.Fatal(err)
+		}
+		if export.ServerID != id || len(export.Snapshots) != 10 {
+			t.Fatalf("%s: server_id %q with %d snapshots", id, export.ServerID, len(export.Snapshots))
+		}
+		// Mark each file so a rewrite is visible
+		if err := os.WriteFile(path(id), []byte("unchanged"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.json")); !os.IsNotExist(err) {
+		t.Fatal("a server ID escaped the metrics directory")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "metrics.json")); !os.IsNotExist(err) {
+		t.Fatal("per-server mode wrote metrics.json")
+	}
+
+	// Only srv-2 gets a new sample; srv-3 is no longer monitored
+	if err := db.InsertSnapshots([]models.ResourceSnapshot{{
+		ServerID:   "srv-2",
+		Timestamp:  time.Date(2026, 1, 5, 13, 0, 0, 0, time.UTC),
+		PowerState: "running",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	w.Update([]string{"srv-1", "srv-2"}, 20)
+
+	if data, _ := os.ReadFile(path("srv-1")); string(data) != "unchanged" {
+		t.Error("srv-1 was rewritten without new data")
+	}
+	if data, _ := os.ReadFile(path("srv-2")); string(data) == "unchanged" {
+		t.Error("srv-2 was not rewritten after a new sample")
+	}
+	if _, err := os.Stat(path("srv-3")); !os.IsNotExist(err) {
+		t.Errorf("metrics for the unmonitored srv-3 were kept: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package status
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+
+	"github.com/xyidactyl/agent/internal/security"
+)
+
+// encryptedSuffix is appended to state files written with ENCRYPT_STATE_FILES.
+const encryptedSuffix = ".enc"
+
+// writeStateFile atomically writes a state file for the app. With crypto set, the data is
+// encrypted to path+".enc" and any plaintext copy left from before is removed.
+func writeStateFile(path string, data []byte, crypto *security.Crypto) error {
+	if crypto != nil {
+		enc, err := crypto.EncryptBytes(data)
+		if err != nil {
+			return fmt.Errorf("encrypt: %w", err)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove plaintext copy: %w", err)
+		}
+		path, data = path+encryptedSuffix, enc
+	}
+
+	// Write to temp file then rename for atomicity
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// readStateFile reads a state file written by writeStateFile.
+func readStateFile(path string, crypto *security.Crypto) ([]byte, error) {
+	if crypto == nil {
+		return os.ReadFile(path)
+	}
+
+	enc, err := os.ReadFile(path + encryptedSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.DecryptBytes(enc)
+}
+
+// gzipBytes compresses data with gzip.
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
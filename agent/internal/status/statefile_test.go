@@ -0,0 +1,117 @@
+package status
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/database"
+	"github.com/xyidactyl/agent/internal/security"
+)
+
+func testCrypto(t *testing.T) *security.Crypto {
+	t.Helper()
+	c, err := security.NewCrypto("status-test-secret-0123456789", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEncryptedStatusRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	crypto := testCrypto(t)
+
+	// A plaintext status.json from before encryption was enabled is removed
+	NewWriter(dir, nil).Update(AgentStatus{AgentVersion: "old"})
+
+	want := AgentStatus{
+		AgentVersion:     "1.2.3",
+		LastSampleAt:     time.Now().UTC().Format(time.RFC3339),
+		ServersMonitored: 2,
+		ServerNames:      map[string]string{"srv-1": "survival"},
+		OfflineServers:   []OfflineServer{{ServerID: "srv-2", PowerState: "offline"}},
+	}
+	NewWriter(dir, crypto).Update(want)
+
+	if _, err := os.Stat(filepath.Join(dir, "status.json")); !os.IsNotExist(err) {
+		t.Fatalf("plaintext status.json still present: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "status.json.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("survival")) {
+		t.Fatal("status.json.enc contains plaintext")
+	}
+
+	data, err := crypto.DecryptBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got AgentStatus
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("decrypted status = %+v, want %+v", got, want)
+	}
+
+	if h, err := ReadHealth(dir, time.Minute, crypto); err != nil || !h.Healthy {
+		t.Fatalf("ReadHealth with crypto = %+v, %v", h, err)
+	}
+	if _, err := ReadHealth(dir, time.Minute, nil); err == nil {
+		t.Fatal("ReadHealth without crypto read an encrypted status file")
+	}
+	other, err := security.NewCrypto("another-secret-0123456789", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadHealth(dir, time.Minute, other); err == nil {
+		t.Fatal("status.json.enc decrypted with a different secret")
+	}
+}
+
+func TestEncryptedGzipMetrics(t *testing.T) {
+	dir := t.TempDir()
+	crypto := testCrypto(t)
+	db, err := database.Open(filepath.Join(dir, "agent.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	NewMetricsWriter(dir, db, crypto, MetricsFormatVerbose, true, false, 0).Update([]string{"srv-1"}, 10)
+
+	raw, err := os.ReadFile(filepath.Join(dir, "metrics.json.gz.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The app decrypts first, then decompresses
+	compressed, err := crypto.DecryptBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var export MetricsExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		t.Fatal(err)
+	}
+	if export.SchemaVersion != MetricsSchemaVersion || !strings.Contains(string(data), `"srv-1"`) {
+		t.Fatalf("decrypted metrics = %s", data)
+	}
+}
@@ -0,0 +1,138 @@
+package status
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+// PruneEntry is a device token the control plane should remove from control.json.
+type PruneEntry struct {
+	UserUUID  string `json:"user_uuid"`
+	Token     string `json:"token"`
+	FirstSeen string `json:"first_seen"` // when a provider first reported the token invalid
+}
+
+// TokenReconciler accumulates the (user, token) pairs push providers report as invalid and
+// writes them to token_prune.json for the control plane, which owns control.json and prunes
+// them there. The agent never edits control.json; an entry is cleared once its token is no
+// longer listed for its user.
+type TokenReconciler struct {
+	mu       sync.Mutex
+	filePath string
+	clock    clock.Clock
+	entries  map[string]PruneEntry // user_uuid|token -> entry
+	dirty    bool                  // entries changed since the last write
+}
+
+// NewTokenReconciler creates a reconciler, keeping any entries already on disk.
+func NewTokenReconciler(dataDir string, clk clock.Clock) *TokenReconciler {
+	r := &TokenReconciler{
+		filePath: filepath.Join(dataDir, "token_prune.json"),
+		clock:    clock.OrReal(clk),
+		entries:  make(map[string]PruneEntry),
+	}
+
+	if data, err := os.ReadFile(r.filePath); err == nil {
+		var entries []PruneEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			logging.Warn("Ignoring unreadable token_prune.json: %v", err)
+		}
+		for _, e := range entries {
+			r.entries[e.UserUUID+"|"+e.Token] = e
+		}
+	}
+	return r
+}
+
+// Add records an invalid token. The first report's time is kept; the file is written on
+// the next Reconcile.
+func (r *TokenReconciler) Add(userUUID, token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := userUUID + "|" + token
+	if _, ok := r.entries[key]; ok {
+		return
+	}
+	r.entries[key] = PruneEntry{
+		UserUUID:  userUUID,
+		Token:     token,
+		FirstSeen: r.clock.Now().UTC().Format(time.RFC3339),
+	}
+	r.dirty = true
+}
+
+// Reconcile clears entries whose token is gone from control.json and writes
+// token_prune.json if anything changed. It runs once per sampling cycle.
+func (r *TokenReconciler) Reconcile(cf *models.ControlFile) {
+	if cf == nil {
+		return
+	}
+
+	current := make(map[string]bool)
+	for _, u := range cf.Users {
+		for _, t := range u.DeviceTokens {
+			current[u.UserUUID+"|"+t] = true
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cleared := 0
+	for key := range r.entries {
+		if !current[key] {
+			delete(r.entries, key)
+			cleared++
+		}
+	}
+	if cleared > 0 {
+		logging.Info("%d pruned push tokens are gone from control.json, clearing them from token_prune.json", cleared)
+		r.dirty = true
+	}
+	if r.dirty {
+		r.write()
+	}
+}
+
+// Entries returns the pending entries, oldest first.
+func (r *TokenReconciler) Entries() []PruneEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sorted()
+}
+
+func (r *TokenReconciler) sorted() []PruneEntry {
+	out := make([]PruneEntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].FirstSeen != out[j].FirstSeen {
+			return out[i].FirstSeen < out[j].FirstSeen
+		}
+		return out[i].UserUUID+out[i].Token < out[j].UserUUID+out[j].Token
+	})
+	return out
+}
+
+func (r *TokenReconciler) write() {
+	data, err := json.MarshalIndent(r.sorted(), "", "  ")
+	if err != nil {
+		logging.Error("Failed to marshal token_prune.json: %v", err)
+		return
+	}
+	if err := writeStateFile(r.filePath, data, nil); err != nil {
+		logging.Error("Failed to write token_prune.json: %v", err)
+		return
+	}
+	r.dirty = false
+}
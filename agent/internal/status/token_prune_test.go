@@ -0,0 +1,100 @@
+package status
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xyidactyl/agent/internal/clock"
+	"github.com/xyidactyl/agent/internal/models"
+)
+
+func controlWithTokens(tokens map[string][]string) *models.ControlFile {
+	cf := &models.ControlFile{Version: 1}
+	for user, t := range tokens {
+		cf.Users = append(cf.Users, models.ControlUser{UserUUID: user, DeviceTokens: t})
+	}
+	return cf
+}
+
+func readPruneFile(t *testing.T, dir string) []PruneEntry {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "token_prune.json"))
+	if err != nil {
+		t.Fatalf("read token_prune.json: %v", err)
+	}
+	var entries []PruneEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("parse token_prune.json: %v", err)
+	}
+	return entries
+}
+
+func TestTokenReconcilerAccumulates(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	r := NewTokenReconciler(dir, clk)
+	cf := controlWithTokens(map[string][]string{"u1": {"a", "b"}, "u2": {"c"}})
+
+	r.Add("u1", "a")
+	clk.Advance(time.Minute)
+	r.Add("u2", "c")
+	clk.Advance(time.Minute)
+	r.Add("u1", "a") // reported again: first-seen is kept
+
+	if _, err := os.Stat(filepath.Join(dir, "token_prune.json")); !os.IsNotExist(err) {
+		t.Fatal("token_prune.json written before Reconcile")
+	}
+	r.Reconcile(cf)
+
+	entries := readPruneFile(t, dir)
+	if len(entries) != 2 {
+		t.Fatalf("%d entries, want 2: %+v", len(entries), entries)
+	}
+	if entries[0].UserUUID != "u1" || entries[0].Token != "a" || entries[0].FirstSeen != start.Format(time.RFC3339) {
+		t.Errorf("first entry %+v, want u1/a first seen %s", entries[0], start.Format(time.RFC3339))
+	}
+	if entries[1].Token != "c" || entries[1].FirstSeen != start.Add(time.Minute).Format(time.RFC3339) {
+		t.Errorf("second entry %+v", entries[1])
+	}
+	if _, err := os.Stat(filepath.Join(dir, "token_prune.json.tmp")); !os.IsNotExist(err) {
+		t.Error("temp file left behind")
+	}
+}
+
+func TestTokenReconcilerClears(t *testing.T) {
+	dir := t.TempDir()
+	r := NewTokenReconciler(dir, clock.NewFake(time.Unix(0, 0)))
+	r.Add("u1", "a")
+	r.Add("u1", "b")
+	r.Add("u2", "c")
+	r.Reconcile(controlWithTokens(map[string][]string{"u1": {"a", "b"}, "u2": {"c"}}))
+
+	// The control plane pruned u1/a and removed u2 entirely
+	r.Reconcile(controlWithTokens(map[string][]string{"u1": {"b"}}))
+	entries := readPruneFile(t, dir)
+	if len(entries) != 1 || entries[0].Token != "b" {
+		t.Fatalf("entries after pruning: %+v, want only u1/b", entries)
+	}
+
+	r.Reconcile(controlWithTokens(map[string][]string{"u1": {}}))
+	if entries := readPruneFile(t, dir); len(entries) != 0 {
+		t.Fatalf("entries after all tokens pruned: %+v", entries)
+	}
+}
+
+func TestTokenReconcilerKeepsEntriesAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	r := NewTokenReconciler(dir, clock.NewFake(time.Unix(0, 0)))
+	r.Add("u1", "a")
+	r.Reconcile(controlWithTokens(map[string][]string{"u1": {"a"}}))
+
+	restarted := NewTokenReconciler(dir, nil)
+	got := restarted.Entries()
+	if len(got) != 1 || got[0].Token != "a" || got[0].FirstSeen != time.Unix(0, 0).UTC().Format(time.RFC3339) {
+		t.Fatalf("entries after restart: %+v", got)
+	}
+}
@@ -2,37 +2,84 @@ package status
 
 import (
 	"encoding/json"
-	"os"
 	"path/filepath"
 	"sync"
 
 	"github.com/xyidactyl/agent/internal/logging"
+	"github.com/xyidactyl/agent/internal/pterodactyl"
+	"github.com/xyidactyl/agent/internal/security"
 )
 
 // AgentStatus represents the agent's health data written to status.json.
 type AgentStatus struct {
-	AgentVersion      string   `json:"agent_version"`
-	UptimeSeconds     int64    `json:"uptime_seconds"`
-	LastSampleAt      string   `json:"last_sample_at"`
-	ControlVersion    int      `json:"control_version"`
-	UsersCount        int      `json:"users_count"`
-	ActiveAlerts      int      `json:"active_alerts"`
-	ActiveAutomations int      `json:"active_automations"`
-	ServersMonitored  int      `json:"servers_monitored"`
-	DBSizeBytes       int64    `json:"db_size_bytes,omitempty"`
-	Errors            []string `json:"errors,omitempty"`
+	AgentVersion       string                      `json:"agent_version"`
+	UptimeSeconds      int64                       `json:"uptime_seconds"`
+	LastSampleAt       string                      `json:"last_sample_at"`
+	ControlVersion     int                         `json:"control_version"`
+	UsersCount         int                         `json:"users_count"`
+	ActiveAlerts       int                         `json:"active_alerts"`
+	ActiveAutomations  int                         `json:"active_automations"`
+	ServersMonitored   int                         `json:"servers_monitored"`
+	DBSizeBytes        int64                       `json:"db_size_bytes,omitempty"`
+	Errors             []string                    `json:"errors,omitempty"`
+	ActiveSnoozes      []Snooze                    `json:"active_snoozes,omitempty"`
+	ControlError       string                      `json:"control_error,omitempty"` // why the latest control.json was rejected
+	AccessIssues       []AccessIssue               `json:"access_issues,omitempty"`
+	AutomationsEnabled bool                        `json:"automations_enabled"`
+	Paused             bool                        `json:"paused"`
+	OfflineServers     []OfflineServer             `json:"offline_servers,omitempty"`
+	ServerNames        map[string]string           `json:"server_names,omitempty"` // server_id -> panel name
+	PanelCalls         []pterodactyl.EndpointStats `json:"panel_calls,omitempty"`
+	LastTestPush       *TestPush                   `json:"last_test_push,omitempty"`
+}
+
+// TestPush is the outcome of the latest test notification sent through the API.
+type TestPush struct {
+	UserUUID string          `json:"user_uuid"`
+	Provider string          `json:"provider"`
+	SentAt   string          `json:"sent_at"`
+	Tokens   []TestPushToken `json:"tokens"`
+}
+
+// TestPushToken is the delivery result for one device token (truncated).
+type TestPushToken struct {
+	Token string `json:"token"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// OfflineServer is a monitored server that was offline or stopped on its last sample.
+type OfflineServer struct {
+	ServerID   string `json:"server_id"`
+	PowerState string `json:"power_state"`
+	Since      string `json:"since"` // when it went offline, possibly before the agent started
+	LastSeenAt string `json:"last_seen_at"`
+}
+
+// AccessIssue lists allowed servers a user's API key cannot see.
+type AccessIssue struct {
+	UserUUID  string   `json:"user_uuid"`
+	ServerIDs []string `json:"server_ids"`
+}
+
+// Snooze describes a user whose notifications are currently muted.
+type Snooze struct {
+	UserUUID string `json:"user_uuid"`
+	Until    int64  `json:"until"` // unix seconds
 }
 
 // Writer writes status.json to the data directory for the iOS app to read.
 type Writer struct {
 	mu       sync.Mutex
 	filePath string
+	crypto   *security.Crypto // non-nil writes status.json.enc instead
 }
 
-// NewWriter creates a new status writer.
-func NewWriter(dataDir string) *Writer {
+// NewWriter creates a new status writer. crypto is optional; see writeStateFile.
+func NewWriter(dataDir string, crypto *security.Crypto) *Writer {
 	return &Writer{
 		filePath: filepath.Join(dataDir, "status.json"),
+		crypto:   crypto,
 	}
 }
 
@@ -47,14 +94,7 @@ func (w *Writer) Update(s AgentStatus) {
 		return
 	}
 
-	// Write to temp file then rename for atomicity
-	tmpPath := w.filePath + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+	if err := writeStateFile(w.filePath, data, w.crypto); err != nil {
 		logging.Error("Failed to write status.json: %v", err)
-		return
-	}
-
-	if err := os.Rename(tmpPath, w.filePath); err != nil {
-		logging.Error("Failed to rename status.json: %v", err)
 	}
 }